@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+// agentRunRequest is the body of POST /run
+type agentRunRequest struct {
+	Args     []string `json:"args"`
+	Protocol int      `json:"protocol"` // Agent protocol of the coordinator
+}
+
+// agentRunResponse is returned by POST /run
+type agentRunResponse struct {
+	Region  string      `json:"region"`
+	Summary *RunSummary `json:"summary,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// agentCmd represents the agent command
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Run a benchmark agent that executes workloads on request",
+	Long: `Run a benchmark agent: an HTTP server that executes run workloads on behalf of a
+coordinator (see the matrix command) and returns their summary.
+
+Deploy one agent per region to measure latency from where the application actually runs.
+Workloads are executed one at a time; concurrent requests are rejected with 409.
+
+API:
+  POST /run      {"args": ["--cache-type", "redis", "--test-time", "30"], "protocol": 1} -> run summary
+  GET  /healthz  -> 200 with the agent region
+  GET  /version  -> agent protocol, tool version, OS and architecture
+  POST /update   new agent binary (with the update token, over TLS) -> 202, then the agent restarts on it
+
+Runs are refused (412) when the coordinator uses another agent protocol, so a fleet never
+returns results in mismatched formats. The matrix command checks versions before starting
+and can push the right binary to outdated agents (--agent-binary).
+
+The API is not authenticated, so workloads can't use the flags that run shell commands on
+the agent host (--pre-hook, --post-hook): requests using them are refused.
+
+Self-update is enabled by an update token, read from --update-token-file or from the
+AGENT_UPDATE_TOKEN environment variable; it is not accepted as an argument, where other
+users of the host could read it. As the token lets its holder run any binary on the host,
+updates also require the agent to serve TLS (--tls-cert, --tls-key).
+
+Invalid workload options, failed setup steps and runs aborted by --max-rss are returned to
+the coordinator (400) and the agent keeps serving.
+
+Examples:
+  # Start an agent in us-east-1
+  serverless-cache-benchmark agent --listen :8080 --region us-east-1
+
+  # Accept binary updates over HTTPS from coordinators holding the token
+  serverless-cache-benchmark agent --listen :8443 --region us-east-1 --update-token-file /etc/cache-agent/update-token \
+    --tls-cert /etc/cache-agent/agent.crt --tls-key /etc/cache-agent/agent.key`,
+	Run: runAgent,
+}
+
+func runAgent(cmd *cobra.Command, args []string) {
+	listen, _ := cmd.Flags().GetString("listen")
+	region, _ := cmd.Flags().GetString("region")
+	updateTokenFile, _ := cmd.Flags().GetString("update-token-file")
+	tlsCert, _ := cmd.Flags().GetString("tls-cert")
+	tlsKey, _ := cmd.Flags().GetString("tls-key")
+
+	if region == "" {
+		log.Fatalf("Agent region is required (--region)")
+	}
+	if (tlsCert == "") != (tlsKey == "") {
+		log.Fatalf("--tls-cert and --tls-key must be given together")
+	}
+	serveTLS := tlsCert != ""
+	updateToken, err := readAgentUpdateToken(updateTokenFile)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if updateToken != "" && !serveTLS {
+		log.Printf("Warning: self-update is disabled: it requires TLS (--tls-cert, --tls-key)")
+	}
+
+	var mutex sync.Mutex
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, region)
+	})
+
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(agentVersion(region, updateToken, serveTLS))
+	})
+
+	mux.HandleFunc("/update", func(w http.ResponseWriter, r *http.Request) {
+		handleAgentUpdate(w, r, region, updateToken, &mutex)
+	})
+
+	mux.HandleFunc("/run", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var request agentRunRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeAgentResponse(w, http.StatusBadRequest, agentRunResponse{Region: region, Error: err.Error()})
+			return
+		}
+		if request.Protocol != agentProtocolVersion {
+			writeAgentResponse(w, http.StatusPreconditionFailed, agentRunResponse{Region: region,
+				Error: fmt.Sprintf("incompatible coordinator: protocol %d, agent protocol %d (version %s)", request.Protocol, agentProtocolVersion, currentBuildInfo())})
+			return
+		}
+
+		if !mutex.TryLock() {
+			writeAgentResponse(w, http.StatusConflict, agentRunResponse{Region: region, Error: "a workload is already running"})
+			return
+		}
+		defer mutex.Unlock()
+
+		log.Printf("Running workload: %v", request.Args)
+		summary, err := RunRemoteWithArgs(request.Args)
+		if err != nil {
+			writeAgentResponse(w, http.StatusBadRequest, agentRunResponse{Region: region, Error: err.Error()})
+			return
+		}
+		if summary == nil {
+			writeAgentResponse(w, http.StatusBadRequest, agentRunResponse{Region: region, Error: "workload did not produce a summary"})
+			return
+		}
+		writeAgentResponse(w, http.StatusOK, agentRunResponse{Region: region, Summary: summary})
+	})
+
+	fmt.Printf("Benchmark agent %s (protocol %d) for region %s listening on %s\n", currentBuildInfo(), agentProtocolVersion, region, listen)
+	if serveTLS {
+		log.Fatal(http.ListenAndServeTLS(listen, tlsCert, tlsKey, mux))
+	}
+	log.Fatal(http.ListenAndServe(listen, mux))
+}
+
+func writeAgentResponse(w http.ResponseWriter, status int, response agentRunResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+
+	agentCmd.Flags().String("listen", ":8080", "Address to listen on")
+	agentCmd.Flags().String("region", "", "Region label reported with every result (e.g. us-east-1)")
+	agentCmd.Flags().String("tls-cert", "", "Serve the API over HTTPS with this certificate (PEM), with --tls-key; required for self-update")
+	agentCmd.Flags().String("tls-key", "", "Private key (PEM) of --tls-cert")
+	agentCmd.Flags().String("update-token-file", "", "File holding the token coordinators must present to push new agent binaries (POST /update); defaults to $AGENT_UPDATE_TOKEN, disabled when neither is set")
+}
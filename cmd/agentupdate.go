@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// agentProtocolVersion is the version of the agent API and of the summaries it returns;
+// bump it on changes a coordinator of another version would misread
+const agentProtocolVersion = 1
+
+// Agent self-update limits
+const (
+	agentMaxBinarySize   = 512 << 20
+	agentRestartTimeout  = 60 * time.Second
+	agentRestartPollWait = time.Second
+)
+
+// agentUpdateTokenEnv holds the update token when no token file is given. The token is
+// never taken from the command line, where ps and the restarted agent's arguments show it.
+const agentUpdateTokenEnv = "AGENT_UPDATE_TOKEN"
+
+// agentVersionResponse is returned by GET /version
+type agentVersionResponse struct {
+	Region    string     `json:"region"`
+	Protocol  int        `json:"protocol"`
+	Build     *BuildInfo `json:"build"`
+	OS        string     `json:"os"`
+	Arch      string     `json:"arch"`
+	Updatable bool       `json:"updatable"` // Accepts POST /update (started with an update token and TLS)
+}
+
+// readAgentUpdateToken reads the update token from file, or from AGENT_UPDATE_TOKEN when
+// file is empty; surrounding whitespace is ignored
+func readAgentUpdateToken(file string) (string, error) {
+	if file == "" {
+		return strings.TrimSpace(os.Getenv(agentUpdateTokenEnv)), nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read update token: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("update token file %s is empty", file)
+	}
+	return token, nil
+}
+
+// agentVersion describes the running agent
+func agentVersion(region, updateToken string, serveTLS bool) agentVersionResponse {
+	return agentVersionResponse{
+		Region:    region,
+		Protocol:  agentProtocolVersion,
+		Build:     currentBuildInfo(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Updatable: updateToken != "" && serveTLS && runtime.GOOS != "windows",
+	}
+}
+
+// handleAgentUpdate replaces the agent binary with the request body and restarts the agent
+// with the same arguments. The body must match the X-Binary-SHA256 header, and the request
+// must carry the agent's update token over TLS: in clear, whoever sees the token once can
+// run any binary on the agent. Updates are refused while a workload runs.
+func handleAgentUpdate(w http.ResponseWriter, r *http.Request, region, updateToken string, busy *sync.Mutex) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if updateToken == "" || runtime.GOOS == "windows" {
+		writeAgentResponse(w, http.StatusForbidden, agentRunResponse{Region: region, Error: "self-update is disabled (start the agent with an update token; not supported on Windows)"})
+		return
+	}
+	if r.TLS == nil {
+		writeAgentResponse(w, http.StatusForbidden, agentRunResponse{Region: region, Error: "self-update requires the agent to serve TLS (--tls-cert, --tls-key)"})
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(updateToken)) != 1 {
+		writeAgentResponse(w, http.StatusUnauthorized, agentRunResponse{Region: region, Error: "invalid update token"})
+		return
+	}
+	if !busy.TryLock() {
+		writeAgentResponse(w, http.StatusConflict, agentRunResponse{Region: region, Error: "a workload is running"})
+		return
+	}
+	// The lock is kept: the process is replaced below, or released on failure
+
+	path, err := replaceAgentBinary(r.Body, r.Header.Get("X-Binary-SHA256"))
+	if err != nil {
+		busy.Unlock()
+		writeAgentResponse(w, http.StatusBadRequest, agentRunResponse{Region: region, Error: err.Error()})
+		return
+	}
+
+	writeAgentResponse(w, http.StatusAccepted, agentRunResponse{Region: region})
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond) // Let the response reach the coordinator
+		log.Printf("Agent binary updated, restarting: %s", path)
+		if err := reexec(path); err != nil {
+			log.Fatalf("Failed to restart the updated agent: %v", err)
+		}
+	}()
+}
+
+// replaceAgentBinary writes a new binary next to the running one, checks its digest and
+// atomically moves it in place; it returns the path of the executable
+func replaceAgentBinary(body io.Reader, digest string) (string, error) {
+	if digest == "" {
+		return "", fmt.Errorf("missing X-Binary-SHA256 header")
+	}
+	path, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the agent binary: %w", err)
+	}
+	if path, err = filepath.EvalSymlinks(path); err != nil {
+		return "", fmt.Errorf("failed to locate the agent binary: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".update-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to write the new binary: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(body, agentMaxBinarySize+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write the new binary: %w", err)
+	}
+	if n > agentMaxBinarySize {
+		return "", fmt.Errorf("binary exceeds %d MB", agentMaxBinarySize>>20)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(sum, digest) {
+		return "", fmt.Errorf("binary digest %s does not match X-Binary-SHA256 %s", sum, digest)
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to replace the agent binary: %w", err)
+	}
+	return path, nil
+}
+
+// getAgentVersion asks an agent for its version; agents predating GET /version report protocol 0
+func getAgentVersion(client *http.Client, agentURL string) (*agentVersionResponse, error) {
+	resp, err := client.Get(strings.TrimRight(agentURL, "/") + "/version")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return &agentVersionResponse{}, nil
+	}
+	var version agentVersionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return nil, fmt.Errorf("invalid version response (status %d): %w", resp.StatusCode, err)
+	}
+	return &version, nil
+}
+
+// agentIncompatibility explains why an agent can't run for this coordinator ("" when it can).
+// mode is protocol (same agent protocol) or exact (also the same tool version and commit).
+func agentIncompatibility(version *agentVersionResponse, mode string) string {
+	if version.Protocol != agentProtocolVersion {
+		return fmt.Sprintf("agent protocol %d, coordinator protocol %d", version.Protocol, agentProtocolVersion)
+	}
+	if mode == "exact" {
+		local := currentBuildInfo()
+		if version.Build == nil || version.Build.Version != local.Version || version.Build.Commit != local.Commit {
+			remote := "unknown"
+			if version.Build != nil {
+				remote = version.Build.String()
+			}
+			return fmt.Sprintf("agent version %s, coordinator version %s", remote, local)
+		}
+	}
+	return ""
+}
+
+// agentHTTPClient is the coordinator's client for agents, trusting the certificates in
+// caFile (PEM) on top of the system roots when set, e.g. for self-signed agent certificates
+func agentHTTPClient(timeout time.Duration, caFile string) (*http.Client, error) {
+	client := &http.Client{Timeout: timeout}
+	if caFile == "" {
+		return client, nil
+	}
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agent CA: %w", err)
+	}
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if !roots.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates in agent CA file %s", caFile)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+	client.Transport = transport
+	return client, nil
+}
+
+// pushAgentBinary uploads a binary to an agent and waits for it to come back compatible.
+// The update token is only sent over HTTPS.
+func pushAgentBinary(client *http.Client, agentURL, binary, token, mode string) error {
+	if !strings.HasPrefix(strings.ToLower(agentURL), "https://") {
+		return fmt.Errorf("agent updates require an https:// agent URL, not to send the update token in clear")
+	}
+	data, err := os.ReadFile(binary)
+	if err != nil {
+		return fmt.Errorf("failed to read agent binary: %w", err)
+	}
+	sum := sha256.Sum256(data)
+
+	request, err := http.NewRequest(http.MethodPost, strings.TrimRight(agentURL, "/")+"/update", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/octet-stream")
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("X-Binary-SHA256", hex.EncodeToString(sum[:]))
+	resp, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		var response agentRunResponse
+		json.NewDecoder(resp.Body).Decode(&response)
+		return fmt.Errorf("update rejected (status %d): %s", resp.StatusCode, response.Error)
+	}
+
+	deadline := time.Now().Add(agentRestartTimeout)
+	problem := "agent did not restart"
+	for time.Now().Before(deadline) {
+		time.Sleep(agentRestartPollWait)
+		version, err := getAgentVersion(client, agentURL)
+		if err != nil {
+			continue // Restarting
+		}
+		if problem = agentIncompatibility(version, mode); problem == "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("still incompatible after the update: %s", problem)
+}
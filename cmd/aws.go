@@ -0,0 +1,65 @@
+//go:build !noaws
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registerBuildFeature("aws", "cache-type lambda, CloudWatch, S3/DynamoDB reports, Secrets Manager/SSM passwords, IAM preflight")
+
+	// Shared by all AWS-backed engines
+	for _, cmd := range []*cobra.Command{populateCmd, runCmd} {
+		cmd.Flags().String("aws-region", "", "AWS region for AWS-backed targets (default: from AWS config/environment)")
+		cmd.Flags().String("aws-role-arn", "", "IAM role to assume for all AWS calls (e.g. the cache's workload account)")
+	}
+}
+
+// awsRoleSessionName identifies benchmark sessions in CloudTrail of the assumed role's account
+const awsRoleSessionName = "serverless-cache-benchmark"
+
+// loadAWSConfig loads the default AWS configuration (env, shared config, instance role),
+// optionally overriding the region. With a role ARN, calls are made with credentials of
+// that role, assumed (and refreshed) with the default credentials.
+func loadAWSConfig(ctx context.Context, region, roleARN string) (aws.Config, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	if roleARN != "" {
+		if _, err := arn.Parse(roleARN); err != nil {
+			return aws.Config{}, fmt.Errorf("invalid role ARN %s: %w", roleARN, err)
+		}
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = awsRoleSessionName
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+	return cfg, nil
+}
+
+// awsRoleARN returns the role for an integration: its own role flag, falling back to --aws-role-arn
+func awsRoleARN(cmd *cobra.Command, roleFlag string) string {
+	if roleFlag != "" {
+		if roleARN, _ := cmd.Flags().GetString(roleFlag); roleARN != "" {
+			return roleARN
+		}
+	}
+	roleARN, _ := cmd.Flags().GetString("aws-role-arn")
+	return roleARN
+}
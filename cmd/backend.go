@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Backend describes a cache engine selectable with --engine (or --cache-type)
+type Backend struct {
+	Name string
+
+	// AddFlags registers the engine's own options on a command (optional).
+	// It is called once for populate and once for run.
+	AddFlags func(cmd *cobra.Command)
+
+	// New creates a client from the command's flags. Each engine owns its
+	// connection pooling and authentication configuration.
+	New func(ctx context.Context, cmd *cobra.Command) (CacheClient, error)
+
+	// Permissions lists the IAM actions an AWS engine calls, verified by the
+	// permissions preflight before populate and run (optional).
+	Permissions func(cmd *cobra.Command) []AWSPermission
+}
+
+var backends = make(map[string]*Backend)
+
+// RegisterBackend makes a cache engine available to populate and run.
+// Backends register themselves from init() in their own file.
+func RegisterBackend(b *Backend) {
+	if _, exists := backends[b.Name]; exists {
+		panic(fmt.Sprintf("cache backend %s registered twice", b.Name))
+	}
+	backends[b.Name] = b
+
+	if b.AddFlags != nil {
+		b.AddFlags(populateCmd)
+		b.AddFlags(runCmd)
+	}
+}
+
+// lookupBackend returns the registered engine with the given name
+func lookupBackend(name string) (*Backend, error) {
+	b, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("invalid cache type: %s. Must be one of: %s", name, strings.Join(backendNames(), ", "))
+	}
+	return b, nil
+}
+
+// backendNames returns the registered engine names in alphabetical order
+func backendNames() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getCacheType returns the engine selected with --engine, falling back to --cache-type
+func getCacheType(cmd *cobra.Command) string {
+	if engine, _ := cmd.Flags().GetString("engine"); engine != "" {
+		return engine
+	}
+	cacheType, _ := cmd.Flags().GetString("cache-type")
+	return cacheType
+}
+
+// newBackendClient creates a client for the named engine
+func newBackendClient(ctx context.Context, name string, cmd *cobra.Command) (CacheClient, error) {
+	b, err := lookupBackend(name)
+	if err != nil {
+		return nil, err
+	}
+	return b.New(ctx, cmd)
+}
+
+// newEndpointClient creates a client for a second endpoint given as a URI (mirror, shard
+// nodes): redis[s]://, memcached[s]://host:port[,host:port] or http[s]://, with the
+// run's connection settings and the credentials of the URI itself
+func newEndpointClient(cmd *cobra.Command, endpoint string, clusterMode bool, idleConns int) (CacheClient, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint '%s': expected redis[s]://, memcached[s]:// or http[s]://", redactURIs(endpoint))
+	}
+
+	switch parsed.Scheme {
+	case "redis", "rediss":
+		config, err := redisConfigFromFlags(cmd)
+		if err != nil {
+			return nil, err
+		}
+		config.Username, config.Password = "", ""
+		config.OverrideDB, config.Reauthenticate = false, false
+		config.ClusterMode = clusterMode
+		return NewRedisClientFromURI(endpoint, config)
+
+	case "memcached", "memcacheds":
+		skipVerify, _ := cmd.Flags().GetBool("memcached-tls-skip-verify")
+		return NewMemcachedClient(MemcachedConfig{
+			Servers:      strings.Split(parsed.Host, ","),
+			TLS:          parsed.Scheme == "memcacheds",
+			SkipVerify:   skipVerify,
+			Timeout:      memcachedSocketTimeout(cmd),
+			MaxIdleConns: idleConns,
+		})
+
+	case "http", "https":
+		config, err := httpConfigFromFlags(cmd)
+		if err != nil {
+			return nil, err
+		}
+		config.URL = strings.TrimRight(endpoint, "/")
+		return NewHTTPClient(config)
+	}
+	return nil, fmt.Errorf("unsupported endpoint scheme '%s': expected redis[s], memcached[s] or http[s]", parsed.Scheme)
+}
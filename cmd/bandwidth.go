@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Pipeliner is implemented by cache clients that can batch operations in one round trip
+// (Redis pipelines); both calls return the value bytes transferred
+type Pipeliner interface {
+	PipelineGet(ctx context.Context, keys []string) (int64, error)
+	PipelineSet(ctx context.Context, keys []string, value []byte, expiration time.Duration) (int64, error)
+}
+
+// bandwidthStep is the result of running a number of clients for one step
+type bandwidthStep struct {
+	Clients  int
+	Duration time.Duration
+	Batches  int64 // Successful pipelined batches
+	Errors   int64 // Failed batches
+	Bytes    int64 // Value bytes transferred by successful batches
+	NICBytes uint64
+	Latency  *PerformanceStats // Per batch
+}
+
+// MBps is the value throughput of the step in MB/s
+func (s *bandwidthStep) MBps() float64 {
+	return float64(atomic.LoadInt64(&s.Bytes)) / s.Duration.Seconds() / (1024 * 1024)
+}
+
+// ErrorRate is the percentage of batches that failed
+func (s *bandwidthStep) ErrorRate() float64 {
+	batches := atomic.LoadInt64(&s.Batches)
+	errors := atomic.LoadInt64(&s.Errors)
+	if batches+errors == 0 {
+		return 0
+	}
+	return float64(errors) / float64(batches+errors) * 100
+}
+
+// bandwidthCmd represents the bandwidth command
+var bandwidthCmd = &cobra.Command{
+	Use:   "bandwidth",
+	Short: "Find the sustained bytes/s ceiling of a cache with large values and pipelining",
+	Long: `Find the sustained bandwidth ceiling of a cache: rather than maximizing operations per
+second, large values are read (or written) in pipelined batches by a growing number of
+clients, doubling every step, to measure the data-plane throughput limit in bytes/s.
+
+The search stops at the first step whose batch error rate exceeds --max-error-rate or whose
+batch P99 exceeds --latency-collapse times the first step's (the throughput collapses), or
+once two steps in a row fail to improve the best throughput by --min-gain percent (the
+throughput has plateaued). The ceiling is the best step before that.
+
+The client NIC throughput is reported for every step: when it is close to the instance's
+network limit, the client rather than the cache is the bottleneck.
+
+Examples:
+  # Read 1MB values in batches of 8 from 1 up to 128 clients
+  serverless-cache-benchmark bandwidth --redis-uri rediss://my-cache.serverless.use1.cache.amazonaws.com:6379 \
+    --cluster-mode --value-size 1048576 --pipeline 8 --max-clients 128
+
+  # Write bandwidth with 256KB values, 30 seconds per step
+  serverless-cache-benchmark bandwidth --direction write --value-size 262144 --step-time 30s`,
+	Run: runBandwidth,
+}
+
+func runBandwidth(cmd *cobra.Command, args []string) {
+	uri, _ := cmd.Flags().GetString("redis-uri")
+	clusterMode, _ := cmd.Flags().GetBool("cluster-mode")
+	direction, _ := cmd.Flags().GetString("direction")
+	valueSize, _ := cmd.Flags().GetInt("value-size")
+	pipeline, _ := cmd.Flags().GetInt("pipeline")
+	keyCount, _ := cmd.Flags().GetInt("keys")
+	keyPrefix, _ := cmd.Flags().GetString("key-prefix")
+	startClients, _ := cmd.Flags().GetInt("start-clients")
+	maxClients, _ := cmd.Flags().GetInt("max-clients")
+	stepTime, _ := cmd.Flags().GetDuration("step-time")
+	maxErrorRate, _ := cmd.Flags().GetFloat64("max-error-rate")
+	latencyCollapse, _ := cmd.Flags().GetFloat64("latency-collapse")
+	minGain, _ := cmd.Flags().GetFloat64("min-gain")
+	cleanup, _ := cmd.Flags().GetBool("cleanup")
+
+	if direction != "read" && direction != "write" {
+		log.Fatalf("Invalid --direction '%s': expected read or write", direction)
+	}
+	if valueSize <= 0 || pipeline <= 0 || keyCount <= 0 {
+		log.Fatalf("Value size, pipeline depth and keys must be positive")
+	}
+	if startClients <= 0 || maxClients < startClients {
+		log.Fatalf("Start clients must be positive and not above max clients")
+	}
+	if stepTime <= 0 {
+		log.Fatalf("Step time must be positive")
+	}
+	if latencyCollapse <= 1 {
+		log.Fatalf("Latency collapse factor must be above 1, got: %.2f", latencyCollapse)
+	}
+
+	password, err := resolvePasswordFrom(cmd)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	config := RedisConfig{
+		DialTimeout:  10 * time.Second,
+		ReadTimeout:  30 * time.Second, // Large pipelined batches take a while to transfer
+		WriteTimeout: 30 * time.Second,
+		PoolTimeout:  30 * time.Second,
+		MaxRetries:   0, // A retried batch would hide the errors that mark the ceiling
+		ClusterMode:  clusterMode,
+		Password:     password,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		fmt.Println("\nReceived interrupt signal. Stopping and printing summary...")
+		cancel()
+	}()
+
+	// Every worker has its own client, so workers never queue for a pooled connection
+	var clients []*RedisClient
+	defer func() {
+		for _, client := range clients {
+			client.Close()
+		}
+	}()
+	connect := func() *RedisClient {
+		client, err := NewRedisClientFromURI(uri, config)
+		if err != nil {
+			log.Fatalf("Failed to create client: %v", err)
+		}
+		pingCtx, pingCancel := context.WithTimeout(ctx, 10*time.Second)
+		defer pingCancel()
+		if err := client.Ping(pingCtx); err != nil {
+			log.Fatalf("Failed to connect: %v", err)
+		}
+		clients = append(clients, client)
+		return client
+	}
+	connect()
+
+	generator := &DataGenerator{DataSize: valueSize, RandomData: true}
+	value, err := generator.GenerateData()
+	if err != nil {
+		log.Fatalf("Failed to generate value: %v", err)
+	}
+	keys := make([]string, keyCount)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%sbandwidth-%d", keyPrefix, i)
+	}
+
+	fmt.Printf("Bandwidth search: %s %d-byte values in batches of %d over %d keys\n", direction, valueSize, pipeline, keyCount)
+	fmt.Printf("Clients: %d doubling up to %d, %s per step\n", startClients, maxClients, stepTime)
+	fmt.Printf("Stop: error rate over %.1f%%, batch P99 over %.1fx the first step's, or under %.0f%% gain twice\n",
+		maxErrorRate, latencyCollapse, minGain)
+
+	// Reads need the keys to exist; writes overwrite them
+	if direction == "read" {
+		fmt.Printf("Writing %d keys...\n", keyCount)
+		for i := 0; i < len(keys); i += pipeline {
+			batch := keys[i:min(i+pipeline, len(keys))]
+			if _, err := clients[0].PipelineSet(ctx, batch, value, time.Hour); err != nil {
+				log.Fatalf("Failed to write keys: %v", err)
+			}
+		}
+	}
+	if cleanup {
+		defer func() {
+			deleteCtx, deleteCancel := context.WithTimeout(context.Background(), time.Minute)
+			defer deleteCancel()
+			for _, key := range keys {
+				clients[0].Delete(deleteCtx, key)
+			}
+		}()
+	}
+	fmt.Println()
+
+	var steps []*bandwidthStep
+	var best *bandwidthStep
+	var baselineP99 int64
+	stopReason := "reached --max-clients"
+	flat := 0
+	for n := startClients; ctx.Err() == nil; n = min(n*2, maxClients) {
+		for len(clients) < n {
+			connect()
+		}
+		step := runBandwidthStep(ctx, clients[:n], direction, keys, pipeline, value, stepTime)
+		steps = append(steps, step)
+		p99 := step.Latency.Histogram.ValueAtQuantile(99)
+		fmt.Printf("%4d clients: %8.1f MB/s, %6.2f%% errors, batch P99 %d μs\n", n, step.MBps(), step.ErrorRate(), p99)
+
+		if baselineP99 == 0 {
+			baselineP99 = p99
+		}
+		if step.ErrorRate() > maxErrorRate {
+			stopReason = fmt.Sprintf("error rate %.2f%% at %d clients", step.ErrorRate(), n)
+			break
+		}
+		if baselineP99 > 0 && float64(p99) > latencyCollapse*float64(baselineP99) {
+			stopReason = fmt.Sprintf("batch P99 %.1fx the first step's at %d clients", float64(p99)/float64(baselineP99), n)
+			break
+		}
+		if best == nil || step.MBps() > best.MBps()*(1+minGain/100) {
+			best = step
+			flat = 0
+		} else if flat++; flat >= 2 {
+			stopReason = fmt.Sprintf("throughput plateaued from %d clients", best.Clients)
+			break
+		}
+		if n == maxClients {
+			break
+		}
+	}
+	if ctx.Err() != nil {
+		stopReason = "interrupted"
+	}
+
+	printBandwidthResults(steps, best, stopReason, valueSize, pipeline)
+	for _, step := range steps {
+		step.Latency.Close()
+	}
+}
+
+// runBandwidthStep runs the clients for one step, each issuing pipelined batches back to back
+func runBandwidthStep(ctx context.Context, clients []*RedisClient, direction string, keys []string,
+	pipeline int, value []byte, duration time.Duration) *bandwidthStep {
+
+	step := &bandwidthStep{Clients: len(clients), Latency: NewPerformanceStats()}
+	stepCtx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	nicStart := readNetworkStats()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func(worker int, client *RedisClient) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+			batch := make([]string, pipeline)
+			for stepCtx.Err() == nil {
+				for j := range batch {
+					batch[j] = keys[rng.Intn(len(keys))]
+				}
+				opStart := time.Now()
+				var bytes int64
+				var err error
+				if direction == "write" {
+					bytes, err = client.PipelineSet(stepCtx, batch, value, time.Hour)
+				} else {
+					bytes, err = client.PipelineGet(stepCtx, batch)
+				}
+				if err != nil {
+					// Batches cut short by the end of the step are not failures
+					if stepCtx.Err() == nil {
+						atomic.AddInt64(&step.Errors, 1)
+					}
+					continue
+				}
+				step.Latency.RecordLatency(time.Since(opStart).Microseconds())
+				atomic.AddInt64(&step.Batches, 1)
+				atomic.AddInt64(&step.Bytes, bytes)
+			}
+		}(i, client)
+	}
+	wg.Wait()
+	step.Duration = time.Since(start)
+
+	if nicEnd := readNetworkStats(); nicStart != nil && nicEnd != nil {
+		step.NICBytes = (nicEnd.RxBytes - nicStart.RxBytes) + (nicEnd.TxBytes - nicStart.TxBytes)
+	}
+	// Collect the last batches before the step is reported
+	step.Latency.Flush()
+	return step
+}
+
+// printBandwidthResults prints the steps of the search and the ceiling found
+func printBandwidthResults(steps []*bandwidthStep, best *bandwidthStep, stopReason string, valueSize, pipeline int) {
+	fmt.Println("\n" + strings.Repeat("=", 90))
+	fmt.Println("BANDWIDTH RESULTS")
+	fmt.Println(strings.Repeat("=", 90))
+	fmt.Printf("%-8s %-10s %-10s %-12s %-12s %-10s %-12s %-12s\n",
+		"Clients", "MB/s", "Gbit/s", "NIC MB/s", "Values/s", "Errors", "Batch P50", "Batch P99")
+	for _, step := range steps {
+		seconds := step.Duration.Seconds()
+		_, _, _, _, p50, _, p99 := step.Latency.GetStats()
+		fmt.Printf("%-8d %-10.1f %-10.2f %-12.1f %-12.0f %-10s %-12d %-12d\n",
+			step.Clients, step.MBps(), step.MBps()*8*1024*1024/1e9, float64(step.NICBytes)/seconds/(1024*1024),
+			float64(atomic.LoadInt64(&step.Bytes))/float64(valueSize)/seconds, fmt.Sprintf("%.2f%%", step.ErrorRate()), p50, p99)
+	}
+	fmt.Println("(batch latencies in μs; NIC is all client interfaces, both directions)")
+	fmt.Println()
+
+	if best == nil {
+		fmt.Printf("No sustained throughput measured (stopped: %s)\n", stopReason)
+	} else {
+		fmt.Printf("Sustained throughput ceiling: %.1f MB/s (%.2f Gbit/s) with %d clients x pipeline %d of %d-byte values\n",
+			best.MBps(), best.MBps()*8*1024*1024/1e9, best.Clients, pipeline, valueSize)
+		fmt.Printf("Stopped: %s\n", stopReason)
+	}
+	fmt.Println(strings.Repeat("=", 90))
+}
+
+func init() {
+	rootCmd.AddCommand(bandwidthCmd)
+
+	bandwidthCmd.Flags().StringP("redis-uri", "u", "redis://localhost:6379", "Redis URI")
+	bandwidthCmd.Flags().Bool("cluster-mode", false, "Run client in cluster mode")
+	bandwidthCmd.Flags().String("direction", "read", "Measure read (GET) or write (SET) bandwidth")
+	bandwidthCmd.Flags().Int("value-size", 1024*1024, "Value size in bytes")
+	bandwidthCmd.Flags().Int("pipeline", 8, "Operations per pipelined batch")
+	bandwidthCmd.Flags().Int("keys", 100, "Number of distinct keys batches draw from")
+	bandwidthCmd.Flags().String("key-prefix", "memtier-", "Prefix for keys")
+	bandwidthCmd.Flags().Int("start-clients", 1, "Clients of the first step")
+	bandwidthCmd.Flags().Int("max-clients", 256, "Clients of the last step; clients double every step")
+	bandwidthCmd.Flags().Duration("step-time", 10*time.Second, "Duration of each step")
+	bandwidthCmd.Flags().Float64("max-error-rate", 1, "Stop when more than this percentage of batches fail")
+	bandwidthCmd.Flags().Float64("latency-collapse", 5, "Stop when the batch P99 exceeds this multiple of the first step's")
+	bandwidthCmd.Flags().Float64("min-gain", 5, "Stop when two steps in a row improve the best throughput by less than this percentage")
+	bandwidthCmd.Flags().Bool("cleanup", true, "Delete the keys at the end")
+}
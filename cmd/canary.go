@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	runCmd.Flags().Bool("canary", false, "Latency canary for permanent use against production caches: a low constant rate (--canary-rate) from one client until interrupted, with low-memory stats; alerts come from --notify-webhook with the --slo-* thresholds")
+	runCmd.Flags().Int("canary-rate", 5, "Operations per second of --canary")
+}
+
+// canaryTestTime stands for "until interrupted" in canary mode (about 68 years)
+const canaryTestTime = math.MaxInt32
+
+// applyCanaryDefaults switches the run to canary mode when --canary is set, filling in the
+// flags that weren't given explicitly: one client at --canary-rate over a small keyspace,
+// bounded stats memory and no end time. It reports whether canary mode is on.
+func applyCanaryDefaults(cmd *cobra.Command) (bool, error) {
+	if enabled, _ := cmd.Flags().GetBool("canary"); !enabled {
+		return false, nil
+	}
+	rate, _ := cmd.Flags().GetInt("canary-rate")
+	if rate <= 0 {
+		return false, fmt.Errorf("canary rate must be positive, got: %d", rate)
+	}
+	// The canary keeps a constant rate; shaped loads also keep per-phase stats forever
+	for _, name := range []string{"traffic-pattern", "rate", "ramp", "step", "sine"} {
+		if cmd.Flags().Changed(name) {
+			return false, fmt.Errorf("--%s cannot be combined with --canary", name)
+		}
+	}
+
+	defaults := []struct{ name, value string }{
+		{"clients", "1"},
+		{"rps", strconv.Itoa(rate)},
+		{"test-time", strconv.Itoa(canaryTestTime)},
+		{"key-maximum", "1000"},
+		{"stats-lowmem", "true"},
+	}
+	for _, d := range defaults {
+		if cmd.Flags().Changed(d.name) {
+			continue
+		}
+		if err := cmd.Flags().Set(d.name, d.value); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Incrementer is implemented by cache clients with an atomic counter increment (INCR on
+// Redis); chains fall back to a GET, increment, SET cycle on other clients
+type Incrementer interface {
+	Incr(ctx context.Context, key string) (int64, error)
+}
+
+// incrementKey increments a counter key, with INCR where the client has it and a GET,
+// increment, SET cycle otherwise
+func incrementKey(ctx context.Context, client CacheClient, key string, expiration time.Duration) error {
+	if incrementer, ok := client.(Incrementer); ok {
+		_, err := incrementer.Incr(ctx, key)
+		return err
+	}
+	value, err := client.Get(ctx, key)
+	if errors.Is(err, ErrCacheMiss) {
+		value, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	updated, err := incrementCounter(value)
+	if err != nil {
+		return err
+	}
+	return client.Set(ctx, key, updated, expiration)
+}
+
+// ChainStep is one operation of a chain. Key may contain {key}, replaced with the key ID
+// drawn for the chain; it is always prefixed with --key-prefix. If makes the step
+// conditional on the outcome of the chain's most recent GET ("hit" or "miss").
+type ChainStep struct {
+	Op  string `json:"op"` // get, set, delete or incr
+	Key string `json:"key"`
+	If  string `json:"if,omitempty"`
+}
+
+// Chain is a small script of dependent operations measured end-to-end as one logical
+// transaction, e.g. cache-aside: GET a, SET a if it missed, then INCR a counter
+type Chain struct {
+	Name   string      `json:"name"`
+	Weight float64     `json:"weight,omitempty"` // Relative frequency among chains (default 1)
+	Steps  []ChainStep `json:"steps"`
+}
+
+// ChainSet is the chains of a --chains file
+type ChainSet struct {
+	Chains      []*Chain `json:"chains"`
+	KeyPrefix   string   `json:"-"` // --key-prefix of the run
+	totalWeight float64
+}
+
+// LoadChainSet reads and validates a --chains file
+func LoadChainSet(path, keyPrefix string) (*ChainSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chains: %w", err)
+	}
+	set := ChainSet{KeyPrefix: keyPrefix}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse chains: %w", err)
+	}
+	if len(set.Chains) == 0 {
+		return nil, fmt.Errorf("no chains defined in %s", path)
+	}
+
+	for i, chain := range set.Chains {
+		if chain.Name == "" {
+			chain.Name = fmt.Sprintf("chain-%d", i+1)
+		}
+		if chain.Weight == 0 {
+			chain.Weight = 1
+		}
+		if chain.Weight < 0 {
+			return nil, fmt.Errorf("chain %s: weight must be positive", chain.Name)
+		}
+		if len(chain.Steps) == 0 {
+			return nil, fmt.Errorf("chain %s has no steps", chain.Name)
+		}
+		sawGet := false
+		for j, step := range chain.Steps {
+			switch step.Op {
+			case "get", "set", "delete", "incr":
+			default:
+				return nil, fmt.Errorf("chain %s step %d: unknown op '%s' (must be get, set, delete or incr)", chain.Name, j+1, step.Op)
+			}
+			if step.Key == "" {
+				return nil, fmt.Errorf("chain %s step %d: key is required", chain.Name, j+1)
+			}
+			switch step.If {
+			case "":
+			case "hit", "miss":
+				if !sawGet {
+					return nil, fmt.Errorf("chain %s step %d: '%s' condition needs an earlier get step", chain.Name, j+1, step.If)
+				}
+			default:
+				return nil, fmt.Errorf("chain %s step %d: unknown condition '%s' (must be hit or miss)", chain.Name, j+1, step.If)
+			}
+			if step.Op == "get" {
+				sawGet = true
+			}
+		}
+		set.totalWeight += chain.Weight
+	}
+	return &set, nil
+}
+
+// Pick returns the index of a chain drawn by weight
+func (cs *ChainSet) Pick(rng *rand.Rand) int {
+	target := rng.Float64() * cs.totalWeight
+	for i, chain := range cs.Chains {
+		target -= chain.Weight
+		if target < 0 {
+			return i
+		}
+	}
+	return len(cs.Chains) - 1
+}
+
+// chainKey resolves a step's key for the key ID drawn for the chain
+func chainKey(keyPrefix, key string, keyID int) string {
+	return keyPrefix + strings.ReplaceAll(key, "{key}", keyFormat.Codec.Encode(keyID))
+}
+
+// processChain runs the steps of a chain, skipping steps whose condition doesn't hold;
+// the first failing step aborts the chain. A chain that fails after some of its steps
+// succeeded is a partial failure: its earlier writes are left applied.
+func processChain(ctx context.Context, request requestInfo, client CacheClient, generator *DataGenerator,
+	chains *ChainSet, timeoutSeconds int, verbose bool) workloadResult {
+	chain := chains.Chains[request.chainIndex]
+
+	// The timeout covers the whole chain, as it is one logical transaction
+	opCtx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+	defer cancel()
+
+	// Generate data BEFORE timing the chain
+	values := make([][]byte, len(chain.Steps))
+	for i, step := range chain.Steps {
+		if step.Op == "set" {
+			data, err := generator.GenerateData()
+			if err != nil {
+				return workloadResult{isChain: true, chainIndex: request.chainIndex, isError: true, failedStep: -1}
+			}
+			values[i] = data
+		}
+	}
+	expiration := generator.GetExpiration()
+
+	var steps, failedStep int
+	hit := false
+	start := time.Now()
+	var err error
+	for i, step := range chain.Steps {
+		if (step.If == "hit" && !hit) || (step.If == "miss" && hit) {
+			continue
+		}
+		key := chainKey(chains.KeyPrefix, step.Key, request.keyID)
+		switch step.Op {
+		case "get":
+			_, err = client.Get(opCtx, key)
+			hit = err == nil
+			if errors.Is(err, ErrCacheMiss) {
+				err = nil
+			}
+		case "set":
+			err = client.Set(opCtx, key, values[i], expiration)
+		case "delete":
+			err = client.Delete(opCtx, key)
+		case "incr":
+			err = incrementKey(opCtx, client, key, expiration)
+		}
+		if err != nil {
+			failedStep = i
+			break
+		}
+		steps++
+	}
+	latency := time.Since(start)
+
+	if err != nil {
+		if verbose {
+			log.Printf("Worker %d: chain %s failed at step %d after %d succeeded: %v", request.workerID, chain.Name, failedStep+1, steps, err)
+		}
+		return workloadResult{isChain: true, chainIndex: request.chainIndex, isError: true, noPerm: isNoPermError(err),
+			timeout: classifyTimeout(err), chainSteps: steps, failedStep: failedStep}
+	}
+	return workloadResult{isChain: true, chainIndex: request.chainIndex, chainSteps: steps, latencyMicros: latency.Microseconds()}
+}
+
+// ChainResult tracks the executions of one chain
+type ChainResult struct {
+	Ops             int64 // Chains whose steps all succeeded
+	PartialFailures int64 // Chains that failed after at least one step succeeded
+	FullFailures    int64 // Chains that failed before any step succeeded
+	Steps           int64 // Steps executed by successful chains (conditional steps may be skipped)
+	StepFailures    []int64
+	Stats           *PerformanceStats
+}
+
+// Runs returns the number of completed chain executions, successful or not
+func (cr *ChainResult) Runs() int64 {
+	return atomic.LoadInt64(&cr.Ops) + atomic.LoadInt64(&cr.PartialFailures) + atomic.LoadInt64(&cr.FullFailures)
+}
+
+// ChainStats tracks the chains of a run, each with its own end-to-end histogram
+type ChainStats struct {
+	Set     *ChainSet
+	Ratio   float64
+	Results []*ChainResult
+}
+
+func NewChainStats(set *ChainSet, ratio float64) *ChainStats {
+	cs := &ChainStats{Set: set, Ratio: ratio}
+	for _, chain := range set.Chains {
+		cs.Results = append(cs.Results, &ChainResult{
+			StepFailures: make([]int64, len(chain.Steps)),
+			Stats:        NewPerformanceStats(),
+		})
+	}
+	return cs
+}
+
+func (cs *ChainStats) Close() {
+	for _, result := range cs.Results {
+		result.Stats.Close()
+	}
+}
+
+// recordChainResult records the outcome of a chain
+func recordChainResult(stats *WorkloadStats, result workloadResult) {
+	if result.noPerm {
+		atomic.AddInt64(&stats.NoPermErrors, 1)
+	}
+	chain := stats.Chains.Results[result.chainIndex]
+	if result.isError {
+		if result.chainSteps > 0 {
+			atomic.AddInt64(&chain.PartialFailures, 1)
+		} else {
+			atomic.AddInt64(&chain.FullFailures, 1)
+		}
+		if result.failedStep >= 0 {
+			atomic.AddInt64(&chain.StepFailures[result.failedStep], 1)
+		}
+		return
+	}
+	atomic.AddInt64(&chain.Ops, 1)
+	atomic.AddInt64(&chain.Steps, int64(result.chainSteps))
+	chain.Stats.RecordLatency(result.latencyMicros)
+}
+
+// printChainResults prints the end-to-end results of each chain
+func printChainResults(stats *WorkloadStats) {
+	cs := stats.Chains
+	fmt.Printf("Operation Chains (%d defined, %.1f%% of operations):\n", len(cs.Set.Chains), cs.Ratio*100)
+	for i, chain := range cs.Set.Chains {
+		result := cs.Results[i]
+		ops := atomic.LoadInt64(&result.Ops)
+		runs := result.Runs()
+		successRate, avgSteps := 0.0, 0.0
+		if runs > 0 {
+			successRate = float64(ops) / float64(runs) * 100
+		}
+		if ops > 0 {
+			avgSteps = float64(atomic.LoadInt64(&result.Steps)) / float64(ops)
+		}
+		_, _, _, _, p50, p95, p99 := result.Stats.GetStats()
+		fmt.Printf("Chain %s: %d runs, %d succeeded (%.2f%% success rate), %.2f of %d steps executed on average\n",
+			chain.Name, runs, ops, successRate, avgSteps, len(chain.Steps))
+		fmt.Printf("Chain %s Failures - Partial: %d (some steps succeeded), Full: %d\n",
+			chain.Name, atomic.LoadInt64(&result.PartialFailures), atomic.LoadInt64(&result.FullFailures))
+		for j, step := range chain.Steps {
+			if failures := atomic.LoadInt64(&result.StepFailures[j]); failures > 0 {
+				fmt.Printf("  Failed at step %d (%s %s): %d\n", j+1, step.Op, step.Key, failures)
+			}
+		}
+		fmt.Printf("Chain %s Latency - P50: %d μs, P95: %d μs, P99: %d μs\n", chain.Name, p50, p95, p99)
+	}
+	fmt.Println()
+}
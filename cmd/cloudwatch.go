@@ -0,0 +1,301 @@
+//go:build !noaws
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/spf13/cobra"
+)
+
+// CloudWatch publishing limits and tuning
+const (
+	cloudWatchMaxBatch       = 1000 // PutMetricData accepts up to 1000 metrics per request
+	cloudWatchQueueSize      = 16384
+	cloudWatchFlushInterval  = 5 * time.Second
+	cloudWatchRequestTimeout = 10 * time.Second
+	cloudWatchMaxAttempts    = 6
+	cloudWatchBaseBackoff    = 250 * time.Millisecond
+	cloudWatchMaxBackoff     = 8 * time.Second
+	cloudWatchCloseTimeout   = 30 * time.Second
+)
+
+func init() {
+	runCmd.Flags().String("cloudwatch-namespace", "", "Publish live run metrics (one value per metrics window) to this CloudWatch namespace")
+	runCmd.Flags().String("cloudwatch-dimensions", "", "Extra CloudWatch dimensions as Name=Value pairs (e.g. Run=baseline,Env=staging)")
+	runCmd.Flags().String("cloudwatch-role-arn", "", "IAM role to assume for publishing metrics, e.g. in a central monitoring account (default: --aws-role-arn)")
+
+	RegisterAWSPermissions(func(cmd *cobra.Command) []AWSPermission {
+		namespace, _ := cmd.Flags().GetString("cloudwatch-namespace")
+		if namespace == "" {
+			return nil
+		}
+		// PutMetricData has no resource-level permissions
+		return []AWSPermission{{
+			Action:   "cloudwatch:PutMetricData",
+			Resource: "*",
+			RoleARN:  awsRoleARN(cmd, "cloudwatch-role-arn"),
+			Reason:   "publishing metrics to " + namespace,
+		}}
+	})
+}
+
+// CloudWatchPublisher publishes run metrics to CloudWatch in the background. Metrics are
+// queued without blocking the caller (dropped when the queue is full), batched up to the
+// PutMetricData limit and retried with exponential backoff on throttling and transient
+// errors, so CloudWatch slowness never stalls or skews the benchmark loop.
+type CloudWatchPublisher struct {
+	Namespace  string
+	Dimensions []types.Dimension
+
+	Published int64 // Metrics accepted by CloudWatch
+	Requests  int64 // Successful PutMetricData requests
+	Retries   int64 // Retried PutMetricData attempts
+	Failed    int64 // Metrics lost after exhausting retries (or at shutdown)
+	Dropped   int64 // Metrics dropped because the queue was full
+
+	client    *cloudwatch.Client
+	queue     chan types.MetricDatum
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mutex     sync.Mutex
+	lastError error
+
+	// Previous counters, to publish errors per window rather than cumulative
+	lastGetErrors int64
+	lastSetErrors int64
+}
+
+// NewCloudWatchPublisher starts a publisher; dimensions is a Name=Value list added to every
+// metric, next to the engine and the tool version (so dashboards can split by release)
+func NewCloudWatchPublisher(ctx context.Context, region, roleARN, namespace, engine, dimensions string) (*CloudWatchPublisher, error) {
+	dims := []types.Dimension{
+		{Name: aws.String("Engine"), Value: aws.String(engine)},
+		{Name: aws.String("ToolVersion"), Value: aws.String(currentBuildInfo().Version)},
+	}
+	if dimensions != "" {
+		for _, pair := range strings.Split(dimensions, ",") {
+			name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || name == "" || value == "" {
+				return nil, fmt.Errorf("invalid CloudWatch dimension '%s': expected Name=Value", pair)
+			}
+			dims = append(dims, types.Dimension{Name: aws.String(name), Value: aws.String(value)})
+		}
+	}
+	if len(dims) > 30 {
+		return nil, fmt.Errorf("too many CloudWatch dimensions: %d (max 30)", len(dims))
+	}
+
+	cfg, err := loadAWSConfig(ctx, region, roleARN)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &CloudWatchPublisher{
+		Namespace:  namespace,
+		Dimensions: dims,
+		// Retries are handled by the publisher so they can be counted and backed off
+		client: cloudwatch.NewFromConfig(cfg, func(o *cloudwatch.Options) {
+			o.Retryer = aws.NopRetryer{}
+		}),
+		queue: make(chan types.MetricDatum, cloudWatchQueueSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go p.run()
+	return p, nil
+}
+
+// PublishSnapshot queues the metrics of one progress window; it never blocks
+func (p *CloudWatchPublisher) PublishSnapshot(snapshot MetricsSnapshot) {
+	getErrors := snapshot.GetErrors - p.lastGetErrors
+	setErrors := snapshot.SetErrors - p.lastSetErrors
+	p.lastGetErrors, p.lastSetErrors = snapshot.GetErrors, snapshot.SetErrors
+
+	for _, metric := range []struct {
+		name  string
+		value float64
+		unit  types.StandardUnit
+	}{
+		{"GetQPS", snapshot.ActualGetQPS, types.StandardUnitCountSecond},
+		{"SetQPS", snapshot.ActualSetQPS, types.StandardUnitCountSecond},
+		{"GetErrors", float64(getErrors), types.StandardUnitCount},
+		{"SetErrors", float64(setErrors), types.StandardUnitCount},
+		{"Clients", float64(snapshot.ActualClients), types.StandardUnitCount},
+	} {
+		p.enqueueWindowMetric(snapshot.Timestamp, metric.name, metric.value, metric.unit)
+	}
+
+	// One metric per --percentiles entry (GetLatencyP50, GetLatencyP99.9...)
+	for _, op := range []struct {
+		prefix string
+		values []PercentileValue
+	}{{"GetLatency", snapshot.GetPercentiles}, {"SetLatency", snapshot.SetPercentiles}} {
+		for _, v := range op.values {
+			p.enqueueWindowMetric(snapshot.Timestamp, op.prefix+percentileLabel(v.Percentile), float64(v.Value), types.StandardUnitMicroseconds)
+		}
+	}
+}
+
+// enqueueWindowMetric queues one value of a progress window
+func (p *CloudWatchPublisher) enqueueWindowMetric(timestamp time.Time, name string, value float64, unit types.StandardUnit) {
+	p.enqueue(types.MetricDatum{
+		MetricName:        aws.String(name),
+		Dimensions:        p.Dimensions,
+		Timestamp:         aws.Time(timestamp),
+		Value:             aws.Float64(value),
+		Unit:              unit,
+		StorageResolution: aws.Int32(1), // High resolution: one value per progress window
+	})
+}
+
+// PublishHeartbeat queues a liveness heartbeat: alarm on missing Heartbeat data points to
+// detect dead runners
+func (p *CloudWatchPublisher) PublishHeartbeat(beat Heartbeat) {
+	p.enqueue(types.MetricDatum{
+		MetricName: aws.String("Heartbeat"),
+		Dimensions: p.Dimensions,
+		Timestamp:  aws.Time(beat.Timestamp),
+		Value:      aws.Float64(1),
+		Unit:       types.StandardUnitCount,
+	})
+	p.enqueue(types.MetricDatum{
+		MetricName: aws.String("ProgressPercent"),
+		Dimensions: p.Dimensions,
+		Timestamp:  aws.Time(beat.Timestamp),
+		Value:      aws.Float64(beat.ProgressPercent),
+		Unit:       types.StandardUnitPercent,
+	})
+}
+
+func (p *CloudWatchPublisher) enqueue(datum types.MetricDatum) {
+	select {
+	case p.queue <- datum:
+	default:
+		atomic.AddInt64(&p.Dropped, 1)
+	}
+}
+
+// run batches queued metrics and flushes them when a batch is full or on every interval
+func (p *CloudWatchPublisher) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(cloudWatchFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]types.MetricDatum, 0, cloudWatchMaxBatch)
+	for {
+		select {
+		case datum := <-p.queue:
+			batch = p.add(batch, datum)
+		case <-ticker.C:
+			p.flush(batch)
+			batch = batch[:0]
+		case <-p.stop:
+			// Drain what was queued before Close
+			for {
+				select {
+				case datum := <-p.queue:
+					batch = p.add(batch, datum)
+				default:
+					p.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// add appends a metric to the batch, sending the batch once it reaches the request limit
+func (p *CloudWatchPublisher) add(batch []types.MetricDatum, datum types.MetricDatum) []types.MetricDatum {
+	batch = append(batch, datum)
+	if len(batch) == cloudWatchMaxBatch {
+		p.flush(batch)
+		batch = batch[:0]
+	}
+	return batch
+}
+
+// flush sends a batch, retrying throttled and transient failures with exponential backoff
+func (p *CloudWatchPublisher) flush(batch []types.MetricDatum) {
+	if len(batch) == 0 {
+		return
+	}
+
+	retryable := retry.IsErrorRetryables(retry.DefaultRetryables)
+	backoff := cloudWatchBaseBackoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), cloudWatchRequestTimeout)
+		_, err := p.client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(p.Namespace),
+			MetricData: batch,
+		})
+		cancel()
+		if err == nil {
+			atomic.AddInt64(&p.Published, int64(len(batch)))
+			atomic.AddInt64(&p.Requests, 1)
+			return
+		}
+
+		if attempt == cloudWatchMaxAttempts || retryable.IsErrorRetryable(err) != aws.TrueTernary {
+			atomic.AddInt64(&p.Failed, int64(len(batch)))
+			p.mutex.Lock()
+			p.lastError = err
+			p.mutex.Unlock()
+			return
+		}
+
+		atomic.AddInt64(&p.Retries, 1)
+		time.Sleep(backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)))) // Jittered
+		backoff = min(backoff*2, cloudWatchMaxBackoff)
+	}
+}
+
+// Close flushes the queued metrics, waiting at most cloudWatchCloseTimeout; metrics still
+// queued after that are counted as failed
+func (p *CloudWatchPublisher) Close() {
+	p.closeOnce.Do(func() {
+		close(p.stop)
+		select {
+		case <-p.done:
+		case <-time.After(cloudWatchCloseTimeout):
+			atomic.AddInt64(&p.Failed, int64(len(p.queue)))
+			p.mutex.Lock()
+			p.lastError = fmt.Errorf("timed out flushing metrics at shutdown")
+			p.mutex.Unlock()
+		}
+	})
+}
+
+// LastError returns the error of the most recent failed publish, if any
+func (p *CloudWatchPublisher) LastError() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.lastError
+}
+
+// printCloudWatchResults reports publishing outcomes separately from the benchmark results
+func printCloudWatchResults(publisher *CloudWatchPublisher) {
+	fmt.Printf("\nCloudWatch Publishing (namespace %s):\n", publisher.Namespace)
+	fmt.Printf("Metrics Published: %d in %d requests, Retries: %d\n",
+		atomic.LoadInt64(&publisher.Published), atomic.LoadInt64(&publisher.Requests), atomic.LoadInt64(&publisher.Retries))
+	failed, dropped := atomic.LoadInt64(&publisher.Failed), atomic.LoadInt64(&publisher.Dropped)
+	if failed > 0 || dropped > 0 {
+		fmt.Printf("Warning: %d metrics failed to publish, %d dropped (queue full)\n", failed, dropped)
+		if err := publisher.LastError(); err != nil {
+			fmt.Printf("Last publish error: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+func init() {
+	runCmd.Flags().String("command-mix", "", "Weighted command table replacing --ratio, with per-command stats: COMMAND:WEIGHT,... (e.g. GET:70,SET:20,DEL:5,INCR:5) or file:<path> with one entry per line; other commands run verbatim on Redis with {key} replaced by the key and {value} by a --data-size value (e.g. 'HINCRBY {key}:stats views 1:5')")
+}
+
+// CommandRunner is implemented by cache clients that can run arbitrary commands (Redis);
+// custom entries of a command mix need it
+type CommandRunner interface {
+	Do(ctx context.Context, args ...any) (any, error)
+}
+
+// Command kinds of a mix: GET and SET take the regular workload path, the others are
+// measured per command
+const (
+	mixGet = iota
+	mixSet
+	mixDel
+	mixIncr
+	mixCustom
+)
+
+// MixCommand is one entry of a weighted command table
+type MixCommand struct {
+	Name   string // GET, SET, DEL, INCR or the custom command line
+	Weight float64
+	Args   []string // Custom command words, {key} replaced with the operation's key and {value} with a value
+	kind   int
+
+	usesValue bool // Some word takes a generated {value}
+}
+
+// CommandMix is the weighted command table of --command-mix
+type CommandMix struct {
+	Commands    []*MixCommand
+	totalWeight float64
+}
+
+// parseCommandMix parses a --command-mix table. The weight follows the last colon of an
+// entry, so custom command lines may contain colons themselves.
+func parseCommandMix(value string) (*CommandMix, error) {
+	entries := strings.Split(value, ",")
+	if path, ok := strings.CutPrefix(value, "file:"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read command mix: %w", err)
+		}
+		entries = strings.Split(string(data), "\n")
+	}
+
+	mix := &CommandMix{}
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		i := strings.LastIndex(entry, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid command mix entry '%s': expected COMMAND:WEIGHT", entry)
+		}
+		line, weightStr := strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:])
+		weight, err := strconv.ParseFloat(weightStr, 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight '%s' for command '%s'", weightStr, line)
+		}
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			return nil, fmt.Errorf("invalid command mix entry '%s': missing command", entry)
+		}
+
+		command := &MixCommand{Name: strings.ToUpper(words[0]), Weight: weight, kind: mixCustom}
+		if len(words) == 1 {
+			switch command.Name {
+			case "GET":
+				command.kind = mixGet
+			case "SET":
+				command.kind = mixSet
+			case "DEL", "DELETE":
+				command.Name, command.kind = "DEL", mixDel
+			case "INCR":
+				command.kind = mixIncr
+			default:
+				command.Args = []string{command.Name, "{key}"}
+			}
+		} else {
+			command.Name = strings.Join(words, " ")
+			command.Args = words
+		}
+		for _, arg := range command.Args {
+			command.usesValue = command.usesValue || strings.Contains(arg, "{value}")
+		}
+		if seen[command.Name] {
+			return nil, fmt.Errorf("command '%s' is listed twice", command.Name)
+		}
+		seen[command.Name] = true
+		if weight > 0 {
+			mix.Commands = append(mix.Commands, command)
+			mix.totalWeight += weight
+		}
+	}
+	if len(mix.Commands) == 0 {
+		return nil, fmt.Errorf("no commands with a positive weight given")
+	}
+	return mix, nil
+}
+
+// Pick returns the index of a command drawn by weight
+func (cm *CommandMix) Pick(rng *rand.Rand) int {
+	target := rng.Float64() * cm.totalWeight
+	for i, command := range cm.Commands {
+		target -= command.Weight
+		if target < 0 {
+			return i
+		}
+	}
+	return len(cm.Commands) - 1
+}
+
+// HasCustom reports whether the mix runs commands that need a CommandRunner
+func (cm *CommandMix) HasCustom() bool {
+	for _, command := range cm.Commands {
+		if command.kind == mixCustom {
+			return true
+		}
+	}
+	return false
+}
+
+// Calls reports whether the mix runs one of the given custom commands (e.g. FCALL)
+func (cm *CommandMix) Calls(names ...string) bool {
+	for _, command := range cm.Commands {
+		if command.kind != mixCustom {
+			continue
+		}
+		for _, name := range names {
+			if strings.EqualFold(command.Args[0], name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasCommands reports whether the mix has commands other than GET and SET
+func (cm *CommandMix) HasCommands() bool {
+	for _, command := range cm.Commands {
+		if command.kind != mixGet && command.kind != mixSet {
+			return true
+		}
+	}
+	return false
+}
+
+// Share returns a command's fraction of the operations
+func (cm *CommandMix) Share(i int) float64 {
+	return cm.Commands[i].Weight / cm.totalWeight
+}
+
+// Describe renders the mix for the run header: GET 70%, SET 20%, ...
+func (cm *CommandMix) Describe() string {
+	parts := make([]string, len(cm.Commands))
+	for i, command := range cm.Commands {
+		parts[i] = fmt.Sprintf("%s %.1f%%", command.Name, cm.Share(i)*100)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatCounterKey returns the counter key INCR commands of a mix operate on; counters are
+// kept apart from the values written by SET, which don't parse as integers
+func formatCounterKey(keyPrefix string, keyID int) string {
+	return keyPrefix + "counter-" + keyFormat.render(keyID)
+}
+
+// processCommand runs a DEL, INCR or custom command of the mix
+func processCommand(ctx context.Context, request requestInfo, client CacheClient, generator *DataGenerator,
+	mix *CommandMix, timeoutSeconds int, verbose bool) workloadResult {
+	command := mix.Commands[request.commandIndex]
+
+	opCtx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+	defer cancel()
+
+	var args []any
+	if command.kind == mixCustom {
+		// Values are generated before timing, like the ones of SETs
+		var value []byte
+		if command.usesValue {
+			var err error
+			if value, err = generator.GenerateData(); err != nil {
+				return workloadResult{isCommand: true, commandIndex: request.commandIndex, isError: true}
+			}
+		}
+		args = make([]any, len(command.Args))
+		for i, arg := range command.Args {
+			switch arg {
+			case "{key}":
+				args[i] = request.key
+			case "{value}":
+				args[i] = value // Binary-safe as a whole argument
+			default:
+				args[i] = strings.ReplaceAll(strings.ReplaceAll(arg, "{key}", request.key), "{value}", string(value))
+			}
+		}
+	}
+
+	var err error
+	start := time.Now()
+	switch command.kind {
+	case mixDel:
+		err = client.Delete(opCtx, request.key)
+	case mixIncr:
+		err = incrementKey(opCtx, client, request.key, generator.GetExpiration())
+	case mixCustom:
+		runner, ok := client.(CommandRunner)
+		if !ok {
+			err = fmt.Errorf("%s does not support custom commands", client.Name())
+			break
+		}
+		_, err = runner.Do(opCtx, args...)
+		if errors.Is(err, ErrCacheMiss) {
+			err = nil
+		}
+	}
+	latency := time.Since(start)
+
+	if err != nil {
+		if verbose {
+			log.Printf("Worker %d: %s failed for key %s: %v", request.workerID, command.Name, request.key, err)
+		}
+		return workloadResult{isCommand: true, commandIndex: request.commandIndex, isError: true, noPerm: isNoPermError(err),
+			timeout: classifyTimeout(err)}
+	}
+	return workloadResult{isCommand: true, commandIndex: request.commandIndex, latencyMicros: latency.Microseconds()}
+}
+
+// CommandResult tracks the executions of one DEL, INCR or custom command of the mix
+type CommandResult struct {
+	Ops    int64
+	Errors int64
+	Stats  *PerformanceStats
+}
+
+// CommandMixStats tracks the commands of a mix other than GET and SET, which are
+// reported from the regular GET and SET stats
+type CommandMixStats struct {
+	Mix     *CommandMix
+	Results []*CommandResult // By command index; nil for GET and SET
+}
+
+func NewCommandMixStats(mix *CommandMix) *CommandMixStats {
+	cs := &CommandMixStats{Mix: mix, Results: make([]*CommandResult, len(mix.Commands))}
+	for i, command := range mix.Commands {
+		if command.kind != mixGet && command.kind != mixSet {
+			cs.Results[i] = &CommandResult{Stats: NewPerformanceStats()}
+			cs.Results[i].Stats.Name = command.Name
+		}
+	}
+	return cs
+}
+
+func (cs *CommandMixStats) Close() {
+	for _, result := range cs.Results {
+		if result != nil {
+			result.Stats.Close()
+		}
+	}
+}
+
+// recordCommandResult records the outcome of a DEL, INCR or custom command
+func recordCommandResult(stats *WorkloadStats, result workloadResult) {
+	if result.noPerm {
+		atomic.AddInt64(&stats.NoPermErrors, 1)
+	}
+	command := stats.Commands.Results[result.commandIndex]
+	if result.isError {
+		atomic.AddInt64(&command.Errors, 1)
+		return
+	}
+	atomic.AddInt64(&command.Ops, 1)
+	command.Stats.RecordLatency(result.latencyMicros)
+}
+
+// CommandSummary is the machine-readable result of one command of the mix
+type CommandSummary struct {
+	Command string  `json:"command"`
+	Share   float64 `json:"share"` // Configured fraction of the operations
+	LatencySummary
+}
+
+// Summary returns the results of every command of the mix over the measurement window
+func (cs *CommandMixStats) Summary(stats *WorkloadStats, seconds float64) []CommandSummary {
+	summaries := make([]CommandSummary, len(cs.Mix.Commands))
+	for i, command := range cs.Mix.Commands {
+		ps, ops, errs := cs.source(stats, i)
+		summaries[i] = CommandSummary{
+			Command:        command.Name,
+			Share:          cs.Mix.Share(i),
+			LatencySummary: summarizeTotals(ps, ops, errs, seconds),
+		}
+	}
+	return summaries
+}
+
+// source returns the stats and counters a command is reported from
+func (cs *CommandMixStats) source(stats *WorkloadStats, i int) (*PerformanceStats, int64, int64) {
+	switch cs.Mix.Commands[i].kind {
+	case mixGet:
+		return stats.GetStats, atomic.LoadInt64(&stats.GetOps), atomic.LoadInt64(&stats.GetErrors)
+	case mixSet:
+		return stats.SetStats, atomic.LoadInt64(&stats.SetOps), atomic.LoadInt64(&stats.SetErrors)
+	}
+	result := cs.Results[i]
+	return result.Stats, atomic.LoadInt64(&result.Ops), atomic.LoadInt64(&result.Errors)
+}
+
+// printCommandMixResults prints a per-command table of the mix
+func printCommandMixResults(stats *WorkloadStats, seconds float64) {
+	cs := stats.Commands
+	fmt.Printf("Command Mix (%d commands):\n", len(cs.Mix.Commands))
+	fmt.Printf("%-24s %-8s %-10s %-8s %-10s %s\n", "Command", "Share", "Ops", "Errors", "QPS", "Latency")
+	for i, command := range cs.Mix.Commands {
+		ps, ops, errs := cs.source(stats, i)
+		qps := 0.0
+		if seconds > 0 {
+			qps = float64(ops) / seconds
+		}
+		fmt.Printf("%-24s %-8s %-10d %-8d %-10.0f %s\n", command.Name, fmt.Sprintf("%.1f%%", cs.Mix.Share(i)*100),
+			ops, errs, qps, formatPercentiles(latencyPercentiles(ps.Histogram)))
+	}
+	fmt.Println()
+}
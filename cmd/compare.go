@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare <baseline.json> <candidate.json>",
+	Short: "Compare two run summaries",
+	Long: `Compare two run summaries written with --output json, showing throughput and
+latency percentiles side by side with the relative change of the candidate.
+
+Runs are only directly comparable when they ran the same workload: a warning is printed
+when the workload hashes differ, listing the settings that changed.
+
+Summaries written by older releases are migrated to the current schema version on load,
+with a note when a migrated value isn't directly comparable.
+
+Examples:
+  # Compare Memcached against a Redis baseline
+  serverless-cache-benchmark run --cache-type redis --output json --output-file redis.json
+  serverless-cache-benchmark run --engine memcached --output json --output-file memcached.json
+  serverless-cache-benchmark compare redis.json memcached.json`,
+	Args: cobra.ExactArgs(2),
+	Run:  runCompare,
+}
+
+// loadRunSummary reads a run summary written with --output json by this or an older
+// release, returning the caveats of migrating it to the current schema
+func loadRunSummary(filename string) (*RunSummary, []string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	summary, notes, err := decodeRunSummary(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid run summary %s: %w", filename, err)
+	}
+	return summary, notes, nil
+}
+
+func runCompare(cmd *cobra.Command, args []string) {
+	baseline, baselineNotes, err := loadRunSummary(args[0])
+	if err != nil {
+		log.Fatalf("Failed to load baseline: %v", err)
+	}
+	candidate, candidateNotes, err := loadRunSummary(args[1])
+	if err != nil {
+		log.Fatalf("Failed to load candidate: %v", err)
+	}
+
+	printMigrationNotes(args[0], baselineNotes)
+	printMigrationNotes(args[1], candidateNotes)
+	warnWorkloadDrift(baseline, candidate)
+	printComparison(args[0], baseline, args[1], candidate)
+}
+
+// printComparison prints the throughput and latency of two runs side by side
+func printComparison(baselineName string, baseline *RunSummary, candidateName string, candidate *RunSummary) {
+	fmt.Println("\n" + strings.Repeat("=", 60))
+	fmt.Printf("COMPARISON: %s (%s) vs %s (%s)\n", baselineName, baseline.Engine, candidateName, candidate.Engine)
+	fmt.Println(strings.Repeat("=", 60))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "METRIC\tBASELINE\tCANDIDATE\tCHANGE")
+	for _, op := range []struct {
+		name                string
+		baseline, candidate LatencySummary
+	}{
+		{"GET", baseline.Get, candidate.Get},
+		{"SET", baseline.Set, candidate.Set},
+	} {
+		if op.baseline.Ops == 0 && op.candidate.Ops == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s QPS\t%.0f\t%.0f\t%s\n", op.name, op.baseline.QPS, op.candidate.QPS, relativeChange(op.baseline.QPS, op.candidate.QPS))
+		fmt.Fprintf(w, "%s errors\t%d\t%d\t\n", op.name, op.baseline.Errors, op.candidate.Errors)
+		for _, p := range []struct {
+			name                string
+			baseline, candidate int64
+		}{
+			{"P50", op.baseline.P50, op.candidate.P50},
+			{"P95", op.baseline.P95, op.candidate.P95},
+			{"P99", op.baseline.P99, op.candidate.P99},
+			{"P99.9", op.baseline.P999, op.candidate.P999},
+			{"Max", op.baseline.Max, op.candidate.Max},
+		} {
+			fmt.Fprintf(w, "%s %s (μs)\t%d\t%d\t%s\n", op.name, p.name, p.baseline, p.candidate,
+				relativeChange(float64(p.baseline), float64(p.candidate)))
+		}
+	}
+	w.Flush()
+	fmt.Println(strings.Repeat("=", 60))
+}
+
+// warnWorkloadDrift prints a warning when two runs did not use the same workload
+func warnWorkloadDrift(baseline, candidate *RunSummary) {
+	if baseline.WorkloadHash == "" || candidate.WorkloadHash == "" {
+		fmt.Println("Warning: a summary has no workload hash; cannot verify both runs used the same workload")
+		return
+	}
+	if baseline.WorkloadHash == candidate.WorkloadHash {
+		fmt.Printf("Workload hash: %s (identical)\n", shortHash(baseline.WorkloadHash))
+		return
+	}
+
+	fmt.Printf("Warning: workload hashes differ (%s vs %s); results may not be comparable\n",
+		shortHash(baseline.WorkloadHash), shortHash(candidate.WorkloadHash))
+	if baseline.Workload != nil && candidate.Workload != nil {
+		fmt.Printf("Changed settings: %s\n", strings.Join(baseline.Workload.Diff(candidate.Workload), ", "))
+	}
+}
+
+// shortHash abbreviates a workload hash for display
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+// relativeChange formats the change from baseline to candidate as a percentage
+func relativeChange(baseline, candidate float64) string {
+	if baseline == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.1f%%", (candidate-baseline)/baseline*100)
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// connSkewOutlier is how far above the median connection P99 a connection counts as slow
+const connSkewOutlier = 2.0
+
+// connSkewShown bounds the slowest connections listed in the report
+const connSkewShown = 5
+
+// connLatency is the coarse latency histogram of one client connection
+type connLatency struct {
+	mutex     sync.Mutex
+	ops       int64
+	errors    int64
+	histogram *hdrhistogram.Histogram
+}
+
+// ConnSkew keeps a coarse latency histogram per client connection, to detect a subset of
+// connections landing on a slow backend node behind a single serverless endpoint. Each
+// worker owns one client and issues one operation at a time, so a worker's operations
+// share a connection (with producer-consumer batching, the worker's client pool).
+type ConnSkew struct {
+	conns sync.Map // Worker ID -> *connLatency; each worker only contends on its own lock
+}
+
+func NewConnSkew() *ConnSkew {
+	return &ConnSkew{}
+}
+
+// Record adds the result of an operation to its worker's connection histogram
+func (cs *ConnSkew) Record(result workloadResult) {
+	value, ok := cs.conns.Load(result.workerID)
+	if !ok {
+		// 2 significant digits are enough to compare connections and keep them small
+		value, _ = cs.conns.LoadOrStore(result.workerID, &connLatency{histogram: hdrhistogram.New(1, 60*1000*1000, 2)})
+	}
+	conn := value.(*connLatency)
+
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	if result.isError {
+		conn.errors++
+		return
+	}
+	conn.ops++
+	conn.histogram.RecordValue(result.latencyMicros)
+}
+
+// connP99 is the P99 of one connection, for sorting
+type connP99 struct {
+	workerID int
+	ops      int64
+	errors   int64
+	p50, p99 int64
+}
+
+// Spread returns the connections' P99s, slowest first, and the median P99
+func (cs *ConnSkew) Spread() ([]connP99, int64) {
+	var conns []connP99
+	cs.conns.Range(func(key, value interface{}) bool {
+		conn := value.(*connLatency)
+		conn.mutex.Lock()
+		defer conn.mutex.Unlock()
+		if conn.ops > 0 {
+			conns = append(conns, connP99{
+				workerID: key.(int),
+				ops:      conn.ops,
+				errors:   conn.errors,
+				p50:      conn.histogram.ValueAtQuantile(50),
+				p99:      conn.histogram.ValueAtQuantile(99),
+			})
+		}
+		return true
+	})
+	if len(conns) == 0 {
+		return nil, 0
+	}
+
+	sort.Slice(conns, func(i, j int) bool {
+		if conns[i].p99 != conns[j].p99 {
+			return conns[i].p99 > conns[j].p99
+		}
+		return conns[i].workerID < conns[j].workerID
+	})
+	return conns, conns[len(conns)/2].p99
+}
+
+// Outliers returns the connections whose P99 exceeds connSkewOutlier times the median
+func (cs *ConnSkew) Outliers() ([]connP99, int64, int) {
+	conns, median := cs.Spread()
+	outliers := 0
+	for outliers < len(conns) && float64(conns[outliers].p99) > float64(median)*connSkewOutlier {
+		outliers++
+	}
+	return conns[:outliers], median, len(conns)
+}
+
+// printConnSkewResults prints the P99 spread across connections and the slowest ones
+func printConnSkewResults(stats *WorkloadStats) {
+	conns, median := stats.ConnSkew.Spread()
+	if len(conns) == 0 {
+		return
+	}
+	fastest, slowest := conns[len(conns)-1].p99, conns[0].p99
+
+	fmt.Printf("Per-Connection Latency (%d connections):\n", len(conns))
+	fmt.Printf("P99 Spread - Min: %d μs, Median: %d μs, Max: %d μs", fastest, median, slowest)
+	if median > 0 {
+		fmt.Printf(" (max/median %.1fx)", float64(slowest)/float64(median))
+	}
+	fmt.Println()
+
+	outliers, _, _ := stats.ConnSkew.Outliers()
+	if len(outliers) > 0 {
+		fmt.Printf("Slow Connections: %d with P99 over %.0fx the median; they may land on a slow backend node\n",
+			len(outliers), connSkewOutlier)
+	}
+
+	fmt.Printf("%-12s %-10s %-8s %-10s %-10s\n", "Connection", "Ops", "Errors", "P50", "P99")
+	for _, conn := range conns[:min(connSkewShown, len(conns))] {
+		fmt.Printf("%-12s %-10d %-8d %-10d %-10d\n", fmt.Sprintf("worker %d", conn.workerID), conn.ops, conn.errors, conn.p50, conn.p99)
+	}
+	fmt.Println("(slowest connections by P99, latencies in μs)")
+	fmt.Println()
+}
@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	runCmd.Flags().String("control-addr", "", "Serve a control API on this address (e.g. 127.0.0.1:9091) to switch the running workload between mixed, read-only and write-only (POST /mode?mode=read-only, GET /phases); every switch starts a phase that is reported apart")
+}
+
+// maxModePhases caps the phases of a run, each of which keeps its own stats
+const maxModePhases = 64
+
+// workloadMode is the operation mix the workers follow
+type workloadMode int32
+
+const (
+	modeMixed     workloadMode = iota // The --ratio (or command table, chains, RMW) of the run
+	modeReadOnly                      // Plain GETs only
+	modeWriteOnly                     // Plain SETs only
+)
+
+var workloadModeNames = []string{"mixed", "read-only", "write-only"}
+
+func (m workloadMode) String() string {
+	return workloadModeNames[m]
+}
+
+func parseWorkloadMode(s string) (workloadMode, error) {
+	for i, name := range workloadModeNames {
+		if s == name {
+			return workloadMode(i), nil
+		}
+	}
+	return modeMixed, fmt.Errorf("unknown mode '%s': expected mixed, read-only or write-only", s)
+}
+
+// ModePhase is a stretch of the run in one mode, between two switches
+type ModePhase struct {
+	*ClientGroup     // Results during the phase (named after its number and mode)
+	Number       int // From 1
+	Mode         workloadMode
+	Start        time.Time
+	End          time.Time // Zero while the phase is running
+	Source       string    // Remote address that switched to the phase ("" for the first)
+}
+
+// ModeControl switches the running workload between mixed, read-only and write-only, so an
+// operator can run targeted experiments mid-run, such as pausing writes while a serverless
+// cache scales. Each switch ends a phase and starts the next, and the phases' results are
+// reported apart, so the boundaries line up with the per-window stats.
+type ModeControl struct {
+	mode   int32
+	phase  atomic.Pointer[ModePhase]
+	mutex  sync.Mutex
+	phases []*ModePhase
+}
+
+func NewModeControl() *ModeControl {
+	mc := &ModeControl{}
+	mc.startPhase(modeMixed, "")
+	return mc
+}
+
+// controlFromFlags reads --control-addr (nil when not set)
+func controlFromFlags(cmd *cobra.Command) (*ModeControl, string, error) {
+	addr, _ := cmd.Flags().GetString("control-addr")
+	if addr == "" {
+		return nil, "", nil
+	}
+	if cmd.Flags().Changed("replay-self") {
+		return nil, "", fmt.Errorf("--replay-self replays recorded operations and cannot be combined with --control-addr")
+	}
+	return NewModeControl(), addr, nil
+}
+
+// Mode returns the mode the workers follow
+func (mc *ModeControl) Mode() workloadMode {
+	return workloadMode(atomic.LoadInt32(&mc.mode))
+}
+
+// startPhase ends the current phase and starts one in the given mode; the caller holds the
+// mutex (or is the constructor)
+func (mc *ModeControl) startPhase(mode workloadMode, source string) *ModePhase {
+	now := time.Now()
+	if current := mc.phase.Load(); current != nil {
+		current.End = now
+	}
+	number := len(mc.phases) + 1
+	phase := &ModePhase{
+		ClientGroup: NewClientGroup(fmt.Sprintf("#%d %s", number, mode)),
+		Number:      number,
+		Mode:        mode,
+		Start:       now,
+		Source:      source,
+	}
+	mc.phases = append(mc.phases, phase)
+	mc.phase.Store(phase)
+	atomic.StoreInt32(&mc.mode, int32(mode))
+	return phase
+}
+
+// Switch moves the workload to a mode, starting a new phase; switching to the current mode
+// keeps the phase
+func (mc *ModeControl) Switch(mode workloadMode, source string) (*ModePhase, bool, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if current := mc.phase.Load(); current.Mode == mode {
+		return current, false, nil
+	}
+	if len(mc.phases) >= maxModePhases {
+		return nil, false, fmt.Errorf("the run already has %d phases, the most it can report", maxModePhases)
+	}
+	return mc.startPhase(mode, source), true, nil
+}
+
+// Record adds a GET or SET result to the current phase
+func (mc *ModeControl) Record(result workloadResult) {
+	mc.phase.Load().Record(result)
+}
+
+// Stop ends the last phase at the end of the run
+func (mc *ModeControl) Stop() {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if current := mc.phase.Load(); current.End.IsZero() {
+		current.End = time.Now()
+	}
+}
+
+func (mc *ModeControl) Close() {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	for _, phase := range mc.phases {
+		phase.Close()
+	}
+}
+
+// ModePhaseSummary is the machine-readable result of one phase
+type ModePhaseSummary struct {
+	Phase   int            `json:"phase"`
+	Mode    string         `json:"mode"`
+	Start   time.Time      `json:"start"`
+	End     *time.Time     `json:"end,omitempty"` // Missing while the phase is running
+	Seconds float64        `json:"seconds"`
+	Source  string         `json:"source,omitempty"` // Who switched to the phase
+	Errors  int64          `json:"errors"`
+	Get     LatencySummary `json:"get"`
+	Set     LatencySummary `json:"set"`
+}
+
+// Summary returns the phases so far; a running phase is measured up to now
+func (mc *ModeControl) Summary() []ModePhaseSummary {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	summaries := make([]ModePhaseSummary, 0, len(mc.phases))
+	for _, phase := range mc.phases {
+		summary := ModePhaseSummary{
+			Phase:  phase.Number,
+			Mode:   phase.Mode.String(),
+			Start:  phase.Start,
+			Source: phase.Source,
+			Errors: atomic.LoadInt64(&phase.Errors),
+		}
+		end := phase.End
+		if end.IsZero() {
+			end = time.Now()
+		} else {
+			summary.End = &end
+		}
+		summary.Seconds = end.Sub(phase.Start).Seconds()
+		summary.Get = summarizeTotals(phase.GetStats, phase.GetStats.Histogram.TotalCount(), 0, summary.Seconds)
+		summary.Set = summarizeTotals(phase.SetStats, phase.SetStats.Histogram.TotalCount(), 0, summary.Seconds)
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// controlModeResponse is the reply of the /mode endpoint
+type controlModeResponse struct {
+	Mode    string    `json:"mode"`
+	Phase   int       `json:"phase"`
+	Since   time.Time `json:"since"`
+	Changed bool      `json:"changed"`
+	Error   string    `json:"error,omitempty"`
+}
+
+func newControlModeResponse(phase *ModePhase, changed bool, err error) controlModeResponse {
+	response := controlModeResponse{Mode: phase.Mode.String(), Phase: phase.Number, Since: phase.Start, Changed: changed}
+	if err != nil {
+		response.Error = err.Error()
+	}
+	return response
+}
+
+// startControlServer serves the control API: GET /mode returns the current mode, POST /mode
+// switches it (mode=mixed|read-only|write-only as a query or form parameter) and GET /phases
+// returns the phases so far
+func startControlServer(addr string, mc *ModeControl) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mode", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeControlResponse(w, http.StatusOK, newControlModeResponse(mc.phase.Load(), false, nil))
+		case http.MethodPost:
+			mode, err := parseWorkloadMode(r.FormValue("mode"))
+			if err != nil {
+				writeControlResponse(w, http.StatusBadRequest, newControlModeResponse(mc.phase.Load(), false, err))
+				return
+			}
+			phase, changed, err := mc.Switch(mode, r.RemoteAddr)
+			if err != nil {
+				writeControlResponse(w, http.StatusConflict, newControlModeResponse(mc.phase.Load(), false, err))
+				return
+			}
+			if changed {
+				fmt.Printf("\nControl: switched to %s (phase %d) from %s\n", mode, phase.Number, r.RemoteAddr)
+			}
+			writeControlResponse(w, http.StatusOK, newControlModeResponse(phase, changed, nil))
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/phases", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(mc.Summary())
+	})
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		fmt.Printf("Serving the control API on http://%s/mode\n", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Control server failed: %v", err)
+		}
+	}()
+	return server
+}
+
+func writeControlResponse(w http.ResponseWriter, status int, response controlModeResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// printModePhaseResults prints the phases of a run whose mode was switched
+func printModePhaseResults(stats *WorkloadStats) {
+	summaries := stats.Control.Summary()
+	fmt.Printf("Workload Mode Phases (%d, switched through the control API):\n", len(summaries))
+	fmt.Printf("%-16s %-10s %-10s %-10s %-10s %-8s %-10s %-10s %-10s %-10s\n",
+		"Phase", "Start", "Seconds", "GET/s", "SET/s", "Errors", "GET P50", "GET P99", "SET P50", "SET P99")
+	for _, s := range summaries {
+		fmt.Printf("%-16s %-10s %-10.1f %-10.0f %-10.0f %-8d %-10d %-10d %-10d %-10d\n",
+			fmt.Sprintf("#%d %s", s.Phase, s.Mode), s.Start.Format("15:04:05"), s.Seconds, s.Get.QPS, s.Set.QPS,
+			s.Errors, s.Get.P50, s.Get.P99, s.Set.P50, s.Set.P99)
+	}
+	fmt.Println("(latencies in μs; phases span the whole run, warmup included)")
+	fmt.Println()
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	runCmd.Flags().String("correlate-cache", "", "ElastiCache cache whose CloudWatch metrics (CPU/ECPU, network, evictions, connections) are fetched at the end of the run and correlated with the client P99 per minute, as <cache-id>[,serverless]")
+	runCmd.Flags().String("correlate-role-arn", "", "IAM role to assume for reading the --correlate-cache metrics (default: --aws-role-arn)")
+	runCmd.Flags().Duration("correlate-wait", 0, "Wait this long after the run before fetching the --correlate-cache metrics, so CloudWatch has published the last minutes")
+}
+
+// correlationPeriod is the resolution of the correlated series: standard CloudWatch metrics
+// are per minute
+const correlationPeriod = 60 * time.Second
+
+// correlationMinPoints is the number of common minutes below which a correlation is noise
+const correlationMinPoints = 3
+
+// ServerMetricSeries is one server-side metric per period start (Unix seconds)
+type ServerMetricSeries struct {
+	Name   string
+	Points map[int64]float64
+}
+
+// MetricCorrelation is the correlation of a server metric with the client P99
+type MetricCorrelation struct {
+	Metric      string  `json:"metric"`
+	Points      int     `json:"points"`      // Minutes with both a client P99 and a metric value
+	Correlation float64 `json:"correlation"` // Pearson coefficient, -1 to 1
+}
+
+// ServerCorrelation fetches the server-side metrics of the target cache after a run and ranks
+// them by how closely they follow the client P99, pointing the root-cause analysis of
+// latency spikes at CPU, network, evictions or connections first.
+type ServerCorrelation struct {
+	CacheID    string
+	Serverless bool
+	RoleARN    string
+	Region     string
+	Wait       time.Duration
+}
+
+// Correlate fetches the server metrics over the run and correlates them with the client P99
+// per minute, strongest correlates first
+func (sc *ServerCorrelation) Correlate(stats *WorkloadStats, start, end time.Time) ([]MetricCorrelation, error) {
+	if sc.Wait > 0 {
+		fmt.Printf("Waiting %v for CloudWatch to publish the last minutes of the run...\n", sc.Wait)
+		time.Sleep(sc.Wait)
+	}
+	series, err := fetchServerMetrics(sc, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return correlateWithP99(clientP99PerPeriod(stats), series), nil
+}
+
+// clientP99PerPeriod merges the GET and SET metrics windows into correlation periods and
+// returns the P99 of each
+func clientP99PerPeriod(stats *WorkloadStats) map[int64]float64 {
+	period := int64(correlationPeriod.Seconds())
+	merged := make(map[int64]*hdrhistogram.Histogram)
+	for _, ps := range []*PerformanceStats{stats.GetStats, stats.SetStats} {
+		err := ps.EachWindow(func(window LatencyWindow) error {
+			if window.Histogram.TotalCount() == 0 {
+				return nil
+			}
+			bucket := window.StartSecond - window.StartSecond%period
+			hist := merged[bucket]
+			if hist == nil {
+				hist = hdrhistogram.New(window.Histogram.LowestTrackableValue(),
+					window.Histogram.HighestTrackableValue(), int(window.Histogram.SignificantFigures()))
+				merged[bucket] = hist
+			}
+			hist.Merge(window.Histogram)
+			return nil
+		})
+		if err != nil {
+			log.Printf("Warning: correlating with incomplete %s windows: %v", ps.Name, err)
+		}
+	}
+	p99 := make(map[int64]float64, len(merged))
+	for bucket, hist := range merged {
+		p99[bucket] = float64(hist.ValueAtQuantile(99))
+	}
+	return p99
+}
+
+// correlateWithP99 correlates every series with the client P99 over their common periods,
+// sorted by the strength of the correlation; series with too few common points are left out
+func correlateWithP99(p99 map[int64]float64, series []ServerMetricSeries) []MetricCorrelation {
+	var correlations []MetricCorrelation
+	for _, s := range series {
+		var client, server []float64
+		for bucket, value := range s.Points {
+			if latency, ok := p99[bucket]; ok {
+				client, server = append(client, latency), append(server, value)
+			}
+		}
+		if len(client) < correlationMinPoints {
+			continue
+		}
+		correlations = append(correlations, MetricCorrelation{
+			Metric:      s.Name,
+			Points:      len(client),
+			Correlation: pearsonCorrelation(client, server),
+		})
+	}
+	sort.SliceStable(correlations, func(i, j int) bool {
+		return math.Abs(correlations[i].Correlation) > math.Abs(correlations[j].Correlation)
+	})
+	return correlations
+}
+
+// serverCorrelationFromFlags creates the correlation selected with --correlate-cache (nil when not set)
+func serverCorrelationFromFlags(cmd *cobra.Command) (*ServerCorrelation, error) {
+	value, _ := cmd.Flags().GetString("correlate-cache")
+	if value == "" {
+		return nil, nil
+	}
+	if buildFeatures["aws"] == "" {
+		return nil, fmt.Errorf("--correlate-cache needs CloudWatch: built without AWS support (-tags noaws)")
+	}
+	cacheID, option, _ := strings.Cut(value, ",")
+	if cacheID == "" || (option != "" && option != "serverless") {
+		return nil, fmt.Errorf("invalid --correlate-cache '%s': expected <cache-id>[,serverless]", value)
+	}
+	wait, _ := cmd.Flags().GetDuration("correlate-wait")
+	if wait < 0 {
+		return nil, fmt.Errorf("correlate wait must not be negative, got: %v", wait)
+	}
+	region, _ := cmd.Flags().GetString("aws-region")
+	return &ServerCorrelation{
+		CacheID:    cacheID,
+		Serverless: option == "serverless",
+		RoleARN:    awsRoleARN(cmd, "correlate-role-arn"),
+		Region:     region,
+		Wait:       wait,
+	}, nil
+}
+
+// correlationStrength describes the absolute value of a correlation coefficient
+func correlationStrength(r float64) string {
+	switch r = math.Abs(r); {
+	case r >= 0.7:
+		return "strong"
+	case r >= 0.4:
+		return "moderate"
+	case r >= 0.2:
+		return "weak"
+	default:
+		return "none"
+	}
+}
+
+// printServerCorrelation prints the server metrics ranked by their correlation with the client P99
+func printServerCorrelation(sc *ServerCorrelation, correlations []MetricCorrelation) {
+	fmt.Printf("Server Metric Correlation with Client P99 (%s, per minute):\n", sc.CacheID)
+	if len(correlations) == 0 {
+		fmt.Printf("Fewer than %d minutes with both client and CloudWatch data; run longer or raise --correlate-wait\n", correlationMinPoints)
+		fmt.Println()
+		return
+	}
+	fmt.Printf("%-28s %-8s %-12s %-10s\n", "Metric", "Minutes", "Correlation", "Strength")
+	for _, c := range correlations {
+		fmt.Printf("%-28s %-8d %-+12.2f %-10s\n", c.Metric, c.Points, c.Correlation, correlationStrength(c.Correlation))
+	}
+	if top := correlations[0]; correlationStrength(top.Correlation) == "strong" {
+		direction := "rises"
+		if top.Correlation < 0 {
+			direction = "falls"
+		}
+		fmt.Printf("Strongest correlate: client P99 %s with %s\n", direction, top.Metric)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,126 @@
+//go:build !noaws
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/spf13/cobra"
+)
+
+// correlationFetchTimeout bounds fetching the server metrics at the end of the run
+const correlationFetchTimeout = time.Minute
+
+func init() {
+	RegisterAWSPermissions(func(cmd *cobra.Command) []AWSPermission {
+		cache, _ := cmd.Flags().GetString("correlate-cache")
+		if cache == "" {
+			return nil
+		}
+		// GetMetricData has no resource-level permissions
+		return []AWSPermission{{
+			Action:   "cloudwatch:GetMetricData",
+			Resource: "*",
+			RoleARN:  awsRoleARN(cmd, "correlate-role-arn"),
+			Reason:   "correlating the server metrics of " + cache,
+		}}
+	})
+}
+
+// elastiCacheMetric is an AWS/ElastiCache metric and the statistic correlated per minute
+type elastiCacheMetric struct {
+	name string
+	stat string
+}
+
+// Serverless caches bill and throttle by ECPU; node-based clusters expose host and engine CPU
+var (
+	serverlessCorrelationMetrics = []elastiCacheMetric{
+		{"ElastiCacheProcessingUnits", "Sum"},
+		{"NetworkBytesIn", "Sum"},
+		{"NetworkBytesOut", "Sum"},
+		{"Evictions", "Sum"},
+		{"ThrottledCmds", "Sum"},
+		{"CurrConnections", "Average"},
+		{"NewConnections", "Sum"},
+	}
+	nodeCorrelationMetrics = []elastiCacheMetric{
+		{"EngineCPUUtilization", "Average"},
+		{"CPUUtilization", "Average"},
+		{"NetworkBytesIn", "Sum"},
+		{"NetworkBytesOut", "Sum"},
+		{"Evictions", "Sum"},
+		{"CurrConnections", "Average"},
+		{"NewConnections", "Sum"},
+		{"SwapUsage", "Average"},
+	}
+)
+
+// fetchServerMetrics reads the per-minute AWS/ElastiCache metrics of the cache over the run
+func fetchServerMetrics(sc *ServerCorrelation, start, end time.Time) ([]ServerMetricSeries, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), correlationFetchTimeout)
+	defer cancel()
+	cfg, err := loadAWSConfig(ctx, sc.Region, sc.RoleARN)
+	if err != nil {
+		return nil, err
+	}
+	client := cloudwatch.NewFromConfig(cfg)
+
+	metrics, dimension := nodeCorrelationMetrics, "CacheClusterId"
+	if sc.Serverless {
+		metrics, dimension = serverlessCorrelationMetrics, "clusterId"
+	}
+	queries := make([]types.MetricDataQuery, len(metrics))
+	for i, metric := range metrics {
+		queries[i] = types.MetricDataQuery{
+			Id: aws.String(fmt.Sprintf("m%d", i)),
+			MetricStat: &types.MetricStat{
+				Metric: &types.Metric{
+					Namespace:  aws.String("AWS/ElastiCache"),
+					MetricName: aws.String(metric.name),
+					Dimensions: []types.Dimension{{Name: aws.String(dimension), Value: aws.String(sc.CacheID)}},
+				},
+				Period: aws.Int32(int32(correlationPeriod.Seconds())),
+				Stat:   aws.String(metric.stat),
+			},
+		}
+	}
+
+	series := make([]ServerMetricSeries, len(metrics))
+	for i, metric := range metrics {
+		series[i] = ServerMetricSeries{Name: metric.name, Points: make(map[int64]float64)}
+	}
+	paginator := cloudwatch.NewGetMetricDataPaginator(client, &cloudwatch.GetMetricDataInput{
+		MetricDataQueries: queries,
+		StartTime:         aws.Time(start.Truncate(correlationPeriod)),
+		EndTime:           aws.Time(end.Truncate(correlationPeriod).Add(correlationPeriod)),
+	})
+	found := 0
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch CloudWatch metrics of %s: %w", sc.CacheID, err)
+		}
+		for _, result := range page.MetricDataResults {
+			var index int
+			if _, err := fmt.Sscanf(aws.ToString(result.Id), "m%d", &index); err != nil || index >= len(series) {
+				continue
+			}
+			for j, timestamp := range result.Timestamps {
+				if j < len(result.Values) {
+					series[index].Points[timestamp.Unix()] = result.Values[j]
+					found++
+				}
+			}
+		}
+	}
+	if found == 0 {
+		return nil, fmt.Errorf("no CloudWatch metrics found for %s (wrong cache ID or region, or add ,serverless?)", sc.CacheID)
+	}
+	return series, nil
+}
@@ -46,6 +46,24 @@ type DataGenerator struct {
 	DataSizePattern string
 	ExpiryRange     string
 	DefaultTTL      int // Default TTL in seconds (0 = no expiration)
+
+	// Random sizes and TTLs come from Rand when set (a worker's stream), otherwise from
+	// the global generator; a Rand must not be shared between goroutines
+	Rand *rand.Rand
+}
+
+// WithRand returns a copy of the generator drawing from rng
+func (dg *DataGenerator) WithRand(rng *rand.Rand) *DataGenerator {
+	clone := *dg
+	clone.Rand = rng
+	return &clone
+}
+
+func (dg *DataGenerator) intn(n int) int {
+	if dg.Rand != nil {
+		return dg.Rand.Intn(n)
+	}
+	return rand.Intn(n)
 }
 
 func (dg *DataGenerator) GenerateData() ([]byte, error) {
@@ -60,7 +78,7 @@ func (dg *DataGenerator) GenerateData() ([]byte, error) {
 			if err1 == nil && err2 == nil && min <= max {
 				if dg.DataSizePattern == "R" {
 					// Random size between min and max
-					size = min + rand.Intn(max-min+1)
+					size = min + dg.intn(max-min+1)
 				} else {
 					// For simplicity, use average for "S" pattern
 					size = (min + max) / 2
@@ -69,6 +87,11 @@ func (dg *DataGenerator) GenerateData() ([]byte, error) {
 		}
 	}
 
+	return dg.GenerateDataOfSize(size)
+}
+
+// GenerateDataOfSize generates a value of an exact size (e.g. a size taken from a recorded operation log)
+func (dg *DataGenerator) GenerateDataOfSize(size int) ([]byte, error) {
 	data := make([]byte, size)
 
 	if dg.RandomData {
@@ -93,7 +116,7 @@ func (dg *DataGenerator) GetExpiration() time.Duration {
 			min, err1 := strconv.Atoi(parts[0])
 			max, err2 := strconv.Atoi(parts[1])
 			if err1 == nil && err2 == nil && min <= max {
-				expiry := min + rand.Intn(max-min+1)
+				expiry := min + dg.intn(max-min+1)
 				return time.Duration(expiry) * time.Second
 			}
 		}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{populateCmd, runCmd} {
+		cmd.Flags().String("db", "", "Redis logical database(s) to use instead of the URI's: N, a list (0,2,5) or a range (0-3); run assigns workers to databases round-robin")
+	}
+}
+
+// parseDatabases parses a --db value into database indexes
+func parseDatabases(value string) ([]int, error) {
+	var dbs []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(first)
+		if err != nil || from < 0 {
+			return nil, fmt.Errorf("invalid database index '%s'", part)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(last); err != nil || to < from {
+				return nil, fmt.Errorf("invalid database range '%s'", part)
+			}
+		}
+		for db := from; db <= to; db++ {
+			dbs = append(dbs, db)
+		}
+	}
+	return dbs, nil
+}
+
+// singleDatabase returns the database selected with --db when it names exactly one;
+// ok is false when --db is unset or lists several databases
+func singleDatabase(cmd *cobra.Command) (db int, ok bool) {
+	value, _ := cmd.Flags().GetString("db")
+	if value == "" {
+		return 0, false
+	}
+	dbs, err := parseDatabases(value)
+	if err != nil || len(dbs) != 1 {
+		return 0, false
+	}
+	return dbs[0], true
+}
+
+// DatabaseStats assigns workers to logical databases round-robin (worker i uses database
+// i mod len(databases)) and breaks the results down per database
+type DatabaseStats struct {
+	DBs    []int
+	Groups []*ClientGroup
+}
+
+func NewDatabaseStats(dbs []int) *DatabaseStats {
+	ds := &DatabaseStats{DBs: dbs}
+	for _, db := range dbs {
+		ds.Groups = append(ds.Groups, NewClientGroup(fmt.Sprintf("db%d", db)))
+	}
+	return ds
+}
+
+// GroupOf returns the stats of the database a worker uses
+func (ds *DatabaseStats) GroupOf(workerID int) *ClientGroup {
+	return ds.Groups[workerID%len(ds.DBs)]
+}
+
+// Apply sets a worker's database on its connection configuration
+func (ds *DatabaseStats) Apply(config *RedisConfig, workerID int) *ClientGroup {
+	config.DB = ds.DBs[workerID%len(ds.DBs)]
+	config.OverrideDB = true
+	return ds.GroupOf(workerID)
+}
+
+func (ds *DatabaseStats) Close() {
+	for _, g := range ds.Groups {
+		g.Close()
+	}
+}
+
+// printDatabaseResults prints the per-database breakdown of a multi-database run
+func printDatabaseResults(stats *WorkloadStats) {
+	fmt.Printf("Databases (%d, workers assigned round-robin):\n", len(stats.Databases.DBs))
+	printClientGroups("Database", stats.Databases.Groups)
+}
+
+// newGroupedRedisClient creates and pings the Redis client of a worker whose connection
+// depends on its ID (its ACL user and/or database)
+func newGroupedRedisClient(ctx context.Context, cmd *cobra.Command, stats *WorkloadStats, workerID int) (CacheClient, error) {
+	uri, _ := cmd.Flags().GetString("redis-uri")
+	config, err := redisConfigFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []*ClientGroup
+	if stats.ACL != nil {
+		groups = append(groups, stats.ACL.Apply(&config, workerID))
+	}
+	if stats.Databases != nil {
+		groups = append(groups, stats.Databases.Apply(&config, workerID))
+	}
+
+	config.ResponseTiming = responseTiming
+
+	client, err := NewRedisClientFromURI(uri, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Redis client: %w", err)
+	}
+	// Connect now so authentication failures are reported at setup, not as operation errors
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		if stats.ACL != nil {
+			return nil, fmt.Errorf("ACL user %s failed to connect: %w", config.Username, err)
+		}
+		return nil, fmt.Errorf("failed to connect to database %d: %w", config.DB, err)
+	}
+	for _, g := range groups {
+		atomic.AddInt64(&g.Clients, 1)
+	}
+	return client, nil
+}
@@ -0,0 +1,358 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
+)
+
+func init() {
+	runCmd.Flags().Float64("delete-fraction", 0, "Delete this fraction (0-1) of the keyspace during the run while reads continue, reporting the delete latency, reads of deleted keys and GET latency before, during and after the deletes")
+	runCmd.Flags().Int("delete-rate", 100, "Deletes per second of --delete-fraction")
+	runCmd.Flags().Duration("delete-start", 30*time.Second, "Delay from the start of the run to the first delete of --delete-fraction")
+	runCmd.Flags().Int("delete-clients", 4, "Connections issuing the deletes of --delete-fraction")
+}
+
+// Delete churn phases; GETs are recorded by the phase they complete in
+const (
+	churnBefore = iota
+	churnDuring
+	churnAfter
+)
+
+var churnPhaseNames = [...]string{"before", "during", "after"}
+
+// DeleteChurn deletes a fraction of the keyspace at a controlled rate while the workload
+// keeps reading, spreading the deleted keys over the keyspace with a stride coprime to its
+// size, so hot and cold keys are deleted alike. Deleted key IDs are tracked client-side:
+// GETs of deleted keys are expected to miss and are reported apart, as are the ones that
+// still returned a value. A later SET of a deleted key recreates it.
+type DeleteChurn struct {
+	Fraction float64
+	Rate     int
+	Delay    time.Duration
+	Clients  int
+	Keys     int // Keys to delete
+
+	keyPrefix string
+	keyMin    int
+	totalKeys int
+	stride    int
+	deleted   []uint64 // One bit per key ID, set once its delete succeeded
+	phase     int32
+
+	Deletes      int64
+	DeleteErrors int64
+	DeleteStats  *PerformanceStats
+
+	// GET latency by phase
+	PhaseOps   [3]int64
+	PhaseStats [3]*PerformanceStats
+
+	DeletedMisses int64 // GETs of deleted keys that missed, as expected
+	DeletedHits   int64 // GETs of deleted keys that still returned a value
+	MissStats     *PerformanceStats
+
+	startTime, endTime time.Time
+	stop               chan struct{}
+	done               chan struct{}
+}
+
+func NewDeleteChurn(fraction float64, deleteRate int, delay time.Duration, clients int, keyPrefix string, keyMin, totalKeys int) (*DeleteChurn, error) {
+	if fraction <= 0 || fraction > 1 {
+		return nil, fmt.Errorf("delete fraction must be in (0, 1], got: %.2f", fraction)
+	}
+	if deleteRate <= 0 {
+		return nil, fmt.Errorf("delete rate must be positive, got: %d", deleteRate)
+	}
+	if clients <= 0 {
+		return nil, fmt.Errorf("delete clients must be positive, got: %d", clients)
+	}
+	if delay < 0 {
+		return nil, fmt.Errorf("delete start must not be negative, got: %v", delay)
+	}
+
+	dc := &DeleteChurn{
+		Fraction:    fraction,
+		Rate:        deleteRate,
+		Delay:       delay,
+		Clients:     clients,
+		Keys:        max(int(fraction*float64(totalKeys)), 1),
+		keyPrefix:   keyPrefix,
+		keyMin:      keyMin,
+		totalKeys:   totalKeys,
+		stride:      coprimeStride(totalKeys),
+		deleted:     make([]uint64, (totalKeys+63)/64),
+		DeleteStats: NewPerformanceStats(),
+		MissStats:   NewPerformanceStats(),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	dc.DeleteStats.Name = "DELETE"
+	dc.MissStats.Name = "DELETED_MISS"
+	for i := range dc.PhaseStats {
+		dc.PhaseStats[i] = NewPerformanceStats()
+		dc.PhaseStats[i].Name = "GET_" + churnPhaseNames[i]
+	}
+	return dc, nil
+}
+
+func (dc *DeleteChurn) Close() {
+	dc.DeleteStats.Close()
+	dc.MissStats.Close()
+	for _, ps := range dc.PhaseStats {
+		ps.Close()
+	}
+}
+
+// coprimeStride returns a step of about 0.618 times n sharing no factor with n, so stepping
+// through 0..n-1 with it visits every index once in a scattered order
+func coprimeStride(n int) int {
+	gcd := func(a, b int) int {
+		for b != 0 {
+			a, b = b, a%b
+		}
+		return a
+	}
+	stride := max(int(float64(n)*0.618), 1)
+	for gcd(stride, n) != 1 {
+		stride++
+	}
+	return stride
+}
+
+// IsDeleted reports whether a key ID was deleted (and not written since)
+func (dc *DeleteChurn) IsDeleted(keyID int) bool {
+	index := keyID - dc.keyMin
+	if index < 0 || index >= dc.totalKeys {
+		return false
+	}
+	return atomic.LoadUint64(&dc.deleted[index/64])&(1<<(index%64)) != 0
+}
+
+// markDeleted records the deletion of a key index
+func (dc *DeleteChurn) markDeleted(index int) {
+	atomic.OrUint64(&dc.deleted[index/64], 1<<(index%64))
+}
+
+// Recreated clears the deleted mark of a key ID written again
+func (dc *DeleteChurn) Recreated(keyID int) {
+	index := keyID - dc.keyMin
+	if index < 0 || index >= dc.totalKeys {
+		return
+	}
+	atomic.AndUint64(&dc.deleted[index/64], ^(uint64(1) << (index % 64)))
+}
+
+// Start deletes the keys in the background after the delay, using clients from connect
+func (dc *DeleteChurn) Start(connect func() (CacheClient, error), timeoutSeconds int) {
+	go func() {
+		defer close(dc.done)
+		select {
+		case <-time.After(dc.Delay):
+		case <-dc.stop:
+			return
+		}
+
+		var clients []CacheClient
+		for i := 0; i < dc.Clients; i++ {
+			client, err := connect()
+			if err != nil {
+				log.Printf("Delete churn: failed to create a client: %v", err)
+				continue
+			}
+			defer client.Close()
+			clients = append(clients, client)
+		}
+		if len(clients) == 0 {
+			log.Printf("Delete churn: no client, skipping the deletes")
+			return
+		}
+
+		dc.startTime = time.Now()
+		atomic.StoreInt32(&dc.phase, churnDuring)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-dc.stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		limiter := rate.NewLimiter(rate.Limit(dc.Rate), 1)
+		var next int64 = -1
+		var wg sync.WaitGroup
+		for _, client := range clients {
+			wg.Add(1)
+			go func(client CacheClient) {
+				defer wg.Done()
+				for {
+					i := int(atomic.AddInt64(&next, 1))
+					if i >= dc.Keys || limiter.Wait(ctx) != nil {
+						return
+					}
+					index := int((int64(i) * int64(dc.stride)) % int64(dc.totalKeys))
+					dc.delete(ctx, client, index, timeoutSeconds)
+				}
+			}(client)
+		}
+		wg.Wait()
+
+		dc.endTime = time.Now()
+		atomic.StoreInt32(&dc.phase, churnAfter)
+	}()
+}
+
+// delete removes one key, marking it deleted once the cache acknowledged it
+func (dc *DeleteChurn) delete(ctx context.Context, client CacheClient, index, timeoutSeconds int) {
+	opCtx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := client.Delete(opCtx, formatKey(dc.keyPrefix, dc.keyMin+index))
+	latency := time.Since(start)
+	if err != nil {
+		if ctx.Err() == nil {
+			atomic.AddInt64(&dc.DeleteErrors, 1)
+		}
+		return
+	}
+	dc.markDeleted(index)
+	atomic.AddInt64(&dc.Deletes, 1)
+	dc.DeleteStats.RecordLatency(latency.Microseconds())
+}
+
+// Stop ends the deletes
+func (dc *DeleteChurn) Stop() {
+	select {
+	case <-dc.stop:
+	default:
+		close(dc.stop)
+		<-dc.done
+	}
+}
+
+// Record adds a successful GET to the stats of the current phase and, for deleted keys,
+// to the post-delete read stats
+func (dc *DeleteChurn) Record(result workloadResult) {
+	if result.isError || result.isNegative {
+		return
+	}
+	phase := atomic.LoadInt32(&dc.phase)
+	atomic.AddInt64(&dc.PhaseOps[phase], 1)
+	dc.PhaseStats[phase].RecordLatency(result.latencyMicros)
+
+	if !result.deletedKey {
+		return
+	}
+	if result.miss {
+		atomic.AddInt64(&dc.DeletedMisses, 1)
+		dc.MissStats.RecordLatency(result.latencyMicros)
+	} else {
+		atomic.AddInt64(&dc.DeletedHits, 1)
+	}
+}
+
+// DeleteChurnPhase is the GET latency of one phase of the delete churn
+type DeleteChurnPhase struct {
+	Phase string `json:"phase"` // before, during or after the deletes
+	LatencySummary
+}
+
+// DeleteChurnSummary is the machine-readable result of the delete churn
+type DeleteChurnSummary struct {
+	Fraction        float64            `json:"fraction"`
+	Rate            int                `json:"rate"`
+	Keys            int                `json:"keys"` // Keys to delete
+	DurationSeconds float64            `json:"duration_seconds"`
+	Deletes         LatencySummary     `json:"deletes"`
+	Gets            []DeleteChurnPhase `json:"gets"`
+	DeletedMisses   LatencySummary     `json:"deleted_misses"` // GETs of deleted keys that missed
+	DeletedHits     int64              `json:"deleted_hits"`   // GETs of deleted keys that returned a value
+}
+
+// duration returns how long the deletes ran (so far)
+func (dc *DeleteChurn) duration() time.Duration {
+	switch atomic.LoadInt32(&dc.phase) {
+	case churnBefore:
+		return 0
+	case churnDuring:
+		return time.Since(dc.startTime)
+	}
+	return dc.endTime.Sub(dc.startTime)
+}
+
+// Summary returns the results of the delete churn
+func (dc *DeleteChurn) Summary() *DeleteChurnSummary {
+	seconds := dc.duration().Seconds()
+	summary := &DeleteChurnSummary{
+		Fraction:        dc.Fraction,
+		Rate:            dc.Rate,
+		Keys:            dc.Keys,
+		DurationSeconds: seconds,
+		Deletes:         summarizeTotals(dc.DeleteStats, atomic.LoadInt64(&dc.Deletes), atomic.LoadInt64(&dc.DeleteErrors), seconds),
+		DeletedMisses:   summarizeTotals(dc.MissStats, atomic.LoadInt64(&dc.DeletedMisses), 0, 0),
+		DeletedHits:     atomic.LoadInt64(&dc.DeletedHits),
+	}
+	for i, ps := range dc.PhaseStats {
+		summary.Gets = append(summary.Gets, DeleteChurnPhase{
+			Phase:          churnPhaseNames[i],
+			LatencySummary: summarizeTotals(ps, atomic.LoadInt64(&dc.PhaseOps[i]), 0, 0),
+		})
+	}
+	return summary
+}
+
+// validateDeleteChurn rejects --delete-fraction where keys aren't plain GET/SET keys
+func validateDeleteChurn(cmd *cobra.Command) error {
+	if fraction, _ := cmd.Flags().GetFloat64("delete-fraction"); fraction == 0 {
+		return nil
+	}
+	if replay, _ := cmd.Flags().GetString("replay-self"); replay != "" {
+		return fmt.Errorf("delete churn cannot be combined with --replay-self: the replayed log would not include the deletes")
+	}
+	return nil
+}
+
+// deleteChurnFromFlags creates the delete churn selected with --delete-fraction (nil when not set)
+func deleteChurnFromFlags(cmd *cobra.Command, keyPrefix string, keyMin, totalKeys int) (*DeleteChurn, error) {
+	fraction, _ := cmd.Flags().GetFloat64("delete-fraction")
+	if fraction == 0 {
+		return nil, nil
+	}
+	deleteRate, _ := cmd.Flags().GetInt("delete-rate")
+	delay, _ := cmd.Flags().GetDuration("delete-start")
+	clients, _ := cmd.Flags().GetInt("delete-clients")
+	return NewDeleteChurn(fraction, deleteRate, delay, clients, keyPrefix, keyMin, totalKeys)
+}
+
+// printDeleteChurnResults prints the deletes and how reads behaved around them
+func printDeleteChurnResults(dc *DeleteChurn) {
+	summary := dc.Summary()
+	fmt.Printf("Delete Churn (%.1f%% of the keyspace, %d keys at %d/s after %v):\n", dc.Fraction*100, dc.Keys, dc.Rate, dc.Delay)
+	if summary.DurationSeconds == 0 {
+		fmt.Println("The deletes did not start during the run")
+		fmt.Println()
+		return
+	}
+	fmt.Printf("Deletes - Ops: %d, Errors: %d, %.0f/s over %.1fs, %s\n", summary.Deletes.Ops, summary.Deletes.Errors,
+		summary.Deletes.QPS, summary.DurationSeconds, formatPercentiles(summary.Deletes.Percentiles))
+	for _, phase := range summary.Gets {
+		if phase.Ops > 0 {
+			fmt.Printf("GET %-6s deletes - Ops: %d, %s\n", phase.Phase, phase.Ops, formatPercentiles(phase.Percentiles))
+		}
+	}
+	fmt.Printf("Reads of deleted keys: %d missed, %d still returned a value\n", summary.DeletedMisses.Ops, summary.DeletedHits)
+	if summary.DeletedMisses.Ops > 0 {
+		fmt.Printf("Post-delete Miss - %s\n", formatPercentiles(summary.DeletedMisses.Percentiles))
+	}
+	fmt.Println()
+}
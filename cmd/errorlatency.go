@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+func init() {
+	runCmd.Flags().Bool("error-latency", false, "Record how long failed GETs and SETs took before failing, per metrics window, and split the failures into fast failures and timeouts")
+}
+
+// errorLatencyReportRows bounds the windows printed in the report; the JSON summary has all
+const errorLatencyReportRows = 24
+
+// ErrorLatency records the time to failure of failed operations, which the operation stats
+// drop: a fast failure (connection refused, throttled, NOPERM) costs a user little and is
+// worth retrying at once, while an operation that hangs until the timeout holds the caller
+// for the whole timeout and argues for a shorter one and fewer retries.
+type ErrorLatency struct {
+	TimeoutMicros int64 // Operation timeout (--timeout)
+
+	GetErrors int64
+	SetErrors int64
+	Gets      *PerformanceStats
+	Sets      *PerformanceStats
+}
+
+func NewErrorLatency(timeoutSeconds int) *ErrorLatency {
+	el := &ErrorLatency{
+		TimeoutMicros: int64(timeoutSeconds) * 1000 * 1000,
+		Gets:          NewPerformanceStats(),
+		Sets:          NewPerformanceStats(),
+	}
+	el.Gets.Name = "GET_ERROR"
+	el.Sets.Name = "SET_ERROR"
+	return el
+}
+
+func (el *ErrorLatency) Close() {
+	el.Gets.Close()
+	el.Sets.Close()
+}
+
+// Record adds the time to failure of a failed GET or SET; failures before the operation
+// was sent (data generation) have none
+func (el *ErrorLatency) Record(result workloadResult) {
+	if !result.isError || result.failedMicros == 0 {
+		return
+	}
+	if result.isSet {
+		atomic.AddInt64(&el.SetErrors, 1)
+		el.Sets.RecordLatency(result.failedMicros)
+	} else {
+		atomic.AddInt64(&el.GetErrors, 1)
+		el.Gets.RecordLatency(result.failedMicros)
+	}
+}
+
+// errorLatencyClasses splits failures into those that failed within the P99 of the
+// successful operations (fast failures), those that ran into the timeout (at 90% of it or
+// more) and the rest
+func errorLatencyClasses(failures *hdrhistogram.Histogram, successP99, timeoutMicros int64) (fast, timedOut, other int64) {
+	for _, bar := range failures.Distribution() {
+		switch {
+		case bar.Count == 0:
+		case bar.To <= successP99:
+			fast += bar.Count
+		case bar.From >= timeoutMicros*9/10:
+			timedOut += bar.Count
+		default:
+			other += bar.Count
+		}
+	}
+	return fast, timedOut, other
+}
+
+// ErrorLatencyClass is the split of the failures of one operation type
+type ErrorLatencyClass struct {
+	FastPercent    float64 `json:"fast_percent"`    // Failed within the P99 of the successful operations
+	TimeoutPercent float64 `json:"timeout_percent"` // Failed at 90% of the timeout or later
+	OtherPercent   float64 `json:"other_percent"`
+}
+
+// ErrorLatencySummary is the machine-readable time to failure of the failed operations;
+// Ops of the latency summaries counts failures
+type ErrorLatencySummary struct {
+	TimeoutMicros int64             `json:"timeout_us"`
+	Get           LatencySummary    `json:"get"`
+	Set           LatencySummary    `json:"set"`
+	GetClasses    ErrorLatencyClass `json:"get_classes"`
+	SetClasses    ErrorLatencyClass `json:"set_classes"`
+}
+
+// classify splits the failures recorded in ps against the successful operations in success
+func (el *ErrorLatency) classify(ps, success *PerformanceStats) ErrorLatencyClass {
+	total := ps.Histogram.TotalCount()
+	if total == 0 {
+		return ErrorLatencyClass{}
+	}
+	successP99 := int64(0)
+	if success.Histogram.TotalCount() > 0 {
+		successP99 = success.Histogram.ValueAtQuantile(99)
+	}
+	fast, timedOut, other := errorLatencyClasses(ps.Histogram, successP99, el.TimeoutMicros)
+	return ErrorLatencyClass{
+		FastPercent:    float64(fast) / float64(total) * 100,
+		TimeoutPercent: float64(timedOut) / float64(total) * 100,
+		OtherPercent:   float64(other) / float64(total) * 100,
+	}
+}
+
+// Summary returns the time to failure with its per-window breakdown; recording must have stopped
+func (el *ErrorLatency) Summary(stats *WorkloadStats) *ErrorLatencySummary {
+	return &ErrorLatencySummary{
+		TimeoutMicros: el.TimeoutMicros,
+		Get:           summarizeLatency(el.Gets, atomic.LoadInt64(&el.GetErrors), 0, 0),
+		Set:           summarizeLatency(el.Sets, atomic.LoadInt64(&el.SetErrors), 0, 0),
+		GetClasses:    el.classify(el.Gets, stats.GetStats),
+		SetClasses:    el.classify(el.Sets, stats.SetStats),
+	}
+}
+
+// printErrorLatencyResults prints the time to failure of GETs and SETs, its split into fast
+// failures and timeouts, and the windows in which failures occurred
+func printErrorLatencyResults(stats *WorkloadStats) {
+	el := stats.ErrorLatency
+	getErrors, setErrors := atomic.LoadInt64(&el.GetErrors), atomic.LoadInt64(&el.SetErrors)
+	fmt.Printf("Time to Failure (timeout %v): %d failed GETs, %d failed SETs\n",
+		time.Duration(el.TimeoutMicros)*time.Microsecond, getErrors, setErrors)
+	if getErrors+setErrors == 0 {
+		fmt.Println("No failed operations")
+		fmt.Println()
+		return
+	}
+	for _, op := range []struct {
+		name              string
+		failed            int64
+		failures, success *PerformanceStats
+	}{
+		{"GET", getErrors, el.Gets, stats.GetStats},
+		{"SET", setErrors, el.Sets, stats.SetStats},
+	} {
+		if op.failed == 0 {
+			continue
+		}
+		classes := el.classify(op.failures, op.success)
+		fmt.Printf("Failed %s - %s\n", op.name, formatPercentiles(latencyPercentiles(op.failures.Histogram)))
+		fmt.Printf("Failed %s - %.1f%% fast (within the successful P99), %.1f%% at the timeout, %.1f%% in between\n",
+			op.name, classes.FastPercent, classes.TimeoutPercent, classes.OtherPercent)
+	}
+
+	// Windows with failures, GETs and SETs side by side; the collectors start their windows
+	// independently, so rows are aligned to the window size
+	type windowRow struct{ get, set *hdrhistogram.Histogram }
+	rows := make(map[int64]*windowRow)
+	add := func(window LatencyWindow, set bool) {
+		start := window.StartSecond - window.StartSecond%MetricWindowSizeSeconds
+		row := rows[start]
+		if row == nil {
+			row = &windowRow{}
+			rows[start] = row
+		}
+		target := &row.get
+		if set {
+			target = &row.set
+		}
+		if *target == nil {
+			*target = hdrhistogram.New(1, latencyMaxMicros, 3)
+		}
+		(*target).Merge(window.Histogram)
+	}
+	for _, window := range el.Gets.Windows() {
+		add(window, false)
+	}
+	for _, window := range el.Sets.Windows() {
+		add(window, true)
+	}
+	starts := make([]int64, 0, len(rows))
+	for start := range rows {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+	if len(starts) > errorLatencyReportRows {
+		fmt.Printf("Failures occurred in %d windows; the %d last are shown (all are in the JSON summary)\n",
+			len(starts), errorLatencyReportRows)
+		starts = starts[len(starts)-errorLatencyReportRows:]
+	}
+	fmt.Printf("%-10s %-10s %-10s %-10s %-10s %-10s %-10s\n", "Window", "GET Errs", "GET P50", "GET Max", "SET Errs", "SET P50", "SET Max")
+	column := func(hist *hdrhistogram.Histogram) (int64, int64, int64) {
+		if hist == nil {
+			return 0, 0, 0
+		}
+		return hist.TotalCount(), hist.ValueAtQuantile(50), hist.Max()
+	}
+	for _, start := range starts {
+		getCount, getP50, getMax := column(rows[start].get)
+		setCount, setP50, setMax := column(rows[start].set)
+		fmt.Printf("%-10s %-10d %-10d %-10d %-10d %-10d %-10d\n", time.Unix(start, 0).Format("15:04:05"),
+			getCount, getP50, getMax, setCount, setP50, setMax)
+	}
+	fmt.Printf("(times to failure in μs per %ds window)\n", MetricWindowSizeSeconds)
+	fmt.Println()
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// outputFormats are the formats supported by --output
+var outputFormats = map[string]string{
+	"json": "json",
+	"csv":  "csv",
+	"hdr":  "hlog",
+}
+
+// validateOutputFormat checks an --output value; empty disables the export
+func validateOutputFormat(format string) error {
+	if _, ok := outputFormats[format]; format != "" && !ok {
+		return fmt.Errorf("invalid output format '%s': expected json, csv or hdr", format)
+	}
+	return nil
+}
+
+// defaultOutputFile returns the export filename used when --output-file is not set
+func defaultOutputFile(format string, startTime time.Time) string {
+	return fmt.Sprintf("results-%s.%s", startTime.Format("20060102-150405"), outputFormats[format])
+}
+
+// writeRunOutput exports the results of a run; stats must not be closed yet
+func writeRunOutput(format, filename string, summary *RunSummary, stats *WorkloadStats) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer file.Close()
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(summary)
+	case "csv":
+		err = writeSummaryCSV(file, summary)
+	case "hdr":
+		err = writeHistogramLog(file, summary, stats)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s output: %w", format, err)
+	}
+	return file.Close()
+}
+
+// writeSummaryCSV writes one row per operation type for the whole run ("all") and one per metrics window
+func writeSummaryCSV(file *os.File, summary *RunSummary) error {
+	writer := csv.NewWriter(file)
+	header := []string{
+		"operation", "window_start", "ops", "errors", "qps", "min_us", "mean_us",
+		"p50_us", "p75_us", "p90_us", "p95_us", "p99_us", "p99_9_us", "p99_99_us", "max_us",
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	operations := []struct {
+		name    string
+		latency *LatencySummary
+	}{
+		{"get", &summary.Get},
+		{"set", &summary.Set},
+		{"setup", summary.Setup},
+	}
+
+	formatInt := func(v int64) string { return strconv.FormatInt(v, 10) }
+	for _, op := range operations {
+		if op.latency == nil {
+			continue
+		}
+		l := op.latency
+		row := []string{
+			op.name, "all", formatInt(l.Ops), formatInt(l.Errors), fmt.Sprintf("%.2f", l.QPS),
+			formatInt(l.Min), fmt.Sprintf("%.2f", l.Mean), formatInt(l.P50), formatInt(l.P75), formatInt(l.P90),
+			formatInt(l.P95), formatInt(l.P99), formatInt(l.P999), formatInt(l.P9999), formatInt(l.Max),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+
+		// Windows only carry throughput and the main percentiles
+		for _, w := range l.Windows {
+			row := []string{
+				op.name, w.Start.Format(time.RFC3339), formatInt(w.Ops), "",
+				fmt.Sprintf("%.2f", float64(w.Ops)/MetricWindowSizeSeconds), "", "",
+				formatInt(w.P50), "", "", formatInt(w.P95), formatInt(w.P99), "", "", formatInt(w.Max),
+			}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeHistogramLog writes the per-window histograms as an HDR histogram log readable by
+// HistogramLogReader (and tools such as HistogramLogAnalyzer), tagged by operation type.
+// Values are in microseconds, so the interval max column is reported in milliseconds.
+func writeHistogramLog(file *os.File, summary *RunSummary, stats *WorkloadStats) error {
+	logWriter := hdrhistogram.NewHistogramLogWriter(file)
+	startMs := summary.StartTime.UnixNano() / int64(time.Millisecond)
+	// The library writes the base time in whole seconds, and windows start on whole seconds:
+	// interval offsets are taken from the second the run started in, so none is negative
+	baseMs := startMs - startMs%1000
+
+	header := []error{
+		logWriter.OutputLogFormatVersion(),
+		logWriter.OutputComment(fmt.Sprintf("[Logged with serverless-cache-benchmark, engine %s, values in microseconds]", summary.Engine)),
+		logWriter.OutputComment(fmt.Sprintf("[Build: %s]", currentBuildInfo())),
+	}
+	if summary.Command != nil {
+		// Comments are single lines: the resolved flags are written as compact JSON
+		flags, err := json.Marshal(summary.Command.Flags)
+		if err != nil {
+			return err
+		}
+		header = append(header,
+			logWriter.OutputComment(fmt.Sprintf("[Command: %s]", summary.Command)),
+			logWriter.OutputComment(fmt.Sprintf("[Flags: %s]", flags)))
+	}
+	for _, err := range append(header,
+		writeHistogramLogStartTime(file, summary.StartTime),
+		logWriter.OutputBaseTime(baseMs),
+		logWriter.OutputLegend(),
+	) {
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, op := range []struct {
+		tag   string
+		stats *PerformanceStats
+	}{
+		{"GET", stats.GetStats},
+		{"SET", stats.SetStats},
+	} {
+		err := op.stats.EachWindow(func(window LatencyWindow) error {
+			start := float64(window.StartSecond*1000-baseMs) / 1000
+			return writeHistogramLogInterval(file, op.tag, start, window.Histogram)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHistogramLogStartTime writes the StartTime header with millisecond precision, as the
+// Java writer does; the library's truncates it to seconds and misformats the date
+func writeHistogramLogStartTime(w io.Writer, start time.Time) error {
+	_, err := fmt.Fprintf(w, "#[StartTime: %.3f (seconds since epoch), %s]\n",
+		float64(start.UnixMilli())/1000, start.UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+	return err
+}
+
+// writeHistogramLogInterval writes one metrics window as an interval line of an HDR histogram log.
+// Interval lines are written directly: the library writer expects histogram timestamps in a mix
+// of units and does not emit the interval length.
+func writeHistogramLogInterval(w io.Writer, tag string, start float64, hist *hdrhistogram.Histogram) error {
+	payload, err := hist.Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "Tag=%s,%.3f,%.3f,%.3f,%s\n", tag, start, float64(MetricWindowSizeSeconds),
+		float64(hist.Max())/1000, payload)
+	return err
+}
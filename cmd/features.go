@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+// buildFeatures lists the optional integrations and whether they are compiled in; files
+// behind a build tag flip their entry from init()
+var buildFeatures = map[string]string{
+	"aws":        "", // -tags noaws
+	"http3":      "", // -tags noquic
+	"prometheus": "", // -tags noprometheus
+}
+
+// buildFeatureTags are the tags that leave each feature out
+var buildFeatureTags = map[string]string{
+	"aws":        "noaws",
+	"http3":      "noquic",
+	"prometheus": "noprometheus",
+}
+
+// registerBuildFeature marks an optional integration as compiled in
+func registerBuildFeature(name, description string) {
+	buildFeatures[name] = description
+}
+
+var featuresCmd = &cobra.Command{
+	Use:   "features",
+	Short: "List the optional integrations compiled into this binary",
+	Long: `List the optional integrations compiled into this binary.
+
+A minimal binary for tiny containers and air-gapped environments leaves out the AWS SDK
+(cache-type lambda, CloudWatch, S3/DynamoDB reports, Secrets Manager/SSM passwords, the
+IAM preflight), the QUIC stack of HTTP/3 and the Prometheus endpoint:
+
+  go build -tags noaws,noquic,noprometheus .   # or: make build-minimal`,
+	Run: func(cmd *cobra.Command, args []string) {
+		names := make([]string, 0, len(buildFeatures))
+		for name := range buildFeatures {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if description := buildFeatures[name]; description != "" {
+				fmt.Printf("  %-12s included  %s\n", name, description)
+			} else {
+				fmt.Printf("  %-12s excluded  (built with -tags %s)\n", name, buildFeatureTags[name])
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(featuresCmd)
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// File sync modes for FileClient writes
+const (
+	FileSyncNone = "none" // rely on the page cache, no fsync
+	FileSyncData = "data" // fsync the file before rename
+	FileSyncFull = "full" // fsync the file and its parent directory after rename
+)
+
+func init() {
+	RegisterBackend(&Backend{
+		Name: "file",
+		AddFlags: func(cmd *cobra.Command) {
+			cmd.Flags().String("file-dir", "./file-cache", "Directory for the file-backed target, e.g. an EFS mount (cache-type file)")
+			cmd.Flags().String("file-fsync", "none", "File write durability: none, data (fsync file) or full (fsync file and directory)")
+		},
+		New: func(ctx context.Context, cmd *cobra.Command) (CacheClient, error) {
+			dir, _ := cmd.Flags().GetString("file-dir")
+			fsyncMode, _ := cmd.Flags().GetString("file-fsync")
+
+			client, err := NewFileClient(dir, fsyncMode)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create file client: %w", err)
+			}
+			return client, nil
+		},
+	})
+}
+
+// fileHeaderSize is the size of the expiry header stored in front of every value
+const fileHeaderSize = 8
+
+// FileClient implements CacheClient on a local or network (EFS/NFS) filesystem,
+// storing one file per key. Files are spread across 256 subdirectories to keep
+// directory sizes manageable, and writes go through a temp file + rename so
+// readers never observe partially written values. Files are named after the SHA-256
+// of their key, so any key maps to its own valid file name, apart from the temp files.
+type FileClient struct {
+	baseDir  string
+	syncMode string
+}
+
+func NewFileClient(baseDir, syncMode string) (*FileClient, error) {
+	if baseDir == "" {
+		return nil, fmt.Errorf("file cache directory is required")
+	}
+
+	switch syncMode {
+	case FileSyncNone, FileSyncData, FileSyncFull:
+	default:
+		return nil, fmt.Errorf("invalid fsync mode: %s. Must be '%s', '%s' or '%s'", syncMode, FileSyncNone, FileSyncData, FileSyncFull)
+	}
+
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create file cache directory: %w", err)
+	}
+
+	return &FileClient{
+		baseDir:  baseDir,
+		syncMode: syncMode,
+	}, nil
+}
+
+// pathForKey maps a key to its directory and file path. Hex names never start with the
+// dot of the temp files, and don't depend on which characters or length a key has.
+func (f *FileClient) pathForKey(key string) (string, string) {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	dir := filepath.Join(f.baseDir, name[:2])
+	return dir, filepath.Join(dir, name)
+}
+
+func (f *FileClient) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	dir, path := f.pathForKey(key)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var expiresAt int64
+	if expiration > 0 {
+		expiresAt = time.Now().Add(expiration).UnixNano()
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	header := make([]byte, fileHeaderSize)
+	binary.LittleEndian.PutUint64(header, uint64(expiresAt))
+
+	if _, err := tmp.Write(header); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+
+	if f.syncMode != FileSyncNone {
+		if err := tmp.Sync(); err != nil {
+			tmp.Close()
+			os.Remove(tmpName)
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	if f.syncMode == FileSyncFull {
+		d, err := os.Open(dir)
+		if err != nil {
+			return err
+		}
+		defer d.Close()
+		return d.Sync()
+	}
+	return nil
+}
+
+func (f *FileClient) Get(ctx context.Context, key string) ([]byte, error) {
+	_, path := f.pathForKey(key)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrCacheMiss
+		}
+		return nil, err
+	}
+	if len(data) < fileHeaderSize {
+		return nil, fmt.Errorf("corrupt cache file for key %s", key)
+	}
+
+	expiresAt := int64(binary.LittleEndian.Uint64(data[:fileHeaderSize]))
+	if expiresAt > 0 && time.Now().UnixNano() > expiresAt {
+		return nil, ErrCacheMiss
+	}
+
+	return data[fileHeaderSize:], nil
+}
+
+func (f *FileClient) Delete(ctx context.Context, key string) error {
+	_, path := f.pathForKey(key)
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Connect is a no-op: files are opened per operation
+func (f *FileClient) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (f *FileClient) Ping(ctx context.Context) error {
+	info, err := os.Stat(f.baseDir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", f.baseDir)
+	}
+	return nil
+}
+
+func (f *FileClient) Close() error {
+	return nil
+}
+
+func (f *FileClient) Name() string {
+	return "File"
+}
@@ -0,0 +1,355 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	runCmd.Flags().Duration("fragmentation-watch", 0, "Sample the server memory (INFO memory: used_memory vs used_memory_rss) at this interval and report the fragmentation trend next to the latency trend, for soak tests (0 = off)")
+}
+
+// fragmentationReportRows bounds the samples printed in the report; the JSON summary has all
+const fragmentationReportRows = 24
+
+// FragmentationSample is the server memory at one poll and the latency since the previous one
+type FragmentationSample struct {
+	Time   time.Time `json:"time"`
+	UsedMB float64   `json:"used_mb"`
+	RSSMB  float64   `json:"rss_mb"`
+	Ratio  float64   `json:"ratio"` // RSS / used memory
+	Ops    int64     `json:"ops"`
+	Errors int64     `json:"errors"`
+	P50    int64     `json:"p50_us"`
+	P99    int64     `json:"p99_us"`
+}
+
+// FragmentationSummary is the machine-readable memory fragmentation trend of a run
+type FragmentationSummary struct {
+	Interval       string                `json:"interval"`
+	Samples        []FragmentationSample `json:"samples"`
+	PollsFailed    int64                 `json:"polls_failed"`
+	RatioPerHour   float64               `json:"ratio_per_hour"`        // Slope of the fragmentation ratio
+	P99PerHour     float64               `json:"p99_us_per_hour"`       // Slope of the interval P99
+	RSSMBPerHour   float64               `json:"rss_mb_per_hour"`       // Slope of the RSS
+	RatioP99Correl float64               `json:"ratio_p99_correlation"` // Pearson correlation of the ratio and the interval P99
+	Degrading      bool                  `json:"degrading,omitempty"`   // Fragmentation and P99 rose together
+}
+
+// FragmentationWatch samples the memory of the server during long runs. Allocator
+// fragmentation (RSS growing past the memory actually used) builds up over hours of
+// overwrites and expirations and slows the engine down gradually, which short runs never
+// show; the samples put the fragmentation trend next to the latency of each interval.
+type FragmentationWatch struct {
+	Interval    time.Duration
+	PollsFailed int64
+
+	client   *RedisClient // Separate connection, outside the workload's pools
+	mutex    sync.Mutex
+	samples  []FragmentationSample
+	interval *hdrhistogram.Histogram // Latency since the last sample
+	ops      int64
+	errors   int64
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewFragmentationWatch connects to the server and takes the initial sample
+func NewFragmentationWatch(uri string, config RedisConfig, interval time.Duration) (*FragmentationWatch, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("fragmentation watch interval must be positive, got: %v", interval)
+	}
+
+	config.Reauthenticate = false
+	client, err := NewRedisClientFromURI(uri, config)
+	if err != nil {
+		return nil, err
+	}
+	fw := &FragmentationWatch{
+		Interval: interval,
+		client:   client,
+		interval: hdrhistogram.New(1, 60*1000*1000, 3),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if err := fw.sample(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to read the server memory (INFO memory): %w", err)
+	}
+	return fw, nil
+}
+
+// Start samples the server memory in the background until Stop
+func (fw *FragmentationWatch) Start() {
+	go func() {
+		defer close(fw.done)
+		ticker := time.NewTicker(fw.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := fw.sample(); err != nil {
+					fw.mutex.Lock()
+					fw.PollsFailed++
+					fw.mutex.Unlock()
+					log.Printf("Memory fragmentation poll failed: %v", err)
+				}
+			case <-fw.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop takes a last sample and closes the watch's connection
+func (fw *FragmentationWatch) Stop() {
+	fw.stopOnce.Do(func() {
+		close(fw.stop)
+		<-fw.done
+		fw.sample()
+		fw.client.Close()
+	})
+}
+
+// sample reads the server memory and closes the latency interval
+func (fw *FragmentationWatch) sample() error {
+	ctx, cancel := context.WithTimeout(context.Background(), min(fw.Interval, 10*time.Second))
+	defer cancel()
+	used, rss, err := fw.client.MemoryInfo(ctx)
+	if err != nil {
+		return err
+	}
+
+	sample := FragmentationSample{
+		Time:   time.Now(),
+		UsedMB: float64(used) / (1024 * 1024),
+		RSSMB:  float64(rss) / (1024 * 1024),
+	}
+	if used > 0 {
+		sample.Ratio = float64(rss) / float64(used)
+	}
+	fw.mutex.Lock()
+	sample.Ops, sample.Errors = fw.ops, fw.errors
+	if fw.ops > 0 {
+		sample.P50 = fw.interval.ValueAtQuantile(50)
+		sample.P99 = fw.interval.ValueAtQuantile(99)
+	}
+	fw.interval.Reset()
+	fw.ops, fw.errors = 0, 0
+	fw.samples = append(fw.samples, sample)
+	fw.mutex.Unlock()
+	return nil
+}
+
+// Record adds an operation to the latency of the current interval
+func (fw *FragmentationWatch) Record(result workloadResult) {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+	if result.isError {
+		fw.errors++
+		return
+	}
+	fw.ops++
+	fw.interval.RecordValue(result.latencyMicros)
+}
+
+// Summary returns the samples so far with the fitted trends
+func (fw *FragmentationWatch) Summary() *FragmentationSummary {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+	summary := &FragmentationSummary{
+		Interval:    fw.Interval.String(),
+		Samples:     append([]FragmentationSample(nil), fw.samples...),
+		PollsFailed: fw.PollsFailed,
+	}
+	if len(fw.samples) < 2 {
+		return summary
+	}
+
+	// The first sample has no interval latency yet; latency trends use the samples with operations
+	start := fw.samples[0].Time
+	var hours, ratios, rss, latencyHours, latencyRatios, p99s []float64
+	for _, s := range fw.samples {
+		h := s.Time.Sub(start).Hours()
+		hours, ratios, rss = append(hours, h), append(ratios, s.Ratio), append(rss, s.RSSMB)
+		if s.Ops > 0 {
+			latencyHours, latencyRatios, p99s = append(latencyHours, h), append(latencyRatios, s.Ratio), append(p99s, float64(s.P99))
+		}
+	}
+	summary.RatioPerHour = linearSlope(hours, ratios)
+	summary.RSSMBPerHour = linearSlope(hours, rss)
+	summary.P99PerHour = linearSlope(latencyHours, p99s)
+	summary.RatioP99Correl = pearsonCorrelation(latencyRatios, p99s)
+
+	first, last := fw.samples[0], fw.samples[len(fw.samples)-1]
+	summary.Degrading = last.Ratio > first.Ratio*1.1 && summary.P99PerHour > 0 && summary.RatioP99Correl > 0.5
+	return summary
+}
+
+// linearSlope is the least-squares slope of y over x (0 with fewer than two points)
+func linearSlope(x, y []float64) float64 {
+	n := float64(len(x))
+	if n < 2 {
+		return 0
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	for i := range x {
+		sumX += x[i]
+		sumY += y[i]
+		sumXY += x[i] * y[i]
+		sumXX += x[i] * x[i]
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
+
+// pearsonCorrelation is the correlation coefficient of x and y (0 when either is constant)
+func pearsonCorrelation(x, y []float64) float64 {
+	n := float64(len(x))
+	if n < 2 {
+		return 0
+	}
+	var sumX, sumY float64
+	for i := range x {
+		sumX += x[i]
+		sumY += y[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+	var covariance, varianceX, varianceY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		covariance += dx * dy
+		varianceX += dx * dx
+		varianceY += dy * dy
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return 0
+	}
+	return covariance / math.Sqrt(varianceX*varianceY)
+}
+
+// parseMemoryInfo reads used_memory and used_memory_rss from an INFO memory reply
+func parseMemoryInfo(info string) (used, rss int64, err error) {
+	found := 0
+	for _, line := range strings.Split(info, "\n") {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok || (name != "used_memory" && name != "used_memory_rss") {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid %s '%s' in INFO memory", name, value)
+		}
+		if name == "used_memory" {
+			used = n
+		} else {
+			rss = n
+		}
+		found++
+	}
+	if found < 2 {
+		return 0, 0, fmt.Errorf("INFO memory has no used_memory and used_memory_rss (unsupported by this engine?)")
+	}
+	return used, rss, nil
+}
+
+// MemoryInfo returns the used memory and RSS of the server in bytes, summed over the
+// primaries in cluster mode
+func (r *RedisClient) MemoryInfo(ctx context.Context) (used, rss int64, err error) {
+	if !r.isCluster {
+		info, err := r.client.Info(ctx, "memory").Result()
+		if err != nil {
+			return 0, 0, err
+		}
+		return parseMemoryInfo(info)
+	}
+
+	var mutex sync.Mutex
+	err = r.clusterClient.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		info, err := node.Info(ctx, "memory").Result()
+		if err != nil {
+			return err
+		}
+		nodeUsed, nodeRSS, err := parseMemoryInfo(info)
+		if err != nil {
+			return err
+		}
+		mutex.Lock()
+		used, rss = used+nodeUsed, rss+nodeRSS
+		mutex.Unlock()
+		return nil
+	})
+	return used, rss, err
+}
+
+// validateFragmentationWatch rejects --fragmentation-watch for engines without INFO memory
+func validateFragmentationWatch(cmd *cobra.Command, cacheType string) error {
+	if interval, _ := cmd.Flags().GetDuration("fragmentation-watch"); interval == 0 {
+		return nil
+	}
+	if cacheType != "redis" {
+		return fmt.Errorf("fragmentation watching is only supported with redis, got: %s", cacheType)
+	}
+	return nil
+}
+
+// fragmentationWatchFromFlags creates the watch selected with --fragmentation-watch (nil when not set)
+func fragmentationWatchFromFlags(cmd *cobra.Command) (*FragmentationWatch, error) {
+	interval, _ := cmd.Flags().GetDuration("fragmentation-watch")
+	if interval == 0 {
+		return nil, nil
+	}
+	uri, _ := cmd.Flags().GetString("redis-uri")
+	config, err := redisConfigFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return NewFragmentationWatch(uri, config, interval)
+}
+
+// printFragmentationResults prints the memory samples with the latency of each interval and
+// the fitted trends
+func printFragmentationResults(stats *WorkloadStats) {
+	summary := stats.Fragmentation.Summary()
+	samples := summary.Samples
+	fmt.Printf("Server Memory Fragmentation (sampled every %s): %d samples, %d failed polls\n",
+		summary.Interval, len(samples), summary.PollsFailed)
+	if len(samples) < 2 {
+		fmt.Println("Not enough samples for a trend; use a shorter --fragmentation-watch or a longer run")
+		fmt.Println()
+		return
+	}
+
+	// Long soaks print an evenly spaced subset, always including the last sample
+	every := (len(samples) + fragmentationReportRows - 1) / fragmentationReportRows
+	fmt.Printf("%-10s %-12s %-12s %-8s %-10s %-10s %-10s\n", "Time", "Used MB", "RSS MB", "Ratio", "Ops", "P50", "P99")
+	for i, s := range samples {
+		if i%every != 0 && i != len(samples)-1 {
+			continue
+		}
+		fmt.Printf("%-10s %-12.1f %-12.1f %-8.2f %-10d %-10d %-10d\n",
+			s.Time.Format("15:04:05"), s.UsedMB, s.RSSMB, s.Ratio, s.Ops, s.P50, s.P99)
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	fmt.Printf("Fragmentation %.2f -> %.2f (%+.3f/h), RSS %+.1f MB/h, P99 %+.0f μs/h, ratio/P99 correlation %.2f\n",
+		first.Ratio, last.Ratio, summary.RatioPerHour, summary.RSSMBPerHour, summary.P99PerHour, summary.RatioP99Correl)
+	if summary.Degrading {
+		fmt.Println("Warning: fragmentation and P99 latency rose together; the engine is degrading over the run")
+	}
+	fmt.Println("(latencies in μs over the interval before each sample)")
+	fmt.Println()
+}
@@ -0,0 +1,354 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// FreshConnStats tracks the phases of the no-pool mode, where every operation
+// resolves, connects, optionally handshakes TLS and authenticates from scratch.
+// This is the access pattern of a Lambda function that doesn't reuse connections.
+type FreshConnStats struct {
+	Addr      string
+	Username  string
+	Password  string
+	DB        int
+	TLSConfig *tls.Config
+
+	DialTimeout time.Duration
+	IOTimeout   time.Duration
+
+	// TLS session resumption: sessions (tickets or IDs) are shared across all
+	// fresh connections, like a warm Lambda execution environment would
+	TLSResumption bool
+	sessionCache  tls.ClientSessionCache
+
+	Connections       int64 // Connections opened (one per operation)
+	ConnectErrors     int64 // Failures before the command could be sent
+	FullHandshakes    int64
+	ResumedHandshakes int64
+	NegotiatedVersion uint32 // TLS version and cipher suite of the last handshake
+	NegotiatedCipher  uint32
+
+	DNSStats        *PerformanceStats
+	ConnectStats    *PerformanceStats
+	TLSStats        *PerformanceStats // Full TLS handshakes
+	ResumedTLSStats *PerformanceStats // Abbreviated handshakes that resumed a session
+	AuthStats       *PerformanceStats
+	CommandStats    *PerformanceStats
+}
+
+func NewFreshConnStats(uri string, dialTimeout, ioTimeout time.Duration, tlsResumption bool) (*FreshConnStats, error) {
+	opts, err := redis.ParseURL(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URI '%s': %w", uri, err)
+	}
+	if tlsResumption && opts.TLSConfig == nil {
+		return nil, fmt.Errorf("TLS session resumption requires a rediss:// URI")
+	}
+
+	fs := &FreshConnStats{
+		Addr:            opts.Addr,
+		Username:        opts.Username,
+		Password:        opts.Password,
+		DB:              opts.DB,
+		TLSConfig:       opts.TLSConfig,
+		DialTimeout:     dialTimeout,
+		IOTimeout:       ioTimeout,
+		TLSResumption:   tlsResumption,
+		DNSStats:        NewPerformanceStats(),
+		ConnectStats:    NewPerformanceStats(),
+		TLSStats:        NewPerformanceStats(),
+		ResumedTLSStats: NewPerformanceStats(),
+		AuthStats:       NewPerformanceStats(),
+		CommandStats:    NewPerformanceStats(),
+	}
+	if tlsResumption {
+		fs.sessionCache = tls.NewLRUClientSessionCache(0)
+	}
+	return fs, nil
+}
+
+// NewClient returns a client that opens a new connection for every operation
+func (fs *FreshConnStats) NewClient() *FreshConnClient {
+	return &FreshConnClient{stats: fs}
+}
+
+// Close shuts down the stats collectors
+func (fs *FreshConnStats) Close() {
+	fs.DNSStats.Close()
+	fs.ConnectStats.Close()
+	fs.TLSStats.Close()
+	fs.ResumedTLSStats.Close()
+	fs.AuthStats.Close()
+	fs.CommandStats.Close()
+}
+
+// FreshConnClient implements CacheClient for Redis without connection reuse.
+// It speaks RESP directly so each phase of the connection can be timed separately.
+type FreshConnClient struct {
+	stats *FreshConnStats
+}
+
+func (f *FreshConnClient) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	args := [][]byte{[]byte("SET"), []byte(key), value}
+	if expiration > 0 {
+		args = append(args, []byte("PX"), []byte(strconv.FormatInt(expiration.Milliseconds(), 10)))
+	}
+	_, _, err := f.do(ctx, args...)
+	return err
+}
+
+func (f *FreshConnClient) Get(ctx context.Context, key string) ([]byte, error) {
+	value, isNil, err := f.do(ctx, []byte("GET"), []byte(key))
+	if err != nil {
+		return nil, err
+	}
+	if isNil {
+		return nil, ErrCacheMiss
+	}
+	return value, nil
+}
+
+func (f *FreshConnClient) Delete(ctx context.Context, key string) error {
+	_, _, err := f.do(ctx, []byte("DEL"), []byte(key))
+	return err
+}
+
+// Connect is a no-op: every operation opens its own connection, which is what is measured
+func (f *FreshConnClient) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (f *FreshConnClient) Ping(ctx context.Context) error {
+	_, _, err := f.do(ctx, []byte("PING"))
+	return err
+}
+
+func (f *FreshConnClient) Close() error {
+	return nil
+}
+
+func (f *FreshConnClient) Name() string {
+	return "Redis (no pool)"
+}
+
+// do runs a single command on a brand new connection: DNS, connect, TLS, auth, command, close
+func (f *FreshConnClient) do(ctx context.Context, args ...[]byte) ([]byte, bool, error) {
+	fs := f.stats
+	atomic.AddInt64(&fs.Connections, 1)
+
+	conn, reader, err := f.connect(ctx)
+	if err != nil {
+		atomic.AddInt64(&fs.ConnectErrors, 1)
+		return nil, false, err
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	value, isNil, err := roundTrip(conn, reader, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	fs.CommandStats.RecordLatency(time.Since(start).Microseconds())
+	return value, isNil, nil
+}
+
+// connect opens and prepares a connection, recording the latency of each phase
+func (f *FreshConnClient) connect(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	fs := f.stats
+
+	host, port, err := net.SplitHostPort(fs.Addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, fs.DialTimeout)
+	defer cancel()
+
+	// DNS, skipped when the address is already an IP
+	ip := host
+	if net.ParseIP(host) == nil {
+		start := time.Now()
+		addrs, err := net.DefaultResolver.LookupHost(dialCtx, host)
+		if err != nil {
+			return nil, nil, fmt.Errorf("DNS lookup failed: %w", err)
+		}
+		fs.DNSStats.RecordLatency(time.Since(start).Microseconds())
+		ip = addrs[0]
+	}
+
+	// TCP connect
+	start := time.Now()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(dialCtx, "tcp", net.JoinHostPort(ip, port))
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect failed: %w", err)
+	}
+	fs.ConnectStats.RecordLatency(time.Since(start).Microseconds())
+
+	if fs.IOTimeout > 0 {
+		conn.SetDeadline(time.Now().Add(fs.IOTimeout))
+	}
+
+	// TLS handshake
+	if fs.TLSConfig != nil {
+		config := fs.TLSConfig.Clone()
+		if config.ServerName == "" {
+			config.ServerName = host
+		}
+		// A nil cache disables resumption, so every handshake is a full one
+		config.ClientSessionCache = fs.sessionCache
+		tlsConn := tls.Client(conn, config)
+
+		start := time.Now()
+		if err := tlsConn.HandshakeContext(dialCtx); err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("TLS handshake failed: %w", err)
+		}
+		latency := time.Since(start).Microseconds()
+		state := tlsConn.ConnectionState()
+		atomic.StoreUint32(&fs.NegotiatedVersion, uint32(state.Version))
+		atomic.StoreUint32(&fs.NegotiatedCipher, uint32(state.CipherSuite))
+		if state.DidResume {
+			atomic.AddInt64(&fs.ResumedHandshakes, 1)
+			fs.ResumedTLSStats.RecordLatency(latency)
+		} else {
+			atomic.AddInt64(&fs.FullHandshakes, 1)
+			fs.TLSStats.RecordLatency(latency)
+		}
+		conn = tlsConn
+	}
+
+	reader := bufio.NewReader(conn)
+
+	// AUTH and SELECT
+	if fs.Password != "" || fs.DB != 0 {
+		start := time.Now()
+		if fs.Password != "" {
+			args := [][]byte{[]byte("AUTH"), []byte(fs.Password)}
+			if fs.Username != "" {
+				args = [][]byte{[]byte("AUTH"), []byte(fs.Username), []byte(fs.Password)}
+			}
+			if _, _, err := roundTrip(conn, reader, args...); err != nil {
+				conn.Close()
+				return nil, nil, fmt.Errorf("AUTH failed: %w", err)
+			}
+		}
+		if fs.DB != 0 {
+			if _, _, err := roundTrip(conn, reader, []byte("SELECT"), []byte(strconv.Itoa(fs.DB))); err != nil {
+				conn.Close()
+				return nil, nil, fmt.Errorf("SELECT failed: %w", err)
+			}
+		}
+		fs.AuthStats.RecordLatency(time.Since(start).Microseconds())
+	}
+
+	return conn, reader, nil
+}
+
+// roundTrip writes a RESP command and reads a single reply
+func roundTrip(w io.Writer, r *bufio.Reader, args ...[]byte) ([]byte, bool, error) {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	if _, err := w.Write(buf); err != nil {
+		return nil, false, err
+	}
+	return readReply(r)
+}
+
+// readReply reads a simple, error, integer or bulk string RESP reply
+func readReply(r *bufio.Reader) ([]byte, bool, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return nil, false, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, false, fmt.Errorf("malformed reply: %q", line)
+	}
+	payload := line[1 : len(line)-2]
+
+	switch line[0] {
+	case '+', ':':
+		return append([]byte(nil), payload...), false, nil
+	case '-':
+		return nil, false, fmt.Errorf("%s", payload)
+	case '$':
+		n, err := strconv.Atoi(string(payload))
+		if err != nil {
+			return nil, false, fmt.Errorf("malformed bulk length: %q", payload)
+		}
+		if n < 0 {
+			return nil, true, nil
+		}
+		value := make([]byte, n+2)
+		if _, err := io.ReadFull(r, value); err != nil {
+			return nil, false, err
+		}
+		return value[:n], false, nil
+	default:
+		return nil, false, fmt.Errorf("unexpected reply type: %q", line[0])
+	}
+}
+
+// printFreshConnResults prints the per-phase breakdown of the no-pool mode
+func printFreshConnResults(stats *WorkloadStats) {
+	fs := stats.FreshConn
+	connections := atomic.LoadInt64(&fs.Connections)
+	if connections == 0 {
+		return
+	}
+
+	fmt.Printf("Fresh Connections (no pool):\n")
+	fmt.Printf("Connections Opened: %d, Connection Errors: %d\n", connections, atomic.LoadInt64(&fs.ConnectErrors))
+	if fs.TLSConfig != nil {
+		full := atomic.LoadInt64(&fs.FullHandshakes)
+		resumed := atomic.LoadInt64(&fs.ResumedHandshakes)
+		resumption := "disabled"
+		if fs.TLSResumption {
+			resumption = "enabled"
+		}
+		fmt.Printf("TLS Handshakes - Full: %d, Resumed: %d (session resumption %s)\n", full, resumed, resumption)
+		if fs.TLSResumption && resumed == 0 && full > 0 {
+			fmt.Printf("Warning: no TLS session was resumed; the server may not support session tickets or IDs\n")
+		}
+	}
+
+	phases := []struct {
+		name  string
+		stats *PerformanceStats
+	}{
+		{"DNS", fs.DNSStats},
+		{"Connect", fs.ConnectStats},
+		{"TLS Handshake (full)", fs.TLSStats},
+		{"TLS Handshake (resumed)", fs.ResumedTLSStats},
+		{"Auth", fs.AuthStats},
+		{"Command", fs.CommandStats},
+	}
+	for _, phase := range phases {
+		total, _, _, _, p50, p95, p99 := phase.stats.GetStats()
+		if total == 0 {
+			continue
+		}
+		fmt.Printf("%s Latency - P50: %d μs, P95: %d μs, P99: %d μs\n", phase.name, p50, p95, p99)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	runCmd.Flags().String("function-library", "", "Redis Function library (Lua file starting with '#!lua name=<library>') loaded with FUNCTION LOAD REPLACE before the run, for FCALL entries of --command-mix (e.g. 'FCALL hit 1 {key} {value}:10')")
+	runCmd.Flags().Bool("function-library-keep", false, "Leave the --function-library loaded after the run instead of deleting it")
+}
+
+// FunctionLoader is implemented by cache clients that manage Redis Function libraries
+type FunctionLoader interface {
+	LoadFunctionLibrary(ctx context.Context, code string) error
+	DeleteFunctionLibrary(ctx context.Context, name string) error
+}
+
+// functionLibraryName returns the library name declared by the shebang line of a library,
+// e.g. "#!lua name=mylib"
+func functionLibraryName(code string) (string, error) {
+	shebang, _, _ := strings.Cut(code, "\n")
+	if !strings.HasPrefix(shebang, "#!") {
+		return "", fmt.Errorf("function library must start with a shebang line like '#!lua name=<library>'")
+	}
+	for _, field := range strings.Fields(shebang[2:]) {
+		if name, ok := strings.CutPrefix(field, "name="); ok && name != "" {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("function library shebang '%s' has no name=<library>", shebang)
+}
+
+// validateFunctionLibrary checks --function-library against the run
+func validateFunctionLibrary(cmd *cobra.Command, cacheType string, mix *CommandMix) error {
+	path, _ := cmd.Flags().GetString("function-library")
+	if path == "" {
+		return nil
+	}
+	if cacheType != "redis" {
+		return fmt.Errorf("function libraries are only supported for Redis")
+	}
+	if mix == nil || !mix.Calls("FCALL", "FCALL_RO") {
+		fmt.Println("Warning: --function-library is loaded but no FCALL entry of --command-mix calls it")
+	}
+	return nil
+}
+
+// setupFunctionLibrary loads --function-library into the cache, returning the cleanup that
+// deletes it again (a no-op without a library or with --function-library-keep)
+func setupFunctionLibrary(cmd *cobra.Command, cacheType string) (func(), error) {
+	path, _ := cmd.Flags().GetString("function-library")
+	if path == "" {
+		return func() {}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read function library: %w", err)
+	}
+	code := string(data)
+	name, err := functionLibraryName(code)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	client, err := createCacheClientForRun(ctx, cacheType, cmd)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	loader, ok := client.(FunctionLoader)
+	if !ok {
+		return nil, fmt.Errorf("%s does not support function libraries", client.Name())
+	}
+	if err := loader.LoadFunctionLibrary(ctx, code); err != nil {
+		return nil, fmt.Errorf("FUNCTION LOAD of library '%s' failed (functions need Redis 7 or a compatible engine): %w", name, err)
+	}
+	fmt.Printf("Function library: %s loaded from %s\n", name, path)
+
+	if keep, _ := cmd.Flags().GetBool("function-library-keep"); keep {
+		return func() {}, nil
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		client, err := createCacheClientForRun(ctx, cacheType, cmd)
+		if err == nil {
+			defer client.Close()
+			err = client.(FunctionLoader).DeleteFunctionLibrary(ctx, name)
+		}
+		if err != nil {
+			log.Printf("Failed to delete function library '%s': %v", name, err)
+		}
+	}, nil
+}
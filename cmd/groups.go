@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// ClientGroup tracks the results of the workers sharing a connection setting, such as
+// an ACL user or a logical database, so the setting's effect shows up in the latencies
+type ClientGroup struct {
+	Name string
+
+	Clients      int64 // Clients connected with this setting
+	Ops          int64
+	Errors       int64
+	NoPermErrors int64
+	GetStats     *PerformanceStats
+	SetStats     *PerformanceStats
+}
+
+func NewClientGroup(name string) *ClientGroup {
+	return &ClientGroup{
+		Name:     name,
+		GetStats: NewPerformanceStats(),
+		SetStats: NewPerformanceStats(),
+	}
+}
+
+// Record adds the result of one of the group's workers
+func (g *ClientGroup) Record(result workloadResult) {
+	switch {
+	case result.isError:
+		atomic.AddInt64(&g.Errors, 1)
+		if result.noPerm {
+			atomic.AddInt64(&g.NoPermErrors, 1)
+		}
+	case result.isSet:
+		atomic.AddInt64(&g.Ops, 1)
+		g.SetStats.RecordLatency(result.latencyMicros)
+	default:
+		atomic.AddInt64(&g.Ops, 1)
+		g.GetStats.RecordLatency(result.latencyMicros)
+	}
+}
+
+func (g *ClientGroup) Close() {
+	g.GetStats.Close()
+	g.SetStats.Close()
+}
+
+// printClientGroups prints a per-group table; label names the grouping column
+func printClientGroups(label string, groups []*ClientGroup) {
+	fmt.Printf("%-16s %-8s %-10s %-8s %-8s %-10s %-10s %-10s %-10s\n",
+		label, "Clients", "Ops", "Errors", "NOPERM", "GET P50", "GET P99", "SET P50", "SET P99")
+	for _, g := range groups {
+		_, _, _, _, getP50, _, getP99 := g.GetStats.GetStats()
+		_, _, _, _, setP50, _, setP99 := g.SetStats.GetStats()
+		fmt.Printf("%-16s %-8d %-10d %-8d %-8d %-10d %-10d %-10d %-10d\n",
+			g.Name, atomic.LoadInt64(&g.Clients), atomic.LoadInt64(&g.Ops), atomic.LoadInt64(&g.Errors),
+			atomic.LoadInt64(&g.NoPermErrors), getP50, getP99, setP50, setP99)
+	}
+	fmt.Println("(latencies in μs)")
+	fmt.Println()
+}
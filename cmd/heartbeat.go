@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// heartbeatTimeout bounds delivering one heartbeat to one sink
+const heartbeatTimeout = 5 * time.Second
+
+func init() {
+	runCmd.Flags().String("heartbeat", "", "Emit periodic liveness heartbeats with run progress to these sinks: file:<path>, an http(s):// webhook and/or cloudwatch (needs --cloudwatch-namespace), comma-separated")
+	runCmd.Flags().Duration("heartbeat-interval", 30*time.Second, "Interval between heartbeats")
+}
+
+// Heartbeat is the liveness record of a running benchmark. Orchestration can restart an
+// agent whose heartbeats stop, or whose ops stop increasing (stalled) during measurement.
+type Heartbeat struct {
+	Timestamp       time.Time `json:"timestamp"`
+	Sequence        int64     `json:"sequence"`
+	Host            string    `json:"host"`
+	PID             int       `json:"pid"`
+	Engine          string    `json:"engine"`
+	WorkloadHash    string    `json:"workload_hash"`
+	Phase           string    `json:"phase"`            // setup, populate, warmup, measurement, teardown or done
+	ProgressPercent float64   `json:"progress_percent"` // Measurement time elapsed of the planned duration
+	ElapsedSeconds  float64   `json:"elapsed_seconds"`
+	TotalOps        int64     `json:"total_ops"`
+	TotalErrors     int64     `json:"total_errors"`
+	Stalled         bool      `json:"stalled"` // No operations completed since the previous heartbeat
+}
+
+// heartbeatSink delivers heartbeats to one destination
+type heartbeatSink interface {
+	Send(ctx context.Context, beat Heartbeat) error
+	String() string
+}
+
+// fileHeartbeatSink replaces a file with the latest heartbeat (its mtime is a liveness signal too)
+type fileHeartbeatSink struct {
+	path string
+}
+
+func (s fileHeartbeatSink) Send(ctx context.Context, beat Heartbeat) error {
+	data, err := json.Marshal(beat)
+	if err != nil {
+		return err
+	}
+	// Write then rename, so readers never see a partial heartbeat
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".heartbeat-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func (s fileHeartbeatSink) String() string { return "file:" + s.path }
+
+// webhookHeartbeatSink POSTs each heartbeat as JSON
+type webhookHeartbeatSink struct {
+	url string
+}
+
+func (s webhookHeartbeatSink) Send(ctx context.Context, beat Heartbeat) error {
+	data, err := json.Marshal(beat)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (s webhookHeartbeatSink) String() string { return s.url }
+
+// cloudWatchHeartbeatSink publishes heartbeats through the run's CloudWatch publisher
+type cloudWatchHeartbeatSink struct {
+	publisher *CloudWatchPublisher
+}
+
+func (s cloudWatchHeartbeatSink) Send(ctx context.Context, beat Heartbeat) error {
+	s.publisher.PublishHeartbeat(beat)
+	return nil
+}
+
+func (s cloudWatchHeartbeatSink) String() string { return "cloudwatch" }
+
+// parseHeartbeatSinks parses --heartbeat; the CloudWatch sink needs the run's publisher
+func parseHeartbeatSinks(value string, publisher *CloudWatchPublisher) ([]heartbeatSink, error) {
+	var sinks []heartbeatSink
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+		case entry == "cloudwatch":
+			if publisher == nil {
+				return nil, fmt.Errorf("cloudwatch heartbeats need --cloudwatch-namespace")
+			}
+			sinks = append(sinks, cloudWatchHeartbeatSink{publisher: publisher})
+		case strings.HasPrefix(entry, "file:"):
+			sinks = append(sinks, fileHeartbeatSink{path: strings.TrimPrefix(entry, "file:")})
+		case strings.HasPrefix(entry, "http://"), strings.HasPrefix(entry, "https://"):
+			sinks = append(sinks, webhookHeartbeatSink{url: entry})
+		default:
+			return nil, fmt.Errorf("invalid heartbeat sink '%s': expected file:<path>, an http(s):// URL or cloudwatch", entry)
+		}
+	}
+	if len(sinks) == 0 {
+		return nil, fmt.Errorf("no heartbeat sinks given")
+	}
+	return sinks, nil
+}
+
+// Heartbeater emits heartbeats in the background until Stop, which sends a final one
+type Heartbeater struct {
+	Sinks    []heartbeatSink
+	Interval time.Duration
+	Sent     int64
+	Failed   int64
+
+	stats     *WorkloadStats
+	engine    string
+	workload  string
+	planned   time.Duration // Planned measurement duration, for the progress percentage
+	host      string
+	sequence  int64
+	lastOps   int64
+	stop      chan struct{}
+	done      chan struct{}
+	stopOnce  sync.Once
+	errorOnce sync.Map // Sinks whose first failure was already logged
+}
+
+func NewHeartbeater(sinks []heartbeatSink, interval time.Duration, stats *WorkloadStats, engine, workloadHash string, planned time.Duration) (*Heartbeater, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("heartbeat interval must be positive, got: %v", interval)
+	}
+	host, _ := os.Hostname()
+	return &Heartbeater{
+		Sinks:    sinks,
+		Interval: interval,
+		stats:    stats,
+		engine:   engine,
+		workload: workloadHash,
+		planned:  planned,
+		host:     host,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Start sends the first heartbeat right away, then one per interval
+func (h *Heartbeater) Start() {
+	go func() {
+		defer close(h.done)
+		ticker := time.NewTicker(h.Interval)
+		defer ticker.Stop()
+
+		h.beat("")
+		for {
+			select {
+			case <-ticker.C:
+				h.beat("")
+			case <-h.stop:
+				h.beat("done")
+				return
+			}
+		}
+	}()
+}
+
+// Stop sends the final heartbeat (phase done) and waits for it to be delivered
+func (h *Heartbeater) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.stop)
+		<-h.done
+	})
+}
+
+// beat sends one heartbeat to all sinks; phase overrides the run's current phase
+func (h *Heartbeater) beat(phase string) {
+	stats := h.stats
+	ops := atomic.LoadInt64(&stats.GetOps) + atomic.LoadInt64(&stats.SetOps)
+	errors := atomic.LoadInt64(&stats.GetErrors) + atomic.LoadInt64(&stats.SetErrors)
+	if phase == "" {
+		phase = stats.Phases.Current()
+	}
+
+	measured := stats.Phases.Measurement()
+	progress := 0.0
+	switch {
+	case phase == "done" || phase == "teardown":
+		progress = 100
+	case h.planned > 0:
+		progress = min(measured.Seconds()/h.planned.Seconds()*100, 100)
+	}
+
+	h.sequence++
+	beat := Heartbeat{
+		Timestamp:       time.Now(),
+		Sequence:        h.sequence,
+		Host:            h.host,
+		PID:             os.Getpid(),
+		Engine:          h.engine,
+		WorkloadHash:    h.workload,
+		Phase:           phase,
+		ProgressPercent: progress,
+		ElapsedSeconds:  measured.Seconds(),
+		TotalOps:        ops,
+		TotalErrors:     errors,
+		Stalled:         phase == "measurement" && h.sequence > 1 && ops+errors == h.lastOps,
+	}
+	h.lastOps = ops + errors
+
+	var wg sync.WaitGroup
+	for _, sink := range h.Sinks {
+		wg.Add(1)
+		go func(sink heartbeatSink) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), heartbeatTimeout)
+			defer cancel()
+			if err := sink.Send(ctx, beat); err != nil {
+				atomic.AddInt64(&h.Failed, 1)
+				if _, logged := h.errorOnce.LoadOrStore(sink.String(), true); !logged {
+					log.Printf("Heartbeat to %s failed: %v", sink, err)
+				}
+				return
+			}
+			atomic.AddInt64(&h.Sent, 1)
+		}(sink)
+	}
+	wg.Wait()
+}
+
+// heartbeatFromFlags starts the heartbeats selected with --heartbeat (nil when not set)
+func heartbeatFromFlags(cmd *cobra.Command, stats *WorkloadStats, engine, workloadHash string, planned time.Duration) (*Heartbeater, error) {
+	value, _ := cmd.Flags().GetString("heartbeat")
+	if value == "" {
+		return nil, nil
+	}
+	interval, _ := cmd.Flags().GetDuration("heartbeat-interval")
+	sinks, err := parseHeartbeatSinks(value, stats.CloudWatch)
+	if err != nil {
+		return nil, err
+	}
+	return NewHeartbeater(sinks, interval, stats, engine, workloadHash, planned)
+}
+
+// printHeartbeatResults reports heartbeat delivery outcomes
+func printHeartbeatResults(h *Heartbeater) {
+	fmt.Printf("Heartbeats: %d delivered, %d failed (%d sinks, every %v)\n",
+		atomic.LoadInt64(&h.Sent), atomic.LoadInt64(&h.Failed), len(h.Sinks), h.Interval)
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Thresholds of the bottleneck heuristics
+const (
+	hintCPUPercent      = 90   // Client host CPU considered saturated
+	hintCPUWindowShare  = 0.25 // Share of windows saturated before the run counts as client-bound
+	hintShortfall       = 0.95 // Achieved share of the target QPS below which load wasn't delivered
+	hintHitRateDrop     = 10   // Percentage points a window's GET hit rate may fall below the run's median
+	hintMissRate        = 50   // GET miss/error rate suggesting an unpopulated keyspace
+	hintTailRatio       = 10   // P99/P50 ratio considered a heavy tail
+	hintWarmupShare     = 0.2  // Share of wall time spent warming up
+	hintMemoryShare     = 0.9  // Client host memory considered exhausted
+	hintPacerLagMicros  = 1000000
+	hintMinWindowGetOps = 100 // GETs a window needs for its hit rate to be meaningful
+)
+
+// hintWindow is what the heuristics keep of one progress snapshot
+type hintWindow struct {
+	snapshot MetricsSnapshot
+	hitRate  float64 // GET hit rate of the window in percent (-1 with too few GETs)
+}
+
+// RunHints turns a run's numbers into guidance: it keeps the progress snapshots and,
+// once the run is over, applies heuristics for the usual ways a benchmark misleads
+// (client-bound runs, undelivered load, cold caches, heavy tails).
+type RunHints struct {
+	Found     []string // Hints of the finished run, set by Analyze
+	TargetQPS int      // Static rate limit (--rps); traffic patterns report theirs per snapshot
+
+	mutex         sync.Mutex
+	windows       []hintWindow
+	lastGetErrors int64
+}
+
+func NewRunHints() *RunHints {
+	return &RunHints{}
+}
+
+// Observe records a progress snapshot (cumulative error counts, latest window rates)
+func (rh *RunHints) Observe(snapshot MetricsSnapshot) {
+	rh.mutex.Lock()
+	defer rh.mutex.Unlock()
+
+	getHits := int64(snapshot.ActualGetQPS * MetricWindowSizeSeconds)
+	getMisses := snapshot.GetErrors - rh.lastGetErrors
+	rh.lastGetErrors = snapshot.GetErrors
+	hitRate := -1.0
+	if getHits+getMisses >= hintMinWindowGetOps {
+		hitRate = float64(getHits) / float64(getHits+getMisses) * 100
+	}
+	rh.windows = append(rh.windows, hintWindow{snapshot: snapshot, hitRate: hitRate})
+}
+
+// Analyze applies the heuristics to the finished run and stores the hints in Found
+func (rh *RunHints) Analyze(stats *WorkloadStats) []string {
+	rh.mutex.Lock()
+	defer rh.mutex.Unlock()
+
+	var hints []string
+	add := func(format string, args ...interface{}) {
+		hints = append(hints, fmt.Sprintf(format, args...))
+	}
+
+	// Client-bound: the load generator host ran out of CPU
+	saturated, peakCPU := 0, 0.0
+	for _, w := range rh.windows {
+		if w.snapshot.CPUPercent >= hintCPUPercent {
+			saturated++
+		}
+		peakCPU = max(peakCPU, w.snapshot.CPUPercent)
+	}
+	if len(rh.windows) > 0 && float64(saturated)/float64(len(rh.windows)) >= hintCPUWindowShare {
+		add("client CPU >%d%% in %d of %d windows (peak %.0f%%): results likely client-bound; use more runners or fewer clients per runner",
+			hintCPUPercent, saturated, len(rh.windows), peakCPU)
+	}
+
+	// Client memory: swapping or GC pressure distorts latency
+	for _, w := range rh.windows {
+		if w.snapshot.MemoryTotalGB > 0 && w.snapshot.MemoryUsedGB/w.snapshot.MemoryTotalGB >= hintMemoryShare {
+			add("client host memory %.0f%% used at %s: swapping or GC pressure may inflate latency",
+				w.snapshot.MemoryUsedGB/w.snapshot.MemoryTotalGB*100, w.snapshot.Timestamp.Format("15:04:05"))
+			break
+		}
+	}
+
+	// Undelivered load: the target rate wasn't reached, so latency was measured at a lower load
+	var targetOps, actualOps float64
+	for _, w := range rh.windows {
+		target := w.snapshot.TargetQPS
+		if target <= 0 {
+			target = rh.TargetQPS
+		}
+		if target > 0 {
+			targetOps += float64(target)
+			actualOps += min(w.snapshot.ActualTotalQPS, float64(target))
+		}
+	}
+	if targetOps > 0 && actualOps/targetOps < hintShortfall {
+		add("drop rate %.0f%%: only %.0f%% of the target QPS was delivered, so percentiles underestimate latency at the target load",
+			(1-actualOps/targetOps)*100, actualOps/targetOps*100)
+	}
+	if stats.Pacer != nil {
+		if lag := atomic.LoadInt64(&stats.Pacer.MaxLag); lag >= hintPacerLagMicros {
+			add("workers fell up to %.1fs behind the load shape schedule: add clients so operations start on time", float64(lag)/1e6)
+		}
+	}
+
+	// Cache behaviour: a cold keyspace, or hit rate collapsing in part of the run
+	getOps, getErrors := atomic.LoadInt64(&stats.GetOps), atomic.LoadInt64(&stats.GetErrors)
+	if missRate := errorRatePercent(getOps, getErrors); getOps+getErrors > 0 && missRate >= hintMissRate {
+		add("GET miss/error rate %.0f%%: the keyspace may not be populated; run populate with the same key range first", missRate)
+	}
+	if hint := rh.hitRateDropHint(); hint != "" {
+		add("%s", hint)
+	}
+
+	// Connection skew: some connections much slower than the rest point at a slow backend node
+	if stats.ConnSkew != nil {
+		if outliers, median, total := stats.ConnSkew.Outliers(); len(outliers) > 0 {
+			add("%d of %d connections have a P99 over %.0fx the median (%d vs %d μs): they likely land on a slow backend node",
+				len(outliers), total, connSkewOutlier, outliers[0].p99, median)
+		}
+	}
+
+	// Heavy tail: P99 far above the median points at outliers rather than steady-state cost
+	for _, ps := range []*PerformanceStats{stats.GetStats, stats.SetStats} {
+		_, _, _, _, p50, _, p99 := ps.GetStats()
+		if p50 > 0 && p99 >= p50*hintTailRatio {
+			add("%s P99 is %dx P50 (%d vs %d μs): the tail is dominated by outliers (connection churn, GC, retries, noisy neighbours)",
+				ps.Name, p99/p50, p99, p50)
+		}
+	}
+
+	// Warmup: a long setup phase usually means slow connection establishment
+	if phases := stats.Phases.Summary(); phases.WallSeconds > 0 && phases.WarmupSeconds/phases.WallSeconds >= hintWarmupShare {
+		add("warmup took %.0f%% of wall time (%.1fs): client connection setup is slow",
+			phases.WarmupSeconds/phases.WallSeconds*100, phases.WarmupSeconds)
+	}
+
+	// Telemetry: metrics that never arrived
+	if stats.CloudWatch != nil {
+		if dropped := atomic.LoadInt64(&stats.CloudWatch.Dropped); dropped > 0 {
+			add("%d CloudWatch metrics were dropped (queue full): dashboards miss part of the run", dropped)
+		}
+	}
+	if stats.Timestream != nil {
+		if dropped := atomic.LoadInt64(&stats.Timestream.Dropped); dropped > 0 {
+			add("%d Timestream records were dropped (queue full): the history misses part of the run", dropped)
+		}
+	}
+
+	rh.Found = hints
+	return hints
+}
+
+// hitRateDropHint finds the window whose GET hit rate fell furthest below the run's median
+func (rh *RunHints) hitRateDropHint() string {
+	var rates []float64
+	for _, w := range rh.windows {
+		if w.hitRate >= 0 {
+			rates = append(rates, w.hitRate)
+		}
+	}
+	if len(rates) < 3 {
+		return ""
+	}
+	sort.Float64s(rates)
+	median := rates[len(rates)/2]
+
+	worst := -1
+	for i, w := range rh.windows {
+		if w.hitRate >= 0 && median-w.hitRate >= hintHitRateDrop && (worst < 0 || w.hitRate < rh.windows[worst].hitRate) {
+			worst = i
+		}
+	}
+	if worst < 0 {
+		return ""
+	}
+
+	w := rh.windows[worst]
+	during := ""
+	if worst > 0 {
+		previous := rh.windows[worst-1].snapshot.ActualClients
+		switch {
+		case w.snapshot.ActualClients < previous:
+			during = fmt.Sprintf(" during scale-down (%d to %d clients)", previous, w.snapshot.ActualClients)
+		case w.snapshot.ActualClients > previous:
+			during = fmt.Sprintf(" during scale-up (%d to %d clients)", previous, w.snapshot.ActualClients)
+		}
+	}
+	return fmt.Sprintf("hit rate fell from %.0f%% to %.0f%%%s at %s: check evictions and TTL expiry around that time",
+		median, w.hitRate, during, w.snapshot.Timestamp.Format("15:04:05"))
+}
+
+// printRunHints prints the heuristic analysis of the run
+func printRunHints(stats *WorkloadStats) {
+	hints := stats.Hints.Analyze(stats)
+	fmt.Println("\nAnalysis:")
+	if len(hints) == 0 {
+		fmt.Println("  No bottlenecks detected")
+		return
+	}
+	for _, hint := range hints {
+		fmt.Printf("  - %s\n", hint)
+	}
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	runCmd.Flags().String("run-id", "", "Identifier of the run, passed to the hooks and recorded in the results (default: generated from the start time)")
+	runCmd.Flags().String("pre-hook", "", "Shell command run before the workload starts (e.g. flip a feature flag or trigger a scaling action); the run is aborted when it fails. Command line and schedule only, refused by agent and Lambda requests")
+	runCmd.Flags().String("post-hook", "", "Shell command run after the results are written (e.g. snapshot dashboards or upload the results). Command line and schedule only, refused by agent and Lambda requests")
+	runCmd.Flags().Duration("hook-timeout", 5*time.Minute, "Time a --pre-hook or --post-hook may take before it is killed")
+}
+
+// hostCommandFlags are the run flags that execute commands on the benchmark host; workloads
+// requested over the network (agent, Lambda) can't use them, see RunRemoteWithArgs
+var hostCommandFlags = []string{"pre-hook", "post-hook"}
+
+// RunHooks runs user commands around a run, so the benchmark can be driven from a larger
+// experiment harness. Hooks run through the shell with the run described in SCB_*
+// environment variables:
+//
+//	SCB_RUN_ID, SCB_HOOK (pre or post), SCB_ENGINE, SCB_WORKLOAD_HASH
+//	post only: SCB_RESULTS_FILE (with --output), SCB_TOTAL_OPS, SCB_TOTAL_ERRORS,
+//	SCB_DURATION_SECONDS, SCB_GET_P99_US, SCB_SET_P99_US
+type RunHooks struct {
+	RunID   string
+	Pre     string
+	Post    string
+	Timeout time.Duration
+}
+
+// newRunID generates a run ID from the start time and a random suffix, unique across the
+// agents of a fleet starting in the same second
+func newRunID(start time.Time) string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return fmt.Sprintf("run-%s-%s", start.Format("20060102-150405"), hex.EncodeToString(suffix))
+}
+
+// runHooksFromFlags reads the run ID and the hooks
+func runHooksFromFlags(cmd *cobra.Command) (*RunHooks, error) {
+	hooks := &RunHooks{}
+	hooks.RunID, _ = cmd.Flags().GetString("run-id")
+	hooks.Pre, _ = cmd.Flags().GetString("pre-hook")
+	hooks.Post, _ = cmd.Flags().GetString("post-hook")
+	hooks.Timeout, _ = cmd.Flags().GetDuration("hook-timeout")
+	if hooks.Timeout <= 0 {
+		return nil, fmt.Errorf("hook timeout must be positive, got: %v", hooks.Timeout)
+	}
+	if hooks.RunID == "" {
+		hooks.RunID = newRunID(time.Now())
+	}
+	return hooks, nil
+}
+
+// environment returns the variables describing the run to a hook
+func (h *RunHooks) environment(hook, engine, workloadHash string) []string {
+	return append(os.Environ(),
+		"SCB_RUN_ID="+h.RunID,
+		"SCB_HOOK="+hook,
+		"SCB_ENGINE="+engine,
+		"SCB_WORKLOAD_HASH="+workloadHash,
+	)
+}
+
+// run executes a hook command through the shell, with its output passed through
+func (h *RunHooks) run(hook, command string, env []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	fmt.Printf("Running %s-hook: %s\n", hook, command)
+	start := time.Now()
+	c := exec.CommandContext(ctx, shell, flag, command)
+	c.Env = env
+	c.Stdout, c.Stderr = os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("%s-hook timed out after %v", hook, h.Timeout)
+		}
+		return fmt.Errorf("%s-hook failed: %w", hook, err)
+	}
+	fmt.Printf("%s-hook finished in %v\n", hook, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// RunPre runs the --pre-hook, if any
+func (h *RunHooks) RunPre(engine, workloadHash string) error {
+	if h.Pre == "" {
+		return nil
+	}
+	return h.run("pre", h.Pre, h.environment("pre", engine, workloadHash))
+}
+
+// RunPost runs the --post-hook, if any, with the results of the run
+func (h *RunHooks) RunPost(summary *RunSummary, resultsFile string) error {
+	if h.Post == "" {
+		return nil
+	}
+	env := append(h.environment("post", summary.Engine, summary.WorkloadHash),
+		"SCB_TOTAL_OPS="+strconv.FormatInt(summary.TotalOps, 10),
+		"SCB_TOTAL_ERRORS="+strconv.FormatInt(summary.TotalErrors, 10),
+		"SCB_DURATION_SECONDS="+strconv.FormatFloat(summary.DurationSeconds, 'f', 3, 64),
+		"SCB_GET_P99_US="+strconv.FormatInt(summary.Get.P99, 10),
+		"SCB_SET_P99_US="+strconv.FormatInt(summary.Set.P99, 10),
+	)
+	if resultsFile != "" {
+		env = append(env, "SCB_RESULTS_FILE="+resultsFile)
+	}
+	return h.run("post", h.Post, env)
+}
@@ -0,0 +1,378 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RegisterBackend(&Backend{
+		Name: "http",
+		AddFlags: func(cmd *cobra.Command) {
+			cmd.Flags().String("http-url", "", "Base URL of an HTTP key-value API (cache-type http): GET/PUT/DELETE <url>/<key>")
+			cmd.Flags().String("http-protocol", "h1", "HTTP version: h1, h2 (h2c for http:// URLs) or h3 (QUIC, experimental); a comma-separated list assigns workers to versions round-robin to compare them")
+			cmd.Flags().Bool("http-coalesce", true, "Share one transport per HTTP version between clients, so h2/h3 clients multiplex over a single connection; false gives every client its own connection")
+			cmd.Flags().StringArray("http-header", nil, "Header sent with every request, as 'Name: Value' (repeatable), e.g. an Authorization header")
+			cmd.Flags().String("http-ttl-header", "X-TTL-Seconds", "Request header carrying the TTL of a PUT in seconds (empty to not send TTLs)")
+			cmd.Flags().Int("http-timeout", 1000, "HTTP request timeout in milliseconds")
+			cmd.Flags().Bool("http-tls-skip-verify", false, "Skip TLS certificate verification")
+		},
+		New: func(ctx context.Context, cmd *cobra.Command) (CacheClient, error) {
+			config, err := httpConfigFromFlags(cmd)
+			if err != nil {
+				return nil, err
+			}
+			return NewHTTPClient(config)
+		},
+	})
+}
+
+// HTTP protocol versions of the http backend, by major version of their responses
+var httpProtocols = map[string]int{
+	"h1": 1,
+	"h2": 2,
+	"h3": 3,
+}
+
+// HTTPConfig holds the configuration of an HTTP key-value client
+type HTTPConfig struct {
+	URL        string
+	Protocol   string // h1, h2 or h3 (the first of --http-protocol)
+	Coalesce   bool
+	Headers    http.Header
+	TTLHeader  string
+	Timeout    time.Duration
+	SkipVerify bool
+}
+
+// parseHTTPProtocols parses an --http-protocol list
+func parseHTTPProtocols(value string) ([]string, error) {
+	var protocols []string
+	for _, protocol := range strings.Split(value, ",") {
+		protocol = strings.ToLower(strings.TrimSpace(protocol))
+		if _, ok := httpProtocols[protocol]; !ok {
+			return nil, fmt.Errorf("invalid HTTP protocol '%s': expected h1, h2 or h3", protocol)
+		}
+		protocols = append(protocols, protocol)
+	}
+	return protocols, nil
+}
+
+func httpConfigFromFlags(cmd *cobra.Command) (HTTPConfig, error) {
+	rawURL, _ := cmd.Flags().GetString("http-url")
+	protocols, _ := cmd.Flags().GetString("http-protocol")
+	coalesce, _ := cmd.Flags().GetBool("http-coalesce")
+	headers, _ := cmd.Flags().GetStringArray("http-header")
+	ttlHeader, _ := cmd.Flags().GetString("http-ttl-header")
+	timeout, _ := cmd.Flags().GetInt("http-timeout")
+	skipVerify, _ := cmd.Flags().GetBool("http-tls-skip-verify")
+
+	config := HTTPConfig{
+		URL:        strings.TrimRight(rawURL, "/"),
+		Coalesce:   coalesce,
+		Headers:    make(http.Header),
+		TTLHeader:  ttlHeader,
+		Timeout:    time.Duration(timeout) * time.Millisecond,
+		SkipVerify: skipVerify,
+	}
+	parsed, err := parseHTTPProtocols(protocols)
+	if err != nil {
+		return config, err
+	}
+	config.Protocol = parsed[0]
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return config, fmt.Errorf("invalid HTTP header '%s': expected 'Name: Value'", header)
+		}
+		config.Headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return config, nil
+}
+
+// httpTransport is a transport shared by the clients of one protocol (with --http-coalesce)
+type httpTransport struct {
+	http.RoundTripper
+	close func()
+	refs  int
+}
+
+var (
+	httpSharedTransports = make(map[string]*httpTransport)
+	httpSharedMutex      sync.Mutex
+)
+
+// newHTTPTransport creates the transport of a protocol
+func newHTTPTransport(config HTTPConfig) (*httpTransport, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.SkipVerify}
+	if config.Protocol == "h3" {
+		return newHTTP3Transport(tlsConfig)
+	}
+
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     tlsConfig,
+		MaxIdleConnsPerHost: 1024, // Shared h1 transports keep one connection per in-flight client
+		IdleConnTimeout:     90 * time.Second,
+		Protocols:           new(http.Protocols),
+	}
+	if config.Protocol == "h2" {
+		transport.Protocols.SetHTTP2(true)
+		transport.Protocols.SetUnencryptedHTTP2(true) // h2c (prior knowledge) for http:// URLs
+	} else {
+		transport.Protocols.SetHTTP1(true)
+	}
+	return &httpTransport{RoundTripper: transport, close: transport.CloseIdleConnections}, nil
+}
+
+// acquireHTTPTransport returns the shared transport of a protocol, or a new one without coalescing
+func acquireHTTPTransport(config HTTPConfig) (*httpTransport, error) {
+	if !config.Coalesce {
+		return newHTTPTransport(config)
+	}
+
+	key := config.Protocol + " " + strconv.FormatBool(config.SkipVerify)
+	httpSharedMutex.Lock()
+	defer httpSharedMutex.Unlock()
+	transport, ok := httpSharedTransports[key]
+	if !ok {
+		var err error
+		if transport, err = newHTTPTransport(config); err != nil {
+			return nil, err
+		}
+		httpSharedTransports[key] = transport
+	}
+	transport.refs++
+	return transport, nil
+}
+
+// releaseHTTPTransport closes a transport once its last client is closed
+func releaseHTTPTransport(config HTTPConfig, transport *httpTransport) {
+	if !config.Coalesce {
+		transport.close()
+		return
+	}
+
+	httpSharedMutex.Lock()
+	defer httpSharedMutex.Unlock()
+	if transport.refs--; transport.refs == 0 {
+		transport.close()
+		for key, shared := range httpSharedTransports {
+			if shared == transport {
+				delete(httpSharedTransports, key)
+			}
+		}
+	}
+}
+
+// HTTPClient implements CacheClient for HTTP key-value APIs (edge KV stores, cache
+// gateways): GET returns the value (404 is a miss), PUT stores the body, DELETE removes it
+type HTTPClient struct {
+	config    HTTPConfig
+	client    *http.Client
+	transport *httpTransport
+}
+
+func NewHTTPClient(config HTTPConfig) (*HTTPClient, error) {
+	parsed, err := url.Parse(config.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid HTTP URL '%s': expected http(s)://host[:port][/path]", config.URL)
+	}
+	if config.Protocol == "h3" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("HTTP/3 requires an https:// URL")
+	}
+
+	transport, err := acquireHTTPTransport(config)
+	if err != nil {
+		return nil, err
+	}
+	return &HTTPClient{
+		config:    config,
+		client:    &http.Client{Transport: transport, Timeout: config.Timeout},
+		transport: transport,
+	}, nil
+}
+
+// do sends a request for a key and checks the server used the configured protocol
+func (h *HTTPClient) do(ctx context.Context, method, key string, body []byte, header http.Header) (*http.Response, error) {
+	target := h.config.URL
+	if key != "" {
+		target += "/" + url.PathEscape(key)
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range h.config.Headers {
+		request.Header[name] = values
+	}
+	for name, values := range header {
+		request.Header[name] = values
+	}
+
+	response, err := h.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.ProtoMajor != httpProtocols[h.config.Protocol] {
+		response.Body.Close()
+		return nil, fmt.Errorf("server answered with %s instead of %s", response.Proto, h.config.Protocol)
+	}
+	return response, nil
+}
+
+// httpStatusError describes an unexpected response
+func httpStatusError(response *http.Response) error {
+	message, _ := io.ReadAll(io.LimitReader(response.Body, 256))
+	if len(message) == 0 {
+		return fmt.Errorf("HTTP %s", response.Status)
+	}
+	return fmt.Errorf("HTTP %s: %s", response.Status, strings.TrimSpace(string(message)))
+}
+
+func (h *HTTPClient) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	header := make(http.Header)
+	if h.config.TTLHeader != "" && expiration > 0 {
+		header.Set(h.config.TTLHeader, strconv.FormatInt(int64(max(expiration/time.Second, 1)), 10))
+	}
+	response, err := h.do(ctx, http.MethodPut, key, value, header)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		return httpStatusError(response)
+	}
+	io.Copy(io.Discard, response.Body) // Drain so the connection is reused
+	return nil
+}
+
+func (h *HTTPClient) Get(ctx context.Context, key string) ([]byte, error) {
+	response, err := h.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		io.Copy(io.Discard, response.Body)
+		return nil, ErrCacheMiss
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, httpStatusError(response)
+	}
+	return io.ReadAll(response.Body)
+}
+
+func (h *HTTPClient) Delete(ctx context.Context, key string) error {
+	response, err := h.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 && response.StatusCode != http.StatusNotFound {
+		return httpStatusError(response)
+	}
+	io.Copy(io.Discard, response.Body)
+	return nil
+}
+
+// Ping checks the API answers (any non-5xx status of the base URL) with the configured protocol
+// Connect opens a keep-alive connection (and its TLS session) with a HEAD request
+func (h *HTTPClient) Connect(ctx context.Context) error {
+	return h.Ping(ctx)
+}
+
+func (h *HTTPClient) Ping(ctx context.Context) error {
+	response, err := h.do(ctx, http.MethodHead, "", nil, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 500 {
+		return httpStatusError(response)
+	}
+	return nil
+}
+
+func (h *HTTPClient) Close() error {
+	releaseHTTPTransport(h.config, h.transport)
+	return nil
+}
+
+func (h *HTTPClient) Name() string {
+	return "HTTP/" + h.config.Protocol
+}
+
+// HTTPProtocolStats assigns workers to HTTP versions round-robin (worker i uses version
+// i mod len(protocols)) and breaks the results down per version, to compare them against
+// the same API in one run
+type HTTPProtocolStats struct {
+	Protocols []string
+	Coalesce  bool
+	Groups    []*ClientGroup
+}
+
+func NewHTTPProtocolStats(protocols []string, coalesce bool) *HTTPProtocolStats {
+	hs := &HTTPProtocolStats{Protocols: protocols, Coalesce: coalesce}
+	for _, protocol := range protocols {
+		hs.Groups = append(hs.Groups, NewClientGroup(protocol))
+	}
+	return hs
+}
+
+// GroupOf returns the stats of the HTTP version a worker uses
+func (hs *HTTPProtocolStats) GroupOf(workerID int) *ClientGroup {
+	return hs.Groups[workerID%len(hs.Protocols)]
+}
+
+// NewClient creates and pings the client of a worker with its HTTP version
+func (hs *HTTPProtocolStats) NewClient(ctx context.Context, cmd *cobra.Command, workerID int) (CacheClient, error) {
+	config, err := httpConfigFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	config.Protocol = hs.Protocols[workerID%len(hs.Protocols)]
+
+	client, err := NewHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("%s client failed to connect: %w", config.Protocol, err)
+	}
+	atomic.AddInt64(&hs.GroupOf(workerID).Clients, 1)
+	return client, nil
+}
+
+func (hs *HTTPProtocolStats) Close() {
+	for _, g := range hs.Groups {
+		g.Close()
+	}
+}
+
+// printHTTPProtocolResults prints the per-version breakdown of a multi-protocol HTTP run
+func printHTTPProtocolResults(stats *WorkloadStats) {
+	connections := "one per client"
+	if stats.HTTPProtocols.Coalesce {
+		connections = "coalesced per version"
+	}
+	fmt.Printf("HTTP Protocols (%d, workers assigned round-robin, connections %s):\n", len(stats.HTTPProtocols.Protocols), connections)
+	printClientGroups("Protocol", stats.HTTPProtocols.Groups)
+}
@@ -0,0 +1,19 @@
+//go:build !noquic
+
+package cmd
+
+import (
+	"crypto/tls"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+func init() {
+	registerBuildFeature("http3", "HTTP/3 (QUIC) transport of cache-type http")
+}
+
+// newHTTP3Transport creates an HTTP/3 transport; all its requests to a host share one QUIC connection
+func newHTTP3Transport(tlsConfig *tls.Config) (*httpTransport, error) {
+	transport := &http3.Transport{TLSClientConfig: tlsConfig}
+	return &httpTransport{RoundTripper: transport, close: func() { transport.Close() }}, nil
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// RunWithArgs runs a workload in-process using run command flags, e.g. for the schedule
+// command, and returns its summary. Flags are reset to their defaults first so warm
+// invocations don't inherit options from previous ones. Invalid configurations and failed
+// setup steps (hooks, preflight, secrets) are returned, so the calling process survives them.
+func RunWithArgs(args []string) (*RunSummary, error) {
+	return runWithArgs(args, false)
+}
+
+// RunRemoteWithArgs is RunWithArgs for workloads requested over the network (agent, Lambda),
+// which refuses the flags that run commands on the host (see hostCommandFlags)
+func RunRemoteWithArgs(args []string) (*RunSummary, error) {
+	return runWithArgs(args, true)
+}
+
+func runWithArgs(args []string, remote bool) (*RunSummary, error) {
+	flags := runCmd.Flags()
+	flags.VisitAll(resetFlag)
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if remote {
+		for _, name := range hostCommandFlags {
+			if flags.Changed(name) {
+				return nil, fmt.Errorf("--%s is only accepted on the command line: it runs a shell command on this host", name)
+			}
+		}
+	}
+	return executeWorkload(runCmd, flags.Args())
+}
+
+// resetFlag restores a flag to its default. Slice and array flags can't be Set to their
+// DefValue, which pflag writes as "[a,b]": Set would append it as a single element.
+func resetFlag(f *pflag.Flag) {
+	if slice, ok := f.Value.(pflag.SliceValue); ok {
+		var values []string
+		if def := strings.TrimSuffix(strings.TrimPrefix(f.DefValue, "["), "]"); def != "" {
+			values = strings.Split(def, ",")
+		}
+		slice.Replace(values)
+	} else {
+		f.Value.Set(f.DefValue)
+	}
+	f.Changed = false
+}
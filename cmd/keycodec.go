@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{populateCmd, runCmd} {
+		cmd.Flags().String("key-codec", "numeric", "Encoding of key IDs in keys: numeric (decimal), hash (hex of a truncated SHA-256) or base64 (URL-safe, of the big-endian ID)")
+		cmd.Flags().Int("key-hash-bytes", 8, "SHA-256 bytes kept by --key-codec hash (1-32); short hashes can collide")
+		cmd.Flags().String("key-template", "{key}", "Key layout after --key-prefix, with {key} replaced by the encoded ID (e.g. 'user:{key}:profile')")
+	}
+	populateCmd.Flags().String("key-collision-check", "auto", "Check that no two key IDs map to the same key before populating: auto (when the codec can collide), on or off; needs memory for every key")
+}
+
+// KeyCodec encodes logical key IDs into the ID part of cache keys
+type KeyCodec interface {
+	Encode(keyID int) string
+	// Injective reports whether distinct IDs always encode to distinct strings
+	Injective() bool
+	Name() string
+}
+
+type numericKeyCodec struct{}
+
+func (numericKeyCodec) Encode(keyID int) string { return strconv.Itoa(keyID) }
+func (numericKeyCodec) Injective() bool         { return true }
+func (numericKeyCodec) Name() string            { return "numeric" }
+
+// hashKeyCodec spreads keys like hashed user IDs do; truncation to fewer bytes trades key
+// length for collision risk
+type hashKeyCodec struct {
+	bytes int
+}
+
+func (c hashKeyCodec) Encode(keyID int) string {
+	sum := sha256.Sum256([]byte(strconv.Itoa(keyID)))
+	return hex.EncodeToString(sum[:c.bytes])
+}
+func (c hashKeyCodec) Injective() bool { return false }
+func (c hashKeyCodec) Name() string    { return fmt.Sprintf("hash/%d", c.bytes) }
+
+// base64KeyCodec encodes the ID's big-endian bytes without leading zeros, which keeps it injective
+type base64KeyCodec struct{}
+
+func (base64KeyCodec) Encode(keyID int) string {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(keyID))
+	i := 0
+	for i < len(buf)-1 && buf[i] == 0 {
+		i++
+	}
+	return base64.RawURLEncoding.EncodeToString(buf[i:])
+}
+func (base64KeyCodec) Injective() bool { return true }
+func (base64KeyCodec) Name() string    { return "base64" }
+
+func newKeyCodec(kind string, hashBytes int) (KeyCodec, error) {
+	switch kind {
+	case "numeric":
+		return numericKeyCodec{}, nil
+	case "hash":
+		if hashBytes < 1 || hashBytes > sha256.Size {
+			return nil, fmt.Errorf("--key-hash-bytes must be between 1 and %d, got: %d", sha256.Size, hashBytes)
+		}
+		return hashKeyCodec{bytes: hashBytes}, nil
+	case "base64":
+		return base64KeyCodec{}, nil
+	}
+	return nil, fmt.Errorf("unknown key codec '%s': expected numeric, hash or base64", kind)
+}
+
+// KeyFormat maps logical key IDs to physical cache keys: the prefix, then the template with
+// {key} replaced by the encoded ID
+type KeyFormat struct {
+	Codec    KeyCodec
+	Template string
+}
+
+// keyFormat is the key layout of the current populate or run, set from the flags
+var keyFormat = &KeyFormat{Codec: numericKeyCodec{}, Template: "{key}"}
+
+// keyFormatFromFlags resolves --key-codec and --key-template
+func keyFormatFromFlags(cmd *cobra.Command) (*KeyFormat, error) {
+	kind, _ := cmd.Flags().GetString("key-codec")
+	hashBytes, _ := cmd.Flags().GetInt("key-hash-bytes")
+	template, _ := cmd.Flags().GetString("key-template")
+
+	codec, err := newKeyCodec(kind, hashBytes)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(template, "{key}") {
+		return nil, fmt.Errorf("key template '%s' has no {key}: every key ID would map to the same key", template)
+	}
+	return &KeyFormat{Codec: codec, Template: template}, nil
+}
+
+// IsDefault reports whether keys are the prefix followed by the decimal ID
+func (kf *KeyFormat) IsDefault() bool {
+	_, numeric := kf.Codec.(numericKeyCodec)
+	return numeric && kf.Template == "{key}"
+}
+
+// render returns the key of an ID without the prefix
+func (kf *KeyFormat) render(keyID int) string {
+	if kf.Template == "{key}" {
+		return kf.Codec.Encode(keyID)
+	}
+	return strings.ReplaceAll(kf.Template, "{key}", kf.Codec.Encode(keyID))
+}
+
+// formatKey returns the cache key of a key ID
+func formatKey(keyPrefix string, keyID int) string {
+	return keyPrefix + keyFormat.render(keyID)
+}
+
+// formatNegativeKey returns the never-written key of a negative GET
+func formatNegativeKey(keyPrefix string, keyID int) string {
+	return keyPrefix + "negative-" + keyFormat.render(keyID)
+}
+
+// keyCollision is the first collision found between two key IDs
+type keyCollision struct {
+	first, second int
+	key           string
+}
+
+// checkKeyCollisions maps every key ID of the range to its key and counts the IDs whose
+// key was already taken. Keys are indexed by a 64-bit hash, verified on a match, so the
+// check holds a few tens of bytes per key rather than the keys themselves.
+func checkKeyCollisions(keyPrefix string, keyMin, keyMax int) (int, *keyCollision) {
+	seen := make(map[uint64]int, keyMax-keyMin+1)
+	var overflow map[string]int // Distinct keys whose hash is taken by another key
+	var first *keyCollision
+	collisions := 0
+
+	h := fnv.New64a()
+	for id := keyMin; id <= keyMax; id++ {
+		key := formatKey(keyPrefix, id)
+		h.Reset()
+		h.Write([]byte(key))
+		sum := h.Sum64()
+
+		other, found := seen[sum]
+		if !found {
+			seen[sum] = id
+			continue
+		}
+		if formatKey(keyPrefix, other) != key {
+			if overflow == nil {
+				overflow = make(map[string]int)
+			}
+			if other, found = overflow[key]; !found {
+				overflow[key] = id
+				continue
+			}
+		}
+		collisions++
+		if first == nil {
+			first = &keyCollision{first: other, second: id, key: key}
+		}
+	}
+	return collisions, first
+}
+
+// validateKeyCollisions runs the --key-collision-check of a populate
+func validateKeyCollisions(cmd *cobra.Command, keyPrefix string, keyMin, keyMax int) error {
+	mode, _ := cmd.Flags().GetString("key-collision-check")
+	switch mode {
+	case "off":
+		return nil
+	case "auto":
+		if keyFormat.Codec.Injective() {
+			return nil
+		}
+	case "on":
+	default:
+		return fmt.Errorf("invalid --key-collision-check '%s': expected auto, on or off", mode)
+	}
+
+	total := keyMax - keyMin + 1
+	fmt.Printf("Checking %d keys for collisions (%s codec, template %s)...\n", total, keyFormat.Codec.Name(), keyFormat.Template)
+	collisions, first := checkKeyCollisions(keyPrefix, keyMin, keyMax)
+	if collisions > 0 {
+		return fmt.Errorf("%d of %d key IDs map to a key already used by another ID (e.g. %d and %d both map to %s), "+
+			"so only %d distinct keys would be populated and hit rates inflated; use a larger --key-hash-bytes or another --key-codec",
+			collisions, total, first.first, first.second, first.key, total-collisions)
+	}
+	fmt.Println("No key collisions")
+	return nil
+}
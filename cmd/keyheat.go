@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// keyHeatMaxPrefixes bounds the prefixes tracked; keys of further prefixes are grouped
+// under keyHeatOther so a too-specific regex can't grow the report without bound
+const keyHeatMaxPrefixes = 1000
+
+const (
+	keyHeatOther    = "(other)"
+	keyHeatNoPrefix = "(no prefix)" // The regex didn't match or extracted an empty prefix
+)
+
+// keyHeatPrefix is the latency of the operations on one key prefix
+type keyHeatPrefix struct {
+	Name      string
+	Ops       int64
+	Errors    int64
+	Histogram *hdrhistogram.Histogram
+}
+
+// KeyHeat aggregates latency by key prefix, extracted from each key (without --key-prefix)
+// by a regex: its first capture group, or the whole match when it has none
+type KeyHeat struct {
+	Regex     *regexp.Regexp
+	KeyPrefix string
+	Top       int
+
+	mutex    sync.Mutex
+	prefixes map[string]*keyHeatPrefix
+}
+
+func NewKeyHeat(pattern, keyPrefix string, top int) (*KeyHeat, error) {
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key heat regex: %w", err)
+	}
+	if top <= 0 {
+		return nil, fmt.Errorf("key heat top must be positive, got: %d", top)
+	}
+	return &KeyHeat{
+		Regex:     regex,
+		KeyPrefix: keyPrefix,
+		Top:       top,
+		prefixes:  make(map[string]*keyHeatPrefix),
+	}, nil
+}
+
+// prefixOf extracts the prefix of a key
+func (kh *KeyHeat) prefixOf(key string) string {
+	match := kh.Regex.FindStringSubmatch(strings.TrimPrefix(key, kh.KeyPrefix))
+	prefix := ""
+	switch {
+	case len(match) > 1:
+		prefix = match[1]
+	case len(match) == 1:
+		prefix = match[0]
+	}
+	if prefix == "" {
+		return keyHeatNoPrefix
+	}
+	return prefix
+}
+
+// Record adds the result of an operation on a single key
+func (kh *KeyHeat) Record(result workloadResult) {
+	name := kh.prefixOf(result.key)
+
+	kh.mutex.Lock()
+	defer kh.mutex.Unlock()
+	prefix, ok := kh.prefixes[name]
+	if !ok {
+		if len(kh.prefixes) >= keyHeatMaxPrefixes {
+			name = keyHeatOther
+			prefix, ok = kh.prefixes[name]
+		}
+		if !ok {
+			prefix = &keyHeatPrefix{Name: name, Histogram: hdrhistogram.New(1, 60*1000*1000, 3)}
+			kh.prefixes[name] = prefix
+		}
+	}
+	if result.isError {
+		prefix.Errors++
+		return
+	}
+	prefix.Ops++
+	prefix.Histogram.RecordValue(result.latencyMicros)
+}
+
+// printKeyHeatResults prints the slowest key prefixes by P99 latency
+func printKeyHeatResults(stats *WorkloadStats) {
+	kh := stats.KeyHeat
+	kh.mutex.Lock()
+	defer kh.mutex.Unlock()
+
+	prefixes := make([]*keyHeatPrefix, 0, len(kh.prefixes))
+	for _, prefix := range kh.prefixes {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		pi, pj := prefixes[i].Histogram.ValueAtQuantile(99), prefixes[j].Histogram.ValueAtQuantile(99)
+		if pi != pj {
+			return pi > pj
+		}
+		return prefixes[i].Name < prefixes[j].Name
+	})
+
+	shown := min(kh.Top, len(prefixes))
+	fmt.Printf("Key Prefix Latency Heat (top %d of %d prefixes by P99, regex %s):\n", shown, len(prefixes), kh.Regex)
+	fmt.Printf("%-24s %-10s %-8s %-10s %-10s %-10s %-10s\n", "Prefix", "Ops", "Errors", "Mean", "P50", "P95", "P99")
+	for _, prefix := range prefixes[:shown] {
+		h := prefix.Histogram
+		fmt.Printf("%-24s %-10d %-8d %-10.0f %-10d %-10d %-10d\n", prefix.Name, prefix.Ops, prefix.Errors,
+			h.Mean(), h.ValueAtQuantile(50), h.ValueAtQuantile(95), h.ValueAtQuantile(99))
+	}
+	fmt.Println("(latencies in μs)")
+	fmt.Println()
+}
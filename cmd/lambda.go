@@ -0,0 +1,184 @@
+//go:build !noaws
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RegisterBackend(&Backend{
+		Name: "lambda",
+		AddFlags: func(cmd *cobra.Command) {
+			cmd.Flags().String("lambda-function-name", "", "Lambda function name or ARN to invoke per operation (cache-type lambda); GETs answering {\"found\": false} or a null value count as misses")
+			cmd.Flags().String("lambda-qualifier", "", "Lambda function version or alias to invoke")
+		},
+		New: func(ctx context.Context, cmd *cobra.Command) (CacheClient, error) {
+			region, _ := cmd.Flags().GetString("aws-region")
+			functionName, _ := cmd.Flags().GetString("lambda-function-name")
+			qualifier, _ := cmd.Flags().GetString("lambda-qualifier")
+
+			client, err := NewLambdaClient(ctx, region, awsRoleARN(cmd, ""), functionName, qualifier)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create Lambda client: %w", err)
+			}
+			return client, nil
+		},
+		Permissions: func(cmd *cobra.Command) []AWSPermission {
+			functionName, _ := cmd.Flags().GetString("lambda-function-name")
+			qualifier, _ := cmd.Flags().GetString("lambda-qualifier")
+			if functionName == "" {
+				return nil
+			}
+
+			resource := functionName
+			if !strings.HasPrefix(resource, "arn:") {
+				resource = "arn:{partition}:lambda:{region}:{account}:function:" + functionName
+			}
+			if qualifier != "" {
+				resource += ":" + qualifier
+			}
+			return []AWSPermission{
+				{Action: "lambda:InvokeFunction", Resource: resource, Reason: "GET/SET invocations"},
+			}
+		},
+	})
+}
+
+// LambdaClient implements CacheClient by invoking a Lambda function for every operation.
+// This models the "no cache, compute on demand" alternative: the function is expected to
+// compute (or look up) the value for a key and return it in the response payload.
+type LambdaClient struct {
+	client       *lambda.Client
+	functionName string
+	qualifier    string
+}
+
+// lambdaRequest is the JSON payload sent to the target function
+type lambdaRequest struct {
+	Operation  string `json:"operation"`
+	Key        string `json:"key"`
+	Value      []byte `json:"value,omitempty"`
+	TTLSeconds int64  `json:"ttl_seconds,omitempty"`
+}
+
+// lambdaResponse is the JSON payload expected back from the target function. A GET of a
+// key the function has no value for answers {"found": false}, or a null or missing value
+// when found is left out; both count as cache misses. An empty value is a hit.
+type lambdaResponse struct {
+	Value []byte `json:"value"`
+	Found *bool  `json:"found,omitempty"`
+}
+
+func NewLambdaClient(ctx context.Context, region, roleARN, functionName, qualifier string) (*LambdaClient, error) {
+	if functionName == "" {
+		return nil, fmt.Errorf("lambda function name is required")
+	}
+
+	cfg, err := loadAWSConfig(ctx, region, roleARN)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LambdaClient{
+		client:       lambda.NewFromConfig(cfg),
+		functionName: functionName,
+		qualifier:    qualifier,
+	}, nil
+}
+
+// invoke sends a synchronous invocation and returns the raw response payload
+func (l *LambdaClient) invoke(ctx context.Context, req lambdaRequest, invocationType types.InvocationType) ([]byte, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode lambda payload: %w", err)
+	}
+
+	input := &lambda.InvokeInput{
+		FunctionName:   aws.String(l.functionName),
+		InvocationType: invocationType,
+		Payload:        payload,
+	}
+	if l.qualifier != "" {
+		input.Qualifier = aws.String(l.qualifier)
+	}
+
+	output, err := l.client.Invoke(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Function errors are reported in-band with a 200 status code
+	if output.FunctionError != nil {
+		return nil, fmt.Errorf("lambda function error (%s): %s", aws.ToString(output.FunctionError), string(output.Payload))
+	}
+
+	return output.Payload, nil
+}
+
+func (l *LambdaClient) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	_, err := l.invoke(ctx, lambdaRequest{
+		Operation:  "set",
+		Key:        key,
+		Value:      value,
+		TTLSeconds: int64(expiration.Seconds()),
+	}, types.InvocationTypeRequestResponse)
+	return err
+}
+
+func (l *LambdaClient) Get(ctx context.Context, key string) ([]byte, error) {
+	payload, err := l.invoke(ctx, lambdaRequest{
+		Operation: "get",
+		Key:       key,
+	}, types.InvocationTypeRequestResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	var response lambdaResponse
+	if err := json.Unmarshal(payload, &response); err != nil {
+		// Functions are free to return a raw value instead of the JSON envelope
+		return payload, nil
+	}
+	if response.Found != nil && !*response.Found || response.Found == nil && response.Value == nil {
+		return nil, ErrCacheMiss
+	}
+	return response.Value, nil
+}
+
+func (l *LambdaClient) Delete(ctx context.Context, key string) error {
+	_, err := l.invoke(ctx, lambdaRequest{
+		Operation: "delete",
+		Key:       key,
+	}, types.InvocationTypeRequestResponse)
+	return err
+}
+
+// Connect is a no-op: the SDK's HTTP client connects on the first invocation, and the
+// function's cold start can't be moved out of the operations anyway
+func (l *LambdaClient) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (l *LambdaClient) Ping(ctx context.Context) error {
+	// DryRun validates the function exists and that we are allowed to invoke it without running it
+	_, err := l.invoke(ctx, lambdaRequest{Operation: "ping"}, types.InvocationTypeDryRun)
+	return err
+}
+
+func (l *LambdaClient) Close() error {
+	return nil
+}
+
+func (l *LambdaClient) Name() string {
+	return "Lambda"
+}
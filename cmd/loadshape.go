@@ -0,0 +1,432 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// LoadShape is a target request rate as a function of time since the start of the run
+type LoadShape interface {
+	Rate(elapsed time.Duration) float64
+	Describe() string
+}
+
+// constantShape is a fixed target rate (--rate)
+type constantShape struct {
+	rate float64
+}
+
+func (s *constantShape) Rate(elapsed time.Duration) float64 {
+	return s.rate
+}
+
+func (s *constantShape) Describe() string {
+	return fmt.Sprintf("constant %.0f ops/s", s.rate)
+}
+
+// rampShape ramps linearly from one rate to another and then holds the final rate (--ramp)
+type rampShape struct {
+	from, to float64
+	duration time.Duration
+}
+
+func (s *rampShape) Rate(elapsed time.Duration) float64 {
+	if elapsed >= s.duration {
+		return s.to
+	}
+	return s.from + (s.to-s.from)*elapsed.Seconds()/s.duration.Seconds()
+}
+
+func (s *rampShape) Describe() string {
+	return fmt.Sprintf("ramp %.0f -> %.0f ops/s over %s", s.from, s.to, s.duration)
+}
+
+// stepShape raises the rate by a fixed increment every interval, up to an optional maximum (--step)
+type stepShape struct {
+	start, increment, max float64
+	interval              time.Duration
+}
+
+func (s *stepShape) Rate(elapsed time.Duration) float64 {
+	rate := s.start + s.increment*float64(elapsed/s.interval)
+	if s.max > 0 && rate > s.max {
+		return s.max
+	}
+	return rate
+}
+
+func (s *stepShape) Describe() string {
+	desc := fmt.Sprintf("step %.0f ops/s +%.0f every %s", s.start, s.increment, s.interval)
+	if s.max > 0 {
+		desc += fmt.Sprintf(" up to %.0f", s.max)
+	}
+	return desc
+}
+
+// sineShape oscillates around a mean rate (--sine)
+type sineShape struct {
+	mean, amplitude float64
+	period          time.Duration
+}
+
+func (s *sineShape) Rate(elapsed time.Duration) float64 {
+	return s.mean + s.amplitude*math.Sin(2*math.Pi*elapsed.Seconds()/s.period.Seconds())
+}
+
+func (s *sineShape) Describe() string {
+	return fmt.Sprintf("sine %.0f ± %.0f ops/s, period %s", s.mean, s.amplitude, s.period)
+}
+
+// parseShapeFields splits a colon-separated profile into rates and durations.
+// Fields listed in durations are parsed as Go durations, the rest as rates.
+func parseShapeFields(profile, format string, durations map[int]bool, minFields, maxFields int) ([]float64, []time.Duration, error) {
+	parts := strings.Split(profile, ":")
+	if len(parts) < minFields || len(parts) > maxFields {
+		return nil, nil, fmt.Errorf("invalid profile '%s': expected %s", profile, format)
+	}
+
+	rates := make([]float64, len(parts))
+	periods := make([]time.Duration, len(parts))
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if durations[i] {
+			d, err := time.ParseDuration(part)
+			if err != nil || d <= 0 {
+				return nil, nil, fmt.Errorf("invalid duration '%s' in profile '%s'", part, profile)
+			}
+			periods[i] = d
+			continue
+		}
+		rate, err := strconv.ParseFloat(part, 64)
+		if err != nil || rate < 0 {
+			return nil, nil, fmt.Errorf("invalid rate '%s' in profile '%s'", part, profile)
+		}
+		rates[i] = rate
+	}
+	return rates, periods, nil
+}
+
+// parseLoadShape builds the load shape from the shaping flags; at most one may be set.
+// Returns nil when load shaping is disabled.
+func parseLoadShape(rate float64, ramp, step, sine string) (LoadShape, error) {
+	set := 0
+	for _, enabled := range []bool{rate > 0, ramp != "", step != "", sine != ""} {
+		if enabled {
+			set++
+		}
+	}
+	if set > 1 {
+		return nil, fmt.Errorf("only one of --rate, --ramp, --step and --sine can be used")
+	}
+
+	switch {
+	case rate > 0:
+		return &constantShape{rate: rate}, nil
+	case ramp != "":
+		rates, periods, err := parseShapeFields(ramp, "from:to:duration (e.g. 1000:10000:5m)", map[int]bool{2: true}, 3, 3)
+		if err != nil {
+			return nil, err
+		}
+		return &rampShape{from: rates[0], to: rates[1], duration: periods[2]}, nil
+	case step != "":
+		rates, periods, err := parseShapeFields(step, "start:increment:interval[:max] (e.g. 1000:1000:1m:10000)", map[int]bool{2: true}, 3, 4)
+		if err != nil {
+			return nil, err
+		}
+		shape := &stepShape{start: rates[0], increment: rates[1], interval: periods[2]}
+		if len(rates) == 4 {
+			shape.max = rates[3]
+		}
+		return shape, nil
+	case sine != "":
+		rates, periods, err := parseShapeFields(sine, "mean:amplitude:period (e.g. 5000:2000:2m)", map[int]bool{2: true}, 3, 3)
+		if err != nil {
+			return nil, err
+		}
+		if rates[1] > rates[0] {
+			return nil, fmt.Errorf("sine amplitude (%.0f) must not exceed the mean rate (%.0f)", rates[1], rates[0])
+		}
+		return &sineShape{mean: rates[0], amplitude: rates[1], period: periods[2]}, nil
+	}
+	return nil, nil
+}
+
+// pacedSlot is one scheduled operation: when it should have started and the phase it belongs to
+type pacedSlot struct {
+	intended time.Time
+	phase    int
+}
+
+// Pacer schedules operations according to a load shape and hands them to the workers.
+// Start times are fixed by the schedule, not by when a worker becomes free, so latency can
+// be measured from the intended start time (coordinated-omission correction).
+type Pacer struct {
+	Shape         LoadShape
+	Poisson       bool          // Exponentially distributed inter-arrival times instead of uniform
+	PhaseInterval time.Duration // Length of the per-phase reporting windows
+	Rand          *rand.Rand    // Source of Poisson arrivals (nil = time-seeded)
+	SoftStart     *SoftStart    // Scales the shape down while ramping up (nil without --soft-start)
+
+	StartTime time.Time
+	Scheduled int64 // Operations scheduled so far
+	MaxLag    int64 // Largest delay in microseconds between an intended start and a worker picking it up
+
+	slots chan pacedSlot
+}
+
+// pacerBacklog bounds the number of scheduled operations waiting for a worker
+const pacerBacklog = 1 << 16
+
+// pacerMaxGap is the longest the scheduler waits before re-evaluating the target rate
+const pacerMaxGap = 100 * time.Millisecond
+
+// pacerSpinWait is the end of a wait the scheduler spins through rather than sleeps: timers
+// overshoot by tens to hundreds of microseconds, which would count as corrected latency
+const pacerSpinWait = time.Millisecond
+
+func NewPacer(shape LoadShape, arrival string, phaseInterval time.Duration) (*Pacer, error) {
+	if arrival != "uniform" && arrival != "poisson" {
+		return nil, fmt.Errorf("invalid arrival process '%s': expected uniform or poisson", arrival)
+	}
+	if phaseInterval <= 0 {
+		return nil, fmt.Errorf("phase interval must be positive, got: %s", phaseInterval)
+	}
+	// Steps are reported one phase per step unless configured otherwise
+	if step, ok := shape.(*stepShape); ok && phaseInterval == defaultPhaseInterval {
+		phaseInterval = step.interval
+	}
+
+	return &Pacer{
+		Shape:         shape,
+		Poisson:       arrival == "poisson",
+		PhaseInterval: phaseInterval,
+		slots:         make(chan pacedSlot, pacerBacklog),
+	}, nil
+}
+
+// defaultPhaseInterval is the default of --phase-interval
+const defaultPhaseInterval = 10 * time.Second
+
+// Start begins scheduling operations until ctx is done
+func (p *Pacer) Start(ctx context.Context) {
+	p.StartTime = time.Now()
+	go p.schedule(ctx)
+}
+
+func (p *Pacer) schedule(ctx context.Context) {
+	rng := p.Rand
+	if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	next := p.StartTime
+
+	// Arrivals are spaced in units of work: the rate is integrated over time and an
+	// operation is due each time the accumulated work reaches the next arrival target
+	// (1 for uniform, exponentially distributed for Poisson)
+	nextTarget := func() float64 {
+		if p.Poisson {
+			return rng.ExpFloat64()
+		}
+		return 1
+	}
+	target := nextTarget()
+	var credit float64
+
+	// One timer for the whole schedule, rather than one per operation
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		// Re-evaluate the profile at least every pacerMaxGap so low or zero rates
+		// (e.g. the start of a ramp) don't stall the schedule
+		gap := pacerMaxGap
+		emit := false
+		rate := p.Shape.Rate(next.Sub(p.StartTime))
+		if p.SoftStart != nil {
+			rate *= p.SoftStart.Factor()
+		}
+		if rate > 0 {
+			if d := time.Duration((target - credit) / rate * float64(time.Second)); d < pacerMaxGap {
+				gap = d
+				emit = true
+				credit = 0
+				target = nextTarget()
+			} else {
+				credit += rate * pacerMaxGap.Seconds()
+			}
+		}
+		next = next.Add(gap)
+
+		// Sleep until shortly before the intended start and spin the rest; when running
+		// behind, catch up without sleeping
+		if wait := time.Until(next) - pacerSpinWait; wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+		for time.Now().Before(next) {
+			if ctx.Err() != nil {
+				return
+			}
+			runtime.Gosched()
+		}
+
+		if !emit {
+			continue
+		}
+
+		slot := pacedSlot{intended: next, phase: p.PhaseOf(next)}
+		select {
+		case p.slots <- slot:
+			atomic.AddInt64(&p.Scheduled, 1)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Next blocks until the next scheduled operation; returns false once ctx is done
+func (p *Pacer) Next(ctx context.Context) (pacedSlot, bool) {
+	select {
+	case slot := <-p.slots:
+		lag := time.Since(slot.intended).Microseconds()
+		for current := atomic.LoadInt64(&p.MaxLag); lag > current; current = atomic.LoadInt64(&p.MaxLag) {
+			if atomic.CompareAndSwapInt64(&p.MaxLag, current, lag) {
+				break
+			}
+		}
+		return slot, true
+	case <-ctx.Done():
+		return pacedSlot{}, false
+	}
+}
+
+// PhaseOf returns the reporting phase of a point in time
+func (p *Pacer) PhaseOf(t time.Time) int {
+	return int(t.Sub(p.StartTime) / p.PhaseInterval)
+}
+
+// PhaseTargetRate returns the mean target rate over a phase
+func (p *Pacer) PhaseTargetRate(phase int) float64 {
+	const samples = 20
+	start := time.Duration(phase) * p.PhaseInterval
+	var total float64
+	for i := 0; i < samples; i++ {
+		total += p.Shape.Rate(start + p.PhaseInterval*time.Duration(2*i+1)/(2*samples))
+	}
+	return total / samples
+}
+
+// PacingState is the load shaping schedule of a run and its per-phase breakdown, as saved
+// with --save-state; the latencies themselves come from the phase histograms
+type PacingState struct {
+	Shape     string        `json:"shape"`
+	Arrival   string        `json:"arrival"`
+	Scheduled int64         `json:"scheduled"`
+	MaxLag    int64         `json:"max_lag_us"`
+	Phases    []PacingPhase `json:"phases"`
+}
+
+// PacingPhase is one load shaping phase of a run
+type PacingPhase struct {
+	Phase      int           `json:"phase"`
+	Start      time.Duration `json:"start_ns"`
+	End        time.Duration `json:"end_ns"`
+	TargetRate float64       `json:"target_rate"`
+	SoftStart  bool          `json:"soft_start,omitempty"` // Began before the soft start reached the full rate
+}
+
+// newPacingState captures the schedule of a paced run; recording must have stopped
+func newPacingState(stats *WorkloadStats, testTime int) *PacingState {
+	pacer := stats.Pacer
+	state := &PacingState{
+		Shape:     pacer.Shape.Describe(),
+		Arrival:   "uniform",
+		Scheduled: atomic.LoadInt64(&pacer.Scheduled),
+		MaxLag:    atomic.LoadInt64(&pacer.MaxLag),
+	}
+	if pacer.Poisson {
+		state.Arrival = "poisson"
+	}
+
+	// Merge GET and SET phases into one ordered list
+	seen := make(map[int]bool)
+	var phases []int
+	for _, phase := range append(stats.GetStats.Phases(), stats.SetStats.Phases()...) {
+		if !seen[phase] {
+			seen[phase] = true
+			phases = append(phases, phase)
+		}
+	}
+	sort.Ints(phases)
+
+	// Phases that began before the soft start reached the full rate ran below their target
+	var rampEnd time.Duration
+	if stats.SoftStart != nil {
+		rampEnd = time.Duration(testTime) * time.Second
+		if end := stats.SoftStart.EndTime(); !end.IsZero() {
+			rampEnd = end.Sub(pacer.StartTime)
+		}
+	}
+
+	testDuration := time.Duration(testTime) * time.Second
+	for _, phase := range phases {
+		start := time.Duration(phase) * pacer.PhaseInterval
+		end := start + pacer.PhaseInterval
+		if end > testDuration {
+			end = testDuration
+		}
+		state.Phases = append(state.Phases, PacingPhase{
+			Phase:      phase,
+			Start:      start,
+			End:        end,
+			TargetRate: pacer.PhaseTargetRate(phase),
+			SoftStart:  start < rampEnd,
+		})
+	}
+	return state
+}
+
+// printPacingResults prints the corrected latency broken down per load shaping phase
+func printPacingResults(stats *WorkloadStats, testTime int) {
+	printPacingState(newPacingState(stats, testTime), stats)
+}
+
+// printPacingState prints a load shaping schedule with the phase latencies of the stats
+func printPacingState(state *PacingState, stats *WorkloadStats) {
+	fmt.Printf("Load Shaping: %s, %s arrivals\n", state.Shape, state.Arrival)
+	fmt.Printf("Scheduled Operations: %d (max start delay: %d μs)\n", state.Scheduled, state.MaxLag)
+	fmt.Printf("Per-phase latency from intended start (GET + SET):\n")
+
+	for _, phase := range state.Phases {
+		getCount, getP50, _, getP99 := stats.GetStats.GetPhaseStats(phase.Phase)
+		setCount, setP50, _, setP99 := stats.SetStats.GetPhaseStats(phase.Phase)
+		ops := getCount + setCount
+
+		var achieved float64 // Successful operations only; errors carry no latency
+		if phase.End > phase.Start {
+			achieved = float64(ops) / (phase.End - phase.Start).Seconds()
+		}
+
+		marker := ""
+		if phase.SoftStart {
+			marker = " (soft start)"
+		}
+		fmt.Printf("  %4ds-%4ds | Target: %8.0f ops/s | Succeeded: %8.0f ops/s | GET P50: %d μs, P99: %d μs | SET P50: %d μs, P99: %d μs%s\n",
+			int(phase.Start.Seconds()), int(phase.End.Seconds()), phase.TargetRate, achieved,
+			getP50, getP99, setP50, setP99, marker)
+	}
+	fmt.Println()
+}
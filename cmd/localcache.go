@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Local (L1) cache eviction policies
+const (
+	LocalPolicyLRU = "lru"
+	LocalPolicyLFU = "lfu"
+)
+
+// localCache is an in-process near cache used as the L1 tier
+type localCache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+	Delete(key string)
+}
+
+// localCacheLimits bounds a local cache by entries, bytes and entry age.
+// Evictions and expirations are counted into the (shared) counters.
+type localCacheLimits struct {
+	maxEntries  int
+	maxBytes    int64         // 0 = not bounded by bytes
+	ttl         time.Duration // 0 = entries never expire
+	evictions   *int64
+	expirations *int64
+}
+
+func (l *localCacheLimits) expiresAt() time.Time {
+	if l.ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(l.ttl)
+}
+
+func (l *localCacheLimits) over(entries int, bytes int64) bool {
+	if entries > l.maxEntries {
+		return true
+	}
+	return l.maxBytes > 0 && bytes > l.maxBytes
+}
+
+func isExpired(expiresAt time.Time) bool {
+	return !expiresAt.IsZero() && time.Now().After(expiresAt)
+}
+
+func newLocalCache(policy string, limits localCacheLimits) (localCache, error) {
+	if limits.maxEntries < 1 {
+		limits.maxEntries = 1
+	}
+	switch policy {
+	case LocalPolicyLRU:
+		return newLRUCache(limits), nil
+	case LocalPolicyLFU:
+		return newLFUCache(limits), nil
+	default:
+		return nil, fmt.Errorf("invalid local cache policy: %s. Must be '%s' or '%s'", policy, LocalPolicyLRU, LocalPolicyLFU)
+	}
+}
+
+// lruCache is a mutex-protected least-recently-used cache
+type lruCache struct {
+	mutex  sync.Mutex
+	limits localCacheLimits
+	items  map[string]*list.Element
+	order  *list.List // front = most recently used
+	bytes  int64
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func newLRUCache(limits localCacheLimits) *lruCache {
+	return &lruCache{
+		limits: limits,
+		items:  make(map[string]*list.Element),
+		order:  list.New(),
+	}
+}
+
+func (c *lruCache) Get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if isExpired(entry.expiresAt) {
+		c.remove(elem)
+		atomic.AddInt64(c.limits.expirations, 1)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (c *lruCache) Set(key string, value []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		c.bytes += int64(len(value)) - int64(len(entry.value))
+		entry.value = value
+		entry.expiresAt = c.limits.expiresAt()
+		c.order.MoveToFront(elem)
+	} else {
+		c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value, expiresAt: c.limits.expiresAt()})
+		c.bytes += int64(len(value))
+	}
+
+	// Never evict the entry that was just written
+	for c.order.Len() > 1 && c.limits.over(c.order.Len(), c.bytes) {
+		c.remove(c.order.Back())
+		atomic.AddInt64(c.limits.evictions, 1)
+	}
+}
+
+func (c *lruCache) Delete(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+}
+
+func (c *lruCache) remove(elem *list.Element) {
+	entry := elem.Value.(*lruEntry)
+	c.order.Remove(elem)
+	delete(c.items, entry.key)
+	c.bytes -= int64(len(entry.value))
+}
+
+// lfuCache is a mutex-protected least-frequently-used cache with O(1) operations.
+// Entries live in per-frequency buckets ordered by ascending frequency; ties within
+// the lowest bucket are broken by recency.
+type lfuCache struct {
+	mutex   sync.Mutex
+	limits  localCacheLimits
+	items   map[string]*list.Element // element in its bucket's entry list
+	buckets *list.List               // of *lfuBucket, front = lowest frequency
+	bytes   int64
+}
+
+type lfuBucket struct {
+	freq    int64
+	entries *list.List // of *lfuEntry, front = most recently used
+}
+
+type lfuEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+	bucket    *list.Element
+}
+
+func newLFUCache(limits localCacheLimits) *lfuCache {
+	return &lfuCache{
+		limits:  limits,
+		items:   make(map[string]*list.Element),
+		buckets: list.New(),
+	}
+}
+
+func (c *lfuCache) Get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lfuEntry)
+	if isExpired(entry.expiresAt) {
+		c.remove(elem)
+		atomic.AddInt64(c.limits.expirations, 1)
+		return nil, false
+	}
+	c.touch(elem)
+	return entry.value, true
+}
+
+func (c *lfuCache) Set(key string, value []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lfuEntry)
+		c.bytes += int64(len(value)) - int64(len(entry.value))
+		entry.value = value
+		entry.expiresAt = c.limits.expiresAt()
+		c.touch(elem)
+
+		// A larger value can take the cache over its size; never evict the entry just written
+		for len(c.items) > 1 && c.limits.over(len(c.items), c.bytes) {
+			c.evict(entry)
+		}
+	} else {
+		// Make room before inserting so the new entry (frequency 1) is not the victim
+		for len(c.items) > 0 && c.limits.over(len(c.items)+1, c.bytes+int64(len(value))) {
+			c.evict(nil)
+		}
+
+		first := c.buckets.Front()
+		if first == nil || first.Value.(*lfuBucket).freq != 1 {
+			first = c.buckets.PushFront(&lfuBucket{freq: 1, entries: list.New()})
+		}
+		entry := &lfuEntry{key: key, value: value, expiresAt: c.limits.expiresAt(), bucket: first}
+		c.items[key] = first.Value.(*lfuBucket).entries.PushFront(entry)
+		c.bytes += int64(len(value))
+	}
+}
+
+func (c *lfuCache) Delete(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// touch moves an entry to the bucket for its next frequency
+func (c *lfuCache) touch(elem *list.Element) {
+	entry := elem.Value.(*lfuEntry)
+	current := entry.bucket
+	bucket := current.Value.(*lfuBucket)
+
+	next := current.Next()
+	if next == nil || next.Value.(*lfuBucket).freq != bucket.freq+1 {
+		next = c.buckets.InsertAfter(&lfuBucket{freq: bucket.freq + 1, entries: list.New()}, current)
+	}
+
+	bucket.entries.Remove(elem)
+	entry.bucket = next
+	c.items[entry.key] = next.Value.(*lfuBucket).entries.PushFront(entry)
+
+	if bucket.entries.Len() == 0 {
+		c.buckets.Remove(current)
+	}
+}
+
+// evict removes the least recently used entry of the lowest frequency bucket, other than keep
+func (c *lfuCache) evict(keep *lfuEntry) {
+	for bucket := c.buckets.Front(); bucket != nil; bucket = bucket.Next() {
+		for elem := bucket.Value.(*lfuBucket).entries.Back(); elem != nil; elem = elem.Prev() {
+			if elem.Value.(*lfuEntry) != keep {
+				c.remove(elem)
+				atomic.AddInt64(c.limits.evictions, 1)
+				return
+			}
+		}
+	}
+}
+
+func (c *lfuCache) remove(elem *list.Element) {
+	entry := elem.Value.(*lfuEntry)
+	bucket := entry.bucket.Value.(*lfuBucket)
+	bucket.entries.Remove(elem)
+	if bucket.entries.Len() == 0 {
+		c.buckets.Remove(entry.bucket)
+	}
+	delete(c.items, entry.key)
+	c.bytes -= int64(len(entry.value))
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// lowMemChannelSize replaces the 1M-event latency buffer of the stats collector in low-memory mode (3 MB)
+const lowMemChannelSize = 65536
+
+// LowMemStats bounds the memory used by latency collection (--stats-lowmem): latency
+// buffers are small and only the most recent windows stay in memory, older windows are
+// streamed to an HDR histogram log on disk.
+type LowMemStats struct {
+	MaxWindows int
+	SpillFile  string
+
+	mutex   sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	spilled int64 // Windows written to the spill file
+	failed  int64 // Windows lost to a failed write
+	lastErr error
+}
+
+// StatsSpillSummary is the machine-readable state of the --stats-lowmem spill file
+type StatsSpillSummary struct {
+	File    string `json:"file"`
+	Windows int64  `json:"windows"`          // Windows spilled, read back into the results
+	Failed  int64  `json:"failed,omitempty"` // Windows lost to write errors, missing from the results
+	Error   string `json:"error,omitempty"`  // Last write error
+}
+
+// lowMemStats applies to every PerformanceStats created while it is set (nil = unbounded)
+var lowMemStats *LowMemStats
+
+// EnableLowMemStats switches new PerformanceStats to low-memory mode, spilling windows to dir
+func EnableLowMemStats(dir string, maxWindows int) (*LowMemStats, error) {
+	if maxWindows < 2 {
+		return nil, fmt.Errorf("at least 2 in-memory windows are required, got: %d", maxWindows)
+	}
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	spillFile := filepath.Join(dir, fmt.Sprintf("stats-spill-%s.hlog", time.Now().Format("20060102-150405")))
+	file, err := os.Create(spillFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stats spill file: %w", err)
+	}
+
+	lm := &LowMemStats{
+		MaxWindows: maxWindows,
+		SpillFile:  spillFile,
+		file:       file,
+		writer:     bufio.NewWriter(file),
+	}
+
+	logWriter := hdrhistogram.NewHistogramLogWriter(lm.writer)
+	for _, err := range []error{
+		logWriter.OutputLogFormatVersion(),
+		logWriter.OutputComment("[Evicted stats windows of serverless-cache-benchmark, values in microseconds]"),
+		logWriter.OutputComment(fmt.Sprintf("[Build: %s]", currentBuildInfo())),
+		logWriter.OutputLegend(),
+	} {
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to write stats spill file: %w", err)
+		}
+	}
+
+	lowMemStats = lm
+	return lm, nil
+}
+
+// spill writes an evicted window to disk (called from stats collector goroutines)
+func (lm *LowMemStats) spill(name string, window LatencyWindow) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+
+	if lm.writer == nil {
+		return // Already closed
+	}
+	// Timestamps are absolute seconds since the epoch (the log has no base time).
+	// Best effort: a failed write loses the window but must not stop the collector; it is
+	// counted and reported with the results.
+	if err := writeHistogramLogInterval(lm.writer, name, float64(window.StartSecond), window.Histogram); err != nil {
+		lm.failed++
+		lm.lastErr = err
+		return
+	}
+	lm.spilled++
+}
+
+// flush writes out the buffered windows (mutex held); a failure is reported as the last error
+func (lm *LowMemStats) flush() {
+	if lm.writer == nil {
+		return
+	}
+	if err := lm.writer.Flush(); err != nil {
+		lm.lastErr = err
+	}
+}
+
+// readSpilled calls fn with the windows spilled under a name, in the order they were
+// spilled (oldest first), decoding one at a time
+func (lm *LowMemStats) readSpilled(name string, fn func(LatencyWindow) error) error {
+	lm.mutex.Lock()
+	lm.flush()
+	lm.mutex.Unlock()
+
+	file, err := os.Open(lm.SpillFile)
+	if err != nil {
+		return fmt.Errorf("failed to open stats spill file: %w", err)
+	}
+	defer file.Close()
+
+	prefix := "Tag=" + name + ","
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		// start,length,max,payload (see writeHistogramLogInterval)
+		fields := strings.Split(line[len(prefix):], ",")
+		if len(fields) != 4 {
+			return fmt.Errorf("invalid line in stats spill file: %.40s...", line)
+		}
+		start, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return fmt.Errorf("invalid window start in stats spill file: %w", err)
+		}
+		hist, err := hdrhistogram.Decode([]byte(fields[3]))
+		if err != nil {
+			return fmt.Errorf("invalid %s window in stats spill file: %w", name, err)
+		}
+		if err := fn(LatencyWindow{StartSecond: int64(start), Histogram: hist}); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stats spill file: %w", err)
+	}
+	return nil
+}
+
+// Summary returns the spill file and how many windows went to it or were lost
+func (lm *LowMemStats) Summary() *StatsSpillSummary {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	lm.flush()
+	summary := &StatsSpillSummary{File: lm.SpillFile, Windows: lm.spilled, Failed: lm.failed}
+	if lm.lastErr != nil {
+		summary.Error = lm.lastErr.Error()
+	}
+	return summary
+}
+
+// Close flushes the spill file and disables low-memory mode for new stats
+func (lm *LowMemStats) Close() error {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+
+	if lowMemStats == lm {
+		lowMemStats = nil
+	}
+	if lm.writer == nil {
+		return nil
+	}
+	err := lm.writer.Flush()
+	lm.writer = nil
+	if closeErr := lm.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// matrixTarget is a named entry of the matrix (an agent region or a cache endpoint)
+type matrixTarget struct {
+	Name  string
+	Value string
+}
+
+// MatrixCell is the result of one region x endpoint run
+type MatrixCell struct {
+	Region   string      `json:"region"`
+	Endpoint string      `json:"endpoint"`
+	Summary  *RunSummary `json:"summary,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// matrixCmd represents the matrix command
+var matrixCmd = &cobra.Command{
+	Use:   "matrix",
+	Short: "Build a region x endpoint latency matrix using distributed agents",
+	Long: `Run the same workload from agents in several regions against several cache endpoints
+and report a region x endpoint latency matrix in one report.
+
+Each agent (see the agent command) runs the endpoints one after the other; agents run
+in parallel. Endpoints are given as name=run-arguments, agents as region=URL.
+
+Before starting, every agent's version is checked (--agent-version-check): protocol
+requires the same agent protocol, exact also the same tool version and commit. With
+--agent-binary, incompatible agents started with an update token are sent that binary
+(built for their OS/architecture) and restart on it; otherwise the matrix doesn't start.
+The token is read from --agent-update-token-file or from AGENT_UPDATE_TOKEN, and only sent
+to https:// agents (--agent-ca-file trusts self-signed agent certificates).
+
+Examples:
+  # Two regions against a Redis endpoint in each region, 30 seconds per cell
+  serverless-cache-benchmark matrix \
+    --agent us-east-1=http://10.0.1.10:8080 --agent eu-west-1=http://10.1.1.10:8080 \
+    --endpoint "use1=--cache-type redis --redis-uri rediss://use1-cache:6379" \
+    --endpoint "euw1=--cache-type redis --redis-uri rediss://euw1-cache:6379" \
+    --matrix-args "--test-time 30 --clients 4" --matrix-output matrix.json
+
+  # Require the coordinator's exact build on every agent, pushing it to outdated ones
+  serverless-cache-benchmark matrix --agent us-east-1=https://10.0.1.10:8443 --agent-ca-file agents-ca.pem \
+    --endpoint "use1=--cache-type redis --redis-uri rediss://use1-cache:6379" \
+    --agent-version-check exact --agent-binary ./dist/serverless-cache-benchmark_linux_arm64 \
+    --agent-update-token-file ~/.config/cache-agent/update-token`,
+	Run: runMatrix,
+}
+
+// parseMatrixTargets parses name=value entries
+func parseMatrixTargets(entries []string, kind string) ([]matrixTarget, error) {
+	var targets []matrixTarget
+	for _, entry := range entries {
+		name, value, found := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" || strings.TrimSpace(value) == "" {
+			return nil, fmt.Errorf("invalid %s '%s': expected name=value", kind, entry)
+		}
+		targets = append(targets, matrixTarget{Name: name, Value: strings.TrimSpace(value)})
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("at least one %s is required", kind)
+	}
+	return targets, nil
+}
+
+// checkAgentVersions verifies every agent is compatible with the coordinator, pushing the
+// binary (when set) to the ones that aren't; it returns one error per unusable agent
+func checkAgentVersions(client *http.Client, agents []matrixTarget, mode, binary, token string) []error {
+	errs := make([]error, len(agents))
+	var wg sync.WaitGroup
+	for i, agent := range agents {
+		wg.Add(1)
+		go func(i int, agent matrixTarget) {
+			defer wg.Done()
+			version, err := getAgentVersion(client, agent.Value)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: version check failed: %w", agent.Name, err)
+				return
+			}
+			problem := agentIncompatibility(version, mode)
+			if problem == "" {
+				return
+			}
+			if binary == "" || !version.Updatable {
+				errs[i] = fmt.Errorf("%s: incompatible agent (%s)", agent.Name, problem)
+				return
+			}
+			fmt.Printf("%s: incompatible agent (%s), pushing %s for %s/%s\n", agent.Name, problem, binary, version.OS, version.Arch)
+			if err := pushAgentBinary(client, agent.Value, binary, token, mode); err != nil {
+				errs[i] = fmt.Errorf("%s: agent update failed: %w", agent.Name, err)
+				return
+			}
+			fmt.Printf("%s: agent updated\n", agent.Name)
+		}(i, agent)
+	}
+	wg.Wait()
+
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return failed
+}
+
+// runOnAgent asks an agent to run a workload and waits for its summary
+func runOnAgent(client *http.Client, agentURL string, args []string) (*RunSummary, error) {
+	body, err := json.Marshal(agentRunRequest{Args: args, Protocol: agentProtocolVersion})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Post(strings.TrimRight(agentURL, "/")+"/run", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response agentRunResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("invalid agent response (status %d): %w", resp.StatusCode, err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("agent error (status %d): %s", resp.StatusCode, response.Error)
+	}
+	if response.Summary == nil {
+		return nil, fmt.Errorf("agent returned no summary (status %d)", resp.StatusCode)
+	}
+	return response.Summary, nil
+}
+
+func runMatrix(cmd *cobra.Command, args []string) {
+	agentEntries, _ := cmd.Flags().GetStringArray("agent")
+	endpointEntries, _ := cmd.Flags().GetStringArray("endpoint")
+	commonArgs, _ := cmd.Flags().GetString("matrix-args")
+	output, _ := cmd.Flags().GetString("matrix-output")
+	agentTimeout, _ := cmd.Flags().GetInt("agent-timeout")
+	versionCheck, _ := cmd.Flags().GetString("agent-version-check")
+	agentBinary, _ := cmd.Flags().GetString("agent-binary")
+	updateTokenFile, _ := cmd.Flags().GetString("agent-update-token-file")
+	caFile, _ := cmd.Flags().GetString("agent-ca-file")
+
+	agents, err := parseMatrixTargets(agentEntries, "agent")
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	endpoints, err := parseMatrixTargets(endpointEntries, "endpoint")
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	switch versionCheck {
+	case "protocol", "exact", "off":
+	default:
+		log.Fatalf("Invalid --agent-version-check '%s': expected protocol, exact or off", versionCheck)
+	}
+	updateToken, err := readAgentUpdateToken(updateTokenFile)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if agentBinary != "" && updateToken == "" {
+		log.Fatalf("--agent-binary requires an update token (--agent-update-token-file or AGENT_UPDATE_TOKEN)")
+	}
+
+	client, err := agentHTTPClient(time.Duration(agentTimeout)*time.Second, caFile)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if versionCheck != "off" {
+		if errs := checkAgentVersions(client, agents, versionCheck, agentBinary, updateToken); len(errs) > 0 {
+			for _, err := range errs {
+				log.Printf("%v", err)
+			}
+			log.Fatalf("%d agent(s) are not compatible with this coordinator (%s, protocol %d)", len(errs), currentBuildInfo(), agentProtocolVersion)
+		}
+		fmt.Printf("All %d agents compatible (protocol %d)\n", len(agents), agentProtocolVersion)
+	}
+
+	fmt.Printf("Running %d x %d latency matrix (%d regions, %d endpoints)\n", len(agents), len(endpoints), len(agents), len(endpoints))
+	fmt.Println()
+
+	cells := make([][]MatrixCell, len(agents))
+
+	var wg sync.WaitGroup
+	for i, agent := range agents {
+		cells[i] = make([]MatrixCell, len(endpoints))
+		wg.Add(1)
+		go func(i int, agent matrixTarget) {
+			defer wg.Done()
+			for j, endpoint := range endpoints {
+				runArgs := append(strings.Fields(endpoint.Value), strings.Fields(commonArgs)...)
+				cell := MatrixCell{Region: agent.Name, Endpoint: endpoint.Name}
+
+				summary, err := runOnAgent(client, agent.Value, runArgs)
+				if err != nil {
+					cell.Error = err.Error()
+					log.Printf("%s -> %s failed: %v", agent.Name, endpoint.Name, err)
+				} else {
+					cell.Summary = summary
+					fmt.Printf("%s -> %s: GET P50 %d μs, P99 %d μs\n", agent.Name, endpoint.Name, summary.Get.P50, summary.Get.P99)
+				}
+				cells[i][j] = cell
+			}
+		}(i, agent)
+	}
+	wg.Wait()
+
+	printMatrix(agents, endpoints, cells)
+
+	if output != "" {
+		var flat []MatrixCell
+		for _, row := range cells {
+			flat = append(flat, row...)
+		}
+		data, err := json.MarshalIndent(flat, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode matrix: %v", err)
+		}
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			log.Fatalf("Failed to write matrix output: %v", err)
+		}
+		fmt.Printf("Matrix written to: %s\n", output)
+	}
+}
+
+// printMatrix prints GET P50/P99 latencies as a region x endpoint table
+func printMatrix(agents, endpoints []matrixTarget, cells [][]MatrixCell) {
+	fmt.Println("\n" + strings.Repeat("=", 60))
+	fmt.Println("LATENCY MATRIX (GET P50 / P99 μs)")
+	fmt.Println(strings.Repeat("=", 60))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "REGION"
+	for _, endpoint := range endpoints {
+		header += "\t" + endpoint.Name
+	}
+	fmt.Fprintln(w, header)
+
+	for i, agent := range agents {
+		line := agent.Name
+		for _, cell := range cells[i] {
+			if cell.Summary == nil {
+				line += "\terror"
+			} else {
+				line += fmt.Sprintf("\t%d / %d", cell.Summary.Get.P50, cell.Summary.Get.P99)
+			}
+		}
+		fmt.Fprintln(w, line)
+	}
+	w.Flush()
+	fmt.Println()
+}
+
+func init() {
+	rootCmd.AddCommand(matrixCmd)
+
+	matrixCmd.Flags().StringArray("agent", nil, "Agent as region=URL (repeatable)")
+	matrixCmd.Flags().StringArray("endpoint", nil, "Cache endpoint as name=run-arguments (repeatable)")
+	matrixCmd.Flags().String("matrix-args", "--test-time 30", "Run arguments appended to every cell (e.g. test time, clients, ratio)")
+	matrixCmd.Flags().String("matrix-output", "", "Write all cells with their full summaries to this JSON file")
+	matrixCmd.Flags().Int("agent-timeout", 900, "Timeout in seconds for a single agent run")
+	matrixCmd.Flags().String("agent-version-check", "protocol", "Agent compatibility required before starting: protocol (same agent protocol), exact (same tool version and commit) or off")
+	matrixCmd.Flags().String("agent-binary", "", "Binary to push to incompatible https:// agents started with an update token (must match their OS/architecture)")
+	matrixCmd.Flags().String("agent-update-token-file", "", "File holding the update token of the agents, for --agent-binary; defaults to $AGENT_UPDATE_TOKEN")
+	matrixCmd.Flags().String("agent-ca-file", "", "CA certificates (PEM) trusted for https:// agents, on top of the system roots")
+}
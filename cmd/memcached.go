@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RegisterBackend(&Backend{
+		Name: "memcached",
+		AddFlags: func(cmd *cobra.Command) {
+			cmd.Flags().String("memcached-servers", "localhost:11211", "Comma-separated list of memcached servers (host:port); keys are sharded across them")
+			cmd.Flags().Bool("memcached-tls", false, "Use TLS (required by ElastiCache Serverless for Memcached)")
+			cmd.Flags().Bool("memcached-tls-skip-verify", false, "Skip TLS certificate verification")
+			cmd.Flags().Int("memcached-timeout", 1000, "Memcached socket read/write timeout in milliseconds; capped by the operation timeout (--timeout)")
+			cmd.Flags().Int("memcached-max-idle-conns", 2, "Maximum idle connections kept per memcached server")
+		},
+		New: func(ctx context.Context, cmd *cobra.Command) (CacheClient, error) {
+			servers, _ := cmd.Flags().GetString("memcached-servers")
+			useTLS, _ := cmd.Flags().GetBool("memcached-tls")
+			skipVerify, _ := cmd.Flags().GetBool("memcached-tls-skip-verify")
+			maxIdleConns, _ := cmd.Flags().GetInt("memcached-max-idle-conns")
+
+			config := MemcachedConfig{
+				Servers:      strings.Split(servers, ","),
+				TLS:          useTLS,
+				SkipVerify:   skipVerify,
+				Timeout:      memcachedSocketTimeout(cmd),
+				MaxIdleConns: maxIdleConns,
+
+				ResponseTiming: responseTiming,
+			}
+
+			client, err := NewMemcachedClient(config)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create Memcached client: %w", err)
+			}
+			return client, nil
+		},
+	})
+}
+
+// memcachedMaxRelativeTTL is the largest expiration memcached treats as relative;
+// anything larger is interpreted as an absolute Unix timestamp
+const memcachedMaxRelativeTTL = 30 * 24 * time.Hour
+
+// MemcachedConfig holds Memcached connection configuration
+type MemcachedConfig struct {
+	Servers      []string
+	TLS          bool
+	SkipVerify   bool
+	Timeout      time.Duration
+	MaxIdleConns int
+
+	ResponseTiming *ResponseTiming // Measures the responses read on the client's connections (nil = off)
+}
+
+// MemcachedClient implements CacheClient for Memcached (including ElastiCache Serverless)
+type MemcachedClient struct {
+	client *memcache.Client
+}
+
+func NewMemcachedClient(config MemcachedConfig) (*MemcachedClient, error) {
+	var servers []string
+	for _, server := range config.Servers {
+		if server = strings.TrimSpace(server); server != "" {
+			servers = append(servers, server)
+		}
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("at least one memcached server is required")
+	}
+
+	mc := memcache.New(servers...)
+	mc.Timeout = config.Timeout
+	mc.MaxIdleConns = config.MaxIdleConns
+
+	if config.TLS {
+		dialer := &tls.Dialer{
+			NetDialer: &net.Dialer{Timeout: config.Timeout},
+			Config:    &tls.Config{InsecureSkipVerify: config.SkipVerify},
+		}
+		mc.DialContext = dialer.DialContext
+	}
+	if config.ResponseTiming != nil {
+		dial := mc.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{Timeout: config.Timeout}).DialContext
+		}
+		mc.DialContext = config.ResponseTiming.Dialer(dial)
+	}
+
+	return &MemcachedClient{client: mc}, nil
+}
+
+// memcachedExpiration converts a TTL to memcached's expiration field
+func memcachedExpiration(expiration time.Duration) int32 {
+	if expiration <= 0 {
+		return 0
+	}
+	if expiration > memcachedMaxRelativeTTL {
+		return int32(time.Now().Add(expiration).Unix())
+	}
+	seconds := int32(expiration / time.Second)
+	if seconds == 0 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// memcachedSocketTimeout is the socket timeout of memcached clients: --memcached-timeout,
+// capped by the operation timeout (--timeout) that every operation's context is given.
+// gomemcache takes no context, and gives each read and write the socket timeout, so the
+// cap ends operations by their deadline without a goroutine per call.
+func memcachedSocketTimeout(cmd *cobra.Command) time.Duration {
+	timeoutMs, _ := cmd.Flags().GetInt("memcached-timeout")
+	timeout := time.Duration(timeoutMs) * time.Millisecond
+	if seconds, err := cmd.Flags().GetInt("timeout"); err == nil && seconds > 0 {
+		timeout = min(timeout, time.Duration(seconds)*time.Second)
+	}
+	return timeout
+}
+
+func (m *MemcachedClient) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.client.Set(&memcache.Item{
+		Key:        key,
+		Value:      value,
+		Expiration: memcachedExpiration(expiration),
+	})
+}
+
+func (m *MemcachedClient) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	item, err := m.client.Get(key)
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item.Value, nil
+}
+
+func (m *MemcachedClient) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := m.client.Delete(key)
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return nil
+	}
+	return err
+}
+
+// UpdateOptimistic implements OptimisticUpdater using gets/cas (or add for absent keys)
+func (m *MemcachedClient) UpdateOptimistic(ctx context.Context, key string, mutate func([]byte) ([]byte, error), expiration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	item, err := m.client.Get(key)
+	if err != nil && !errors.Is(err, memcache.ErrCacheMiss) {
+		return err
+	}
+
+	var current []byte
+	if item != nil {
+		current = item.Value
+	}
+	updated, err := mutate(current)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if item == nil {
+		err = m.client.Add(&memcache.Item{Key: key, Value: updated, Expiration: memcachedExpiration(expiration)})
+	} else {
+		item.Value = updated
+		item.Expiration = memcachedExpiration(expiration)
+		err = m.client.CompareAndSwap(item)
+	}
+
+	// Not stored means another client added or deleted the key in between
+	if errors.Is(err, memcache.ErrCASConflict) || errors.Is(err, memcache.ErrNotStored) {
+		return ErrConflict
+	}
+	return err
+}
+
+// Connect opens a connection to every server (gomemcache pools them for the operations)
+func (m *MemcachedClient) Connect(ctx context.Context) error {
+	return m.Ping(ctx)
+}
+
+func (m *MemcachedClient) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.client.Ping()
+}
+
+func (m *MemcachedClient) Close() error {
+	return m.client.Close()
+}
+
+func (m *MemcachedClient) Name() string {
+	return "Memcached"
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// memoryWatchInterval is how often the process memory is sampled
+const memoryWatchInterval = time.Second
+
+func init() {
+	runCmd.Flags().String("max-rss", "", "Abort the run (writing the partial report) when the process RSS exceeds this size, e.g. 512MB or 2GB (plain numbers are MB); without /proc (macOS, Windows) the memory mapped by the Go runtime is used instead")
+}
+
+// Go runtime metrics sampled by MemoryWatch; reading them doesn't stop the world
+var memoryWatchMetrics = []string{
+	"/memory/classes/heap/objects:bytes", // Live and not yet swept heap objects
+	"/memory/classes/total:bytes",        // All memory mapped by the Go runtime
+	"/gc/cycles/total:gc-cycles",
+}
+
+// MemorySummary is the machine-readable memory footprint of a run
+type MemorySummary struct {
+	StartRSSMB  float64   `json:"start_rss_mb"`
+	EndRSSMB    float64   `json:"end_rss_mb"`
+	PeakRSSMB   float64   `json:"peak_rss_mb"`
+	PeakRSSAt   time.Time `json:"peak_rss_at"`
+	PeakHeapMB  float64   `json:"peak_heap_mb"`
+	PeakGoMemMB float64   `json:"peak_go_memory_mb"` // All memory mapped by the Go runtime
+	GCCycles    uint64    `json:"gc_cycles"`
+	MaxRSSMB    float64   `json:"max_rss_mb,omitempty"` // --max-rss limit
+	RSSSource   string    `json:"rss_source"`           // proc (RSS) or go_runtime (no /proc on this platform)
+}
+
+// processMemorySource tells where getProcessMemoryMB reads from: the RSS in /proc, or
+// without it (macOS, Windows) all memory mapped by the Go runtime, which leaves out cgo
+// allocations and counts memory the OS may not have paged in
+func processMemorySource() string {
+	if _, ok := procRSSMB(); ok {
+		return "proc"
+	}
+	return "go_runtime"
+}
+
+// goRuntimeMemoryMB returns all memory mapped by the Go runtime, in MB
+func goRuntimeMemoryMB() float64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/total:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return float64(sample[0].Value.Uint64()) / (1024 * 1024)
+}
+
+// MemoryWatch tracks the RSS and Go heap high-water marks of the benchmark process, so
+// runs packing many agents per host can size them, and optionally aborts the run when RSS
+// exceeds a limit before the host starts swapping or the OOM killer picks a neighbour.
+// Aborting cancels the run (see Bind) rather than the process, so in-process runs of the
+// agent, Lambda and schedule commands fail while the process keeps serving.
+type MemoryWatch struct {
+	MaxRSSMB float64 // Abort threshold (0 = none)
+
+	mutex    sync.Mutex
+	summary  MemorySummary
+	cancel   context.CancelFunc // Cancels the run when the limit is exceeded
+	exceeded error
+	gcStart  uint64
+	samples  []metrics.Sample
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewMemoryWatch(maxRSSMB float64) *MemoryWatch {
+	mw := &MemoryWatch{
+		MaxRSSMB: maxRSSMB,
+		samples:  make([]metrics.Sample, len(memoryWatchMetrics)),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for i, name := range memoryWatchMetrics {
+		mw.samples[i].Name = name
+	}
+	mw.summary.StartRSSMB = getProcessMemoryMB()
+	mw.summary.MaxRSSMB = maxRSSMB
+	mw.summary.RSSSource = processMemorySource()
+	mw.sample(true)
+	mw.gcStart = mw.summary.GCCycles
+	return mw
+}
+
+// Start samples the process memory in the background until Stop
+func (mw *MemoryWatch) Start() {
+	go func() {
+		defer close(mw.done)
+		ticker := time.NewTicker(memoryWatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				mw.sample(true)
+			case <-mw.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Bind makes exceeding the limit cancel the run's context; a limit exceeded before the run
+// started cancels it at once
+func (mw *MemoryWatch) Bind(cancel context.CancelFunc) {
+	mw.mutex.Lock()
+	defer mw.mutex.Unlock()
+	mw.cancel = cancel
+	if mw.exceeded != nil {
+		cancel()
+	}
+}
+
+// Err returns why the run was aborted, or nil when it stayed within the limit
+func (mw *MemoryWatch) Err() error {
+	mw.mutex.Lock()
+	defer mw.mutex.Unlock()
+	return mw.exceeded
+}
+
+// Stop takes a last sample and ends the watch. The limit isn't enforced on that sample:
+// the workload has finished by then and its results are worth more than the memory.
+func (mw *MemoryWatch) Stop() {
+	mw.stopOnce.Do(func() {
+		close(mw.stop)
+		<-mw.done
+		mw.sample(false)
+		mw.mutex.Lock()
+		mw.summary.EndRSSMB = getProcessMemoryMB()
+		mw.mutex.Unlock()
+	})
+}
+
+// sample updates the high-water marks and, with enforce, the RSS limit
+func (mw *MemoryWatch) sample(enforce bool) {
+	rss := getProcessMemoryMB()
+	metrics.Read(mw.samples)
+
+	mw.mutex.Lock()
+	s := &mw.summary
+	now := time.Now()
+	if rss > s.PeakRSSMB {
+		s.PeakRSSMB, s.PeakRSSAt = rss, now
+	}
+	if sample := mw.samples[0].Value; sample.Kind() == metrics.KindUint64 {
+		s.PeakHeapMB = max(s.PeakHeapMB, float64(sample.Uint64())/(1024*1024))
+	}
+	if sample := mw.samples[1].Value; sample.Kind() == metrics.KindUint64 {
+		s.PeakGoMemMB = max(s.PeakGoMemMB, float64(sample.Uint64())/(1024*1024))
+	}
+	if sample := mw.samples[2].Value; sample.Kind() == metrics.KindUint64 {
+		s.GCCycles = sample.Uint64()
+	}
+	if enforce && mw.MaxRSSMB > 0 && rss > mw.MaxRSSMB && mw.exceeded == nil {
+		mw.exceeded = fmt.Errorf("run aborted: RSS %.0f MB exceeded --max-rss %.0f MB", rss, mw.MaxRSSMB)
+		if mw.cancel != nil {
+			mw.cancel()
+		}
+	}
+	mw.mutex.Unlock()
+}
+
+// Summary returns the memory footprint so far; GC cycles are counted from the watch start
+func (mw *MemoryWatch) Summary() *MemorySummary {
+	mw.mutex.Lock()
+	defer mw.mutex.Unlock()
+	summary := mw.summary
+	summary.GCCycles -= mw.gcStart
+	return &summary
+}
+
+// parseMemorySize parses a size such as 512MB, 2GB or 1.5G into MB (plain numbers are MB)
+func parseMemorySize(value string) (float64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := 1.0
+	for _, unit := range []struct {
+		suffix string
+		mb     float64
+	}{
+		{"GB", 1024}, {"G", 1024}, {"MB", 1}, {"M", 1}, {"KB", 1.0 / 1024}, {"K", 1.0 / 1024},
+	} {
+		if strings.HasSuffix(upper, unit.suffix) {
+			upper, multiplier = strings.TrimSuffix(upper, unit.suffix), unit.mb
+			break
+		}
+	}
+	size, err := strconv.ParseFloat(strings.TrimSpace(upper), 64)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid memory size '%s': expected e.g. 512MB or 2GB", value)
+	}
+	return size * multiplier, nil
+}
+
+// memoryWatchFromFlags creates the memory watch, with the --max-rss limit when set
+func memoryWatchFromFlags(cmd *cobra.Command) (*MemoryWatch, error) {
+	maxRSS, _ := cmd.Flags().GetString("max-rss")
+	maxRSSMB := 0.0
+	if maxRSS != "" {
+		var err error
+		if maxRSSMB, err = parseMemorySize(maxRSS); err != nil {
+			return nil, err
+		}
+	}
+	return NewMemoryWatch(maxRSSMB), nil
+}
+
+// printMemoryWatermarks prints the memory footprint of the benchmark process
+func printMemoryWatermarks(stats *WorkloadStats) {
+	summary := stats.Memory.Summary()
+	fmt.Printf("Process Memory - Peak RSS: %.0f MB (at %s), Start: %.0f MB, End: %.0f MB\n",
+		summary.PeakRSSMB, summary.PeakRSSAt.Format("15:04:05"), summary.StartRSSMB, summary.EndRSSMB)
+	fmt.Printf("Go Memory - Peak Heap: %.0f MB, Peak Runtime Total: %.0f MB, GC Cycles: %d\n",
+		summary.PeakHeapMB, summary.PeakGoMemMB, summary.GCCycles)
+	if summary.RSSSource != "proc" {
+		fmt.Println("(RSS is the Go runtime total: /proc is unavailable on this platform)")
+	}
+	if summary.MaxRSSMB > 0 {
+		fmt.Printf("RSS Limit: %.0f MB (peak at %.0f%% of the limit)\n", summary.MaxRSSMB, summary.PeakRSSMB/summary.MaxRSSMB*100)
+	}
+}
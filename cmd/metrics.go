@@ -0,0 +1,80 @@
+//go:build !noprometheus
+
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+func init() {
+	registerBuildFeature("prometheus", "live /metrics endpoint (--metrics-addr)")
+}
+
+// startMetricsServer serves live run metrics in the Prometheus text format on /metrics.
+// Rates and percentiles come from the previous metrics window, like the progress line.
+func startMetricsServer(addr, engine string, stats *WorkloadStats) (*http.Server, error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writePrometheusMetrics(w, engine, stats)
+	})
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		fmt.Printf("Serving live metrics on http://%s/metrics\n", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Metrics server failed: %v", err)
+		}
+	}()
+	return server, nil
+}
+
+// writePrometheusMetrics writes the current counters, window rates and window percentiles
+func writePrometheusMetrics(w http.ResponseWriter, engine string, stats *WorkloadStats) {
+	operations := []struct {
+		name   string
+		ops    int64
+		errors int64
+		stats  *PerformanceStats
+	}{
+		{"get", atomic.LoadInt64(&stats.GetOps), atomic.LoadInt64(&stats.GetErrors), stats.GetStats},
+		{"set", atomic.LoadInt64(&stats.SetOps), atomic.LoadInt64(&stats.SetErrors), stats.SetStats},
+	}
+
+	fmt.Fprintln(w, "# HELP scb_operations_total Successful operations.")
+	fmt.Fprintln(w, "# TYPE scb_operations_total counter")
+	for _, op := range operations {
+		fmt.Fprintf(w, "scb_operations_total{engine=%q,operation=%q} %d\n", engine, op.name, op.ops)
+	}
+
+	fmt.Fprintln(w, "# HELP scb_errors_total Failed operations.")
+	fmt.Fprintln(w, "# TYPE scb_errors_total counter")
+	for _, op := range operations {
+		fmt.Fprintf(w, "scb_errors_total{engine=%q,operation=%q} %d\n", engine, op.name, op.errors)
+	}
+
+	fmt.Fprintln(w, "# HELP scb_qps Successful operations per second over the previous metrics window.")
+	fmt.Fprintln(w, "# TYPE scb_qps gauge")
+	for _, op := range operations {
+		count, _, _, _, _ := op.stats.GetPreviousWindowStats()
+		fmt.Fprintf(w, "scb_qps{engine=%q,operation=%q} %.2f\n", engine, op.name, float64(count)/MetricWindowSizeSeconds)
+	}
+
+	fmt.Fprintln(w, "# HELP scb_latency_microseconds Operation latency over the previous metrics window.")
+	fmt.Fprintln(w, "# TYPE scb_latency_microseconds gauge")
+	for _, op := range operations {
+		_, _, _, _, max := op.stats.GetPreviousWindowStats()
+		for _, v := range op.stats.GetPreviousWindowPercentiles() {
+			if v.Percentile == 100 {
+				continue // Same as the max below
+			}
+			quantile := strconv.FormatFloat(v.Percentile/100, 'g', 10, 64) // 0.999, not 0.9990000000000001
+			fmt.Fprintf(w, "scb_latency_microseconds{engine=%q,operation=%q,quantile=%q} %d\n", engine, op.name, quantile, v.Value)
+		}
+		fmt.Fprintf(w, "scb_latency_microseconds{engine=%q,operation=%q,quantile=%q} %d\n", engine, op.name, "1", max)
+	}
+}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	runCmd.Flags().String("mirror", "", "Shadow traffic: also send operations to this endpoint (redis[s]://, memcached[s]://host:port[,host:port] or http[s]:// URL), asynchronously and with separate stats, to evaluate a candidate cache under the same load")
+	runCmd.Flags().Float64("mirror-fraction", 1, "Fraction of the operations mirrored (0-1]")
+	runCmd.Flags().Int("mirror-clients", 8, "Concurrent mirror operations")
+	runCmd.Flags().Int("mirror-queue", 10000, "Operations queued for the mirror; when full, further operations are not mirrored rather than slowing the primary")
+	runCmd.Flags().Bool("mirror-cluster-mode", false, "Use a cluster client for a redis mirror")
+}
+
+// Mirror replays a fraction of the primary's operations against a second endpoint. Workers
+// only queue an operation once their own has completed, without blocking: mirror clients
+// execute the queue in the background, so a slow or failing mirror never shows in the
+// primary's latency. Operations the queue has no room for are counted as dropped.
+type Mirror struct {
+	Endpoint string
+	Fraction float64
+	Client   CacheClient
+
+	GetStats  *PerformanceStats
+	SetStats  *PerformanceStats
+	GetOps    int64
+	GetErrors int64
+	SetOps    int64
+	SetErrors int64
+	Misses    int64 // GETs that succeeded on the primary but failed on the mirror (e.g. not populated)
+	Dropped   int64 // Operations not mirrored because the queue was full
+	Skipped   int64 // Read-modify-write cycles and chains, which are not mirrored
+
+	queue   chan mirrorOp
+	clients int
+	timeout int
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// mirrorOp is a primary operation queued for the mirror
+type mirrorOp struct {
+	request       requestInfo
+	primaryFailed bool
+}
+
+// MirrorSummary is the machine-readable result of the shadow traffic
+type MirrorSummary struct {
+	Endpoint string         `json:"endpoint"`
+	Fraction float64        `json:"fraction"`
+	Dropped  int64          `json:"dropped"`
+	Misses   int64          `json:"misses"`
+	Get      LatencySummary `json:"get"`
+	Set      LatencySummary `json:"set"`
+}
+
+// NewMirror creates the mirror of a run; Start begins executing mirrored operations
+func NewMirror(endpoint string, client CacheClient, fraction float64, clients, queueSize, timeoutSeconds int) *Mirror {
+	getStats, setStats := NewPerformanceStats(), NewPerformanceStats()
+	getStats.Name, setStats.Name = "mirror-get", "mirror-set"
+	return &Mirror{
+		Endpoint: redactURIs(endpoint),
+		Fraction: fraction,
+		Client:   client,
+		GetStats: getStats,
+		SetStats: setStats,
+		queue:    make(chan mirrorOp, queueSize),
+		clients:  clients,
+		timeout:  timeoutSeconds,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start runs the mirror clients until Stop; they generate their own values of the same sizes
+func (m *Mirror) Start(generator *DataGenerator, opts *WorkloadOptions) {
+	// Mirrored operations must not count as the primary's inserts or recreate its deleted keys
+	mirrorOpts := *opts
+	mirrorOpts.WriteSplit = nil
+	mirrorOpts.Deletes = nil
+	for i := 0; i < m.clients; i++ {
+		m.wg.Add(1)
+		go m.worker(generator.WithRand(nil), &mirrorOpts)
+	}
+}
+
+// Submit queues a completed primary operation for the mirror without blocking. Operations
+// are sampled with rng, the submitting goroutine's stream, so the mirrored subset follows
+// --seed and workers don't contend on the global generator.
+func (m *Mirror) Submit(request requestInfo, result workloadResult, rng *rand.Rand) {
+	if m.Fraction < 1 && rng.Float64() >= m.Fraction {
+		return
+	}
+	if request.isRMW || request.isChain || request.isCommand {
+		atomic.AddInt64(&m.Skipped, 1)
+		return
+	}
+	select {
+	case m.queue <- mirrorOp{request: request, primaryFailed: result.isError}:
+	default:
+		atomic.AddInt64(&m.Dropped, 1)
+	}
+}
+
+func (m *Mirror) worker(generator *DataGenerator, opts *WorkloadOptions) {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case op := <-m.queue:
+			m.execute(op, generator, opts)
+		}
+	}
+}
+
+// execute runs a mirrored operation and records it in the mirror's stats,
+// the way the primary's are, so their counts compare one to one
+func (m *Mirror) execute(op mirrorOp, generator *DataGenerator, opts *WorkloadOptions) {
+	result := processRequest(context.Background(), op.request, m.Client, generator, opts, m.timeout, false)
+	switch {
+	case result.isSet && result.isError:
+		atomic.AddInt64(&m.SetErrors, 1)
+	case result.isSet:
+		atomic.AddInt64(&m.SetOps, 1)
+		m.SetStats.RecordLatency(result.latencyMicros)
+	case result.isError:
+		atomic.AddInt64(&m.GetErrors, 1)
+		if !op.primaryFailed {
+			atomic.AddInt64(&m.Misses, 1)
+		}
+	default:
+		atomic.AddInt64(&m.GetOps, 1)
+		m.GetStats.RecordLatency(result.latencyMicros)
+	}
+}
+
+// Stop ends the mirror clients; operations still queued are counted as dropped
+func (m *Mirror) Stop() {
+	select {
+	case <-m.stop:
+		return
+	default:
+	}
+	close(m.stop)
+	m.wg.Wait()
+	atomic.AddInt64(&m.Dropped, int64(len(m.queue)))
+	m.Client.Close()
+}
+
+// Close shuts down the mirror's stats collectors
+func (m *Mirror) Close() {
+	m.GetStats.Close()
+	m.SetStats.Close()
+}
+
+// Summary returns the mirror's results over the measurement window
+func (m *Mirror) Summary(duration time.Duration) *MirrorSummary {
+	seconds := duration.Seconds()
+	return &MirrorSummary{
+		Endpoint: m.Endpoint,
+		Fraction: m.Fraction,
+		Dropped:  atomic.LoadInt64(&m.Dropped),
+		Misses:   atomic.LoadInt64(&m.Misses),
+		Get:      summarizeLatency(m.GetStats, atomic.LoadInt64(&m.GetOps), atomic.LoadInt64(&m.GetErrors), seconds),
+		Set:      summarizeLatency(m.SetStats, atomic.LoadInt64(&m.SetOps), atomic.LoadInt64(&m.SetErrors), seconds),
+	}
+}
+
+// validateMirror checks the shadow traffic flags of a run
+func validateMirror(cmd *cobra.Command) error {
+	endpoint, _ := cmd.Flags().GetString("mirror")
+	if endpoint == "" {
+		return nil
+	}
+	fraction, _ := cmd.Flags().GetFloat64("mirror-fraction")
+	clients, _ := cmd.Flags().GetInt("mirror-clients")
+	queueSize, _ := cmd.Flags().GetInt("mirror-queue")
+	if fraction <= 0 || fraction > 1 {
+		return fmt.Errorf("mirror fraction must be in (0, 1], got: %.2f", fraction)
+	}
+	if clients <= 0 || queueSize <= 0 {
+		return fmt.Errorf("mirror clients and queue size must be positive")
+	}
+	return nil
+}
+
+// mirrorFromFlags creates the mirror selected with --mirror (nil when not set)
+func mirrorFromFlags(cmd *cobra.Command, timeoutSeconds int) (*Mirror, error) {
+	endpoint, _ := cmd.Flags().GetString("mirror")
+	if endpoint == "" {
+		return nil, nil
+	}
+	fraction, _ := cmd.Flags().GetFloat64("mirror-fraction")
+	clients, _ := cmd.Flags().GetInt("mirror-clients")
+	queueSize, _ := cmd.Flags().GetInt("mirror-queue")
+
+	clusterMode, _ := cmd.Flags().GetBool("mirror-cluster-mode")
+	client, err := newEndpointClient(cmd, endpoint, clusterMode, clients)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSeconds)*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("mirror %s is not reachable: %w", redactURIs(endpoint), err)
+	}
+	return NewMirror(endpoint, client, fraction, clients, queueSize, timeoutSeconds), nil
+}
+
+// printMirrorResults prints the mirror's latency next to the primary's
+func printMirrorResults(stats *WorkloadStats) {
+	m := stats.Mirror
+	fmt.Printf("Shadow Traffic (%s, %.0f%% of operations): %d dropped (queue full), %d not mirrored (RMW/chains/commands)\n",
+		m.Endpoint, m.Fraction*100, atomic.LoadInt64(&m.Dropped), atomic.LoadInt64(&m.Skipped))
+	fmt.Printf("%-12s %-10s %-10s %-8s %-10s %-10s %-10s\n", "Target", "Operation", "Ops", "Errors", "P50", "P99", "P99.9")
+	rows := []struct {
+		target, op string
+		ps         *PerformanceStats
+		ops, errs  int64
+	}{
+		{"primary", "GET", stats.GetStats, atomic.LoadInt64(&stats.GetOps), atomic.LoadInt64(&stats.GetErrors)},
+		{"mirror", "GET", m.GetStats, atomic.LoadInt64(&m.GetOps), atomic.LoadInt64(&m.GetErrors)},
+		{"primary", "SET", stats.SetStats, atomic.LoadInt64(&stats.SetOps), atomic.LoadInt64(&stats.SetErrors)},
+		{"mirror", "SET", m.SetStats, atomic.LoadInt64(&m.SetOps), atomic.LoadInt64(&m.SetErrors)},
+	}
+	for _, row := range rows {
+		fmt.Printf("%-12s %-10s %-10d %-8d %-10d %-10d %-10d\n", row.target, row.op, row.ops, row.errs,
+			row.ps.Histogram.ValueAtQuantile(50), row.ps.Histogram.ValueAtQuantile(99), row.ps.Histogram.ValueAtQuantile(99.9))
+	}
+	if misses := atomic.LoadInt64(&m.Misses); misses > 0 {
+		fmt.Printf("Mirror GETs failing where the primary's succeeded: %d (populate the mirror for comparable hit rates)\n", misses)
+	}
+	fmt.Println("(latencies in μs)")
+	fmt.Println()
+}
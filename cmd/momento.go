@@ -11,8 +11,40 @@ import (
 	"github.com/momentohq/client-sdk-go/config"
 	"github.com/momentohq/client-sdk-go/config/logger/momento_default_logger"
 	"github.com/momentohq/client-sdk-go/momento"
+	"github.com/momentohq/client-sdk-go/responses"
+	"github.com/spf13/cobra"
 )
 
+func init() {
+	RegisterBackend(&Backend{Name: "momento", New: newMomentoBackendClient})
+}
+
+// newMomentoBackendClient creates a Momento client; the cache itself is created once upfront
+func newMomentoBackendClient(ctx context.Context, cmd *cobra.Command) (CacheClient, error) {
+	apiKey, err := momentoAPIKey(cmd)
+	if err != nil {
+		return nil, err
+	}
+	cacheName, _ := cmd.Flags().GetString("momento-cache-name")
+	defaultTTL, _ := cmd.Flags().GetInt("default-ttl")
+	clientConnCount, _ := cmd.Flags().GetUint32("momento-client-conn-count")
+
+	client, err := NewMomentoClient(ctx, apiKey, cacheName, false, defaultTTL, clientConnCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Momento client: %w", err)
+	}
+	return client, nil
+}
+
+// momentoAPIKey returns the API key from --momento-api-key or --password-from; empty
+// falls back to the MOMENTO_API_KEY environment variable
+func momentoAPIKey(cmd *cobra.Command) (string, error) {
+	if apiKey, _ := cmd.Flags().GetString("momento-api-key"); apiKey != "" {
+		return apiKey, nil
+	}
+	return resolvePasswordFrom(cmd)
+}
+
 // MomentoClient implements CacheClient for Momento
 type MomentoClient struct {
 	client    momento.CacheClient
@@ -114,15 +146,27 @@ func (m *MomentoClient) Get(ctx context.Context, key string) ([]byte, error) {
 		return nil, err
 	}
 
-	// For now, let's use a simple approach and handle the response
-	// The exact response type handling may need adjustment based on the SDK version
-	if response == nil {
-		return nil, fmt.Errorf("key not found")
+	switch r := response.(type) {
+	case *responses.GetHit:
+		return r.ValueByte(), nil
+	case *responses.GetMiss:
+		return nil, ErrCacheMiss
+	default:
+		return nil, fmt.Errorf("unexpected Momento get response: %T", response)
 	}
+}
+
+func (m *MomentoClient) Delete(ctx context.Context, key string) error {
+	_, err := m.client.Delete(ctx, &momento.DeleteRequest{
+		CacheName: m.cacheName,
+		Key:       momento.String(key),
+	})
+	return err
+}
 
-	// Try to extract value - this may need adjustment based on actual SDK
-	// For now, return empty data to satisfy the interface
-	return []byte{}, nil
+// Connect opens the gRPC channel with a ping
+func (m *MomentoClient) Connect(ctx context.Context) error {
+	return m.Ping(ctx)
 }
 
 func (m *MomentoClient) Ping(ctx context.Context) error {
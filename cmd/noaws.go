@@ -0,0 +1,107 @@
+//go:build noaws
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Built with -tags noaws: the AWS SDK is left out, together with the engines and sinks
+// that need it (cache-type lambda, CloudWatch, S3/DynamoDB reports, the IAM preflight).
+// The stubs below keep the rest of the tool working and reject AWS options explicitly.
+
+// errNoAWS is returned when an AWS integration is requested from a minimal build
+var errNoAWS = fmt.Errorf("built without AWS support (-tags noaws)")
+
+// AWSPermission is an IAM action a run needs on a resource; unused without AWS support
+type AWSPermission struct {
+	Action   string
+	Resource string
+	RoleARN  string
+	Reason   string
+}
+
+// RegisterAWSPermissions is a no-op: there is no permissions preflight without AWS support
+func RegisterAWSPermissions(source func(cmd *cobra.Command) []AWSPermission) {}
+
+// runAWSPreflight has nothing to check without AWS support
+func runAWSPreflight(cmd *cobra.Command, cacheType string) error {
+	return nil
+}
+
+// awsRoleARN returns no role without AWS support
+func awsRoleARN(cmd *cobra.Command, roleFlag string) string {
+	return ""
+}
+
+// fetchAWSSecret rejects secretsmanager: and ssm: sources; env: and file: still work
+func fetchAWSSecret(kind, id, region, roleARN string) (string, error) {
+	return "", errNoAWS
+}
+
+// CloudWatchPublisher stands in for the CloudWatch publisher, which can't be created
+type CloudWatchPublisher struct {
+	Namespace string
+
+	Published int64
+	Requests  int64
+	Retries   int64
+	Failed    int64
+	Dropped   int64
+}
+
+// NewCloudWatchPublisher always fails without AWS support
+func NewCloudWatchPublisher(ctx context.Context, region, roleARN, namespace, engine, dimensions string) (*CloudWatchPublisher, error) {
+	return nil, errNoAWS
+}
+
+func (p *CloudWatchPublisher) PublishSnapshot(snapshot MetricsSnapshot) {}
+
+func (p *CloudWatchPublisher) PublishHeartbeat(beat Heartbeat) {}
+
+func (p *CloudWatchPublisher) Close() {}
+
+func (p *CloudWatchPublisher) LastError() error {
+	return nil
+}
+
+func printCloudWatchResults(publisher *CloudWatchPublisher) {}
+
+func init() {
+	// Keep the CloudWatch flags so configurations using them fail with a clear error
+	// instead of an unknown flag
+	runCmd.Flags().String("cloudwatch-namespace", "", "Publish live run metrics to this CloudWatch namespace (unavailable: built without AWS support)")
+	runCmd.Flags().String("cloudwatch-dimensions", "", "Extra CloudWatch dimensions (unavailable: built without AWS support)")
+	runCmd.Flags().String("cloudwatch-role-arn", "", "IAM role for publishing metrics (unavailable: built without AWS support)")
+}
+
+// TimestreamPublisher stands in for the Timestream writer, which can't be created
+type TimestreamPublisher struct {
+	Dropped int64
+}
+
+// NewTimestreamPublisher always fails without AWS support
+func NewTimestreamPublisher(ctx context.Context, region, roleARN, target, runID, engine, workloadHash, dimensions string) (*TimestreamPublisher, error) {
+	return nil, errNoAWS
+}
+
+func (p *TimestreamPublisher) PublishSnapshot(snapshot MetricsSnapshot) {}
+
+func (p *TimestreamPublisher) Close() {}
+
+func printTimestreamResults(publisher *TimestreamPublisher) {}
+
+func init() {
+	runCmd.Flags().String("timestream", "", "Write the per-window stats to this Amazon Timestream table (unavailable: built without AWS support)")
+	runCmd.Flags().String("timestream-dimensions", "", "Extra Timestream dimensions (unavailable: built without AWS support)")
+	runCmd.Flags().String("timestream-role-arn", "", "IAM role for writing to Timestream (unavailable: built without AWS support)")
+}
+
+// fetchServerMetrics always fails: the server metrics come from CloudWatch
+func fetchServerMetrics(sc *ServerCorrelation, start, end time.Time) ([]ServerMetricSeries, error) {
+	return nil, errNoAWS
+}
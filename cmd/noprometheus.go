@@ -0,0 +1,13 @@
+//go:build noprometheus
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// startMetricsServer rejects --metrics-addr in builds made with -tags noprometheus
+func startMetricsServer(addr, engine string, stats *WorkloadStats) (*http.Server, error) {
+	return nil, fmt.Errorf("--metrics-addr is unavailable: built without the Prometheus endpoint (-tags noprometheus)")
+}
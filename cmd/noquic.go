@@ -0,0 +1,13 @@
+//go:build noquic
+
+package cmd
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// newHTTP3Transport rejects h3 in builds made with -tags noquic
+func newHTTP3Transport(tlsConfig *tls.Config) (*httpTransport, error) {
+	return nil, fmt.Errorf("HTTP/3 is unavailable: built without QUIC support (-tags noquic)")
+}
@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// notifyTimeout bounds delivering one notification
+const notifyTimeout = 10 * time.Second
+
+func init() {
+	runCmd.Flags().String("notify-webhook", "", "Webhook URL (Slack-compatible) notified on run start, completion with summary stats, and SLO violations")
+	runCmd.Flags().Int64("slo-get-p99", 0, "SLO on GET P99 latency in μs, checked per metrics window and over the run (0 = none)")
+	runCmd.Flags().Int64("slo-set-p99", 0, "SLO on SET P99 latency in μs, checked per metrics window and over the run (0 = none)")
+	runCmd.Flags().Float64("slo-error-rate", 0, "SLO on the error rate in percent of operations, checked per metrics window and over the run (0 = none)")
+}
+
+// SLO holds the service level objectives a run is checked against (zero values are unset)
+type SLO struct {
+	GetP99    int64   // μs
+	SetP99    int64   // μs
+	ErrorRate float64 // Percent of operations
+}
+
+// Enabled reports whether any objective is set
+func (slo SLO) Enabled() bool {
+	return slo.GetP99 > 0 || slo.SetP99 > 0 || slo.ErrorRate > 0
+}
+
+// Violations returns a description of each objective the measured values breach
+func (slo SLO) Violations(getP99, setP99 int64, errorRate float64) []string {
+	var violations []string
+	if slo.GetP99 > 0 && getP99 > slo.GetP99 {
+		violations = append(violations, fmt.Sprintf("GET P99 %d μs > %d μs", getP99, slo.GetP99))
+	}
+	if slo.SetP99 > 0 && setP99 > slo.SetP99 {
+		violations = append(violations, fmt.Sprintf("SET P99 %d μs > %d μs", setP99, slo.SetP99))
+	}
+	if slo.ErrorRate > 0 && errorRate > slo.ErrorRate {
+		violations = append(violations, fmt.Sprintf("error rate %.2f%% > %.2f%%", errorRate, slo.ErrorRate))
+	}
+	return violations
+}
+
+// errorRatePercent returns errors as a percentage of attempted operations
+func errorRatePercent(ops, errors int64) float64 {
+	if ops+errors == 0 {
+		return 0
+	}
+	return float64(errors) / float64(ops+errors) * 100
+}
+
+// slackField is a field of a Slack message attachment
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Fields []slackField `json:"fields,omitempty"`
+}
+
+// slackMessage is the payload of a Slack incoming webhook; most chat tools accept it too
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+// Notifier posts run lifecycle and SLO breach notifications to a webhook. A breach is
+// notified when a metrics window starts violating the SLO, and again only after a
+// window met it, so a sustained breach doesn't flood the channel.
+type Notifier struct {
+	URL    string
+	SLO    SLO
+	Sent   int64
+	Failed int64
+
+	NotifyRecovery bool // Also notify when a breach ends (canary mode)
+
+	engine     string
+	workload   string
+	host       string
+	breaching  bool
+	breaches   int64
+	lastOps    int64
+	lastErrors int64
+	pending    sync.WaitGroup
+	errorOnce  sync.Once
+}
+
+func NewNotifier(url string, slo SLO, engine, workloadHash string) *Notifier {
+	host, _ := os.Hostname()
+	return &Notifier{URL: url, SLO: slo, engine: engine, workload: workloadHash, host: host}
+}
+
+// title prefixes notifications with what ran where
+func (n *Notifier) title(event string) string {
+	return fmt.Sprintf("[%s benchmark on %s, workload %s] %s", n.engine, n.host, shortHash(n.workload), event)
+}
+
+// post delivers a message in the background; Close waits for pending deliveries
+func (n *Notifier) post(message slackMessage) {
+	n.pending.Add(1)
+	go func() {
+		defer n.pending.Done()
+		if err := n.send(message); err != nil {
+			atomic.AddInt64(&n.Failed, 1)
+			n.errorOnce.Do(func() { log.Printf("Webhook notification failed: %v", err) })
+			return
+		}
+		atomic.AddInt64(&n.Sent, 1)
+	}()
+}
+
+func (n *Notifier) send(message slackMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// RunStarted notifies the start of a run
+func (n *Notifier) RunStarted(description string) {
+	n.post(slackMessage{Text: n.title("Run started: " + description)})
+}
+
+// CheckWindow checks the SLO against a progress snapshot (cumulative error counts,
+// P99 of the latest metrics window)
+func (n *Notifier) CheckWindow(snapshot MetricsSnapshot) {
+	if !n.SLO.Enabled() {
+		return
+	}
+	errors := snapshot.GetErrors + snapshot.SetErrors
+	ops, windowErrors := snapshot.TotalOps-n.lastOps, errors-n.lastErrors
+	n.lastOps, n.lastErrors = snapshot.TotalOps, errors
+
+	violations := n.SLO.Violations(snapshot.GetLatencyP99, snapshot.SetLatencyP99, errorRatePercent(ops, windowErrors))
+	if len(violations) == 0 {
+		if n.breaching && n.NotifyRecovery {
+			n.post(slackMessage{
+				Text: n.title("SLO recovered"),
+				Attachments: []slackAttachment{{Color: "good", Fields: []slackField{
+					{Title: "Elapsed", Value: fmt.Sprintf("%ds", snapshot.ElapsedSeconds), Short: true},
+				}}},
+			})
+		}
+		n.breaching = false
+		return
+	}
+	atomic.AddInt64(&n.breaches, 1)
+	if n.breaching {
+		return
+	}
+	n.breaching = true
+
+	fields := make([]slackField, 0, len(violations)+1)
+	for _, violation := range violations {
+		fields = append(fields, slackField{Title: "Violation", Value: violation})
+	}
+	fields = append(fields, slackField{Title: "Elapsed", Value: fmt.Sprintf("%ds", snapshot.ElapsedSeconds), Short: true})
+	n.post(slackMessage{
+		Text:        n.title("SLO breached"),
+		Attachments: []slackAttachment{{Color: "danger", Fields: fields}},
+	})
+}
+
+// RunCompleted notifies the end of a run with its summary stats and overall SLO outcome
+func (n *Notifier) RunCompleted(summary *RunSummary) {
+	violations := n.SLO.Violations(summary.Get.P99, summary.Set.P99, errorRatePercent(summary.TotalOps, summary.TotalErrors))
+
+	fields := []slackField{
+		{Title: "Duration", Value: fmt.Sprintf("%.1fs", summary.DurationSeconds), Short: true},
+		{Title: "Operations", Value: fmt.Sprintf("%d (%d errors)", summary.TotalOps, summary.TotalErrors), Short: true},
+		{Title: "GET", Value: fmt.Sprintf("%.0f QPS, P50 %d μs, P99 %d μs", summary.Get.QPS, summary.Get.P50, summary.Get.P99), Short: true},
+		{Title: "SET", Value: fmt.Sprintf("%.0f QPS, P50 %d μs, P99 %d μs", summary.Set.QPS, summary.Set.P50, summary.Set.P99), Short: true},
+	}
+	color := "good"
+	if n.SLO.Enabled() {
+		outcome := "met"
+		if len(violations) > 0 {
+			outcome = "breached: " + strings.Join(violations, ", ")
+			color = "danger"
+		}
+		if breaches := atomic.LoadInt64(&n.breaches); breaches > 0 {
+			outcome += fmt.Sprintf(" (%d metrics windows in breach)", breaches)
+			if len(violations) == 0 {
+				color = "warning"
+			}
+		}
+		fields = append(fields, slackField{Title: "SLO", Value: outcome})
+	}
+	n.post(slackMessage{
+		Text:        n.title("Run completed"),
+		Attachments: []slackAttachment{{Color: color, Fields: fields}},
+	})
+}
+
+// Close waits for pending notifications to be delivered
+func (n *Notifier) Close() {
+	n.pending.Wait()
+}
+
+// notifierFromFlags creates the webhook notifier selected with --notify-webhook (nil when not set)
+func notifierFromFlags(cmd *cobra.Command, engine, workloadHash string) (*Notifier, error) {
+	url, _ := cmd.Flags().GetString("notify-webhook")
+	var slo SLO
+	slo.GetP99, _ = cmd.Flags().GetInt64("slo-get-p99")
+	slo.SetP99, _ = cmd.Flags().GetInt64("slo-set-p99")
+	slo.ErrorRate, _ = cmd.Flags().GetFloat64("slo-error-rate")
+	if slo.GetP99 < 0 || slo.SetP99 < 0 || slo.ErrorRate < 0 {
+		return nil, fmt.Errorf("SLO thresholds must not be negative")
+	}
+	if url == "" {
+		if slo.Enabled() {
+			return nil, fmt.Errorf("SLO thresholds need --notify-webhook")
+		}
+		return nil, nil
+	}
+	return NewNotifier(url, slo, engine, workloadHash), nil
+}
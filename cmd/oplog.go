@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// opLogMagic starts every operation log; the version is bumped on format changes
+const opLogMagic = "SCBOPS1\n"
+
+// Operation codes in the log
+const (
+	opLogGet byte = iota
+	opLogSet
+	opLogNegativeGet
+)
+
+// opLogQueueSize is the number of replayed operations buffered per worker
+const opLogQueueSize = 1024
+
+// OpRecorder writes the generated operations of a run to a compact binary log:
+// a header (magic, client count) followed by one entry per operation
+// (worker uvarint, op byte, key ID uvarint, value size uvarint).
+type OpRecorder struct {
+	Filename string
+	Count    int64
+
+	mutex  sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+	buf    [3*binary.MaxVarintLen64 + 1]byte
+}
+
+func NewOpRecorder(filename string, clients int) (*OpRecorder, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create operation log: %w", err)
+	}
+
+	r := &OpRecorder{Filename: filename, file: file, writer: bufio.NewWriterSize(file, 1<<20)}
+	r.writer.WriteString(opLogMagic)
+	n := binary.PutUvarint(r.buf[:], uint64(clients))
+	if _, err := r.writer.Write(r.buf[:n]); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write operation log header: %w", err)
+	}
+	return r, nil
+}
+
+// Record appends an operation; the per-worker order is preserved
+func (r *OpRecorder) Record(request requestInfo, size int) {
+	op := opLogGet
+	if request.isSet {
+		op = opLogSet
+	} else if request.isNegative {
+		op = opLogNegativeGet
+	} else {
+		size = 0
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	n := binary.PutUvarint(r.buf[:], uint64(request.workerID))
+	r.buf[n] = op
+	n++
+	n += binary.PutUvarint(r.buf[n:], uint64(request.keyID))
+	n += binary.PutUvarint(r.buf[n:], uint64(size))
+	r.writer.Write(r.buf[:n])
+	r.Count++
+}
+
+func (r *OpRecorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.writer.Flush(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// OpReplay re-issues a recorded operation log: each recorded worker's operations are
+// handed, in order, to the worker with the same ID
+type OpReplay struct {
+	Filename string
+	Clients  int
+	Replayed int64 // Operations handed to workers
+
+	file   *os.File
+	reader *bufio.Reader
+	queues []chan requestInfo
+	err    error
+}
+
+func NewOpReplay(filename string) (*OpReplay, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open operation log: %w", err)
+	}
+
+	reader := bufio.NewReaderSize(file, 1<<20)
+	magic := make([]byte, len(opLogMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != opLogMagic {
+		file.Close()
+		return nil, fmt.Errorf("%s is not an operation log", filename)
+	}
+	clients, err := binary.ReadUvarint(reader)
+	if err != nil || clients == 0 {
+		file.Close()
+		return nil, fmt.Errorf("invalid operation log header in %s", filename)
+	}
+
+	replay := &OpReplay{Filename: filename, Clients: int(clients), file: file, reader: reader}
+	for i := 0; i < replay.Clients; i++ {
+		replay.queues = append(replay.queues, make(chan requestInfo, opLogQueueSize))
+	}
+	return replay, nil
+}
+
+// Start dispatches the log to the worker queues until it is exhausted or ctx is done
+func (r *OpReplay) Start(ctx context.Context, keyPrefix string) {
+	go func() {
+		defer func() {
+			for _, queue := range r.queues {
+				close(queue)
+			}
+		}()
+
+		for {
+			request, err := r.readEntry(keyPrefix)
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					r.err = err
+				}
+				return
+			}
+			select {
+			case r.queues[request.workerID] <- request:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// readEntry decodes the next operation into a request
+func (r *OpReplay) readEntry(keyPrefix string) (requestInfo, error) {
+	workerID, err := binary.ReadUvarint(r.reader)
+	if err != nil {
+		return requestInfo{}, err
+	}
+	op, err := r.reader.ReadByte()
+	if err != nil {
+		return requestInfo{}, io.ErrUnexpectedEOF
+	}
+	keyID, err := binary.ReadUvarint(r.reader)
+	if err != nil {
+		return requestInfo{}, io.ErrUnexpectedEOF
+	}
+	size, err := binary.ReadUvarint(r.reader)
+	if err != nil {
+		return requestInfo{}, io.ErrUnexpectedEOF
+	}
+	if int(workerID) >= r.Clients {
+		return requestInfo{}, fmt.Errorf("operation log entry for worker %d, but only %d clients recorded", workerID, r.Clients)
+	}
+
+	request := requestInfo{workerID: int(workerID), keyID: int(keyID), size: int(size)}
+	switch op {
+	case opLogGet:
+		request.key = formatKey(keyPrefix, int(keyID))
+	case opLogSet:
+		request.isSet = true
+		request.key = formatKey(keyPrefix, int(keyID))
+	case opLogNegativeGet:
+		request.isNegative = true
+		request.key = formatNegativeKey(keyPrefix, int(keyID))
+	default:
+		return requestInfo{}, fmt.Errorf("unknown operation code %d in operation log", op)
+	}
+	return request, nil
+}
+
+// Next returns the next recorded operation of a worker; false once its operations are exhausted
+func (r *OpReplay) Next(ctx context.Context, workerID int) (requestInfo, bool) {
+	if workerID >= len(r.queues) {
+		return requestInfo{}, false
+	}
+	select {
+	case request, ok := <-r.queues[workerID]:
+		if ok {
+			atomic.AddInt64(&r.Replayed, 1)
+		}
+		return request, ok
+	case <-ctx.Done():
+		return requestInfo{}, false
+	}
+}
+
+// Err returns the error that stopped the replay early, if any
+func (r *OpReplay) Err() error {
+	return r.err
+}
+
+func (r *OpReplay) Close() error {
+	return r.file.Close()
+}
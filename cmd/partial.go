@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// partialRun is the run a crash flushes a partial report for (nil when none is armed)
+type partialRun struct {
+	path      string
+	stats     *WorkloadStats
+	engine    string
+	startTime time.Time
+	workload  *WorkloadConfig
+	command   *CommandInfo
+	once      sync.Once // Only the first crash of the run writes its report
+}
+
+// partialCollectorTimeout bounds the wait for the stats collector to stop on a crash
+const partialCollectorTimeout = 5 * time.Second
+
+var (
+	partialMutex   sync.Mutex
+	partialCurrent *partialRun
+)
+
+// armPartialReport makes a crash of the run flush its stats so far to path ("" disables)
+func armPartialReport(path string, stats *WorkloadStats, engine string, workload *WorkloadConfig, command *CommandInfo) {
+	if path == "" {
+		return
+	}
+	partialMutex.Lock()
+	defer partialMutex.Unlock()
+	partialCurrent = &partialRun{path: path, stats: stats, engine: engine, startTime: time.Now(), workload: workload, command: command}
+}
+
+// disarmPartialReport is called once the run has produced its regular results
+func disarmPartialReport() {
+	partialMutex.Lock()
+	defer partialMutex.Unlock()
+	partialCurrent = nil
+}
+
+// recoverPartialReport is deferred by the run's goroutines: on a panic it flushes the
+// partial report, then re-panics so the crash is still reported with its stack trace
+func recoverPartialReport() {
+	if r := recover(); r != nil {
+		flushPartialReport(fmt.Sprintf("panic: %v", r))
+		panic(r)
+	}
+}
+
+// flushPartialReport writes the armed run's stats so far, flagged as truncated; only the
+// first call of each run writes, as a crash can surface in several goroutines at once
+func flushPartialReport(reason string) {
+	partialMutex.Lock()
+	run := partialCurrent
+	partialMutex.Unlock()
+	if run == nil {
+		return
+	}
+
+	run.once.Do(func() {
+		// Histograms can only be read once the collector has stopped writing them; if it
+		// doesn't stop in time, only the operation counters are reported. Windows and
+		// sketches are left out either way.
+		summarize := summarizeTotals
+		stopped := run.stats.GetStats.collector.CloseWithin(partialCollectorTimeout)
+		if !stopped {
+			summarize = summarizeCounters
+		}
+		summary := newRunSummary(run.stats, run.engine, run.startTime, run.stats.Phases.Measurement(), summarize)
+		summary.Phases = run.stats.Phases.Summary()
+		if run.stats.Memory != nil {
+			summary.Memory = run.stats.Memory.Summary()
+		}
+		if run.stats.Topology != nil {
+			summary.Topology = run.stats.Topology.Changes()
+		}
+		if run.stats.Fragmentation != nil {
+			summary.Fragmentation = run.stats.Fragmentation.Summary()
+		}
+		summary.Timeouts = run.stats.Timeouts.Summary()
+		if stopped {
+			summary.Setup = summarizeSetup(run.stats, summarizeTotals)
+			if run.stats.Control != nil {
+				summary.ModePhases = run.stats.Control.Summary()
+			}
+		}
+		summary.Workload = run.workload
+		summary.WorkloadHash = run.workload.Hash()
+		summary.Build = currentBuildInfo()
+		summary.Command = run.command
+		summary.Truncated = true
+		summary.TruncatedReason = reason
+
+		data, err := json.MarshalIndent(summary, "", "  ")
+		if err == nil {
+			err = os.WriteFile(run.path, append(data, '\n'), 0644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write partial report: %v\n", err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Run stopped early (%s); partial results written to: %s\n", reason, run.path)
+	})
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{populateCmd, runCmd, reportCmd} {
+		cmd.Flags().String("percentiles", "50,95,99", "Latency percentiles reported in the console, the JSON summary, CloudWatch and Prometheus (e.g. 50,90,99,99.9,99.99)")
+	}
+}
+
+// reportPercentiles are the latency percentiles of the current populate or run, ascending,
+// set from --percentiles
+var reportPercentiles = []float64{50, 95, 99}
+
+// PercentileValue is the latency at one reported percentile
+type PercentileValue struct {
+	Percentile float64 `json:"percentile"`
+	Value      int64   `json:"value_us"`
+}
+
+// quantileSource is a latency distribution queried by percentile (histograms and sketches)
+type quantileSource interface {
+	ValueAtQuantile(quantile float64) int64
+}
+
+// emptyQuantiles is an empty distribution, all percentiles zero
+type emptyQuantiles struct{}
+
+func (emptyQuantiles) ValueAtQuantile(float64) int64 { return 0 }
+
+// parsePercentiles parses a --percentiles list; the result is sorted and without
+// duplicates, so outputs list the same percentiles in the same order whatever the input
+func parsePercentiles(list string) ([]float64, error) {
+	seen := make(map[float64]bool)
+	var percentiles []float64
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		p, err := strconv.ParseFloat(item, 64)
+		if err != nil || p <= 0 || p > 100 {
+			return nil, fmt.Errorf("invalid percentile '%s': expected a number in (0, 100]", item)
+		}
+		if !seen[p] {
+			seen[p] = true
+			percentiles = append(percentiles, p)
+		}
+	}
+	if len(percentiles) == 0 {
+		return nil, fmt.Errorf("no percentiles given")
+	}
+	sort.Float64s(percentiles)
+	return percentiles, nil
+}
+
+// percentilesFromFlags resolves --percentiles into reportPercentiles
+func percentilesFromFlags(cmd *cobra.Command) error {
+	list, _ := cmd.Flags().GetString("percentiles")
+	percentiles, err := parsePercentiles(list)
+	if err != nil {
+		return err
+	}
+	reportPercentiles = percentiles
+	return nil
+}
+
+// percentileLabel names a percentile the way the reports do: P50, P99.9
+func percentileLabel(p float64) string {
+	return "P" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// latencyPercentiles reads the reported percentiles from a distribution
+func latencyPercentiles(source quantileSource) []PercentileValue {
+	values := make([]PercentileValue, len(reportPercentiles))
+	for i, p := range reportPercentiles {
+		values[i] = PercentileValue{Percentile: p, Value: source.ValueAtQuantile(p)}
+	}
+	return values
+}
+
+// formatPercentiles renders percentiles for the console: "P50: 120 μs, P99: 480 μs"
+func formatPercentiles(values []PercentileValue) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%s: %d μs", percentileLabel(v.Percentile), v.Value)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// RunPhases accounts the wall time of a run by phase: populate (seeding keys the workload
+// reads, e.g. RMW counters), warmup (client setup until the first worker issues operations),
+// measurement (until the last worker stops) and teardown (verification, flushing sinks).
+// Throughput is computed over the measurement window only.
+type RunPhases struct {
+	// Phase boundaries in Unix nanoseconds (0 = not reached), accessed atomically
+	start            int64
+	populateStart    int64
+	populateEnd      int64
+	warmupStart      int64
+	measurementStart int64
+	measurementEnd   int64
+	end              int64
+}
+
+// PhaseSummary is the machine-readable wall time breakdown of a run
+type PhaseSummary struct {
+	PopulateSeconds    float64 `json:"populate_seconds"`
+	WarmupSeconds      float64 `json:"warmup_seconds"`
+	MeasurementSeconds float64 `json:"measurement_seconds"`
+	TeardownSeconds    float64 `json:"teardown_seconds"`
+	WallSeconds        float64 `json:"wall_seconds"`
+}
+
+func NewRunPhases() *RunPhases {
+	return &RunPhases{start: time.Now().UnixNano()}
+}
+
+func (rp *RunPhases) mark(boundary *int64) {
+	atomic.StoreInt64(boundary, time.Now().UnixNano())
+}
+
+func (rp *RunPhases) BeginPopulate() { rp.mark(&rp.populateStart) }
+func (rp *RunPhases) EndPopulate()   { rp.mark(&rp.populateEnd) }
+func (rp *RunPhases) BeginWarmup()   { rp.mark(&rp.warmupStart) }
+func (rp *RunPhases) End()           { rp.mark(&rp.end) }
+
+// span returns the time between two boundaries, up to now when the second isn't reached yet
+func (rp *RunPhases) span(from, to *int64) time.Duration {
+	start := atomic.LoadInt64(from)
+	if start == 0 {
+		return 0
+	}
+	end := atomic.LoadInt64(to)
+	if end == 0 {
+		end = time.Now().UnixNano()
+	}
+	return time.Duration(end - start)
+}
+
+// Measurement returns the length of the measurement window so far
+func (rp *RunPhases) Measurement() time.Duration {
+	return rp.span(&rp.measurementStart, &rp.measurementEnd)
+}
+
+// Current returns the phase the run is in: setup, populate, warmup, measurement, teardown or done
+func (rp *RunPhases) Current() string {
+	switch {
+	case atomic.LoadInt64(&rp.end) != 0:
+		return "done"
+	case atomic.LoadInt64(&rp.measurementEnd) != 0:
+		return "teardown"
+	case atomic.LoadInt64(&rp.measurementStart) != 0:
+		return "measurement"
+	case atomic.LoadInt64(&rp.warmupStart) != 0:
+		return "warmup"
+	case atomic.LoadInt64(&rp.populateStart) != 0 && atomic.LoadInt64(&rp.populateEnd) == 0:
+		return "populate"
+	default:
+		return "setup"
+	}
+}
+
+// BeginMeasurement starts the measurement window when the first worker is ready to issue
+// operations; later calls are ignored. It also starts the window of the GET/SET stats.
+func (ws *WorkloadStats) BeginMeasurement() {
+	now := time.Now()
+	if !atomic.CompareAndSwapInt64(&ws.Phases.measurementStart, 0, now.UnixNano()) {
+		return
+	}
+	ws.GetStats.StartMeasurement(now)
+	ws.SetStats.StartMeasurement(now)
+}
+
+// EndMeasurement ends the measurement window once all workers have stopped
+func (ws *WorkloadStats) EndMeasurement() {
+	now := time.Now()
+	atomic.StoreInt64(&ws.Phases.measurementEnd, now.UnixNano())
+	ws.GetStats.StopMeasurement(now)
+	ws.SetStats.StopMeasurement(now)
+}
+
+// measurementSeconds returns the measurement window in seconds, falling back to the
+// configured test time when no worker became ready
+func (ws *WorkloadStats) measurementSeconds(testTime int) float64 {
+	if seconds := ws.Phases.Measurement().Seconds(); seconds > 0 {
+		return seconds
+	}
+	return float64(testTime)
+}
+
+// Summary returns the wall time breakdown; the end of the run defaults to now
+func (rp *RunPhases) Summary() *PhaseSummary {
+	warmupEnd := &rp.measurementStart
+	if atomic.LoadInt64(warmupEnd) == 0 {
+		warmupEnd = &rp.measurementEnd
+	}
+	return &PhaseSummary{
+		PopulateSeconds:    rp.span(&rp.populateStart, &rp.populateEnd).Seconds(),
+		WarmupSeconds:      rp.span(&rp.warmupStart, warmupEnd).Seconds(),
+		MeasurementSeconds: rp.Measurement().Seconds(),
+		TeardownSeconds:    rp.span(&rp.measurementEnd, &rp.end).Seconds(),
+		WallSeconds:        rp.span(&rp.start, &rp.end).Seconds(),
+	}
+}
+
+// printRunPhases prints the wall time breakdown of a run
+func printRunPhases(stats *WorkloadStats) {
+	phases := stats.Phases.Summary()
+	fmt.Printf("Wall Time: %.2fs - Populate: %.2fs, Warmup: %.2fs, Measurement: %.2fs, Teardown: %.2fs\n",
+		phases.WallSeconds, phases.PopulateSeconds, phases.WarmupSeconds, phases.MeasurementSeconds, phases.TeardownSeconds)
+	fmt.Println("(throughput is computed over the measurement window)")
+}
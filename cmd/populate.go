@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -34,10 +35,18 @@ type PopulateStats struct {
 	PerfStats   *PerformanceStats // Reference to performance stats for latency data
 }
 
-// CacheClient interface defines the operations for cache data sinks
+// ErrCacheMiss is returned by CacheClient.Get when the key does not exist
+var ErrCacheMiss = errors.New("cache miss")
+
+// CacheClient interface defines the operations for cache data sinks. Backend.New returns
+// a client ready to use, connecting lazily; Connect opens its connections up front, so the
+// setup time and not the first operations pay for dials and handshakes. Operations must
+// honour ctx, so the operation timeout and cancellation apply.
 type CacheClient interface {
+	Connect(ctx context.Context) error
 	Set(ctx context.Context, key string, value []byte, expiration time.Duration) error
 	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
 	Ping(ctx context.Context) error
 	Close() error
 	Name() string
@@ -47,7 +56,7 @@ type CacheClient interface {
 var populateCmd = &cobra.Command{
 	Use:   "populate",
 	Short: "Populate cache with test data using multiple concurrent clients",
-	Long: `Populate cache systems (Redis or Momento) with test data for benchmarking using multiple concurrent clients.
+	Long: `Populate cache systems (Redis, Memcached or Momento) with test data for benchmarking using multiple concurrent clients.
 
 This command supports populating both Redis and Momento cache systems with configurable
 test data including different data sizes, key patterns, and expiration settings. It uses
@@ -64,6 +73,9 @@ Examples:
   # Populate Redis with TLS connection
   serverless-cache-benchmark populate --cache-type redis --redis-uri rediss://localhost:6380
 
+  # Populate ElastiCache Serverless for Memcached (TLS) using the --engine flag
+  serverless-cache-benchmark populate --engine memcached --memcached-servers my-cache.serverless.use1.cache.amazonaws.com:11211 --memcached-tls
+
   # Populate Momento with rate limiting at 1000 RPS total
   serverless-cache-benchmark populate --cache-type momento --momento-cache-name test-cache --rps 1000
 
@@ -71,7 +83,11 @@ Examples:
   serverless-cache-benchmark populate --cache-type redis --redis-uri redis://localhost:6379 --clients 4 --rps 500
 
   # Populate with custom Redis timeouts for high-load scenarios
-  serverless-cache-benchmark populate --cache-type redis --redis-dial-timeout 30 --redis-read-timeout 30 --redis-max-retries 5`,
+  serverless-cache-benchmark populate --cache-type redis --redis-dial-timeout 30 --redis-read-timeout 30 --redis-max-retries 5
+
+  # Populate hashed keys laid out like an application's (collisions are checked first);
+  # run with the same --key-codec/--key-template to hit them
+  serverless-cache-benchmark populate --cache-type redis --key-codec hash --key-hash-bytes 6 --key-template 'user:{key}:profile'`,
 	Run: runPopulate,
 }
 
@@ -114,7 +130,7 @@ func (cw *ClientWorker) workerRoutine(ctx context.Context, wg *sync.WaitGroup, k
 			}
 		}
 
-		key := fmt.Sprintf("%s%d", keyPrefix, i)
+		key := formatKey(keyPrefix, i)
 
 		data, err := cw.Generator.GenerateData()
 		if err != nil {
@@ -173,60 +189,12 @@ func (cw *ClientWorker) workerRoutine(ctx context.Context, wg *sync.WaitGroup, k
 
 // createCacheClient creates a cache client based on the cache type
 func createCacheClient(cacheType string, cmd *cobra.Command) (CacheClient, error) {
-	switch cacheType {
-	case "redis":
-		uri, _ := cmd.Flags().GetString("redis-uri")
-		clusterMode, _ := cmd.Flags().GetBool("cluster-mode")
-
-		// Build Redis configuration from flags
-		dialTimeout, _ := cmd.Flags().GetInt("redis-dial-timeout")
-		readTimeout, _ := cmd.Flags().GetInt("redis-read-timeout")
-		writeTimeout, _ := cmd.Flags().GetInt("redis-write-timeout")
-		poolTimeout, _ := cmd.Flags().GetInt("redis-pool-timeout")
-		connMaxIdleTime, _ := cmd.Flags().GetInt("redis-conn-max-idle-time")
-		maxRetries, _ := cmd.Flags().GetInt("redis-max-retries")
-		minRetryBackoff, _ := cmd.Flags().GetInt("redis-min-retry-backoff")
-		maxRetryBackoff, _ := cmd.Flags().GetInt("redis-max-retry-backoff")
-
-		config := RedisConfig{
-			DialTimeout:     time.Duration(dialTimeout) * time.Second,
-			ReadTimeout:     time.Duration(readTimeout) * time.Second,
-			WriteTimeout:    time.Duration(writeTimeout) * time.Second,
-			PoolTimeout:     time.Duration(poolTimeout) * time.Second,
-			ConnMaxIdleTime: time.Duration(connMaxIdleTime) * time.Second,
-			MaxRetries:      maxRetries,
-			MinRetryBackoff: time.Duration(minRetryBackoff) * time.Millisecond,
-			MaxRetryBackoff: time.Duration(maxRetryBackoff) * time.Millisecond,
-			ClusterMode:     clusterMode,
-		}
-
-		client, err := NewRedisClientFromURI(uri, config)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create Redis client from URI '%s': %w", uri, err)
-		}
-		return client, nil
-
-	case "momento":
-		apiKey, _ := cmd.Flags().GetString("momento-api-key")
-		cacheName, _ := cmd.Flags().GetString("momento-cache-name")
-		defaultTTL, _ := cmd.Flags().GetInt("default-ttl")
-		clientConnCount, _ := cmd.Flags().GetUint32("momento-client-conn-count")
-
-		// Don't create cache per worker - it should be created once upfront
-		client, err := NewMomentoClient(context.Background(), apiKey, cacheName, false, defaultTTL, clientConnCount)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create Momento client: %w", err)
-		}
-		return client, nil
-
-	default:
-		return nil, fmt.Errorf("invalid cache type: %s. Must be 'redis' or 'momento'", cacheType)
-	}
+	return newBackendClient(context.Background(), cacheType, cmd)
 }
 
 // printStats prints performance statistics
 func printStats(stats *PerformanceStats, clientCount int) {
-	total, success, failed, qps, p50, p95, p99 := stats.GetStats()
+	total, success, failed, qps, _, _, _ := stats.GetStats()
 
 	fmt.Printf("\n=== Performance Statistics ===\n")
 	fmt.Printf("Clients: %d\n", clientCount)
@@ -235,9 +203,9 @@ func printStats(stats *PerformanceStats, clientCount int) {
 	fmt.Printf("Failed Operations: %d\n", failed)
 	fmt.Printf("Success Rate: %.2f%%\n", float64(success)/float64(total)*100)
 	fmt.Printf("QPS: %.2f\n", qps)
-	fmt.Printf("Latency P50: %d μs (%.2f ms)\n", p50, float64(p50)/1000)
-	fmt.Printf("Latency P95: %d μs (%.2f ms)\n", p95, float64(p95)/1000)
-	fmt.Printf("Latency P99: %d μs (%.2f ms)\n", p99, float64(p99)/1000)
+	for _, v := range latencyPercentiles(stats.Histogram) {
+		fmt.Printf("Latency %s: %d μs (%.2f ms)\n", percentileLabel(v.Percentile), v.Value, float64(v.Value)/1000)
+	}
 }
 
 func runPopulate(cmd *cobra.Command, args []string) {
@@ -338,7 +306,7 @@ func runPopulate(cmd *cobra.Command, args []string) {
 		}()
 	}
 
-	cacheType, _ := cmd.Flags().GetString("cache-type")
+	cacheType := getCacheType(cmd)
 	clientCount, _ := cmd.Flags().GetInt("clients")
 	rps, _ := cmd.Flags().GetInt("rps")
 	timeoutSeconds, _ := cmd.Flags().GetInt("timeout")
@@ -358,10 +326,31 @@ func runPopulate(cmd *cobra.Command, args []string) {
 	keyMax, _ := cmd.Flags().GetInt("key-maximum")
 
 	// Validate parameters
+	if _, err := lookupBackend(cacheType); err != nil {
+		log.Fatalf("%v", err)
+	}
+
 	if clientCount <= 0 {
 		log.Fatalf("Number of clients must be greater than 0")
 	}
 
+	if dbList, _ := cmd.Flags().GetString("db"); dbList != "" {
+		dbs, err := parseDatabases(dbList)
+		if err != nil {
+			log.Fatalf("Invalid --db: %v", err)
+		}
+		if len(dbs) > 1 {
+			log.Fatalf("Populate one database at a time (--db %s lists %d)", dbList, len(dbs))
+		}
+		if cacheType != "redis" {
+			log.Fatalf("Logical databases (--db) are only supported for Redis")
+		}
+	}
+
+	if err := validateProxy(cmd, cacheType, false); err != nil {
+		log.Fatalf("Invalid proxy configuration: %v", err)
+	}
+
 	totalKeys := keyMax - keyMin + 1
 	if totalKeys <= 0 {
 		log.Fatalf("Invalid key range: min=%d, max=%d", keyMin, keyMax)
@@ -373,6 +362,27 @@ func runPopulate(cmd *cobra.Command, args []string) {
 		log.Fatalf("Not enough keys (%d) for %d clients", totalKeys, clientCount)
 	}
 
+	format, err := keyFormatFromFlags(cmd)
+	if err != nil {
+		log.Fatalf("Invalid key format: %v", err)
+	}
+	keyFormat = format
+	if err := percentilesFromFlags(cmd); err != nil {
+		log.Fatalf("Invalid --percentiles: %v", err)
+	}
+	if err := validateKeyCollisions(cmd, keyPrefix, keyMin, keyMax); err != nil {
+		log.Fatalf("Key collision check failed: %v", err)
+	}
+
+	if err := runAWSPreflight(cmd, cacheType); err != nil {
+		log.Fatalf("AWS permissions preflight failed: %v", err)
+	}
+
+	// Resolved once upfront, so a bad secret fails before any client is created
+	if _, err := resolvePasswordFrom(cmd); err != nil {
+		log.Fatalf("%v", err)
+	}
+
 	// Initialize CSV logging
 	if csvOutput == "" {
 		// Generate default filename with timestamp
@@ -404,7 +414,10 @@ func runPopulate(cmd *cobra.Command, args []string) {
 
 	// For Momento, create cache once upfront to avoid multiple clients trying to create it
 	if cacheType == "momento" {
-		apiKey, _ := cmd.Flags().GetString("momento-api-key")
+		apiKey, err := momentoAPIKey(cmd)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
 		cacheName, _ := cmd.Flags().GetString("momento-cache-name")
 		createCache, _ := cmd.Flags().GetBool("momento-create-cache")
 		clientConnCount, _ := cmd.Flags().GetUint32("momento-client-conn-count")
@@ -535,7 +548,8 @@ func init() {
 	rootCmd.AddCommand(populateCmd)
 
 	// Cache Type Options
-	populateCmd.Flags().StringP("cache-type", "t", "redis", "Cache type: redis or momento")
+	populateCmd.Flags().StringP("cache-type", "t", "redis", "Cache engine: redis, momento, memcached, lambda, s3, http, file or sharded")
+	populateCmd.Flags().String("engine", "", "Cache engine (alias for --cache-type)")
 
 	// Client Options
 	defaultClients := runtime.NumCPU()
@@ -572,6 +586,8 @@ func init() {
 	populateCmd.Flags().String("momento-cache-name", "test-cache", "Momento cache name")
 	populateCmd.Flags().Bool("momento-create-cache", true, "Automatically create Momento cache if it doesn't exist")
 
+	// Options of the other engines (AWS, file, memcached, ...) are registered by each backend
+
 	// Object Options
 	populateCmd.Flags().IntP("data-size", "d", 32, "Object data size in bytes")
 	populateCmd.Flags().BoolP("random-data", "R", false, "Indicate that data should be randomized")
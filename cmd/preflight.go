@@ -0,0 +1,234 @@
+//go:build !noaws
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/spf13/cobra"
+)
+
+// preflightTimeout bounds the whole permissions check
+const preflightTimeout = 30 * time.Second
+
+// AWSPermission is an IAM action a run needs on a resource. Resource ARNs may use the
+// placeholders {partition}, {region} and {account}, filled in from the caller identity.
+type AWSPermission struct {
+	Action   string
+	Resource string
+	RoleARN  string // Role the call is made with (default: --aws-role-arn, or the default credentials)
+	Reason   string // What the permission is used for, shown when it is missing
+	Decision string // Simulator decision when not allowed: implicitDeny or explicitDeny
+}
+
+// awsPermissionSources list the permissions of AWS integrations that are not engines
+// (result sinks, metric publishers); engines declare theirs in Backend.Permissions
+var awsPermissionSources []func(cmd *cobra.Command) []AWSPermission
+
+// RegisterAWSPermissions adds an integration to the permissions preflight.
+// Integrations register themselves from init() in their own file.
+func RegisterAWSPermissions(source func(cmd *cobra.Command) []AWSPermission) {
+	awsPermissionSources = append(awsPermissionSources, source)
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{populateCmd, runCmd} {
+		cmd.Flags().Bool("aws-preflight", true, "Verify the IAM permissions of AWS integrations with the policy simulator before starting: explicit denies fail the run, actions the identity policies do not grant are warned about")
+	}
+}
+
+// requiredAWSPermissions collects the permissions the engine and integrations of a command need
+func requiredAWSPermissions(cmd *cobra.Command, cacheType string) []AWSPermission {
+	var permissions []AWSPermission
+	if b, ok := backends[cacheType]; ok && b.Permissions != nil {
+		permissions = append(permissions, b.Permissions(cmd)...)
+	}
+	for _, source := range awsPermissionSources {
+		permissions = append(permissions, source(cmd)...)
+	}
+
+	defaultRole := awsRoleARN(cmd, "")
+	for i := range permissions {
+		if permissions[i].RoleARN == "" {
+			permissions[i].RoleARN = defaultRole
+		}
+	}
+	return permissions
+}
+
+// runAWSPreflight checks the permissions of a command up front (unless --aws-preflight=false)
+// and fails with the list of explicitly denied actions. Actions only implicitly denied are
+// warned about: the simulator ignores resource policies (e.g. bucket policies), which may
+// grant them. When the check itself can't run (e.g. the caller may not use the policy
+// simulator) a warning is printed and the run goes on.
+func runAWSPreflight(cmd *cobra.Command, cacheType string) error {
+	if enabled, _ := cmd.Flags().GetBool("aws-preflight"); !enabled {
+		return nil
+	}
+	permissions := requiredAWSPermissions(cmd, cacheType)
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), preflightTimeout)
+	defer cancel()
+
+	region, _ := cmd.Flags().GetString("aws-region")
+	missing, err := checkAWSPermissions(ctx, region, permissions)
+	if err != nil {
+		fmt.Printf("Warning: could not verify AWS permissions (%v); continuing without preflight\n", err)
+		return nil
+	}
+	var denied, unconfirmed []AWSPermission
+	for _, p := range missing {
+		if p.Decision == string(types.PolicyEvaluationDecisionTypeExplicitDeny) {
+			denied = append(denied, p)
+		} else {
+			unconfirmed = append(unconfirmed, p)
+		}
+	}
+	if len(denied) > 0 {
+		return fmt.Errorf("%d IAM permission(s) explicitly denied:\n%s\n(use --aws-preflight=false to skip this check)",
+			len(denied), formatAWSPermissions(denied))
+	}
+	if len(unconfirmed) > 0 {
+		fmt.Printf("Warning: %d IAM permission(s) not granted by the identity policies; resource policies (e.g. bucket policies) may still allow them:\n%s\n",
+			len(unconfirmed), formatAWSPermissions(unconfirmed))
+		return nil
+	}
+
+	fmt.Printf("AWS preflight: %d permission(s) verified\n", len(permissions))
+	return nil
+}
+
+// formatAWSPermissions lists permissions one per line, with their use and identity
+func formatAWSPermissions(permissions []AWSPermission) string {
+	lines := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		line := fmt.Sprintf("  - %s on %s (%s)", p.Action, p.Resource, p.Reason)
+		if p.RoleARN != "" {
+			line += " as " + p.RoleARN
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// checkAWSPermissions returns the permissions that are not allowed, checking each one with
+// the identity (assumed role or default credentials) its calls are made with
+func checkAWSPermissions(ctx context.Context, region string, permissions []AWSPermission) ([]AWSPermission, error) {
+	var roles []string
+	byRole := make(map[string][]AWSPermission)
+	for _, p := range permissions {
+		if _, ok := byRole[p.RoleARN]; !ok {
+			roles = append(roles, p.RoleARN)
+		}
+		byRole[p.RoleARN] = append(byRole[p.RoleARN], p)
+	}
+
+	var missing []AWSPermission
+	for _, roleARN := range roles {
+		roleMissing, err := checkAWSPermissionsAs(ctx, region, roleARN, byRole[roleARN])
+		if err != nil {
+			return nil, err
+		}
+		missing = append(missing, roleMissing...)
+	}
+	return missing, nil
+}
+
+// checkAWSPermissionsAs simulates the policies of one identity against its permissions
+func checkAWSPermissionsAs(ctx context.Context, region, roleARN string, permissions []AWSPermission) ([]AWSPermission, error) {
+	cfg, err := loadAWSConfig(ctx, region, roleARN)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller identity: %w", err)
+	}
+	callerARN, err := arn.Parse(aws.ToString(identity.Arn))
+	if err != nil {
+		return nil, fmt.Errorf("invalid caller identity ARN: %w", err)
+	}
+
+	client := iam.NewFromConfig(cfg)
+	principal := principalARN(ctx, client, callerARN)
+
+	placeholders := strings.NewReplacer(
+		"{partition}", callerARN.Partition,
+		"{region}", cfg.Region,
+		"{account}", callerARN.AccountID,
+	)
+
+	// One simulation per resource, since the simulator evaluates every action on every resource
+	var resources []string
+	actions := make(map[string][]string)
+	for i := range permissions {
+		permissions[i].Resource = placeholders.Replace(permissions[i].Resource)
+		resource := permissions[i].Resource
+		if _, ok := actions[resource]; !ok {
+			resources = append(resources, resource)
+		}
+		actions[resource] = append(actions[resource], permissions[i].Action)
+	}
+
+	decisions := make(map[string]types.PolicyEvaluationDecisionType)
+	for _, resource := range resources {
+		paginator := iam.NewSimulatePrincipalPolicyPaginator(client, &iam.SimulatePrincipalPolicyInput{
+			PolicySourceArn: aws.String(principal),
+			ActionNames:     actions[resource],
+			ResourceArns:    []string{resource},
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to simulate policies of %s: %w", principal, err)
+			}
+			for _, result := range page.EvaluationResults {
+				decisions[aws.ToString(result.EvalActionName)+" "+resource] = result.EvalDecision
+			}
+		}
+	}
+
+	var missing []AWSPermission
+	for _, p := range permissions {
+		decision, ok := decisions[p.Action+" "+p.Resource]
+		if decision == types.PolicyEvaluationDecisionTypeAllowed {
+			continue
+		}
+		if !ok {
+			decision = types.PolicyEvaluationDecisionTypeImplicitDeny
+		}
+		p.Decision = string(decision)
+		missing = append(missing, p)
+	}
+	return missing, nil
+}
+
+// principalARN maps the caller identity to the IAM entity whose policies apply: an assumed
+// role session (arn:aws:sts::123:assumed-role/Name/session) is simulated as its role
+func principalARN(ctx context.Context, client *iam.Client, caller arn.ARN) string {
+	roleName, ok := strings.CutPrefix(caller.Resource, "assumed-role/")
+	if !ok || caller.Service != "sts" {
+		return caller.String()
+	}
+	roleName, _, _ = strings.Cut(roleName, "/")
+
+	// The session ARN drops the role path; look the role up to get its full ARN
+	// (needs iam:GetRole, otherwise roles without a path are assumed)
+	role, err := client.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(roleName)})
+	if err == nil && role.Role != nil {
+		return aws.ToString(role.Role.Arn)
+	}
+	return fmt.Sprintf("arn:%s:iam::%s:role/%s", caller.Partition, caller.AccountID, roleName)
+}
@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// proxyPreset adapts the Redis client to what a proxy tier supports
+type proxyPreset struct {
+	Description    string
+	RESP2          bool // Proxy doesn't speak RESP3 (HELLO)
+	NoIdentity     bool // Proxy rejects CLIENT SETINFO on connect
+	NoTransactions bool // Proxy doesn't forward WATCH/MULTI/EXEC
+	DBZeroOnly     bool // Proxy doesn't forward SELECT
+}
+
+var proxyPresets = map[string]proxyPreset{
+	"twemproxy": {
+		Description:    "Twemproxy (nutcracker)",
+		RESP2:          true,
+		NoIdentity:     true,
+		NoTransactions: true,
+		DBZeroOnly:     true,
+	},
+	"envoy": {
+		Description:    "Envoy Redis proxy filter",
+		RESP2:          true,
+		NoIdentity:     true,
+		NoTransactions: true,
+		DBZeroOnly:     true,
+	},
+	"redisproxy": {
+		Description: "RESP3-capable Redis proxy (e.g. RedisProxy, Redis Enterprise proxy)",
+		NoIdentity:  true,
+	},
+}
+
+// proxyProbeKey is read by the per-hop probes; it is written once before probing
+const proxyProbeKey = "proxy-probe"
+
+func init() {
+	for _, cmd := range []*cobra.Command{populateCmd, runCmd} {
+		cmd.Flags().String("proxy", "", "Redis URI points at a proxy tier: "+strings.Join(proxyPresetNames(), ", ")+" (adapts the client to what the proxy supports)")
+	}
+	runCmd.Flags().String("proxy-direct-uri", "", "Redis URI of the backend behind the proxy; paired probes through the proxy and direct attribute latency to the proxy hop")
+	runCmd.Flags().Duration("proxy-probe-interval", 10*time.Millisecond, "Interval between paired proxy/direct probes")
+}
+
+// proxyPresetNames returns the preset names in alphabetical order
+func proxyPresetNames() []string {
+	names := make([]string, 0, len(proxyPresets))
+	for name := range proxyPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupProxyPreset returns the preset selected with --proxy (ok is false when not set)
+func lookupProxyPreset(cmd *cobra.Command) (preset proxyPreset, ok bool, err error) {
+	name, _ := cmd.Flags().GetString("proxy")
+	if name == "" {
+		return proxyPreset{}, false, nil
+	}
+	preset, ok = proxyPresets[name]
+	if !ok {
+		return proxyPreset{}, false, fmt.Errorf("invalid proxy '%s'. Must be one of: %s", name, strings.Join(proxyPresetNames(), ", "))
+	}
+	return preset, true, nil
+}
+
+// validateProxy checks that the workload only uses what the selected proxy supports
+func validateProxy(cmd *cobra.Command, cacheType string, rmwOptimistic bool) error {
+	preset, ok, err := lookupProxyPreset(cmd)
+	if err != nil {
+		return err
+	}
+	directURI, _ := cmd.Flags().GetString("proxy-direct-uri")
+	if !ok && directURI == "" {
+		return nil
+	}
+
+	if cacheType != "redis" {
+		return fmt.Errorf("proxy mode is only supported for Redis")
+	}
+	if clusterMode, _ := cmd.Flags().GetBool("cluster-mode"); clusterMode {
+		return fmt.Errorf("proxy mode connects to the proxy's single endpoint; --cluster-mode is not supported")
+	}
+	if preset.NoTransactions && rmwOptimistic {
+		return fmt.Errorf("%s does not support WATCH/MULTI/EXEC (--rmw-optimistic)", preset.Description)
+	}
+	if dbList, _ := cmd.Flags().GetString("db"); preset.DBZeroOnly && dbList != "" {
+		if dbs, err := parseDatabases(dbList); err == nil && (len(dbs) > 1 || dbs[0] != 0) {
+			return fmt.Errorf("%s only supports database 0", preset.Description)
+		}
+	}
+	return nil
+}
+
+// ProxyStats attributes latency to the proxy hop: paired probes read the same key through
+// the proxy and directly from the backend, alternating which goes first, so the difference
+// between the two paths is the cost the proxy tier adds under the workload's load
+type ProxyStats struct {
+	Name      string // Preset name, empty for an unnamed proxy
+	DirectURI string
+	Interval  time.Duration
+
+	Probes      int64
+	ProbeErrors int64
+	ProxyPath   *PerformanceStats
+	DirectPath  *PerformanceStats
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewProxyStats(name, directURI string, interval time.Duration) *ProxyStats {
+	return &ProxyStats{
+		Name:       name,
+		DirectURI:  directURI,
+		Interval:   interval,
+		ProxyPath:  NewPerformanceStats(),
+		DirectPath: NewPerformanceStats(),
+	}
+}
+
+// Start connects both probe clients and probes until Stop
+func (ps *ProxyStats) Start(cmd *cobra.Command, keyPrefix string) error {
+	uri, _ := cmd.Flags().GetString("redis-uri")
+	config, err := redisConfigFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	proxied, err := NewRedisClientFromURI(uri, config)
+	if err != nil {
+		return fmt.Errorf("failed to create proxy probe client: %w", err)
+	}
+	direct, err := NewRedisClientFromURI(ps.DirectURI, config)
+	if err != nil {
+		proxied.Close()
+		return fmt.Errorf("failed to create direct probe client: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ps.cancel = cancel
+
+	key := keyPrefix + proxyProbeKey
+	if err := proxied.Set(ctx, key, []byte("probe"), 0); err != nil {
+		cancel()
+		proxied.Close()
+		direct.Close()
+		return fmt.Errorf("failed to write proxy probe key: %w", err)
+	}
+
+	ps.wg.Add(1)
+	go func() {
+		defer ps.wg.Done()
+		defer proxied.Close()
+		defer direct.Close()
+
+		ticker := time.NewTicker(ps.Interval)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			paths := []struct {
+				client CacheClient
+				stats  *PerformanceStats
+			}{{proxied, ps.ProxyPath}, {direct, ps.DirectPath}}
+			if i%2 == 1 {
+				paths[0], paths[1] = paths[1], paths[0]
+			}
+
+			latencies := make([]int64, len(paths))
+			failed := false
+			for j, path := range paths {
+				start := time.Now()
+				if _, err := path.client.Get(ctx, key); err != nil {
+					failed = true
+					break
+				}
+				latencies[j] = time.Since(start).Microseconds()
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			// Only complete pairs are recorded, so both paths have the same samples
+			if failed {
+				atomic.AddInt64(&ps.ProbeErrors, 1)
+				continue
+			}
+			for j, path := range paths {
+				path.stats.RecordLatency(latencies[j])
+			}
+			atomic.AddInt64(&ps.Probes, 1)
+		}
+	}()
+	return nil
+}
+
+// Stop ends probing; it is safe to call more than once
+func (ps *ProxyStats) Stop() {
+	if ps.cancel != nil {
+		ps.cancel()
+	}
+	ps.wg.Wait()
+}
+
+func (ps *ProxyStats) Close() {
+	ps.Stop()
+	ps.ProxyPath.Close()
+	ps.DirectPath.Close()
+}
+
+// printProxyResults prints the per-hop latency attribution of the proxy probes
+func printProxyResults(stats *WorkloadStats) {
+	ps := stats.Proxy
+	name := ps.Name
+	if name == "" {
+		name = "proxy"
+	}
+	probes := atomic.LoadInt64(&ps.Probes)
+	fmt.Printf("Proxy Hop Attribution (%s, %d paired probes, %d failed):\n", name, probes, atomic.LoadInt64(&ps.ProbeErrors))
+	if probes == 0 {
+		fmt.Println("Warning: no complete proxy/direct probe pairs; check --proxy-direct-uri")
+		fmt.Println()
+		return
+	}
+
+	_, _, _, _, proxyP50, proxyP95, proxyP99 := ps.ProxyPath.GetStats()
+	_, _, _, _, directP50, directP95, directP99 := ps.DirectPath.GetStats()
+	fmt.Printf("Via Proxy Latency - P50: %d μs, P95: %d μs, P99: %d μs\n", proxyP50, proxyP95, proxyP99)
+	fmt.Printf("Direct Latency - P50: %d μs, P95: %d μs, P99: %d μs\n", directP50, directP95, directP99)
+	fmt.Printf("Proxy Hop Overhead - P50: %+d μs (%s), P95: %+d μs, P99: %+d μs\n",
+		proxyP50-directP50, relativeChange(float64(directP50), float64(proxyP50)), proxyP95-directP95, proxyP99-directP99)
+	fmt.Println()
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	runCmd.Flags().Int("queue-depth", 0, "Give every worker a bounded request queue of this depth, filled by a generator and drained by the worker, and report the time requests wait in it apart from the operation latency (0 = closed loop, no queue); needs a target rate, as an unpaced generator keeps the queue full")
+}
+
+// RequestQueue measures the client-side queueing of requests. Each worker's requests are
+// generated into a bounded queue and executed from it, so the time a request waits in the
+// queue tells client-side saturation (requests queue behind the worker) from a slow server
+// (operations take long), which a closed loop folds into one number.
+type RequestQueue struct {
+	Depth int
+
+	Enqueued      int64
+	Full          int64 // Enqueues that found the queue full and waited for room
+	BlockedMicros int64 // Time spent waiting for room
+	Waits         *PerformanceStats
+}
+
+func NewRequestQueue(depth int) *RequestQueue {
+	rq := &RequestQueue{Depth: depth, Waits: NewPerformanceStats()}
+	rq.Waits.Name = "QUEUE_WAIT"
+	return rq
+}
+
+func (rq *RequestQueue) Close() {
+	rq.Waits.Close()
+}
+
+// enqueue stamps a request and adds it to a queue, waiting for room when it is full; it
+// returns false when the context ended first
+func (rq *RequestQueue) enqueue(ctx context.Context, queue chan<- requestInfo, request requestInfo) bool {
+	request.queued = time.Now()
+	select {
+	case queue <- request:
+		atomic.AddInt64(&rq.Enqueued, 1)
+		return true
+	default:
+	}
+
+	atomic.AddInt64(&rq.Full, 1)
+	select {
+	case queue <- request:
+		atomic.AddInt64(&rq.Enqueued, 1)
+		atomic.AddInt64(&rq.BlockedMicros, time.Since(request.queued).Microseconds())
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// dequeued records how long a request waited in the queue
+func (rq *RequestQueue) dequeued(request requestInfo) {
+	rq.Waits.RecordLatency(time.Since(request.queued).Microseconds())
+}
+
+// Run drives a worker through its queue: next generates requests into the queue until it
+// returns false or the context ends, while execute runs them in order
+func (rq *RequestQueue) Run(ctx context.Context, next func() (requestInfo, bool), execute func(requestInfo)) {
+	queue := make(chan requestInfo, rq.Depth)
+	go func() {
+		defer close(queue)
+		defer recoverPartialReport()
+		for ctx.Err() == nil {
+			request, ok := next()
+			if !ok || !rq.enqueue(ctx, queue, request) {
+				return
+			}
+		}
+	}()
+
+	for request := range queue {
+		if ctx.Err() != nil {
+			continue // Drain until the generator stopped
+		}
+		rq.dequeued(request)
+		execute(request)
+	}
+}
+
+// QueueSummary is the machine-readable client-side queueing of a run
+type QueueSummary struct {
+	Depth          int            `json:"depth"`
+	Enqueued       int64          `json:"enqueued"`
+	FullPercent    float64        `json:"full_percent"` // Enqueues that found the queue full
+	BlockedSeconds float64        `json:"blocked_seconds"`
+	Wait           LatencySummary `json:"wait"`
+}
+
+// Summary returns the queueing of the run
+func (rq *RequestQueue) Summary() *QueueSummary {
+	enqueued := atomic.LoadInt64(&rq.Enqueued)
+	summary := &QueueSummary{
+		Depth:          rq.Depth,
+		Enqueued:       enqueued,
+		BlockedSeconds: float64(atomic.LoadInt64(&rq.BlockedMicros)) / 1e6,
+		Wait:           summarizeTotals(rq.Waits, rq.Waits.Histogram.TotalCount(), 0, 0),
+	}
+	if enqueued > 0 {
+		summary.FullPercent = float64(atomic.LoadInt64(&rq.Full)) / float64(enqueued) * 100
+	}
+	return summary
+}
+
+// queueFromFlags creates the request queue selected with --queue-depth (nil when not set).
+// The generator must be paced by a target rate: unpaced, it keeps the queue full, and the
+// queue wait would only measure the depth times the operation latency.
+func queueFromFlags(cmd *cobra.Command, hasTarget bool) (*RequestQueue, error) {
+	depth, _ := cmd.Flags().GetInt("queue-depth")
+	if depth < 0 {
+		return nil, fmt.Errorf("queue depth must not be negative, got: %d", depth)
+	}
+	if depth == 0 {
+		return nil, nil
+	}
+	if !hasTarget {
+		return nil, fmt.Errorf("--queue-depth needs a target rate: --rps, --rate, --ramp, --step, --sine, --services with rate-limited services or a --traffic-pattern with a QPS in every block")
+	}
+	return NewRequestQueue(depth), nil
+}
+
+// printQueueResults prints the queue wait next to the operation latency, and which one
+// dominates
+func printQueueResults(stats *WorkloadStats) {
+	summary := stats.Queue.Summary()
+	fmt.Printf("Client Queue (depth %d per worker): %d requests, %.1f%% found the queue full\n",
+		summary.Depth, summary.Enqueued, summary.FullPercent)
+	if summary.Wait.Ops == 0 {
+		fmt.Println()
+		return
+	}
+	fmt.Printf("Queue Wait - %s\n", formatPercentiles(summary.Wait.Percentiles))
+
+	// The operation latency of the most frequent operation type is the server-side reference
+	service := stats.GetStats
+	if atomic.LoadInt64(&stats.SetOps) > atomic.LoadInt64(&stats.GetOps) {
+		service = stats.SetStats
+	}
+	if service.Histogram.TotalCount() > 0 {
+		serviceP50, waitP50 := service.Histogram.ValueAtQuantile(50), summary.Wait.P50
+		if waitP50 > serviceP50 {
+			fmt.Printf("Queue wait P50 %d μs exceeds the operation P50 %d μs: the client is saturated, latency is mostly queueing\n", waitP50, serviceP50)
+		} else {
+			fmt.Printf("Queue wait P50 %d μs is within the operation P50 %d μs: latency comes from the operations\n", waitP50, serviceP50)
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	runCmd.Flags().Duration("rebalance-interval", 0, "Periodically reconnect a fraction of the clients, so connections pinned to old backend nodes don't hide post-scaling changes (0 = never)")
+	runCmd.Flags().Float64("rebalance-fraction", 0.25, "Fraction of the clients reconnected at each rebalance (0-1]")
+}
+
+// rebalanceEpoch is the latency between two rebalances
+type rebalanceEpoch struct {
+	start     time.Time
+	marked    int // Clients asked to reconnect when the epoch started (0 for the first)
+	histogram *hdrhistogram.Histogram
+}
+
+// Rebalancer periodically asks a fraction of the workers to replace their client with a new
+// connection. Workers reconnect between operations, so no operation is interrupted, rotating
+// through all workers over successive rebalances. Latency is bucketed by epoch (the time
+// between rebalances) to compare it before and after each rebalance.
+type Rebalancer struct {
+	Interval    time.Duration
+	Fraction    float64
+	Reconnected int64 // Clients replaced
+	Failed      int64 // Reconnects that failed (the worker kept its old client)
+
+	mutex   sync.Mutex
+	workers map[int]*rebalanceSlot
+	cursor  int // Next worker ID to rotate, round-robin
+	epochs  []*rebalanceEpoch
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func NewRebalancer(interval time.Duration, fraction float64) (*Rebalancer, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("rebalance interval must be positive, got: %v", interval)
+	}
+	if fraction <= 0 || fraction > 1 {
+		return nil, fmt.Errorf("rebalance fraction must be in (0, 1], got: %.2f", fraction)
+	}
+	return &Rebalancer{
+		Interval: interval,
+		Fraction: fraction,
+		workers:  make(map[int]*rebalanceSlot),
+		epochs:   []*rebalanceEpoch{{start: time.Now(), histogram: hdrhistogram.New(1, 60*1000*1000, 3)}},
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// rebalanceSlot is a worker's place in the rotation
+type rebalanceSlot struct {
+	pending int32 // Set when the worker should reconnect, accessed atomically
+}
+
+// Due reports (once) whether the worker was asked to reconnect
+func (s *rebalanceSlot) Due() bool {
+	return atomic.CompareAndSwapInt32(&s.pending, 1, 0)
+}
+
+// Register adds a worker to the rotation
+func (rb *Rebalancer) Register(workerID int) *rebalanceSlot {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+	slot := &rebalanceSlot{}
+	rb.workers[workerID] = slot
+	return slot
+}
+
+// Unregister removes a stopped worker from the rotation
+func (rb *Rebalancer) Unregister(workerID int) {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+	delete(rb.workers, workerID)
+}
+
+// Reconnect replaces a worker's client using connect; on failure the old client is kept
+func (rb *Rebalancer) Reconnect(workerID int, client CacheClient, connect func() (CacheClient, error)) CacheClient {
+	fresh, err := connect()
+	if err != nil {
+		atomic.AddInt64(&rb.Failed, 1)
+		log.Printf("Worker %d: Rebalance reconnect failed, keeping the current connection: %v", workerID, err)
+		return client
+	}
+	client.Close()
+	atomic.AddInt64(&rb.Reconnected, 1)
+	return fresh
+}
+
+// Start runs the rebalances in the background until Stop
+func (rb *Rebalancer) Start() {
+	go func() {
+		defer close(rb.done)
+		ticker := time.NewTicker(rb.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				rb.rebalance()
+			case <-rb.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the rebalances
+func (rb *Rebalancer) Stop() {
+	select {
+	case <-rb.stop:
+	default:
+		close(rb.stop)
+		<-rb.done
+	}
+}
+
+// rebalance marks the next fraction of the workers for reconnection and starts a new epoch
+func (rb *Rebalancer) rebalance() {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+
+	ids := make([]int, 0, len(rb.workers))
+	for id := range rb.workers {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return
+	}
+	sort.Ints(ids)
+
+	// Continue the rotation after the last worker marked, even if workers came and went
+	first := sort.SearchInts(ids, rb.cursor)
+	count := int(math.Ceil(rb.Fraction * float64(len(ids))))
+	for i := 0; i < count; i++ {
+		id := ids[(first+i)%len(ids)]
+		atomic.StoreInt32(&rb.workers[id].pending, 1)
+		rb.cursor = id + 1
+	}
+
+	rb.epochs = append(rb.epochs, &rebalanceEpoch{start: time.Now(), marked: count, histogram: hdrhistogram.New(1, 60*1000*1000, 3)})
+}
+
+// Record adds a successful operation's latency to the current epoch
+func (rb *Rebalancer) Record(result workloadResult) {
+	if result.isError {
+		return
+	}
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+	rb.epochs[len(rb.epochs)-1].histogram.RecordValue(result.latencyMicros)
+}
+
+// validateRebalance rejects --rebalance-interval where clients can't be replaced
+func validateRebalance(cmd *cobra.Command, cacheType string) error {
+	interval, _ := cmd.Flags().GetDuration("rebalance-interval")
+	if interval == 0 {
+		return nil
+	}
+	if cacheType == "momento" {
+		return fmt.Errorf("rebalancing is not supported with momento: producer-consumer workers share their client")
+	}
+	if noPool, _ := cmd.Flags().GetBool("no-pool"); noPool {
+		return fmt.Errorf("rebalancing is not needed with --no-pool: every operation uses a new connection")
+	}
+	return nil
+}
+
+// rebalancerFromFlags creates the rebalancer selected with --rebalance-interval (nil when not set)
+func rebalancerFromFlags(cmd *cobra.Command) (*Rebalancer, error) {
+	interval, _ := cmd.Flags().GetDuration("rebalance-interval")
+	if interval == 0 {
+		return nil, nil
+	}
+	fraction, _ := cmd.Flags().GetFloat64("rebalance-fraction")
+	return NewRebalancer(interval, fraction)
+}
+
+// printRebalanceResults prints the latency before and after each rebalance
+func printRebalanceResults(stats *WorkloadStats) {
+	rb := stats.Rebalance
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+
+	fmt.Printf("Rebalancing (every %v, %.0f%% of clients): %d reconnected, %d failed\n",
+		rb.Interval, rb.Fraction*100, atomic.LoadInt64(&rb.Reconnected), atomic.LoadInt64(&rb.Failed))
+	if len(rb.epochs) < 2 {
+		fmt.Println("No rebalance happened during the run")
+		fmt.Println()
+		return
+	}
+
+	fmt.Printf("%-10s %-10s %-8s %-12s %-12s %-12s %-12s\n", "Rebalance", "Time", "Clients", "P50 Before", "P99 Before", "P50 After", "P99 After")
+	for i := 1; i < len(rb.epochs); i++ {
+		before, after := rb.epochs[i-1], rb.epochs[i]
+		fmt.Printf("%-10d %-10s %-8d %-12d %-12d %-12d %-12d",
+			i, after.start.Format("15:04:05"), after.marked,
+			before.histogram.ValueAtQuantile(50), before.histogram.ValueAtQuantile(99),
+			after.histogram.ValueAtQuantile(50), after.histogram.ValueAtQuantile(99))
+		if p99 := before.histogram.ValueAtQuantile(99); p99 > 0 && after.histogram.TotalCount() > 0 {
+			fmt.Printf(" (P99 %+.0f%%)", (float64(after.histogram.ValueAtQuantile(99))/float64(p99)-1)*100)
+		}
+		fmt.Println()
+	}
+	fmt.Println("(latencies in μs between consecutive rebalances)")
+	fmt.Println()
+}
@@ -2,16 +2,89 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/spf13/cobra"
 )
 
+// Redis connection flags are defined by populate and run directly since their
+// timeout defaults differ between the two commands
+func init() {
+	RegisterBackend(&Backend{Name: "redis", New: newRedisBackendClient})
+}
+
+// newRedisBackendClient creates a pooled Redis (or Redis Cluster) client from the flags
+func newRedisBackendClient(ctx context.Context, cmd *cobra.Command) (CacheClient, error) {
+	uri, _ := cmd.Flags().GetString("redis-uri")
+	config, err := redisConfigFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	config.ResponseTiming = responseTiming
+
+	client, err := NewRedisClientFromURI(uri, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Redis client from URI '%s': %w", uri, err)
+	}
+	return client, nil
+}
+
+// redisConfigFromFlags builds the Redis connection configuration of a command
+func redisConfigFromFlags(cmd *cobra.Command) (RedisConfig, error) {
+	clusterMode, _ := cmd.Flags().GetBool("cluster-mode")
+
+	// Build Redis configuration from flags
+	dialTimeout, _ := cmd.Flags().GetInt("redis-dial-timeout")
+	readTimeout, _ := cmd.Flags().GetInt("redis-read-timeout")
+	writeTimeout, _ := cmd.Flags().GetInt("redis-write-timeout")
+	poolTimeout, _ := cmd.Flags().GetInt("redis-pool-timeout")
+	connMaxIdleTime, _ := cmd.Flags().GetInt("redis-conn-max-idle-time")
+	maxRetries, _ := cmd.Flags().GetInt("redis-max-retries")
+	minRetryBackoff, _ := cmd.Flags().GetInt("redis-min-retry-backoff")
+	maxRetryBackoff, _ := cmd.Flags().GetInt("redis-max-retry-backoff")
+
+	password, err := resolvePasswordFrom(cmd)
+	if err != nil {
+		return RedisConfig{}, err
+	}
+	db, overrideDB := singleDatabase(cmd)
+	preset, _, err := lookupProxyPreset(cmd)
+	if err != nil {
+		return RedisConfig{}, err
+	}
+	protocol := 0 // go-redis default (RESP3, falling back to RESP2)
+	if preset.RESP2 {
+		protocol = 2
+	}
+
+	return RedisConfig{
+		DialTimeout:     time.Duration(dialTimeout) * time.Second,
+		ReadTimeout:     time.Duration(readTimeout) * time.Second,
+		WriteTimeout:    time.Duration(writeTimeout) * time.Second,
+		PoolTimeout:     time.Duration(poolTimeout) * time.Second,
+		ConnMaxIdleTime: time.Duration(connMaxIdleTime) * time.Second,
+		MaxRetries:      maxRetries,
+		MinRetryBackoff: time.Duration(minRetryBackoff) * time.Millisecond,
+		MaxRetryBackoff: time.Duration(maxRetryBackoff) * time.Millisecond,
+		ClusterMode:     clusterMode,
+		Password:        password,
+		DB:              db,
+		OverrideDB:      overrideDB,
+		Protocol:        protocol,
+		DisableIdentity: preset.NoIdentity,
+		Reauthenticate:  credentialRotationMode(cmd) == "reauth",
+	}, nil
+}
+
 // RedisClient implements CacheClient for Redis
 type RedisClient struct {
 	client        *redis.Client
 	clusterClient *redis.ClusterClient
 	isCluster     bool
+	credentials   *rotatingCredentials // Set with RedisConfig.Reauthenticate
 }
 
 // RedisConfig holds Redis connection configuration
@@ -25,6 +98,15 @@ type RedisConfig struct {
 	MinRetryBackoff time.Duration
 	MaxRetryBackoff time.Duration
 	ClusterMode     bool
+	Username        string // Overrides the URI username when set (ACL users)
+	Password        string // Overrides the URI password when set (--password-from)
+	DB              int    // Database selected instead of the URI's when OverrideDB is set (--db)
+	OverrideDB      bool
+	Protocol        int  // RESP version, 0 for the client default
+	DisableIdentity bool // Skip CLIENT SETINFO on connect (unsupported by some proxies)
+	Reauthenticate  bool // Accept credential rotations on open connections (--auth-rotate-mode reauth)
+
+	ResponseTiming *ResponseTiming // Measures the responses read on the client's connections (nil = off)
 }
 
 func NewRedisClientFromURI(uri string, config RedisConfig) (*RedisClient, error) {
@@ -45,9 +127,31 @@ func NewRedisClientFromURI(uri string, config RedisConfig) (*RedisClient, error)
 	opts.MaxRetries = config.MaxRetries
 	opts.MinRetryBackoff = config.MinRetryBackoff
 	opts.MaxRetryBackoff = config.MaxRetryBackoff
+	if config.Username != "" {
+		opts.Username = config.Username
+	}
+	if config.Password != "" {
+		opts.Password = config.Password
+	}
+	if config.OverrideDB {
+		opts.DB = config.DB
+	}
+	if config.Protocol != 0 {
+		opts.Protocol = config.Protocol
+	}
+	opts.DisableIdentity = config.DisableIdentity
+	if config.ResponseTiming != nil {
+		opts.Dialer = config.ResponseTiming.Dialer(redis.NewDialer(opts))
+	}
+
+	var credentials *rotatingCredentials
+	if config.Reauthenticate {
+		credentials = newRotatingCredentials(opts.Username, opts.Password)
+		opts.StreamingCredentialsProvider = credentials
+	}
 
 	rdb := redis.NewClient(opts)
-	return &RedisClient{client: rdb, isCluster: false}, nil
+	return &RedisClient{client: rdb, isCluster: false, credentials: credentials}, nil
 }
 
 func NewRedisClusterClientFromURI(uri string, config RedisConfig) (*RedisClient, error) {
@@ -59,6 +163,7 @@ func NewRedisClusterClientFromURI(uri string, config RedisConfig) (*RedisClient,
 	// Create cluster options from single node options
 	clusterOpts := &redis.ClusterOptions{
 		Addrs:           []string{opts.Addr}, // Start with single address, cluster discovery will find others
+		Username:        opts.Username,
 		Password:        opts.Password,
 		DialTimeout:     config.DialTimeout,
 		ReadTimeout:     config.ReadTimeout,
@@ -70,13 +175,36 @@ func NewRedisClusterClientFromURI(uri string, config RedisConfig) (*RedisClient,
 		MaxRetryBackoff: config.MaxRetryBackoff,
 	}
 
+	if config.Username != "" {
+		clusterOpts.Username = config.Username
+	}
+	if config.Password != "" {
+		clusterOpts.Password = config.Password
+	}
+	if config.Protocol != 0 {
+		clusterOpts.Protocol = config.Protocol
+	}
+	clusterOpts.DisableIdentity = config.DisableIdentity
+
 	// Apply TLS settings if the URI uses rediss://
 	if opts.TLSConfig != nil {
 		clusterOpts.TLSConfig = opts.TLSConfig
 	}
+	if config.ResponseTiming != nil {
+		clusterOpts.Dialer = config.ResponseTiming.Dialer(redis.NewDialer(&redis.Options{
+			DialTimeout: config.DialTimeout,
+			TLSConfig:   clusterOpts.TLSConfig,
+		}))
+	}
+
+	var credentials *rotatingCredentials
+	if config.Reauthenticate {
+		credentials = newRotatingCredentials(clusterOpts.Username, clusterOpts.Password)
+		clusterOpts.StreamingCredentialsProvider = credentials
+	}
 
 	rdb := redis.NewClusterClient(clusterOpts)
-	return &RedisClient{clusterClient: rdb, isCluster: true}, nil
+	return &RedisClient{clusterClient: rdb, isCluster: true, credentials: credentials}, nil
 }
 
 func NewRedisClient(addr, password string, db int) *RedisClient {
@@ -105,12 +233,146 @@ func (r *RedisClient) Get(ctx context.Context, key string) ([]byte, error) {
 		result, err = r.client.Get(ctx, key).Result()
 	}
 
+	if err == redis.Nil {
+		return nil, ErrCacheMiss
+	}
 	if err != nil {
 		return nil, err
 	}
 	return []byte(result), nil
 }
 
+func (r *RedisClient) Delete(ctx context.Context, key string) error {
+	if r.isCluster {
+		return r.clusterClient.Del(ctx, key).Err()
+	}
+	return r.client.Del(ctx, key).Err()
+}
+
+// Incr atomically increments a counter key
+func (r *RedisClient) Incr(ctx context.Context, key string) (int64, error) {
+	if r.isCluster {
+		return r.clusterClient.Incr(ctx, key).Result()
+	}
+	return r.client.Incr(ctx, key).Result()
+}
+
+// Do implements CommandRunner; a nil reply is reported as ErrCacheMiss
+func (r *RedisClient) Do(ctx context.Context, args ...any) (any, error) {
+	var reply any
+	var err error
+	if r.isCluster {
+		reply, err = r.clusterClient.Do(ctx, args...).Result()
+	} else {
+		reply, err = r.client.Do(ctx, args...).Result()
+	}
+	if err == redis.Nil {
+		return nil, ErrCacheMiss
+	}
+	return reply, err
+}
+
+// LoadFunctionLibrary implements FunctionLoader with FUNCTION LOAD REPLACE, on every
+// primary in cluster mode since functions aren't replicated across shards
+func (r *RedisClient) LoadFunctionLibrary(ctx context.Context, code string) error {
+	if r.isCluster {
+		return r.clusterClient.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return node.FunctionLoadReplace(ctx, code).Err()
+		})
+	}
+	return r.client.FunctionLoadReplace(ctx, code).Err()
+}
+
+// DeleteFunctionLibrary implements FunctionLoader with FUNCTION DELETE
+func (r *RedisClient) DeleteFunctionLibrary(ctx context.Context, name string) error {
+	if r.isCluster {
+		return r.clusterClient.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return node.FunctionDelete(ctx, name).Err()
+		})
+	}
+	return r.client.FunctionDelete(ctx, name).Err()
+}
+
+// pipeline starts a pipeline on the client
+func (r *RedisClient) pipeline() redis.Pipeliner {
+	if r.isCluster {
+		return r.clusterClient.Pipeline()
+	}
+	return r.client.Pipeline()
+}
+
+// PipelineGet implements Pipeliner, reading the keys in one round trip; misses are not errors
+func (r *RedisClient) PipelineGet(ctx context.Context, keys []string) (int64, error) {
+	pipe := r.pipeline()
+	cmds := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Get(ctx, key)
+	}
+	_, err := pipe.Exec(ctx)
+	if err == redis.Nil {
+		err = nil
+	}
+	var bytes int64
+	for _, c := range cmds {
+		if value, err := c.Bytes(); err == nil {
+			bytes += int64(len(value))
+		}
+	}
+	return bytes, err
+}
+
+// PipelineSet implements Pipeliner, writing the value to every key in one round trip
+func (r *RedisClient) PipelineSet(ctx context.Context, keys []string, value []byte, expiration time.Duration) (int64, error) {
+	pipe := r.pipeline()
+	for _, key := range keys {
+		pipe.Set(ctx, key, value, expiration)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int64(len(keys) * len(value)), nil
+}
+
+// UpdateOptimistic implements OptimisticUpdater using WATCH/MULTI/EXEC
+func (r *RedisClient) UpdateOptimistic(ctx context.Context, key string, mutate func([]byte) ([]byte, error), expiration time.Duration) error {
+	txf := func(tx *redis.Tx) error {
+		value, err := tx.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			value, err = nil, nil
+		}
+		if err != nil {
+			return err
+		}
+
+		updated, err := mutate(value)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, updated, expiration)
+			return nil
+		})
+		return err
+	}
+
+	var err error
+	if r.isCluster {
+		err = r.clusterClient.Watch(ctx, txf, key)
+	} else {
+		err = r.client.Watch(ctx, txf, key)
+	}
+	if err == redis.TxFailedErr {
+		return ErrConflict
+	}
+	return err
+}
+
+// Connect opens a pooled connection, with its handshake (HELLO, AUTH, SELECT)
+func (r *RedisClient) Connect(ctx context.Context) error {
+	return r.Ping(ctx)
+}
+
 func (r *RedisClient) Ping(ctx context.Context) error {
 	if r.isCluster {
 		return r.clusterClient.Ping(ctx).Err()
@@ -131,3 +393,13 @@ func (r *RedisClient) Name() string {
 	}
 	return "Redis"
 }
+
+// Reauthenticate implements Reauthenticator: the open connections authenticate again (AUTH)
+// with the password, or with their current one when empty. Only call it between the
+// client's operations, as it writes on the pooled connections directly.
+func (r *RedisClient) Reauthenticate(ctx context.Context, password string) (int, error) {
+	if r.credentials == nil {
+		return 0, errReauthUnsupported
+	}
+	return r.credentials.rotate(password), nil
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+// reexec replaces the process with a new binary, keeping its arguments and environment
+func reexec(path string) error {
+	return syscall.Exec(path, os.Args, os.Environ())
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package cmd
+
+import "fmt"
+
+// reexec is unsupported: Windows has no exec(2) and can't overwrite a running executable
+func reexec(path string) error {
+	return fmt.Errorf("agent self-update is not supported on Windows")
+}
@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// replicationDirection measures lag from writes in one region to reads in another
+type replicationDirection struct {
+	Name   string
+	Writer CacheClient
+	Reader CacheClient
+
+	Writes      int64
+	WriteErrors int64
+	Observed    int64
+	Timeouts    int64 // Writes not visible in the other region within the observe timeout
+	ReadErrors  int64
+
+	Lag     *PerformanceStats // Write acknowledged -> first visible in the other region
+	ReadRTT *PerformanceStats // Poll round trip, the resolution of the lag measurement
+}
+
+// replicationLagCmd represents the replication-lag command
+var replicationLagCmd = &cobra.Command{
+	Use:   "replication-lag",
+	Short: "Measure cross-region replication lag (Global Datastore, active-active)",
+	Long: `Measure cross-region replication lag: versioned keys are written to the primary
+endpoint and polled on the replica endpoint, reporting the time from a write being
+acknowledged until it is visible in the other region.
+
+Both endpoints are used from this process, so there is no clock skew between regions;
+the lag includes the round trip to the replica region and has the poll RTT (reported)
+as its resolution. With --bidirectional, writes are also made on the replica and
+observed on the primary, for active-active deployments.
+
+Examples:
+  # ElastiCache Global Datastore: primary in us-east-1, secondary in eu-west-1
+  serverless-cache-benchmark replication-lag \
+    --primary-uri rediss://primary.use1.cache.amazonaws.com:6379 \
+    --replica-uri rediss://secondary.euw1.cache.amazonaws.com:6379 --test-time 120
+
+  # Active-active (Redis Enterprise CRDB): measure both directions at 200 writes/s
+  serverless-cache-benchmark replication-lag --primary-uri redis://a:6379 \
+    --replica-uri redis://b:6379 --bidirectional --write-rate 200`,
+	Run: runReplicationLag,
+}
+
+// replicationValue encodes a key version; the timestamp only helps when inspecting keys by hand
+func replicationValue(version int64) []byte {
+	return []byte(fmt.Sprintf("%d:%d", version, time.Now().UnixNano()))
+}
+
+// parseReplicationVersion returns the version stored in a replication value
+func parseReplicationVersion(value []byte) (int64, error) {
+	version, _, _ := strings.Cut(string(value), ":")
+	return strconv.ParseInt(version, 10, 64)
+}
+
+func runReplicationLag(cmd *cobra.Command, args []string) {
+	primaryURI, _ := cmd.Flags().GetString("primary-uri")
+	replicaURI, _ := cmd.Flags().GetString("replica-uri")
+	clusterMode, _ := cmd.Flags().GetBool("cluster-mode")
+	bidirectional, _ := cmd.Flags().GetBool("bidirectional")
+	testTime, _ := cmd.Flags().GetInt("test-time")
+	writeRate, _ := cmd.Flags().GetInt("write-rate")
+	keys, _ := cmd.Flags().GetInt("keys")
+	keyPrefix, _ := cmd.Flags().GetString("key-prefix")
+	pollInterval, _ := cmd.Flags().GetDuration("poll-interval")
+	observeTimeout, _ := cmd.Flags().GetDuration("observe-timeout")
+	maxInFlight, _ := cmd.Flags().GetInt("max-in-flight")
+
+	if replicaURI == "" {
+		log.Fatalf("Replica URI is required (--replica-uri)")
+	}
+	if testTime <= 0 || writeRate <= 0 || keys <= 0 || maxInFlight <= 0 {
+		log.Fatalf("Test time, write rate, keys and max in-flight must be positive")
+	}
+	if pollInterval <= 0 || observeTimeout <= 0 {
+		log.Fatalf("Poll interval and observe timeout must be positive")
+	}
+
+	password, err := resolvePasswordFrom(cmd)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	config := RedisConfig{
+		DialTimeout:  10 * time.Second,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		PoolTimeout:  30 * time.Second,
+		MaxRetries:   0, // A retried write would be acknowledged late and hide lag
+		ClusterMode:  clusterMode,
+		Password:     password,
+	}
+	primary, err := NewRedisClientFromURI(primaryURI, config)
+	if err != nil {
+		log.Fatalf("Failed to create primary client: %v", err)
+	}
+	defer primary.Close()
+	replica, err := NewRedisClientFromURI(replicaURI, config)
+	if err != nil {
+		log.Fatalf("Failed to create replica client: %v", err)
+	}
+	defer replica.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(testTime)*time.Second)
+	defer cancel()
+
+	for name, client := range map[string]CacheClient{"primary": primary, "replica": replica} {
+		if err := client.Ping(ctx); err != nil {
+			log.Fatalf("Failed to connect to %s: %v", name, err)
+		}
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		fmt.Println("\nReceived interrupt signal. Stopping and printing summary...")
+		cancel()
+	}()
+
+	directions := []*replicationDirection{
+		{Name: "primary -> replica", Writer: primary, Reader: replica, Lag: NewPerformanceStats(), ReadRTT: NewPerformanceStats()},
+	}
+	if bidirectional {
+		directions = append(directions, &replicationDirection{
+			Name: "replica -> primary", Writer: replica, Reader: primary, Lag: NewPerformanceStats(), ReadRTT: NewPerformanceStats(),
+		})
+	}
+
+	fmt.Printf("Measuring replication lag for %d seconds\n", testTime)
+	fmt.Printf("Primary: %s\n", primaryURI)
+	fmt.Printf("Replica: %s\n", replicaURI)
+	fmt.Printf("Writes: %d/s per direction over %d keys, poll interval %s, observe timeout %s\n",
+		writeRate, keys, pollInterval, observeTimeout)
+	fmt.Println()
+
+	var wg sync.WaitGroup
+	for d, direction := range directions {
+		wg.Add(1)
+		// Each direction uses its own key namespace so writes never race across regions
+		prefix := fmt.Sprintf("%sreplication-%d-", keyPrefix, d)
+		go func(direction *replicationDirection) {
+			defer wg.Done()
+			runReplicationWriter(ctx, direction, prefix, writeRate, keys, pollInterval, observeTimeout, maxInFlight)
+		}(direction)
+	}
+	wg.Wait()
+
+	printReplicationResults(directions, testTime)
+	for _, direction := range directions {
+		direction.Lag.Close()
+		direction.ReadRTT.Close()
+	}
+}
+
+// runReplicationWriter writes versioned keys at a fixed rate and observes each write on the reader
+func runReplicationWriter(ctx context.Context, direction *replicationDirection, prefix string,
+	writeRate, keys int, pollInterval, observeTimeout time.Duration, maxInFlight int) {
+
+	ticker := time.NewTicker(time.Second / time.Duration(writeRate))
+	defer ticker.Stop()
+
+	inFlight := make(chan struct{}, maxInFlight)
+	var observers sync.WaitGroup
+	defer observers.Wait()
+
+	var version int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Skip the tick rather than queue writes when observers are saturated
+		select {
+		case inFlight <- struct{}{}:
+		default:
+			continue
+		}
+
+		version++
+		key := fmt.Sprintf("%s%d", prefix, version%int64(keys))
+
+		opCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		err := direction.Writer.Set(opCtx, key, replicationValue(version), observeTimeout+time.Minute)
+		cancel()
+		acked := time.Now()
+		if err != nil {
+			atomic.AddInt64(&direction.WriteErrors, 1)
+			<-inFlight
+			continue
+		}
+		atomic.AddInt64(&direction.Writes, 1)
+
+		observers.Add(1)
+		go func(key string, version int64, acked time.Time) {
+			defer observers.Done()
+			defer func() { <-inFlight }()
+			observeReplication(direction, key, version, acked, pollInterval, observeTimeout)
+		}(key, version, acked)
+	}
+}
+
+// observeReplication polls the reader until the key holds the version (or a newer one).
+// Observation continues past the end of the run up to the observe timeout so the last
+// writes are not reported as lost.
+func observeReplication(direction *replicationDirection, key string, version int64, acked time.Time,
+	pollInterval, observeTimeout time.Duration) {
+
+	deadline := acked.Add(observeTimeout)
+	for time.Now().Before(deadline) {
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		start := time.Now()
+		value, err := direction.Reader.Get(ctx, key)
+		seen := time.Now()
+		cancel()
+
+		if err == nil {
+			direction.ReadRTT.RecordLatency(seen.Sub(start).Microseconds())
+			if current, parseErr := parseReplicationVersion(value); parseErr == nil && current >= version {
+				// The value may already be visible before the write ack returned
+				lag := seen.Sub(acked)
+				if lag < 0 {
+					lag = 0
+				}
+				atomic.AddInt64(&direction.Observed, 1)
+				direction.Lag.RecordLatency(lag.Microseconds())
+				return
+			}
+		} else if errors.Is(err, ErrCacheMiss) {
+			direction.ReadRTT.RecordLatency(seen.Sub(start).Microseconds())
+		} else if ctx.Err() == nil {
+			atomic.AddInt64(&direction.ReadErrors, 1)
+		}
+
+		time.Sleep(pollInterval)
+	}
+	atomic.AddInt64(&direction.Timeouts, 1)
+}
+
+// printReplicationResults prints replication lag percentiles per direction
+func printReplicationResults(directions []*replicationDirection, testTime int) {
+	// Collect the last events before reporting (the stats share the collector)
+	directions[0].Lag.Flush()
+
+	fmt.Println("\n" + strings.Repeat("=", 60))
+	fmt.Println("REPLICATION LAG RESULTS")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("Test Duration: %d seconds\n", testTime)
+	fmt.Println()
+
+	for _, direction := range directions {
+		writes := atomic.LoadInt64(&direction.Writes)
+		observed := atomic.LoadInt64(&direction.Observed)
+		timeouts := atomic.LoadInt64(&direction.Timeouts)
+
+		fmt.Printf("%s:\n", direction.Name)
+		fmt.Printf("Writes: %d (errors: %d)\n", writes, atomic.LoadInt64(&direction.WriteErrors))
+		fmt.Printf("Observed: %d, Not observed within timeout: %d, Read errors: %d\n",
+			observed, timeouts, atomic.LoadInt64(&direction.ReadErrors))
+		if observed > 0 {
+			_, _, _, _, p50, p95, p99 := direction.Lag.GetStats()
+			fmt.Printf("Replication Lag - P50: %d μs, P95: %d μs, P99: %d μs, Max: %d μs\n",
+				p50, p95, p99, direction.Lag.Histogram.Max())
+		}
+		if direction.ReadRTT.Histogram.TotalCount() > 0 {
+			_, _, _, _, p50, p95, p99 := direction.ReadRTT.GetStats()
+			fmt.Printf("Observer Read RTT - P50: %d μs, P95: %d μs, P99: %d μs\n", p50, p95, p99)
+		}
+		fmt.Println()
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
+
+func init() {
+	rootCmd.AddCommand(replicationLagCmd)
+
+	replicationLagCmd.Flags().String("primary-uri", "redis://localhost:6379", "Redis URI of the region keys are written to")
+	replicationLagCmd.Flags().String("replica-uri", "", "Redis URI of the region keys are read from")
+	replicationLagCmd.Flags().Bool("cluster-mode", false, "Connect to both endpoints in cluster mode")
+	replicationLagCmd.Flags().Bool("bidirectional", false, "Also write on the replica and observe on the primary (active-active)")
+	replicationLagCmd.Flags().Int("test-time", 60, "Number of seconds to write versioned keys")
+	replicationLagCmd.Flags().Int("write-rate", 50, "Versioned writes per second per direction")
+	replicationLagCmd.Flags().Int("keys", 1000, "Number of distinct keys the versions rotate over")
+	replicationLagCmd.Flags().String("key-prefix", "memtier-", "Prefix for keys")
+	replicationLagCmd.Flags().Duration("poll-interval", time.Millisecond, "Delay between replica reads while waiting for a write")
+	replicationLagCmd.Flags().Duration("observe-timeout", 30*time.Second, "Give up observing a write after this long")
+	replicationLagCmd.Flags().Int("max-in-flight", 1000, "Maximum writes being observed at once; further writes are skipped")
+}
@@ -0,0 +1,65 @@
+//go:build !noaws
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// WriteReportToS3 stores a report as a JSON object, assuming roleARN when set
+func WriteReportToS3(ctx context.Context, region, roleARN, bucket, key string, report any) error {
+	cfg, err := loadAWSConfig(ctx, region, roleARN)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	_, err = s3.NewFromConfig(cfg).PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write report to s3://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// WriteReportToDynamoDB stores a report as a DynamoDB item. Attribute names follow the
+// report's JSON tags; the table's key attributes must be part of the report.
+// roleARN, when set, is assumed for the write.
+func WriteReportToDynamoDB(ctx context.Context, region, roleARN, table string, report any) error {
+	cfg, err := loadAWSConfig(ctx, region, roleARN)
+	if err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMapWithOptions(report, func(o *attributevalue.EncoderOptions) {
+		o.TagKey = "json"
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	_, err = dynamodb.NewFromConfig(cfg).PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(table),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write report to DynamoDB table %s: %w", table, err)
+	}
+	return nil
+}
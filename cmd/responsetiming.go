@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	runCmd.Flags().Bool("response-timing", false, "Split the latency of large responses into time to first byte and full response, measured on the Redis/Memcached connections, to tell server processing from transfer limits")
+	runCmd.Flags().Int64("response-timing-min-bytes", 4096, "Smallest response (in bytes) measured by --response-timing")
+}
+
+// responseTiming measures the responses of the run's cache connections (nil when disabled);
+// it is only applied to the benchmarked clients, not to mirrors, probes or watchers
+var responseTiming *ResponseTiming
+
+// ResponseTiming splits the latency of large responses at the connection level: from the
+// request's first written byte to the first and to the last byte read back. The gap between
+// the two is the transfer time, which grows with value size when the network, not the
+// server, limits throughput.
+type ResponseTiming struct {
+	MinBytes int64
+
+	Responses int64 // Responses of at least MinBytes, recorded in the histograms
+	Small     int64 // Smaller responses, not recorded
+	Bytes     int64 // Bytes of the recorded responses
+
+	FirstByte *PerformanceStats
+	Full      *PerformanceStats
+	Transfer  *PerformanceStats // First to last byte
+}
+
+func NewResponseTiming(minBytes int64) *ResponseTiming {
+	rt := &ResponseTiming{
+		MinBytes:  minBytes,
+		FirstByte: NewPerformanceStats(),
+		Full:      NewPerformanceStats(),
+		Transfer:  NewPerformanceStats(),
+	}
+	rt.FirstByte.Name = "FIRST_BYTE"
+	rt.Full.Name = "FULL_RESPONSE"
+	rt.Transfer.Name = "TRANSFER"
+	return rt
+}
+
+func (rt *ResponseTiming) Close() {
+	rt.FirstByte.Close()
+	rt.Full.Close()
+	rt.Transfer.Close()
+}
+
+// Dialer wraps a dial function so the connections it opens are measured
+func (rt *ResponseTiming) Dialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &timedConn{Conn: conn, timing: rt}, nil
+	}
+}
+
+// timedConn times request/response exchanges on a connection that isn't pipelined: a
+// write after bytes were read back starts a new exchange and completes the previous one.
+// Reads return as data arrives, so their return times stand for the arrival of the bytes.
+type timedConn struct {
+	net.Conn
+	timing *ResponseTiming
+
+	mutex     sync.Mutex
+	sent      time.Time // First write of the current request (zero when idle)
+	firstByte time.Time
+	lastByte  time.Time
+	bytes     int64
+}
+
+func (c *timedConn) Write(b []byte) (int, error) {
+	c.mutex.Lock()
+	if c.bytes > 0 {
+		c.complete()
+	}
+	if c.sent.IsZero() {
+		c.sent = time.Now()
+	}
+	c.mutex.Unlock()
+	return c.Conn.Write(b)
+}
+
+func (c *timedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		now := time.Now()
+		c.mutex.Lock()
+		if !c.sent.IsZero() {
+			if c.bytes == 0 {
+				c.firstByte = now
+			}
+			c.lastByte = now
+			c.bytes += int64(n)
+		}
+		c.mutex.Unlock()
+	}
+	return n, err
+}
+
+func (c *timedConn) Close() error {
+	c.mutex.Lock()
+	if c.bytes > 0 {
+		c.complete()
+	}
+	c.mutex.Unlock()
+	return c.Conn.Close()
+}
+
+// complete records the current exchange; the mutex must be held
+func (c *timedConn) complete() {
+	rt := c.timing
+	if c.bytes >= rt.MinBytes {
+		atomic.AddInt64(&rt.Responses, 1)
+		atomic.AddInt64(&rt.Bytes, c.bytes)
+		rt.FirstByte.RecordLatency(c.firstByte.Sub(c.sent).Microseconds())
+		rt.Full.RecordLatency(c.lastByte.Sub(c.sent).Microseconds())
+		rt.Transfer.RecordLatency(c.lastByte.Sub(c.firstByte).Microseconds())
+	} else {
+		atomic.AddInt64(&rt.Small, 1)
+	}
+	c.sent, c.bytes = time.Time{}, 0
+}
+
+// validateResponseTiming checks the response timing flags of a run
+func validateResponseTiming(cmd *cobra.Command, cacheType string) error {
+	enabled, _ := cmd.Flags().GetBool("response-timing")
+	if !enabled {
+		return nil
+	}
+	if cacheType != "redis" && cacheType != "memcached" {
+		return fmt.Errorf("response timing is only supported for Redis and Memcached")
+	}
+	if noPool, _ := cmd.Flags().GetBool("no-pool"); noPool {
+		return fmt.Errorf("response timing is not supported in no-pool mode")
+	}
+	if minBytes, _ := cmd.Flags().GetInt64("response-timing-min-bytes"); minBytes < 1 {
+		return fmt.Errorf("response timing minimum size must be positive, got: %d", minBytes)
+	}
+	return nil
+}
+
+// responseTimingFromFlags creates the response timing selected with --response-timing
+// (nil when not set)
+func responseTimingFromFlags(cmd *cobra.Command) *ResponseTiming {
+	if enabled, _ := cmd.Flags().GetBool("response-timing"); !enabled {
+		return nil
+	}
+	minBytes, _ := cmd.Flags().GetInt64("response-timing-min-bytes")
+	return NewResponseTiming(minBytes)
+}
+
+// ResponseTimingSummary is the machine-readable first byte / full response split
+type ResponseTimingSummary struct {
+	MinBytes     int64          `json:"min_bytes"`
+	Responses    int64          `json:"responses"`
+	Small        int64          `json:"small_responses"` // Below min_bytes, not measured
+	AvgBytes     float64        `json:"avg_bytes"`
+	TransferMBps float64        `json:"transfer_mbps"` // Bytes over the summed transfer times
+	FirstByte    LatencySummary `json:"first_byte"`
+	Full         LatencySummary `json:"full_response"`
+	Transfer     LatencySummary `json:"transfer"`
+}
+
+// Summary returns the split of the responses measured so far
+func (rt *ResponseTiming) Summary() *ResponseTimingSummary {
+	responses := atomic.LoadInt64(&rt.Responses)
+	bytes := atomic.LoadInt64(&rt.Bytes)
+	summary := &ResponseTimingSummary{
+		MinBytes:  rt.MinBytes,
+		Responses: responses,
+		Small:     atomic.LoadInt64(&rt.Small),
+		FirstByte: summarizeTotals(rt.FirstByte, responses, 0, 0),
+		Full:      summarizeTotals(rt.Full, responses, 0, 0),
+		Transfer:  summarizeTotals(rt.Transfer, responses, 0, 0),
+	}
+	if responses > 0 {
+		summary.AvgBytes = float64(bytes) / float64(responses)
+		if transferMicros := summary.Transfer.Mean * float64(responses); transferMicros > 0 {
+			summary.TransferMBps = float64(bytes) / transferMicros * 1e6 / (1024 * 1024)
+		}
+	}
+	return summary
+}
+
+// printResponseTimingResults prints where the latency of large responses goes
+func printResponseTimingResults(rt *ResponseTiming) {
+	summary := rt.Summary()
+	fmt.Printf("Response Timing (responses of %d bytes or more): %d measured, %d smaller\n",
+		summary.MinBytes, summary.Responses, summary.Small)
+	if summary.Responses == 0 {
+		fmt.Println()
+		return
+	}
+	fmt.Printf("First Byte - %s\n", formatPercentiles(summary.FirstByte.Percentiles))
+	fmt.Printf("Full Response - %s\n", formatPercentiles(summary.Full.Percentiles))
+	fmt.Printf("Transfer - %s (%.0f bytes on average, %.1f MB/s)\n",
+		formatPercentiles(summary.Transfer.Percentiles), summary.AvgBytes, summary.TransferMBps)
+	fmt.Println()
+}
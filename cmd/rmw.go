@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// ErrConflict is returned by OptimisticUpdater when a concurrent writer modified the key
+var ErrConflict = errors.New("optimistic update conflict")
+
+// OptimisticUpdater is implemented by cache clients that support optimistic concurrency
+// control (WATCH/MULTI/EXEC on Redis, CAS tokens on Memcached)
+type OptimisticUpdater interface {
+	// UpdateOptimistic reads key, applies mutate to its value (nil when absent) and writes the
+	// result only if no other client modified the key in between. Returns ErrConflict otherwise.
+	UpdateOptimistic(ctx context.Context, key string, mutate func([]byte) ([]byte, error), expiration time.Duration) error
+}
+
+// RMWStats tracks the read-modify-write contention workload.
+// Every RMW increments a decimal counter stored in one of a small set of shared keys;
+// comparing the committed increments with the final counter values reveals lost updates.
+type RMWStats struct {
+	Keys         int
+	Optimistic   bool
+	MaxAttempts  int
+	Ops          int64
+	Errors       int64
+	Conflicts    int64   // Optimistic writes rejected (retried or given up)
+	Exhausted    int64   // Optimistic RMWs that gave up after --rmw-max-attempts conflicts
+	Committed    []int64 // Successful increments per shared key
+	LostUpdates  int64   // Computed by verifyRMWKeys at the end of the run
+	VerifyErrors int64   // Keys that could not be read back
+	Stats        *PerformanceStats
+}
+
+func NewRMWStats(keys int, optimistic bool, maxAttempts int) *RMWStats {
+	return &RMWStats{
+		Keys:        keys,
+		Optimistic:  optimistic,
+		MaxAttempts: maxAttempts,
+		Committed:   make([]int64, keys),
+		Stats:       NewPerformanceStats(),
+	}
+}
+
+// rmwKey returns the shared counter key for an index
+func rmwKey(keyPrefix string, index int) string {
+	return fmt.Sprintf("%srmw-%d", keyPrefix, index)
+}
+
+// incrementCounter is the RMW mutation: parse the decimal counter and add one
+func incrementCounter(value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return []byte("1"), nil
+	}
+	n, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("RMW key does not hold a counter: %w", err)
+	}
+	return []byte(strconv.FormatInt(n+1, 10)), nil
+}
+
+// processRMW performs one GET, mutate, SET cycle, retrying on optimistic conflicts up to
+// the maximum attempts; an RMW that runs out of attempts fails with ErrConflict
+func processRMW(ctx context.Context, request requestInfo, client CacheClient, generator *DataGenerator,
+	opts *WorkloadOptions, timeoutSeconds int, verbose bool) workloadResult {
+
+	opCtx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+	defer cancel()
+
+	expiration := generator.GetExpiration()
+	var conflicts int64
+
+	start := time.Now()
+	var err error
+	var exhausted bool
+	if opts.RMWOptimistic {
+		updater, ok := client.(OptimisticUpdater)
+		if !ok {
+			return workloadResult{isRMW: true, isError: true}
+		}
+		for attempt := 1; ; attempt++ {
+			err = updater.UpdateOptimistic(opCtx, request.key, incrementCounter, expiration)
+			if !errors.Is(err, ErrConflict) {
+				break
+			}
+			conflicts++
+			if attempt >= opts.RMWMaxAttempts {
+				exhausted = true
+				break
+			}
+		}
+	} else {
+		var value, updated []byte
+		value, err = client.Get(opCtx, request.key)
+		if errors.Is(err, ErrCacheMiss) {
+			value, err = nil, nil
+		}
+		if err == nil {
+			updated, err = incrementCounter(value)
+		}
+		if err == nil {
+			err = client.Set(opCtx, request.key, updated, expiration)
+		}
+	}
+	latency := time.Since(start)
+
+	if err != nil {
+		if verbose {
+			log.Printf("Worker %d: RMW operation failed for key %s: %v", request.workerID, request.key, err)
+		}
+		return workloadResult{isRMW: true, isError: true, conflicts: conflicts, rmwExhausted: exhausted, timeout: classifyTimeout(err)}
+	}
+	return workloadResult{isRMW: true, rmwIndex: request.rmwIndex, latencyMicros: latency.Microseconds(), conflicts: conflicts}
+}
+
+// recordRMWResult records the outcome of a read-modify-write cycle
+func recordRMWResult(stats *WorkloadStats, result workloadResult) {
+	rmw := stats.RMW
+	atomic.AddInt64(&rmw.Conflicts, result.conflicts)
+	if result.rmwExhausted {
+		atomic.AddInt64(&rmw.Exhausted, 1)
+	}
+	if result.isError {
+		atomic.AddInt64(&rmw.Errors, 1)
+		return
+	}
+	atomic.AddInt64(&rmw.Ops, 1)
+	atomic.AddInt64(&rmw.Committed[result.rmwIndex], 1)
+	rmw.Stats.RecordLatency(result.latencyMicros)
+}
+
+// initRMWKeys resets all shared counters to zero before the run
+func initRMWKeys(cacheType string, cmd *cobra.Command, keyPrefix string, keys int, optimistic bool) error {
+	ctx := context.Background()
+	client, err := createCacheClientForRun(ctx, cacheType, cmd)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if _, ok := client.(OptimisticUpdater); optimistic && !ok {
+		return fmt.Errorf("%s does not support optimistic concurrency control", client.Name())
+	}
+
+	for i := 0; i < keys; i++ {
+		if err := client.Set(ctx, rmwKey(keyPrefix, i), []byte("0"), 0); err != nil {
+			return fmt.Errorf("failed to initialize RMW key %s: %w", rmwKey(keyPrefix, i), err)
+		}
+	}
+	return nil
+}
+
+// verifyRMWKeys reads back all shared counters and computes lost updates
+func verifyRMWKeys(cacheType string, cmd *cobra.Command, keyPrefix string, rmw *RMWStats) {
+	ctx := context.Background()
+	client, err := createCacheClientForRun(ctx, cacheType, cmd)
+	if err != nil {
+		log.Printf("Failed to create client for RMW verification: %v", err)
+		rmw.VerifyErrors = int64(rmw.Keys)
+		return
+	}
+	defer client.Close()
+
+	for i := 0; i < rmw.Keys; i++ {
+		value, err := client.Get(ctx, rmwKey(keyPrefix, i))
+		if err != nil {
+			rmw.VerifyErrors++
+			continue
+		}
+		final, err := strconv.ParseInt(string(value), 10, 64)
+		if err != nil {
+			rmw.VerifyErrors++
+			continue
+		}
+		if lost := atomic.LoadInt64(&rmw.Committed[i]) - final; lost > 0 {
+			rmw.LostUpdates += lost
+		}
+	}
+}
+
+// printRMWResults prints the read-modify-write contention results
+func printRMWResults(stats *WorkloadStats) {
+	rmw := stats.RMW
+	ops := atomic.LoadInt64(&rmw.Ops)
+	_, _, _, _, p50, p95, p99 := rmw.Stats.GetStats()
+
+	mode := "unprotected"
+	if rmw.Optimistic {
+		mode = "optimistic"
+	}
+
+	fmt.Printf("Read-Modify-Write (%d shared keys, %s):\n", rmw.Keys, mode)
+	fmt.Printf("RMW Operations: %d, Errors: %d, Conflicts: %d\n", ops, atomic.LoadInt64(&rmw.Errors), atomic.LoadInt64(&rmw.Conflicts))
+	if exhausted := atomic.LoadInt64(&rmw.Exhausted); exhausted > 0 {
+		fmt.Printf("Gave up after %d attempts: %d RMW operations (counted as errors)\n", rmw.MaxAttempts, exhausted)
+	}
+	fmt.Printf("RMW Latency - P50: %d μs, P95: %d μs, P99: %d μs\n", p50, p95, p99)
+	if ops > 0 {
+		fmt.Printf("Lost Updates: %d (%.2f%% of committed updates)\n", rmw.LostUpdates, float64(rmw.LostUpdates)/float64(ops)*100)
+	}
+	if rmw.VerifyErrors > 0 {
+		fmt.Printf("Warning: %d RMW keys could not be verified\n", rmw.VerifyErrors)
+	}
+	fmt.Println()
+}
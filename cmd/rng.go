@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	runCmd.Flags().String("rng", "pcg", "Random number generator of the workload: pcg, xoshiro (independent per-worker streams) or math (legacy math/rand sources)")
+	runCmd.Flags().Int64("seed", 0, "Base seed of the workload's random streams (0 = time-based); the same seed reproduces the same traffic")
+	runCmd.Flags().Int("rng-stream-offset", 0, "Offset of this runner's worker streams, so runners sharing a --seed get disjoint streams (e.g. agent index x clients)")
+}
+
+// uint64Source is a generator of uniformly distributed 64-bit values
+type uint64Source interface {
+	Uint64() uint64
+}
+
+// rngSource adapts a uint64Source to math/rand, so streams drive rand.Rand and rand.Zipf
+type rngSource struct {
+	uint64Source
+}
+
+func (s rngSource) Int63() int64 { return int64(s.Uint64() >> 1) }
+
+// Seed is a no-op: streams are seeded by RNGStreams
+func (s rngSource) Seed(int64) {}
+
+// RNGStreams hands out independent random streams derived from one base seed. Stream 0 is
+// run-wide (e.g. the load shaping schedule) and stream i+1 belongs to worker i; substreams
+// split a worker's stream further (e.g. its producer-consumer goroutines). Every stream is
+// owned by one goroutine, so there is no lock contention on the global generator.
+type RNGStreams struct {
+	Kind   string
+	Seed   int64
+	Offset int // Added to worker streams of this runner
+}
+
+func NewRNGStreams(kind string, seed int64, offset int) (*RNGStreams, error) {
+	switch kind {
+	case "pcg", "xoshiro", "math":
+	default:
+		return nil, fmt.Errorf("invalid RNG '%s'. Must be one of: pcg, xoshiro, math", kind)
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("RNG stream offset must not be negative, got: %d", offset)
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &RNGStreams{Kind: kind, Seed: seed, Offset: offset}, nil
+}
+
+// Global returns the run-wide stream
+func (rs *RNGStreams) Global() *rand.Rand {
+	return rs.Stream(0, 0)
+}
+
+// Worker returns a substream of a worker's stream
+func (rs *RNGStreams) Worker(workerID, substream int) *rand.Rand {
+	return rs.Stream(rs.Offset+workerID+1, substream)
+}
+
+// Stream returns a new generator positioned at the start of a (sub)stream
+func (rs *RNGStreams) Stream(stream, substream int) *rand.Rand {
+	switch rs.Kind {
+	case "xoshiro":
+		x := newXoshiro256(uint64(rs.Seed))
+		for i := 0; i < stream; i++ {
+			x.LongJump()
+		}
+		for i := 0; i < substream; i++ {
+			x.Jump()
+		}
+		return rand.New(rngSource{x})
+	case "pcg":
+		p := newPCG(uint64(rs.Seed))
+		// Streams are 2^96 steps apart, substreams 2^64
+		p.Advance(uint64(stream)<<32+uint64(substream), 0)
+		return rand.New(rngSource{p})
+	default:
+		// Legacy sources: distinct seeds, but no guarantee the sequences don't overlap
+		return rand.New(rand.NewSource(rs.Seed + int64(stream)<<20 + int64(substream)))
+	}
+}
+
+// splitMix64 expands a seed into well-mixed state words
+func splitMix64(state *uint64) uint64 {
+	*state += 0x9e3779b97f4a7c15
+	z := *state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// xoshiro256 is the xoshiro256** generator, with jump functions that advance it by 2^128
+// (Jump) or 2^192 (LongJump) steps to start non-overlapping streams
+type xoshiro256 struct {
+	s [4]uint64
+}
+
+func newXoshiro256(seed uint64) *xoshiro256 {
+	x := &xoshiro256{}
+	for i := range x.s {
+		x.s[i] = splitMix64(&seed)
+	}
+	return x
+}
+
+func (x *xoshiro256) Uint64() uint64 {
+	s := &x.s
+	result := bits.RotateLeft64(s[1]*5, 7) * 9
+	t := s[1] << 17
+	s[2] ^= s[0]
+	s[3] ^= s[1]
+	s[1] ^= s[2]
+	s[0] ^= s[3]
+	s[2] ^= t
+	s[3] = bits.RotateLeft64(s[3], 45)
+	return result
+}
+
+func (x *xoshiro256) Jump() {
+	x.jump([4]uint64{0x180ec6d33cfd0aba, 0xd5a61266f0c9392c, 0xa9582618e03fc9aa, 0x39abdc4529b1661c})
+}
+
+func (x *xoshiro256) LongJump() {
+	x.jump([4]uint64{0x76e15d3efefdcbbf, 0xc5004e441c522fb3, 0x77710069854ee241, 0x39109bb02acbe635})
+}
+
+// jump advances the state by the step count encoded in a jump polynomial
+func (x *xoshiro256) jump(polynomial [4]uint64) {
+	var s [4]uint64
+	for _, word := range polynomial {
+		for b := 0; b < 64; b++ {
+			if word&(1<<b) != 0 {
+				for i := range s {
+					s[i] ^= x.s[i]
+				}
+			}
+			x.Uint64()
+		}
+	}
+	x.s = s
+}
+
+// PCG-DXSM constants (the 128-bit LCG used by math/rand/v2)
+const (
+	pcgMulHi = 2549297995355413924
+	pcgMulLo = 4865540595714422341
+	pcgIncHi = 6364136223846793005
+	pcgIncLo = 1442695040888963407
+)
+
+// pcg is the PCG-DXSM generator: a 128-bit LCG, which can jump ahead by any number of
+// steps in O(log n) to start non-overlapping streams
+type pcg struct {
+	hi, lo uint64
+}
+
+func newPCG(seed uint64) *pcg {
+	return &pcg{hi: splitMix64(&seed), lo: splitMix64(&seed)}
+}
+
+// mul128 returns the low 128 bits of a*b
+func mul128(aHi, aLo, bHi, bLo uint64) (hi, lo uint64) {
+	hi, lo = bits.Mul64(aLo, bLo)
+	hi += aHi*bLo + aLo*bHi
+	return hi, lo
+}
+
+// add128 returns a+b modulo 2^128
+func add128(aHi, aLo, bHi, bLo uint64) (hi, lo uint64) {
+	lo, carry := bits.Add64(aLo, bLo, 0)
+	hi, _ = bits.Add64(aHi, bHi, carry)
+	return hi, lo
+}
+
+func (p *pcg) Uint64() uint64 {
+	hi, lo := mul128(p.hi, p.lo, pcgMulHi, pcgMulLo)
+	p.hi, p.lo = add128(hi, lo, pcgIncHi, pcgIncLo)
+
+	const cheapMul = 0xda942042e4dd58b5
+	hi, lo = p.hi, p.lo
+	hi ^= hi >> 32
+	hi *= cheapMul
+	hi ^= hi >> 48
+	hi *= lo | 1
+	return hi
+}
+
+// Advance jumps the LCG ahead by delta (a 128-bit step count) steps
+func (p *pcg) Advance(deltaHi, deltaLo uint64) {
+	accMulHi, accMulLo := uint64(0), uint64(1)
+	var accIncHi, accIncLo uint64
+	curMulHi, curMulLo := uint64(pcgMulHi), uint64(pcgMulLo)
+	curIncHi, curIncLo := uint64(pcgIncHi), uint64(pcgIncLo)
+
+	for deltaHi != 0 || deltaLo != 0 {
+		if deltaLo&1 != 0 {
+			accMulHi, accMulLo = mul128(accMulHi, accMulLo, curMulHi, curMulLo)
+			accIncHi, accIncLo = mul128(accIncHi, accIncLo, curMulHi, curMulLo)
+			accIncHi, accIncLo = add128(accIncHi, accIncLo, curIncHi, curIncLo)
+		}
+		// inc = (mul + 1) * inc, mul = mul * mul
+		plusHi, plusLo := add128(curMulHi, curMulLo, 0, 1)
+		curIncHi, curIncLo = mul128(plusHi, plusLo, curIncHi, curIncLo)
+		curMulHi, curMulLo = mul128(curMulHi, curMulLo, curMulHi, curMulLo)
+		deltaLo = deltaLo>>1 | deltaHi<<63
+		deltaHi >>= 1
+	}
+
+	hi, lo := mul128(accMulHi, accMulLo, p.hi, p.lo)
+	p.hi, p.lo = add128(hi, lo, accIncHi, accIncLo)
+}
+
+// rngFromFlags creates the random streams selected with --rng and --seed
+func rngFromFlags(cmd *cobra.Command) (*RNGStreams, error) {
+	kind, _ := cmd.Flags().GetString("rng")
+	seed, _ := cmd.Flags().GetInt64("seed")
+	offset, _ := cmd.Flags().GetInt("rng-stream-offset")
+	return NewRNGStreams(kind, seed, offset)
+}
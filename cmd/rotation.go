@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/redis/go-redis/v9/auth"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	runCmd.Flags().Duration("auth-rotate-interval", 0, "Rotate the credentials of all clients at this interval during the run, re-reading --password-from (e.g. a rotated secret or a fresh IAM token), and report the latency impact (0 = never)")
+	runCmd.Flags().String("auth-rotate-mode", "reauth", "How connections pick up rotated credentials: reauth (AUTH on the open connections) or recycle (replace every client with a new connection)")
+	runCmd.Flags().Duration("auth-rotate-window", 10*time.Second, "Time after each rotation attributed to it when reporting its latency impact")
+}
+
+// errReauthUnsupported is returned by clients whose connections can't re-authenticate
+var errReauthUnsupported = errors.New("client does not support re-authentication")
+
+// Reauthenticator is implemented by cache clients whose open connections can switch to
+// new credentials without reconnecting
+type Reauthenticator interface {
+	// Reauthenticate sends the password (the current one when empty) on every open
+	// connection and returns the number of connections notified
+	Reauthenticate(ctx context.Context, password string) (int, error)
+}
+
+// rotatingCredentials is the go-redis streaming credentials provider of one client: every
+// connection subscribes when it opens, and a rotation re-authenticates all of them
+type rotatingCredentials struct {
+	mutex     sync.Mutex
+	username  string
+	password  string
+	listeners map[int]auth.CredentialsListener
+	next      int
+}
+
+func newRotatingCredentials(username, password string) *rotatingCredentials {
+	return &rotatingCredentials{username: username, password: password, listeners: make(map[int]auth.CredentialsListener)}
+}
+
+// Subscribe implements auth.StreamingCredentialsProvider
+func (rc *rotatingCredentials) Subscribe(listener auth.CredentialsListener) (auth.Credentials, auth.UnsubscribeFunc, error) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	id := rc.next
+	rc.next++
+	rc.listeners[id] = listener
+	unsubscribe := func() error {
+		rc.mutex.Lock()
+		defer rc.mutex.Unlock()
+		delete(rc.listeners, id)
+		return nil
+	}
+	return auth.NewBasicCredentials(rc.username, rc.password), unsubscribe, nil
+}
+
+// rotate switches to a new password (keeping the current one when empty) and
+// re-authenticates the open connections; go-redis closes those that fail
+func (rc *rotatingCredentials) rotate(password string) int {
+	rc.mutex.Lock()
+	if password != "" {
+		rc.password = password
+	}
+	credentials := auth.NewBasicCredentials(rc.username, rc.password)
+	listeners := make([]auth.CredentialsListener, 0, len(rc.listeners))
+	for _, listener := range rc.listeners {
+		listeners = append(listeners, listener)
+	}
+	rc.mutex.Unlock()
+
+	for _, listener := range listeners {
+		listener.OnNext(credentials)
+	}
+	return len(listeners)
+}
+
+// credentialRotation is one rotation and the results of the operations right after it
+type credentialRotation struct {
+	at        time.Time
+	fetch     time.Duration // Time to read the new credentials
+	ops       int64
+	errors    int64
+	histogram *hdrhistogram.Histogram
+}
+
+// CredentialRotator rotates the credentials of a run at an interval, as in a multi-day soak
+// test against caches with rotated passwords or IAM authentication (tokens expire, and
+// connections must re-authenticate within 12 hours). Each rotation re-reads --password-from;
+// workers pick the new credentials up between operations, so traffic never stops, either
+// re-authenticating their open connections or replacing their client. Operations within
+// the rotation window are attributed to the rotation, the others form the baseline.
+type CredentialRotator struct {
+	Interval time.Duration
+	Mode     string // reauth or recycle
+	Window   time.Duration
+
+	FetchFailed   int64 // Rotations skipped because the new credentials couldn't be read
+	Reauthed      int64 // Connections re-authenticated
+	Recycled      int64 // Clients replaced
+	ApplyFailed   int64 // Workers that couldn't apply a rotation (kept their credentials)
+	generation    int64 // Rotations so far, accessed atomically
+	password      atomic.Value
+	cmd           *cobra.Command
+	mutex         sync.Mutex
+	rotations     []*credentialRotation
+	baseline      *hdrhistogram.Histogram
+	baselineOps   int64
+	baselineErrs  int64
+	stop          chan struct{}
+	done          chan struct{}
+	rotationsDone int64
+}
+
+func NewCredentialRotator(cmd *cobra.Command, interval time.Duration, mode string, window time.Duration) (*CredentialRotator, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("credential rotation interval must be positive, got: %v", interval)
+	}
+	if mode != "reauth" && mode != "recycle" {
+		return nil, fmt.Errorf("invalid credential rotation mode '%s': expected reauth or recycle", mode)
+	}
+	if window <= 0 || window > interval {
+		return nil, fmt.Errorf("credential rotation window must be in (0, %v], got: %v", interval, window)
+	}
+	cr := &CredentialRotator{
+		Interval: interval,
+		Mode:     mode,
+		Window:   window,
+		cmd:      cmd,
+		baseline: hdrhistogram.New(1, 60*1000*1000, 3),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	cr.password.Store("")
+	return cr, nil
+}
+
+// Start runs the rotations in the background until Stop
+func (cr *CredentialRotator) Start() {
+	go func() {
+		defer close(cr.done)
+		ticker := time.NewTicker(cr.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				cr.rotate()
+			case <-cr.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the rotations
+func (cr *CredentialRotator) Stop() {
+	select {
+	case <-cr.stop:
+	default:
+		close(cr.stop)
+		<-cr.done
+	}
+}
+
+// rotate reads the new credentials and publishes them to the workers. Without
+// --password-from the current password is kept, which still exercises the rotation path.
+func (cr *CredentialRotator) rotate() {
+	start := time.Now()
+	password, err := refreshPasswordFrom(cr.cmd)
+	if err != nil {
+		atomic.AddInt64(&cr.FetchFailed, 1)
+		log.Printf("Credential rotation skipped: %v", err)
+		return
+	}
+	cr.password.Store(password)
+
+	cr.mutex.Lock()
+	cr.rotations = append(cr.rotations, &credentialRotation{
+		at:        time.Now(),
+		fetch:     time.Since(start),
+		histogram: hdrhistogram.New(1, 60*1000*1000, 3),
+	})
+	cr.mutex.Unlock()
+	atomic.AddInt64(&cr.generation, 1)
+}
+
+// Generation returns the number of rotations so far; workers compare it with the last
+// one they applied
+func (cr *CredentialRotator) Generation() int64 {
+	return atomic.LoadInt64(&cr.generation)
+}
+
+// Apply makes a worker's client use the current credentials, between its operations. On
+// failure the worker keeps its client, and its connections their credentials.
+func (cr *CredentialRotator) Apply(workerID int, client CacheClient, connect func() (CacheClient, error)) CacheClient {
+	if cr.Mode == "reauth" {
+		reauth, ok := client.(Reauthenticator)
+		if !ok {
+			atomic.AddInt64(&cr.ApplyFailed, 1)
+			return client
+		}
+		connections, err := reauth.Reauthenticate(context.Background(), cr.password.Load().(string))
+		if err != nil {
+			atomic.AddInt64(&cr.ApplyFailed, 1)
+			log.Printf("Worker %d: Re-authentication failed: %v", workerID, err)
+			return client
+		}
+		atomic.AddInt64(&cr.Reauthed, int64(connections))
+		return client
+	}
+
+	fresh, err := connect()
+	if err != nil {
+		atomic.AddInt64(&cr.ApplyFailed, 1)
+		log.Printf("Worker %d: Reconnect with rotated credentials failed, keeping the current connection: %v", workerID, err)
+		return client
+	}
+	client.Close()
+	atomic.AddInt64(&cr.Recycled, 1)
+	return fresh
+}
+
+// Record attributes an operation to the rotation whose window it falls in, or to the baseline
+func (cr *CredentialRotator) Record(result workloadResult) {
+	now := time.Now()
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+
+	if n := len(cr.rotations); n > 0 && now.Sub(cr.rotations[n-1].at) < cr.Window {
+		rotation := cr.rotations[n-1]
+		if result.isError {
+			rotation.errors++
+		} else {
+			rotation.ops++
+			rotation.histogram.RecordValue(result.latencyMicros)
+		}
+		return
+	}
+	if result.isError {
+		cr.baselineErrs++
+	} else {
+		cr.baselineOps++
+		cr.baseline.RecordValue(result.latencyMicros)
+	}
+}
+
+// credentialRotationMode returns the --auth-rotate-mode of a run rotating credentials ("" when not rotating)
+func credentialRotationMode(cmd *cobra.Command) string {
+	if interval, _ := cmd.Flags().GetDuration("auth-rotate-interval"); interval == 0 {
+		return ""
+	}
+	mode, _ := cmd.Flags().GetString("auth-rotate-mode")
+	return mode
+}
+
+// validateCredentialRotation rejects --auth-rotate-interval where clients can't pick up new credentials
+func validateCredentialRotation(cmd *cobra.Command, cacheType string) error {
+	if credentialRotationMode(cmd) == "" {
+		return nil
+	}
+	if cacheType != "redis" {
+		return fmt.Errorf("credential rotation is only supported with redis, got: %s", cacheType)
+	}
+	if noPool, _ := cmd.Flags().GetBool("no-pool"); noPool {
+		return fmt.Errorf("credential rotation is not supported with --no-pool")
+	}
+	if users, _ := cmd.Flags().GetString("redis-acl-users"); users != "" {
+		return fmt.Errorf("credential rotation is not supported with --redis-acl-users: each user has its own password")
+	}
+	_, err := credentialRotatorFromFlags(cmd)
+	return err
+}
+
+// credentialRotatorFromFlags creates the rotator selected with --auth-rotate-interval (nil when not set)
+func credentialRotatorFromFlags(cmd *cobra.Command) (*CredentialRotator, error) {
+	interval, _ := cmd.Flags().GetDuration("auth-rotate-interval")
+	if interval == 0 {
+		return nil, nil
+	}
+	mode, _ := cmd.Flags().GetString("auth-rotate-mode")
+	window, _ := cmd.Flags().GetDuration("auth-rotate-window")
+	if !cmd.Flags().Changed("auth-rotate-window") && window > interval {
+		window = interval
+	}
+	return NewCredentialRotator(cmd, interval, mode, window)
+}
+
+// printCredentialRotationResults prints the latency in the window after each rotation next to the baseline
+func printCredentialRotationResults(stats *WorkloadStats) {
+	cr := stats.CredRotation
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+
+	fmt.Printf("Credential Rotation (every %v, %s): %d rotations, %d fetch failures\n",
+		cr.Interval, cr.Mode, len(cr.rotations), atomic.LoadInt64(&cr.FetchFailed))
+	if cr.Mode == "reauth" {
+		fmt.Printf("Connections re-authenticated: %d, workers failed: %d\n", atomic.LoadInt64(&cr.Reauthed), atomic.LoadInt64(&cr.ApplyFailed))
+	} else {
+		fmt.Printf("Clients recycled: %d, workers failed: %d\n", atomic.LoadInt64(&cr.Recycled), atomic.LoadInt64(&cr.ApplyFailed))
+	}
+	if len(cr.rotations) == 0 {
+		fmt.Println("No rotation happened during the run")
+		fmt.Println()
+		return
+	}
+
+	fmt.Printf("%-10s %-10s %-10s %-10s %-8s %-10s %-10s %-10s\n", "Rotation", "Time", "Fetch ms", "Ops", "Errors", "P50", "P99", "Max")
+	fmt.Printf("%-10s %-10s %-10s %-10d %-8d %-10d %-10d %-10d\n", "baseline", "-", "-",
+		cr.baselineOps, cr.baselineErrs, cr.baseline.ValueAtQuantile(50), cr.baseline.ValueAtQuantile(99), cr.baseline.Max())
+	for i, rotation := range cr.rotations {
+		fmt.Printf("%-10d %-10s %-10.1f %-10d %-8d %-10d %-10d %-10d",
+			i+1, rotation.at.Format("15:04:05"), float64(rotation.fetch.Microseconds())/1000,
+			rotation.ops, rotation.errors, rotation.histogram.ValueAtQuantile(50),
+			rotation.histogram.ValueAtQuantile(99), rotation.histogram.Max())
+		if p99 := cr.baseline.ValueAtQuantile(99); p99 > 0 && rotation.ops > 0 {
+			fmt.Printf(" (P99 %+.0f%%)", (float64(rotation.histogram.ValueAtQuantile(99))/float64(p99)-1)*100)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("(latencies in μs during the %v after each rotation)\n", cr.Window)
+	fmt.Println()
+}
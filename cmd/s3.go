@@ -0,0 +1,158 @@
+//go:build !noaws
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RegisterBackend(&Backend{
+		Name: "s3",
+		AddFlags: func(cmd *cobra.Command) {
+			cmd.Flags().String("s3-bucket", "", "S3 bucket (or S3 Express directory bucket, name--azid--x-s3) to use as target (cache-type s3)")
+			cmd.Flags().String("s3-object-prefix", "", "Object key prefix prepended to every key in the S3 bucket")
+		},
+		New: func(ctx context.Context, cmd *cobra.Command) (CacheClient, error) {
+			region, _ := cmd.Flags().GetString("aws-region")
+			bucket, _ := cmd.Flags().GetString("s3-bucket")
+			objectPrefix, _ := cmd.Flags().GetString("s3-object-prefix")
+
+			client, err := NewS3Client(ctx, region, awsRoleARN(cmd, ""), bucket, objectPrefix)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create S3 client: %w", err)
+			}
+			return client, nil
+		},
+		Permissions: func(cmd *cobra.Command) []AWSPermission {
+			bucket, _ := cmd.Flags().GetString("s3-bucket")
+			objectPrefix, _ := cmd.Flags().GetString("s3-object-prefix")
+			if bucket == "" {
+				return nil
+			}
+
+			// Directory buckets authorize every object operation through a session
+			if strings.HasSuffix(bucket, "--x-s3") {
+				return []AWSPermission{
+					{Action: "s3express:CreateSession", Resource: "arn:{partition}:s3express:{region}:{account}:bucket/" + bucket, Reason: "directory bucket sessions"},
+				}
+			}
+			objects := "arn:{partition}:s3:::" + bucket + "/" + objectPrefix + "*"
+			return []AWSPermission{
+				{Action: "s3:ListBucket", Resource: "arn:{partition}:s3:::" + bucket, Reason: "connectivity check (HeadBucket)"},
+				{Action: "s3:GetObject", Resource: objects, Reason: "GET operations"},
+				{Action: "s3:PutObject", Resource: objects, Reason: "SET operations"},
+			}
+		},
+	})
+}
+
+// S3Client implements CacheClient on top of S3 objects, one object per key.
+// Directory buckets (S3 Express One Zone, names ending in "--x-s3") are supported
+// transparently; the SDK handles the session-based authentication they require.
+type S3Client struct {
+	client    *s3.Client
+	bucket    string
+	keyPrefix string
+	isExpress bool
+}
+
+func NewS3Client(ctx context.Context, region, roleARN, bucket, keyPrefix string) (*S3Client, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("S3 bucket name is required")
+	}
+
+	cfg, err := loadAWSConfig(ctx, region, roleARN)
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3Client{
+		client:    s3.NewFromConfig(cfg),
+		bucket:    bucket,
+		keyPrefix: keyPrefix,
+		isExpress: strings.HasSuffix(bucket, "--x-s3"),
+	}, nil
+}
+
+func (s *S3Client) objectKey(key string) string {
+	return s.keyPrefix + key
+}
+
+func (s *S3Client) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	input := &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(s.objectKey(key)),
+		Body:          bytes.NewReader(value),
+		ContentLength: aws.Int64(int64(len(value))),
+	}
+
+	// S3 has no per-object TTL; the Expires header is only advisory metadata, and
+	// lifecycle rules on the bucket are responsible for actually removing objects
+	if expiration > 0 {
+		input.Expires = aws.Time(time.Now().Add(expiration))
+	}
+
+	_, err := s.client.PutObject(ctx, input)
+	return err
+}
+
+func (s *S3Client) Get(ctx context.Context, key string) ([]byte, error) {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrCacheMiss
+		}
+		return nil, err
+	}
+	defer output.Body.Close()
+
+	// Reading the full body is part of the operation latency, same as a cache GET
+	return io.ReadAll(output.Body)
+}
+
+func (s *S3Client) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	return err
+}
+
+// Connect opens a keep-alive connection to the bucket's endpoint with a HeadBucket
+func (s *S3Client) Connect(ctx context.Context) error {
+	return s.Ping(ctx)
+}
+
+func (s *S3Client) Ping(ctx context.Context) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(s.bucket),
+	})
+	return err
+}
+
+func (s *S3Client) Close() error {
+	return nil
+}
+
+func (s *S3Client) Name() string {
+	if s.isExpress {
+		return "S3 Express One Zone"
+	}
+	return "S3"
+}
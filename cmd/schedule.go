@@ -0,0 +1,364 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// cronSchedule is a parsed five-field cron expression; every field is a bit set of the
+// values it matches
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool // Field was *, see matches
+}
+
+// cronMacros are the shorthand schedules accepted in place of five fields
+var cronMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// parseCron parses "minute hour day-of-month month day-of-week" with *, lists, ranges and
+// steps (e.g. "*/15 2-4 * * 1,3,5"); day-of-week 0 and 7 are both Sunday
+func parseCron(expr string) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[expr]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule '%s': expected 5 fields (minute hour day-of-month month day-of-week)", expr)
+	}
+
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule '%s': %w", expr, err)
+		}
+		sets[i] = set
+	}
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1 // Sunday
+	}
+	return &cronSchedule{
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+		domAny: fields[2] == "*", dowAny: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField parses one comma-separated field into the set of values it matches
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in '%s'", part)
+			}
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			lowStr, highStr, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(lowStr); err != nil {
+				return 0, fmt.Errorf("invalid value in '%s'", part)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highStr); err != nil {
+					return 0, fmt.Errorf("invalid value in '%s'", part)
+				}
+			} else if hasStep {
+				high = max // "5/10" runs from 5 to the end of the range
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("'%s' is out of range %d-%d", part, min, max)
+		}
+		for v := low; v <= high; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// matches reports whether the schedule fires at the minute of t. As in cron, when both
+// day fields are restricted a day matching either one is enough.
+func (c *cronSchedule) matches(t time.Time) bool {
+	if c.minute&(1<<t.Minute()) == 0 || c.hour&(1<<t.Hour()) == 0 || c.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	domMatch := c.dom&(1<<t.Day()) != 0
+	dowMatch := c.dow&(1<<int(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Next returns the first time after t the schedule fires, or the zero time when it never
+// does within a year (e.g. February 30th)
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	for end := next.AddDate(1, 0, 1); next.Before(end); next = next.Add(time.Minute) {
+		if c.matches(next) {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// loadScheduleConfig reads the run arguments of a scheduled benchmark from a YAML file of
+// run flags, one "flag: value" per line:
+//
+//	cache-type: redis
+//	redis-uri: rediss://my-cache:6379
+//	test-time: 300
+func loadScheduleConfig(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule config: %w", err)
+	}
+	defer file.Close()
+
+	var args []string
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+
+		name, value, found := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		if !found || name == "" || value == "" {
+			return nil, fmt.Errorf("%s:%d: expected 'flag: value'", path, lineNumber)
+		}
+		if runCmd.Flags().Lookup(name) == nil {
+			return nil, fmt.Errorf("%s:%d: unknown run flag '%s'", path, lineNumber, name)
+		}
+		args = append(args, "--"+name+"="+unquoteScheduleValue(value))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schedule config: %w", err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("schedule config %s sets no run flags", path)
+	}
+	return args, nil
+}
+
+// unquoteScheduleValue strips the quotes of a quoted YAML scalar
+func unquoteScheduleValue(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// scheduleCmd represents the schedule command
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule <cron-expression>",
+	Short: "Run a benchmark on a recurring schedule, archiving and comparing the results",
+	Long: `Run as a daemon that executes the same benchmark on a cron schedule, for continuous cache
+performance monitoring. Every result is archived as a run summary in --archive-dir and
+compared with the previous one (also across restarts, from the archive); changes beyond
+--regression-threshold in throughput or latency are reported as regressions.
+
+The schedule is a five-field cron expression (minute hour day-of-month month day-of-week)
+in local time, or one of @hourly, @daily, @weekly and @monthly. The benchmark is described
+by a YAML file of run flags, read again before every run so edits apply without a restart:
+
+  cache-type: redis
+  redis-uri: rediss://my-cache.serverless.use1.cache.amazonaws.com:6379
+  cluster-mode: true
+  test-time: 300
+  notify-webhook: https://hooks.slack.com/services/...
+
+A run that fails (invalid flags in the config, a failing --pre-hook, an AWS preflight or
+Secrets Manager error) is logged and skipped, and the schedule waits for the next one.
+
+Examples:
+  # Nightly benchmark at 3am
+  serverless-cache-benchmark schedule "0 3 * * *" --config nightly.yaml
+
+  # Every 6 hours, starting with a run right away
+  serverless-cache-benchmark schedule "0 */6 * * *" --config nightly.yaml --run-now --archive-dir /var/lib/cache-bench`,
+	Args: cobra.ExactArgs(1),
+	Run:  runSchedule,
+}
+
+func runSchedule(cmd *cobra.Command, args []string) {
+	configPath, _ := cmd.Flags().GetString("config")
+	archiveDir, _ := cmd.Flags().GetString("archive-dir")
+	threshold, _ := cmd.Flags().GetFloat64("regression-threshold")
+	runNow, _ := cmd.Flags().GetBool("run-now")
+
+	schedule, err := parseCron(args[0])
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if configPath == "" {
+		log.Fatalf("Schedule config is required (--config)")
+	}
+	if _, err := loadScheduleConfig(configPath); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		log.Fatalf("Failed to create archive directory: %v", err)
+	}
+
+	previousName, previous := latestArchivedSummary(archiveDir)
+	if previous != nil {
+		fmt.Printf("Previous result: %s\n", previousName)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	fmt.Printf("Benchmark schedule '%s' with %s, archiving to %s\n", args[0], configPath, archiveDir)
+	for {
+		if !runNow {
+			next := schedule.Next(time.Now())
+			if next.IsZero() {
+				log.Fatalf("Schedule '%s' never fires", args[0])
+			}
+			fmt.Printf("Next run: %s\n", next.Format(time.RFC3339))
+			select {
+			case <-time.After(time.Until(next)):
+			case <-sigChan:
+				fmt.Println("Received interrupt signal. Stopping schedule.")
+				return
+			}
+		}
+		runNow = false
+
+		name, summary := runScheduledBenchmark(configPath, archiveDir)
+		if summary == nil {
+			continue
+		}
+		if previous != nil {
+			warnWorkloadDrift(previous, summary)
+			printComparison(previousName, previous, name, summary)
+			printRegressions(previous, summary, threshold)
+		}
+		previousName, previous = name, summary
+	}
+}
+
+// runScheduledBenchmark runs the configured benchmark once and archives its summary,
+// returning the archive name; the summary is nil when the run failed
+func runScheduledBenchmark(configPath, archiveDir string) (string, *RunSummary) {
+	runArgs, err := loadScheduleConfig(configPath)
+	if err != nil {
+		log.Printf("Skipping scheduled run: %v", err)
+		return "", nil
+	}
+
+	fmt.Printf("\nScheduled run at %s: %s\n", time.Now().Format(time.RFC3339), strings.Join(runArgs, " "))
+	summary, err := RunWithArgs(runArgs)
+	if err != nil {
+		log.Printf("Scheduled run failed, skipping it: %v", err)
+		return "", nil
+	}
+	if summary == nil {
+		log.Printf("Scheduled run did not produce a summary")
+		return "", nil
+	}
+
+	path := filepath.Join(archiveDir, summary.StartTime.Format("20060102-150405")+".json")
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err == nil {
+		err = os.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		log.Printf("Failed to archive result: %v", err)
+	} else {
+		fmt.Printf("Result archived to: %s\n", path)
+	}
+	return path, summary
+}
+
+// latestArchivedSummary returns the most recent summary of the archive, if any; archive
+// names sort in time order
+func latestArchivedSummary(archiveDir string) (string, *RunSummary) {
+	paths, _ := filepath.Glob(filepath.Join(archiveDir, "*.json"))
+	sort.Strings(paths)
+	for i := len(paths) - 1; i >= 0; i-- {
+		if summary, notes, err := loadRunSummary(paths[i]); err == nil {
+			printMigrationNotes(paths[i], notes)
+			return paths[i], summary
+		}
+	}
+	return "", nil
+}
+
+// printRegressions reports the throughput drops and latency increases of a run over its
+// predecessor beyond the threshold (in percent)
+func printRegressions(previous, current *RunSummary, threshold float64) {
+	var regressions []string
+	for _, op := range []struct {
+		name              string
+		previous, current LatencySummary
+	}{
+		{"GET", previous.Get, current.Get},
+		{"SET", previous.Set, current.Set},
+	} {
+		if op.previous.Ops == 0 || op.current.Ops == 0 {
+			continue
+		}
+		if change := (op.current.QPS - op.previous.QPS) / op.previous.QPS * 100; change < -threshold {
+			regressions = append(regressions, fmt.Sprintf("%s QPS %.1f%%", op.name, change))
+		}
+		for _, p := range []struct {
+			name              string
+			previous, current int64
+		}{
+			{"P50", op.previous.P50, op.current.P50},
+			{"P99", op.previous.P99, op.current.P99},
+		} {
+			if p.previous == 0 {
+				continue
+			}
+			if change := float64(p.current-p.previous) / float64(p.previous) * 100; change > threshold {
+				regressions = append(regressions, fmt.Sprintf("%s %s +%.1f%%", op.name, p.name, change))
+			}
+		}
+	}
+
+	if len(regressions) == 0 {
+		fmt.Printf("No regressions over %.1f%% vs the previous run\n", threshold)
+		return
+	}
+	fmt.Printf("REGRESSION vs the previous run (threshold %.1f%%): %s\n", threshold, strings.Join(regressions, ", "))
+}
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+
+	scheduleCmd.Flags().String("config", "", "YAML file of run flags describing the benchmark (required)")
+	scheduleCmd.Flags().String("archive-dir", "./benchmark-archive", "Directory the run summaries are archived to")
+	scheduleCmd.Flags().Float64("regression-threshold", 10, "Report throughput drops and P50/P99 increases over this percentage vs the previous run")
+	scheduleCmd.Flags().Bool("run-now", false, "Run once right away before following the schedule")
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// summarySchemaVersion is the version of the JSON run summary written by this build.
+// Readers of archived results (compare, schedule) migrate older versions step by step:
+//
+//	1  --output json before the run phases: duration_seconds and the QPS cover the whole
+//	   wall time of the run, setup and warmup included
+//	2  duration_seconds is the measurement window (the "phases" breakdown was added);
+//	   the per-operation "percentiles" list appeared later in this version
+//	3  schema_version is written explicitly
+const summarySchemaVersion = 3
+
+// summaryMigrations upgrade a summary document from version i+1 to i+2, returning notes on
+// what can't be reconstructed
+var summaryMigrations = []func(doc map[string]any) []string{
+	migrateSummaryV1,
+	migrateSummaryV2,
+}
+
+// summarySchemaOf returns the schema version of a summary document; unversioned documents
+// are told apart by the fields they have
+func summarySchemaOf(doc map[string]any) (int, error) {
+	if value, ok := doc["schema_version"]; ok {
+		version, ok := value.(float64)
+		if !ok || version < 1 || version != float64(int(version)) {
+			return 0, fmt.Errorf("invalid schema_version %v", value)
+		}
+		return int(version), nil
+	}
+	if _, ok := doc["phases"]; ok {
+		return 2, nil
+	}
+	return 1, nil
+}
+
+// migrateSummaryV1 can't recover the measurement window of a version 1 summary, only flag it
+func migrateSummaryV1(doc map[string]any) []string {
+	return []string{"schema v1: QPS is over the whole wall time of the run (setup and warmup included) and understated next to newer results"}
+}
+
+// migrateSummaryV2 fills the percentiles list of summaries written before --percentiles from
+// the fixed percentile fields
+func migrateSummaryV2(doc map[string]any) []string {
+	fixed := map[string]float64{
+		"p50_us": 50, "p75_us": 75, "p90_us": 90, "p95_us": 95, "p99_us": 99, "p99_9_us": 99.9, "p99_99_us": 99.99,
+	}
+	for _, name := range []string{"get", "set", "setup"} {
+		op, ok := doc[name].(map[string]any)
+		if !ok {
+			continue
+		}
+		if _, ok := op["percentiles"]; ok {
+			continue
+		}
+		var percentiles []PercentileValue
+		for field, p := range fixed {
+			if value, ok := op[field].(float64); ok {
+				percentiles = append(percentiles, PercentileValue{Percentile: p, Value: int64(value)})
+			}
+		}
+		sort.Slice(percentiles, func(i, j int) bool { return percentiles[i].Percentile < percentiles[j].Percentile })
+		op["percentiles"] = percentiles
+	}
+	return nil
+}
+
+// decodeRunSummary decodes a JSON run summary of any supported schema version, migrating it
+// to the current one; the notes describe what the migration couldn't reconstruct
+func decodeRunSummary(data []byte) (*RunSummary, []string, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+	version, err := summarySchemaOf(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version > summarySchemaVersion {
+		return nil, nil, fmt.Errorf("schema v%d is newer than this build reads (up to v%d); upgrade serverless-cache-benchmark",
+			version, summarySchemaVersion)
+	}
+
+	var notes []string
+	for v := version; v < summarySchemaVersion; v++ {
+		notes = append(notes, summaryMigrations[v-1](doc)...)
+	}
+	doc["schema_version"] = summarySchemaVersion
+	if version < summarySchemaVersion {
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var summary RunSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, nil, err
+	}
+	return &summary, notes, nil
+}
+
+// printMigrationNotes prints the caveats of a summary migrated from an older schema version
+func printMigrationNotes(name string, notes []string) {
+	for _, note := range notes {
+		fmt.Printf("Note: %s: %s\n", name, note)
+	}
+}
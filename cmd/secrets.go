@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{populateCmd, runCmd, replicationLagCmd, bandwidthCmd, tlsMatrixCmd} {
+		cmd.Flags().String("password-from", "", "Read the cache password (Momento: API key) from secretsmanager:<secret-id>, ssm:<parameter>, env:<VAR>, file:<path> or an ElastiCache IAM token (elasticache-iam:<user>@<cache>[,serverless]) instead of the URI/flags")
+	}
+
+	RegisterAWSPermissions(func(cmd *cobra.Command) []AWSPermission {
+		source, _ := cmd.Flags().GetString("password-from")
+		kind, id, _ := strings.Cut(source, ":")
+		switch kind {
+		case "secretsmanager":
+			resource := id
+			if !strings.HasPrefix(id, "arn:") {
+				// Secret ARNs end in a random 6-character suffix
+				resource = "arn:{partition}:secretsmanager:{region}:{account}:secret:" + id + "-??????"
+			}
+			return []AWSPermission{{Action: "secretsmanager:GetSecretValue", Resource: resource, Reason: "reading the cache password"}}
+		case "ssm":
+			resource := id
+			if !strings.HasPrefix(id, "arn:") {
+				resource = "arn:{partition}:ssm:{region}:{account}:parameter/" + strings.TrimPrefix(id, "/")
+			}
+			return []AWSPermission{{Action: "ssm:GetParameter", Resource: resource, Reason: "reading the cache password"}}
+		case "elasticache-iam":
+			target, serverless := strings.CutSuffix(id, ",serverless")
+			user, cache, _ := strings.Cut(target, "@")
+			resourceType := "replicationgroup"
+			if serverless {
+				resourceType = "serverlesscache"
+			}
+			prefix := "arn:{partition}:elasticache:{region}:{account}:"
+			return []AWSPermission{
+				{Action: "elasticache:Connect", Resource: prefix + resourceType + ":" + cache, Reason: "IAM authentication to the cache"},
+				{Action: "elasticache:Connect", Resource: prefix + "user:" + user, Reason: "IAM authentication as the cache user"},
+			}
+		}
+		return nil
+	})
+}
+
+// Secrets are resolved once per source and shared by all clients of a run
+var (
+	secretCache      = make(map[string]string)
+	secretCacheMutex sync.Mutex
+)
+
+// resolvePasswordFrom returns the password selected with --password-from; empty when not set
+func resolvePasswordFrom(cmd *cobra.Command) (string, error) {
+	return readPasswordFrom(cmd, false)
+}
+
+// refreshPasswordFrom reads the --password-from secret again, e.g. after a rotation, so
+// clients created from then on use the new password
+func refreshPasswordFrom(cmd *cobra.Command) (string, error) {
+	return readPasswordFrom(cmd, true)
+}
+
+func readPasswordFrom(cmd *cobra.Command, refresh bool) (string, error) {
+	source, _ := cmd.Flags().GetString("password-from")
+	if source == "" {
+		return "", nil
+	}
+
+	secretCacheMutex.Lock()
+	defer secretCacheMutex.Unlock()
+	if password, ok := secretCache[source]; ok && !refresh {
+		return password, nil
+	}
+
+	region, _ := cmd.Flags().GetString("aws-region")
+	password, err := fetchSecret(source, region, awsRoleARN(cmd, ""))
+	if err != nil {
+		return "", fmt.Errorf("failed to read password from %s: %w", source, err)
+	}
+	secretCache[source] = password
+	return password, nil
+}
+
+// fetchSecret reads a secret from a source of the form kind:id
+func fetchSecret(source, region, roleARN string) (string, error) {
+	kind, id, ok := strings.Cut(source, ":")
+	if !ok || id == "" {
+		return "", fmt.Errorf("expected secretsmanager:<secret-id>, ssm:<parameter>, env:<VAR>, file:<path> or elasticache-iam:<user>@<cache>")
+	}
+
+	switch kind {
+	case "env":
+		value, ok := os.LookupEnv(id)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", id)
+		}
+		return value, nil
+
+	case "file":
+		data, err := os.ReadFile(id)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+
+	case "secretsmanager", "ssm", "elasticache-iam":
+		return fetchAWSSecret(kind, id, region, roleARN)
+	}
+	return "", fmt.Errorf("unknown secret source '%s'", kind)
+}
+
+// secretPassword extracts the password of a secret: either the plain secret string, or the
+// "password" field of a JSON secret (the format of RDS/ElastiCache managed credentials)
+func secretPassword(secret string) (string, error) {
+	if !strings.HasPrefix(strings.TrimSpace(secret), "{") {
+		return secret, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("invalid JSON secret: %w", err)
+	}
+	password, ok := fields["password"].(string)
+	if !ok {
+		return "", fmt.Errorf("JSON secret has no \"password\" field")
+	}
+	return password, nil
+}
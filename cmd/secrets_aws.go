@@ -0,0 +1,101 @@
+//go:build !noaws
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// secretTimeout bounds a single secret lookup
+const secretTimeout = 15 * time.Second
+
+// fetchAWSSecret reads a Secrets Manager secret or an SSM SecureString parameter, or
+// generates an ElastiCache IAM authentication token
+func fetchAWSSecret(kind, id, region, roleARN string) (string, error) {
+	// A full ARN names its region, which then doesn't need to be configured
+	if parsed, err := arn.Parse(id); err == nil && region == "" {
+		region = parsed.Region
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+	defer cancel()
+	cfg, err := loadAWSConfig(ctx, region, roleARN)
+	if err != nil {
+		return "", err
+	}
+
+	if kind == "elasticache-iam" {
+		return elastiCacheIAMToken(ctx, cfg, id)
+	}
+
+	if kind == "ssm" {
+		output, err := ssm.NewFromConfig(cfg).GetParameter(ctx, &ssm.GetParameterInput{
+			Name:           aws.String(id),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return "", err
+		}
+		return aws.ToString(output.Parameter.Value), nil
+	}
+
+	output, err := secretsmanager.NewFromConfig(cfg).GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(id),
+	})
+	if err != nil {
+		return "", err
+	}
+	if output.SecretString == nil {
+		return "", fmt.Errorf("secret has no string value")
+	}
+	return secretPassword(*output.SecretString)
+}
+
+// elastiCacheIAMTokenLifetime is how long an IAM authentication token is accepted for new
+// connections; authenticated connections stay valid for up to 12 hours
+const elastiCacheIAMTokenLifetime = 15 * time.Minute
+
+// emptyPayloadHash is the SHA-256 of an empty body, as signed into presigned requests
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// elastiCacheIAMToken generates the IAM authentication token of an ElastiCache user: a
+// SigV4-presigned connect request for <user>@<cache>[,serverless], used as the password
+func elastiCacheIAMToken(ctx context.Context, cfg aws.Config, id string) (string, error) {
+	target, serverless := strings.CutSuffix(id, ",serverless")
+	user, cache, ok := strings.Cut(target, "@")
+	if !ok || user == "" || cache == "" {
+		return "", fmt.Errorf("expected elasticache-iam:<user>@<cache-name>[,serverless]")
+	}
+
+	query := url.Values{"Action": {"connect"}, "User": {user}}
+	if serverless {
+		query.Set("ResourceType", "ServerlessCache")
+	}
+	query.Set("X-Amz-Expires", strconv.Itoa(int(elastiCacheIAMTokenLifetime.Seconds())))
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+cache+"/?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	credentials, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get AWS credentials: %w", err)
+	}
+	signed, _, err := v4.NewSigner().PresignHTTP(ctx, credentials, request, emptyPayloadHash, "elasticache", cfg.Region, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("failed to sign IAM authentication token: %w", err)
+	}
+	return strings.TrimPrefix(signed, "http://"), nil
+}
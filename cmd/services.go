@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
+)
+
+func init() {
+	runCmd.Flags().String("services", "", "CSV file of logical services sharing the cache, one per line as name,key_prefix,clients,rps,ratio[,keys] (rps 0 = unlimited, keys default to the --key-minimum/--key-maximum range); replaces --clients, --rps, --ratio and --key-prefix, with per-service stats")
+}
+
+// Service is one logical service of a consolidation run, with its own clients, rate,
+// operation mix and key prefix
+type Service struct {
+	*ClientGroup // Results of the service's workers (named after it)
+	KeyPrefix    string
+	Workers      int
+	RPS          int // Across the service's workers (0 = unlimited)
+	Ratio        string
+	SetRatio     int
+	GetRatio     int
+	Keys         int // 0 = the run's key range
+
+	firstWorker int
+}
+
+// ServiceStats models several services sharing one cache: workers are assigned to the
+// services in blocks (the first service's clients get the first worker IDs) and each worker
+// generates its service's traffic, so capacity planners see what every service gets from
+// a consolidated cache and how the noisiest one affects the others.
+type ServiceStats struct {
+	Services []*Service
+}
+
+// LoadServices reads a --services file
+func LoadServices(filename string) (*ServiceStats, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open services file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	ss := &ServiceStats{}
+	names := make(map[string]bool)
+	for lineNum := 1; ; lineNum++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading services line %d: %w", lineNum, err)
+		}
+		if lineNum == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "name") {
+			continue // Header
+		}
+		if len(record) != 5 && len(record) != 6 {
+			return nil, fmt.Errorf("line %d: expected name,key_prefix,clients,rps,ratio[,keys], got %d columns", lineNum, len(record))
+		}
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+
+		service := &Service{KeyPrefix: record[1], Ratio: record[4]}
+		if record[0] == "" || names[record[0]] {
+			return nil, fmt.Errorf("line %d: service names must be unique and non-empty, got '%s'", lineNum, record[0])
+		}
+		names[record[0]] = true
+		if service.Workers, err = strconv.Atoi(record[2]); err != nil || service.Workers <= 0 {
+			return nil, fmt.Errorf("line %d: invalid clients '%s': expected a positive number", lineNum, record[2])
+		}
+		if record[3] != "unlimited" {
+			if service.RPS, err = strconv.Atoi(record[3]); err != nil || service.RPS < 0 {
+				return nil, fmt.Errorf("line %d: invalid rps '%s': expected a non-negative number or unlimited", lineNum, record[3])
+			}
+		}
+		if service.SetRatio, service.GetRatio, err = parseRatio(service.Ratio); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		if service.SetRatio+service.GetRatio == 0 {
+			return nil, fmt.Errorf("line %d: ratio '%s' has no operations", lineNum, service.Ratio)
+		}
+		if len(record) == 6 && record[5] != "" {
+			if service.Keys, err = strconv.Atoi(record[5]); err != nil || service.Keys <= 0 {
+				return nil, fmt.Errorf("line %d: invalid keys '%s': expected a positive number", lineNum, record[5])
+			}
+		}
+
+		service.ClientGroup = NewClientGroup(record[0])
+		service.firstWorker = ss.Clients()
+		ss.Services = append(ss.Services, service)
+	}
+	if len(ss.Services) == 0 {
+		return nil, fmt.Errorf("no services found in %s", filename)
+	}
+	return ss, nil
+}
+
+// Clients returns the number of workers of all services
+func (ss *ServiceStats) Clients() int {
+	clients := 0
+	for _, service := range ss.Services {
+		clients += service.Workers
+	}
+	return clients
+}
+
+// ServiceOf returns the service a worker belongs to
+func (ss *ServiceStats) ServiceOf(workerID int) *Service {
+	for _, service := range ss.Services {
+		if workerID < service.firstWorker+service.Workers {
+			return service
+		}
+	}
+	return ss.Services[len(ss.Services)-1]
+}
+
+// Limiter returns the rate limiter of one of the service's workers (nil when unlimited)
+func (s *Service) Limiter() *rate.Limiter {
+	if s.RPS <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(float64(s.RPS)/float64(s.Workers)), 1)
+}
+
+func (ss *ServiceStats) Close() {
+	for _, service := range ss.Services {
+		service.Close()
+	}
+}
+
+// ServiceSummary is the machine-readable result of one service
+type ServiceSummary struct {
+	Name      string         `json:"name"`
+	KeyPrefix string         `json:"key_prefix"`
+	Clients   int            `json:"clients"`
+	TargetRPS int            `json:"target_rps,omitempty"`
+	Ratio     string         `json:"ratio"`
+	Errors    int64          `json:"errors"`
+	Get       LatencySummary `json:"get"`
+	Set       LatencySummary `json:"set"`
+}
+
+// Summary returns the results per service over the measurement window
+func (ss *ServiceStats) Summary(seconds float64) []ServiceSummary {
+	summaries := make([]ServiceSummary, 0, len(ss.Services))
+	for _, s := range ss.Services {
+		summaries = append(summaries, ServiceSummary{
+			Name:      s.Name,
+			KeyPrefix: s.KeyPrefix,
+			Clients:   s.Workers,
+			TargetRPS: s.RPS,
+			Ratio:     s.Ratio,
+			Errors:    atomic.LoadInt64(&s.Errors),
+			Get:       summarizeTotals(s.GetStats, s.GetStats.Histogram.TotalCount(), 0, seconds),
+			Set:       summarizeTotals(s.SetStats, s.SetStats.Histogram.TotalCount(), 0, seconds),
+		})
+	}
+	return summaries
+}
+
+// validateServices rejects options that can't be split per service
+func validateServices(cmd *cobra.Command) error {
+	if path, _ := cmd.Flags().GetString("services"); path == "" {
+		return nil
+	}
+	for _, name := range []string{"clients", "rps", "ratio", "key-prefix", "traffic-pattern", "rate", "ramp", "step", "sine",
+		"replay-self", "command-mix", "canary", "redis-acl-users", "db", "write-split", "delete-fraction"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s cannot be combined with --services", name)
+		}
+	}
+	return nil
+}
+
+// servicesFromFlags loads the services selected with --services (nil when not set)
+func servicesFromFlags(cmd *cobra.Command) (*ServiceStats, error) {
+	path, _ := cmd.Flags().GetString("services")
+	if path == "" {
+		return nil, nil
+	}
+	return LoadServices(path)
+}
+
+// printServiceResults prints the throughput and latency each service got from the shared cache
+func printServiceResults(stats *WorkloadStats) {
+	seconds := stats.Phases.Measurement().Seconds()
+	fmt.Printf("Services (%d sharing the cache):\n", len(stats.Services.Services))
+	groups := make([]*ClientGroup, 0, len(stats.Services.Services))
+	for _, service := range stats.Services.Services {
+		groups = append(groups, service.ClientGroup)
+	}
+	printClientGroups("Service", groups)
+
+	// The target is in requests, which include the failed ones (GET misses among them)
+	for _, s := range stats.Services.Services {
+		requests, ops := 0.0, 0.0
+		if seconds > 0 {
+			ops = float64(atomic.LoadInt64(&s.Ops)) / seconds
+			requests = ops + float64(atomic.LoadInt64(&s.Errors))/seconds
+		}
+		target := "unlimited"
+		if s.RPS > 0 {
+			target = fmt.Sprintf("%d req/s, %.1f%% achieved", s.RPS, requests/float64(s.RPS)*100)
+		}
+		fmt.Printf("%-16s %.0f req/s, %.0f successful ops/s (target %s), prefix '%s', ratio %s\n",
+			s.Name, requests, ops, target, s.KeyPrefix, s.Ratio)
+	}
+	fmt.Println()
+}
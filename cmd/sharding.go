@@ -0,0 +1,307 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RegisterBackend(&Backend{
+		Name: "sharded",
+		AddFlags: func(cmd *cobra.Command) {
+			cmd.Flags().String("shard-nodes", "", "Comma-separated standalone nodes (redis[s]:// or memcached[s]:// URIs) that cache-type sharded spreads keys across with client-side consistent hashing")
+			cmd.Flags().Int("shard-vnodes", 160, "Virtual nodes per shard node on the hash ring; more even out the key distribution")
+		},
+		New: func(ctx context.Context, cmd *cobra.Command) (CacheClient, error) {
+			return newShardedClientFromFlags(cmd, nil)
+		},
+	})
+}
+
+// hashRingPoint is a virtual node: a position on the ring owned by a node
+type hashRingPoint struct {
+	hash uint64
+	node int
+}
+
+// HashRing maps keys to nodes with consistent hashing (as ketama does for memcached): each
+// node owns the arcs before its virtual nodes, so adding or removing a node only moves
+// the keys of its own arcs
+type HashRing struct {
+	points []hashRingPoint
+	nodes  int
+}
+
+// hashRingKey hashes a key or virtual node name onto the ring; the finalizer spreads the
+// FNV-1a hashes of similar strings (key-1, key-2...) over the whole ring
+func hashRingKey(value string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(value))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// shardRingName is the name a node is placed on the ring under: its host:port, as ketama
+// does, so the credentials in its URI are never hashed and rotating them keeps the ring
+func shardRingName(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return endpoint
+}
+
+// NewHashRing places vnodes virtual nodes per node, named after the nodes' host:port so
+// that the ring doesn't depend on their order
+func NewHashRing(endpoints []string, vnodes int) *HashRing {
+	ring := &HashRing{nodes: len(endpoints)}
+	for node, endpoint := range endpoints {
+		name := shardRingName(endpoint)
+		for i := 0; i < vnodes; i++ {
+			ring.points = append(ring.points, hashRingPoint{hash: hashRingKey(fmt.Sprintf("%s#%d", name, i)), node: node})
+		}
+	}
+	sort.Slice(ring.points, func(i, j int) bool { return ring.points[i].hash < ring.points[j].hash })
+	return ring
+}
+
+// Node returns the node owning a key: the one of the first virtual node at or after its hash
+func (r *HashRing) Node(key string) int {
+	hash := hashRingKey(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i].hash >= hash })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].node
+}
+
+// Shares returns the fraction of the ring each node owns
+func (r *HashRing) Shares() []float64 {
+	shares := make([]float64, r.nodes)
+	if len(r.points) == 1 {
+		shares[r.points[0].node] = 1 // The arc from the only point back to itself is the whole ring
+		return shares
+	}
+	const ringSize = float64(1<<63) * 2
+	for i, point := range r.points {
+		previous := r.points[(i+len(r.points)-1)%len(r.points)].hash
+		shares[point.node] += float64(point.hash-previous) / ringSize // Wraps around for the first point
+	}
+	return shares
+}
+
+// ShardNodeStats are the results of one shard node
+type ShardNodeStats struct {
+	Endpoint string
+	Stats    *PerformanceStats // Successful operations
+	Ops      int64
+	Misses   int64
+	Errors   int64
+}
+
+// ShardStats collects the per-node results of the sharded clients of a run
+type ShardStats struct {
+	Nodes  []*ShardNodeStats
+	Ring   *HashRing
+	VNodes int
+}
+
+func NewShardStats(cmd *cobra.Command) (*ShardStats, error) {
+	endpoints, vnodes, err := shardNodesFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	ss := &ShardStats{Ring: NewHashRing(endpoints, vnodes), VNodes: vnodes}
+	for i, endpoint := range endpoints {
+		stats := NewPerformanceStats()
+		stats.Name = fmt.Sprintf("shard-%d", i)
+		ss.Nodes = append(ss.Nodes, &ShardNodeStats{Endpoint: redactURIs(endpoint), Stats: stats})
+	}
+	return ss, nil
+}
+
+// NewClient creates a worker's sharded client, recording into the per-node stats
+func (ss *ShardStats) NewClient(cmd *cobra.Command) (CacheClient, error) {
+	return newShardedClientFromFlags(cmd, ss)
+}
+
+func (ss *ShardStats) Close() {
+	for _, node := range ss.Nodes {
+		node.Stats.Close()
+	}
+}
+
+// shardNodesFromFlags returns the --shard-nodes endpoints and --shard-vnodes
+func shardNodesFromFlags(cmd *cobra.Command) ([]string, int, error) {
+	list, _ := cmd.Flags().GetString("shard-nodes")
+	vnodes, _ := cmd.Flags().GetInt("shard-vnodes")
+
+	var endpoints []string
+	seen := make(map[string]bool)
+	for _, endpoint := range strings.Split(list, ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint == "" {
+			continue
+		}
+		name := shardRingName(endpoint) // Nodes are placed on the ring by host:port
+		if seen[name] {
+			return nil, 0, fmt.Errorf("shard node %s listed twice", name)
+		}
+		seen[name] = true
+		endpoints = append(endpoints, endpoint)
+	}
+	if len(endpoints) == 0 {
+		return nil, 0, fmt.Errorf("cache-type sharded requires --shard-nodes")
+	}
+	if vnodes <= 0 {
+		return nil, 0, fmt.Errorf("--shard-vnodes must be positive, got: %d", vnodes)
+	}
+	return endpoints, vnodes, nil
+}
+
+// ShardedClient implements CacheClient over several standalone nodes, each key going to
+// the node the hash ring assigns it, like client-side sharding in self-managed fleets
+type ShardedClient struct {
+	ring    *HashRing
+	clients []CacheClient
+	stats   *ShardStats // Per-node results (nil during populate)
+}
+
+// newShardedClientFromFlags connects to every --shard-nodes endpoint
+func newShardedClientFromFlags(cmd *cobra.Command, stats *ShardStats) (*ShardedClient, error) {
+	endpoints, vnodes, err := shardNodesFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	idleConns, _ := cmd.Flags().GetInt("memcached-max-idle-conns")
+
+	client := &ShardedClient{stats: stats}
+	if stats != nil {
+		client.ring = stats.Ring
+	} else {
+		client.ring = NewHashRing(endpoints, vnodes)
+	}
+	for _, endpoint := range endpoints {
+		if strings.HasPrefix(endpoint, "http") {
+			client.Close()
+			return nil, fmt.Errorf("shard node %s: expected a redis[s]:// or memcached[s]:// node", redactURIs(endpoint))
+		}
+		node, err := newEndpointClient(cmd, endpoint, false, idleConns)
+		if err != nil {
+			client.Close()
+			return nil, fmt.Errorf("shard node %s: %w", redactURIs(endpoint), err)
+		}
+		client.clients = append(client.clients, node)
+	}
+	return client, nil
+}
+
+// record adds an operation on a node to its stats
+func (s *ShardedClient) record(node int, start time.Time, err error) {
+	if s.stats == nil {
+		return
+	}
+	ns := s.stats.Nodes[node]
+	switch {
+	case errors.Is(err, ErrCacheMiss):
+		atomic.AddInt64(&ns.Misses, 1)
+	case err != nil:
+		atomic.AddInt64(&ns.Errors, 1)
+		return
+	}
+	atomic.AddInt64(&ns.Ops, 1)
+	ns.Stats.RecordLatency(time.Since(start).Microseconds())
+}
+
+func (s *ShardedClient) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	node := s.ring.Node(key)
+	start := time.Now()
+	err := s.clients[node].Set(ctx, key, value, expiration)
+	s.record(node, start, err)
+	return err
+}
+
+func (s *ShardedClient) Get(ctx context.Context, key string) ([]byte, error) {
+	node := s.ring.Node(key)
+	start := time.Now()
+	value, err := s.clients[node].Get(ctx, key)
+	s.record(node, start, err)
+	return value, err
+}
+
+func (s *ShardedClient) Delete(ctx context.Context, key string) error {
+	node := s.ring.Node(key)
+	start := time.Now()
+	err := s.clients[node].Delete(ctx, key)
+	s.record(node, start, err)
+	return err
+}
+
+// Connect connects to every node
+func (s *ShardedClient) Connect(ctx context.Context) error {
+	for i, client := range s.clients {
+		if err := client.Connect(ctx); err != nil {
+			return fmt.Errorf("shard node %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (s *ShardedClient) Ping(ctx context.Context) error {
+	for i, client := range s.clients {
+		if err := client.Ping(ctx); err != nil {
+			return fmt.Errorf("shard node %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (s *ShardedClient) Close() error {
+	var errs []error
+	for _, client := range s.clients {
+		errs = append(errs, client.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (s *ShardedClient) Name() string {
+	return fmt.Sprintf("Sharded(%d nodes)", len(s.clients))
+}
+
+// printShardResults prints the key distribution and latency of every shard node
+func printShardResults(stats *WorkloadStats) {
+	ss := stats.Shards
+	shares := ss.Ring.Shares()
+
+	var total int64
+	for _, node := range ss.Nodes {
+		total += atomic.LoadInt64(&node.Ops) + atomic.LoadInt64(&node.Errors)
+	}
+
+	fmt.Printf("Shard Nodes (consistent hashing, %d virtual nodes each):\n", ss.VNodes)
+	fmt.Printf("%-5s %-32s %-8s %-8s %-10s %-8s %-8s %-10s %-10s\n", "Node", "Endpoint", "Ring %", "Ops %", "Ops", "Misses", "Errors", "P50", "P99")
+	for i, node := range ss.Nodes {
+		ops, errs := atomic.LoadInt64(&node.Ops), atomic.LoadInt64(&node.Errors)
+		share := 0.0
+		if total > 0 {
+			share = float64(ops+errs) / float64(total) * 100
+		}
+		_, _, _, _, p50, _, p99 := node.Stats.GetStats()
+		fmt.Printf("%-5d %-32s %-8.1f %-8.1f %-10d %-8d %-8d %-10d %-10d\n",
+			i, node.Endpoint, shares[i]*100, share, ops, atomic.LoadInt64(&node.Misses), errs, p50, p99)
+	}
+	fmt.Println("(latencies in μs; ops % is the node's share of the traffic, ring % of the key space)")
+	fmt.Println()
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/DataDog/sketches-go/ddsketch"
+	"github.com/influxdata/tdigest"
+)
+
+// LatencySketch is a quantile sketch recording latencies alongside the HDR histogram.
+// Sketches are only accessed by the stats collector goroutine and read once recording has stopped.
+type LatencySketch interface {
+	Add(latencyMicros, count int64)         // count > 1 for sampled events standing for several operations
+	ValueAtQuantile(quantile float64) int64 // quantile in percent, like hdrhistogram
+	Count() int64
+	Kind() string
+	Encode() (string, error) // Serialized sketch for merging downstream
+}
+
+// Sketch accuracy settings
+const (
+	ddSketchRelativeAccuracy = 0.01
+	tdigestCompression       = 100
+)
+
+// validateSketchKind checks a --sketch value
+func validateSketchKind(kind string) error {
+	switch kind {
+	case "hdr", "ddsketch", "tdigest":
+		return nil
+	}
+	return fmt.Errorf("invalid sketch '%s': expected hdr, ddsketch or tdigest", kind)
+}
+
+// newLatencySketch creates a sketch of the given kind; nil for hdr, which is always recorded
+func newLatencySketch(kind string) (LatencySketch, error) {
+	switch kind {
+	case "hdr":
+		return nil, nil
+	case "ddsketch":
+		sketch, err := ddsketch.NewDefaultDDSketch(ddSketchRelativeAccuracy)
+		if err != nil {
+			return nil, err
+		}
+		return &ddLatencySketch{sketch: sketch}, nil
+	case "tdigest":
+		return &tdigestLatencySketch{digest: tdigest.NewWithCompression(tdigestCompression)}, nil
+	}
+	return nil, validateSketchKind(kind)
+}
+
+// ddLatencySketch records latencies in a DDSketch (relative-error guarantees, cheap merges)
+type ddLatencySketch struct {
+	sketch *ddsketch.DDSketch
+}
+
+func (s *ddLatencySketch) Add(latencyMicros, count int64) {
+	s.sketch.AddWithCount(float64(latencyMicros), float64(count))
+}
+
+func (s *ddLatencySketch) ValueAtQuantile(quantile float64) int64 {
+	value, err := s.sketch.GetValueAtQuantile(quantile / 100)
+	if err != nil {
+		return 0
+	}
+	return int64(value)
+}
+
+func (s *ddLatencySketch) Count() int64 {
+	return int64(s.sketch.GetCount())
+}
+
+func (s *ddLatencySketch) Kind() string {
+	return "ddsketch"
+}
+
+// Encode returns the sketch in the DDSketch protobuf format, base64 encoded
+func (s *ddLatencySketch) Encode() (string, error) {
+	var buf bytes.Buffer
+	s.sketch.EncodeProto(&buf)
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// tdigestLatencySketch records latencies in a t-digest (accurate tails)
+type tdigestLatencySketch struct {
+	digest *tdigest.TDigest
+}
+
+func (s *tdigestLatencySketch) Add(latencyMicros, count int64) {
+	s.digest.Add(float64(latencyMicros), float64(count))
+}
+
+func (s *tdigestLatencySketch) ValueAtQuantile(quantile float64) int64 {
+	return int64(s.digest.Quantile(quantile / 100))
+}
+
+func (s *tdigestLatencySketch) Count() int64 {
+	return int64(s.digest.Count())
+}
+
+func (s *tdigestLatencySketch) Kind() string {
+	return "tdigest"
+}
+
+// Encode returns the t-digest centroids as a JSON array of {"Mean", "Weight"}
+func (s *tdigestLatencySketch) Encode() (string, error) {
+	data, err := json.Marshal(s.digest.Centroids())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// SketchSummary is the machine-readable result of a latency sketch
+type SketchSummary struct {
+	Kind    string `json:"kind"`
+	Count   int64  `json:"count"`
+	P50     int64  `json:"p50_us"`
+	P95     int64  `json:"p95_us"`
+	P99     int64  `json:"p99_us"`
+	P999    int64  `json:"p99_9_us"`
+	Encoded string `json:"encoded"` // ddsketch: base64 protobuf, tdigest: JSON centroids
+
+	Percentiles []PercentileValue `json:"percentiles,omitempty"`
+}
+
+// summarizeSketch builds a SketchSummary; nil when no sketch is recorded
+func summarizeSketch(sketch LatencySketch) *SketchSummary {
+	if sketch == nil || sketch.Count() == 0 {
+		return nil
+	}
+	encoded, err := sketch.Encode()
+	if err != nil {
+		encoded = ""
+	}
+	return &SketchSummary{
+		Kind:    sketch.Kind(),
+		Count:   sketch.Count(),
+		P50:     sketch.ValueAtQuantile(50),
+		P95:     sketch.ValueAtQuantile(95),
+		P99:     sketch.ValueAtQuantile(99),
+		P999:    sketch.ValueAtQuantile(99.9),
+		Encoded: encoded,
+
+		Percentiles: latencyPercentiles(sketch),
+	}
+}
+
+// printSketchLatency prints the sketch percentiles of an operation type
+func printSketchLatency(operation string, sketch LatencySketch) {
+	if sketch == nil || sketch.Count() == 0 {
+		return
+	}
+	fmt.Printf("%s Latency (%s) - %s\n", operation, sketch.Kind(), formatPercentiles(latencyPercentiles(sketch)))
+}
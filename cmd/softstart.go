@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
+)
+
+func init() {
+	runCmd.Flags().Duration("soft-start", 0, "Ramp up to the target rate (--rps, --rate, --ramp, --step, --sine or rate-limited --services) over this period instead of starting at full load, so a serverless cache can scale up first; the ramp is reported apart (0 = off)")
+	runCmd.Flags().Float64("soft-start-from", 0.1, "Fraction of the target rate --soft-start begins with")
+	runCmd.Flags().Float64("soft-start-max-errors", 1, "Error percentage over the last second above which --soft-start holds the rate until the cache catches up (GET misses excluded)")
+}
+
+// softStartTick is how often the soft start re-evaluates the rate
+const softStartTick = 100 * time.Millisecond
+
+// softStartMinRequests is the fewest requests in the last second for the error rate to hold the ramp
+const softStartMinRequests = 20
+
+// SoftStart ramps the rate up at the start of a run. A serverless cache that has been idle
+// is scaled down and throttles a full load until it scales up, which turns the first minutes
+// of a run into an error storm that says nothing about the steady state. The ramp grows
+// the rate geometrically, the way serverless capacity is added (by a factor, not a fixed
+// amount), and holds while the error rate shows the cache hasn't caught up.
+type SoftStart struct {
+	Duration        time.Duration
+	From            float64 // Fraction of the target rate at the start
+	MaxErrorPercent float64
+
+	StartTime time.Time
+	Ramp      *PerformanceStats // Latency of the operations during the ramp, GET and SET together
+	RampOps   int64
+	RampErrs  int64
+
+	factor   uint64 // Current fraction of the target rate, math.Float64bits
+	endTime  int64  // Unix nanoseconds the full rate was reached (0 = still ramping)
+	requests int64  // Requests and failures (misses excluded) over the run, for the error rate
+	failures int64
+	mutex    sync.Mutex
+	held     time.Duration
+	stopTime time.Time
+	limiters []softStartLimiter
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// softStartLimiter is a worker's rate limiter and the rate it ramps to
+type softStartLimiter struct {
+	limiter *rate.Limiter
+	target  rate.Limit
+}
+
+func NewSoftStart(duration time.Duration, from, maxErrorPercent float64) (*SoftStart, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("soft start must be positive, got: %v", duration)
+	}
+	if from <= 0 || from >= 1 {
+		return nil, fmt.Errorf("soft start fraction must be between 0 and 1 (exclusive), got: %g", from)
+	}
+	if maxErrorPercent <= 0 || maxErrorPercent > 100 {
+		return nil, fmt.Errorf("soft start error threshold must be between 0 and 100, got: %g", maxErrorPercent)
+	}
+	ss := &SoftStart{
+		Duration:        duration,
+		From:            from,
+		MaxErrorPercent: maxErrorPercent,
+		Ramp:            NewPerformanceStats(),
+		factor:          math.Float64bits(from),
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+	ss.Ramp.Name = "SOFT_START"
+	return ss, nil
+}
+
+// softStartFromFlags reads --soft-start (nil when not set); hasTarget tells whether the run
+// has a target rate to ramp up to
+func softStartFromFlags(cmd *cobra.Command, hasTarget bool) (*SoftStart, error) {
+	duration, _ := cmd.Flags().GetDuration("soft-start")
+	if duration == 0 {
+		return nil, nil
+	}
+	if !hasTarget {
+		return nil, fmt.Errorf("--soft-start needs a target rate: --rps, --rate, --ramp, --step, --sine or --services with rate-limited services")
+	}
+	for _, name := range []string{"traffic-pattern", "canary"} {
+		if cmd.Flags().Changed(name) {
+			return nil, fmt.Errorf("--%s cannot be combined with --soft-start", name)
+		}
+	}
+	from, _ := cmd.Flags().GetFloat64("soft-start-from")
+	maxErrors, _ := cmd.Flags().GetFloat64("soft-start-max-errors")
+	return NewSoftStart(duration, from, maxErrors)
+}
+
+// Factor returns the fraction of the target rate to run at
+func (ss *SoftStart) Factor() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&ss.factor))
+}
+
+// Ramping reports whether the full rate hasn't been reached yet
+func (ss *SoftStart) Ramping() bool {
+	return atomic.LoadInt64(&ss.endTime) == 0
+}
+
+// EndTime returns when the full rate was reached (zero while ramping)
+func (ss *SoftStart) EndTime() time.Time {
+	if end := atomic.LoadInt64(&ss.endTime); end != 0 {
+		return time.Unix(0, end)
+	}
+	return time.Time{}
+}
+
+// Register ramps a worker's rate limiter up to its current limit
+func (ss *SoftStart) Register(limiter *rate.Limiter) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	target := limiter.Limit()
+	ss.limiters = append(ss.limiters, softStartLimiter{limiter: limiter, target: target})
+	limiter.SetLimit(target * rate.Limit(ss.Factor()))
+}
+
+// setFactor applies a fraction of the target rate to the registered limiters; the pacer
+// reads it on its own
+func (ss *SoftStart) setFactor(factor float64) {
+	atomic.StoreUint64(&ss.factor, math.Float64bits(factor))
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	for _, l := range ss.limiters {
+		l.limiter.SetLimit(l.target * rate.Limit(factor))
+	}
+}
+
+// Start ramps the rate up in the background until the full rate is reached or Stop
+func (ss *SoftStart) Start() {
+	ss.StartTime = time.Now()
+	go func() {
+		defer close(ss.done)
+		ticker := time.NewTicker(softStartTick)
+		defer ticker.Stop()
+
+		// Requests and failures at each of the last ticks, for the error rate over the last second
+		type sample struct{ requests, failures int64 }
+		history := make([]sample, int(time.Second/softStartTick))
+		var progress float64
+		for i := 0; ; i++ {
+			select {
+			case <-ticker.C:
+			case <-ss.stop:
+				return
+			}
+
+			current := sample{atomic.LoadInt64(&ss.requests), atomic.LoadInt64(&ss.failures)}
+			oldest := history[i%len(history)]
+			history[i%len(history)] = current
+			if requests := current.requests - oldest.requests; requests >= softStartMinRequests &&
+				float64(current.failures-oldest.failures)/float64(requests)*100 > ss.MaxErrorPercent {
+				ss.mutex.Lock()
+				ss.held += softStartTick
+				ss.mutex.Unlock()
+				continue
+			}
+
+			progress += softStartTick.Seconds() / ss.Duration.Seconds()
+			if progress >= 1 {
+				ss.setFactor(1)
+				atomic.StoreInt64(&ss.endTime, time.Now().UnixNano())
+				return
+			}
+			// Geometric: the rate grows by the same factor every tick
+			ss.setFactor(math.Pow(ss.From, 1-progress))
+		}
+	}()
+}
+
+// Stop ends the ramp, leaving the rate where it is
+func (ss *SoftStart) Stop() {
+	ss.stopOnce.Do(func() {
+		ss.stopTime = time.Now()
+		close(ss.stop)
+		if !ss.StartTime.IsZero() {
+			<-ss.done
+		}
+	})
+}
+
+func (ss *SoftStart) Close() {
+	ss.Ramp.Close()
+}
+
+// Record counts an operation towards the error rate and, while ramping, the ramp's stats
+func (ss *SoftStart) Record(result workloadResult) {
+	atomic.AddInt64(&ss.requests, 1)
+	if result.isError && !result.cacheMiss {
+		atomic.AddInt64(&ss.failures, 1)
+	}
+	if !ss.Ramping() {
+		return
+	}
+	if result.isError {
+		atomic.AddInt64(&ss.RampErrs, 1)
+		return
+	}
+	atomic.AddInt64(&ss.RampOps, 1)
+	ss.Ramp.RecordLatency(result.latencyMicros)
+}
+
+// SoftStartSummary is the machine-readable ramp of a run
+type SoftStartSummary struct {
+	Duration        string         `json:"duration"`
+	FromFraction    float64        `json:"from_fraction"`
+	MaxErrorPercent float64        `json:"max_error_percent"`
+	Completed       bool           `json:"completed"`     // The full rate was reached before the run ended
+	End             *time.Time     `json:"end,omitempty"` // When the full rate was reached; windows before it are ramp-up
+	FinalFactor     float64        `json:"final_factor"`  // Fraction of the target rate reached
+	RampSeconds     float64        `json:"ramp_seconds"`  // Until the full rate, or the end of the run
+	HeldSeconds     float64        `json:"held_seconds"`  // Time the ramp held on errors
+	Ramp            LatencySummary `json:"ramp"`          // Operations during the ramp (included in the totals)
+}
+
+// Summary returns the ramp; it must have been stopped
+func (ss *SoftStart) Summary() *SoftStartSummary {
+	ss.mutex.Lock()
+	held := ss.held
+	ss.mutex.Unlock()
+
+	end := ss.EndTime()
+	rampEnd := end
+	if rampEnd.IsZero() {
+		rampEnd = ss.stopTime
+	}
+	var seconds float64
+	if !ss.StartTime.IsZero() && !rampEnd.IsZero() {
+		seconds = rampEnd.Sub(ss.StartTime).Seconds()
+	}
+	summary := &SoftStartSummary{
+		Duration:        ss.Duration.String(),
+		FromFraction:    ss.From,
+		MaxErrorPercent: ss.MaxErrorPercent,
+		Completed:       !end.IsZero(),
+		FinalFactor:     ss.Factor(),
+		RampSeconds:     seconds,
+		HeldSeconds:     held.Seconds(),
+		Ramp:            summarizeTotals(ss.Ramp, atomic.LoadInt64(&ss.RampOps), atomic.LoadInt64(&ss.RampErrs), seconds),
+	}
+	if summary.Completed {
+		summary.End = &end
+	}
+	return summary
+}
+
+// printSoftStartResults prints the ramp and the operations during it apart from the steady state
+func printSoftStartResults(stats *WorkloadStats) {
+	summary := stats.SoftStart.Summary()
+	fmt.Printf("Soft Start: %.0f%% -> 100%% of the target rate over %s (geometric, holding above %.1f%% errors)\n",
+		summary.FromFraction*100, summary.Duration, summary.MaxErrorPercent)
+	if summary.Completed {
+		fmt.Printf("Full rate reached after %.1fs at %s (held %.1fs on errors)\n",
+			summary.RampSeconds, summary.End.Format("15:04:05"), summary.HeldSeconds)
+	} else {
+		fmt.Printf("Full rate not reached: the run ended at %.0f%% of the target rate (held %.1fs on errors)\n",
+			summary.FinalFactor*100, summary.HeldSeconds)
+	}
+	if ramp := summary.Ramp; ramp.Ops+ramp.Errors > 0 {
+		fmt.Printf("During the ramp: %d ops (%.0f ops/s), %d errors - %s\n", ramp.Ops, ramp.QPS, ramp.Errors,
+			formatPercentiles(latencyPercentiles(stats.SoftStart.Ramp.Histogram)))
+		fmt.Println("(the ramp is included in the totals; windows before the full rate are ramp-up)")
+	}
+	fmt.Println()
+}
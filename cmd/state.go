@@ -0,0 +1,343 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	runCmd.Flags().String("save-state", "", "Save the summary and the raw latency histograms (overall, per window and, when pacing, from the intended start per load shaping phase) to this compressed file at the end of the run, to regenerate reports later with the report command")
+}
+
+// runStateVersion is the version of the --save-state file format
+const runStateVersion = 1
+
+// RunState is everything needed to rebuild the reports of a run: its summary and the
+// histograms the latency figures were computed from. Reports can then be regenerated in
+// any format, or with other percentiles, without re-running the benchmark.
+type RunState struct {
+	Version int                    `json:"version"`
+	Summary json.RawMessage        `json:"summary"`          // RunSummary, migrated on load
+	Stats   map[string]*StatsState `json:"stats"`            // By operation: get, set, setup, negative_get
+	Pacing  *PacingState           `json:"pacing,omitempty"` // Load shaping schedule (--rate, --ramp...)
+}
+
+// StatsState is the histogram state of one PerformanceStats, V2-compressed HDR encodings
+type StatsState struct {
+	Histogram      string        `json:"histogram"`
+	Windows        []WindowState `json:"windows,omitempty"` // In time order, spilled windows included
+	Overflow       int64         `json:"overflow,omitempty"`
+	OverflowPolicy string        `json:"overflow_policy,omitempty"`
+
+	// Latency from the intended start and its per-phase breakdown (paced runs only)
+	Corrected         string         `json:"corrected,omitempty"`
+	CorrectedOverflow int64          `json:"corrected_overflow,omitempty"`
+	Phases            map[int]string `json:"phases,omitempty"`
+}
+
+// WindowState is the histogram of one metrics window
+type WindowState struct {
+	Start     int64  `json:"start"` // Unix time the window started
+	Histogram string `json:"histogram"`
+}
+
+// encodeHistogram returns the V2-compressed, base64 HDR encoding of a histogram
+func encodeHistogram(hist *hdrhistogram.Histogram) (string, error) {
+	encoded, err := hist.Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// saveStatsState captures the histograms of a PerformanceStats; recording must have stopped
+func saveStatsState(ps *PerformanceStats) (*StatsState, error) {
+	hist, err := encodeHistogram(ps.Histogram)
+	if err != nil {
+		return nil, err
+	}
+	state := &StatsState{Histogram: hist, Overflow: ps.Overflow, OverflowPolicy: ps.overflowPolicy}
+	err = ps.EachWindow(func(window LatencyWindow) error {
+		encoded, err := encodeHistogram(window.Histogram)
+		if err != nil {
+			return err
+		}
+		state.Windows = append(state.Windows, WindowState{Start: window.StartSecond, Histogram: encoded})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ps.CorrectedHistogram != nil {
+		if state.Corrected, err = encodeHistogram(ps.CorrectedHistogram); err != nil {
+			return nil, err
+		}
+		state.CorrectedOverflow = ps.CorrectedOverflow
+		state.Phases = make(map[int]string, len(ps.phaseHistograms))
+		for phase, hist := range ps.phaseHistograms {
+			if state.Phases[phase], err = encodeHistogram(hist); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return state, nil
+}
+
+// restore rebuilds a closed PerformanceStats from the saved histograms
+func (st *StatsState) restore(name string) (*PerformanceStats, error) {
+	ps := NewPerformanceStats()
+	ps.Close()
+	ps.Name = name
+	hist, err := hdrhistogram.Decode([]byte(st.Histogram))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s histogram: %w", name, err)
+	}
+	ps.Histogram = hist
+	ps.Overflow = st.Overflow
+	if st.OverflowPolicy != "" {
+		ps.overflowPolicy = st.OverflowPolicy
+	}
+	for _, window := range st.Windows {
+		hist, err := hdrhistogram.Decode([]byte(window.Histogram))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s window histogram: %w", name, err)
+		}
+		ps.windowedHistograms[window.Start] = hist
+	}
+	if st.Corrected != "" {
+		if ps.CorrectedHistogram, err = hdrhistogram.Decode([]byte(st.Corrected)); err != nil {
+			return nil, fmt.Errorf("invalid %s corrected histogram: %w", name, err)
+		}
+		ps.CorrectedOverflow = st.CorrectedOverflow
+		ps.phaseHistograms = make(map[int]*hdrhistogram.Histogram, len(st.Phases))
+		for phase, encoded := range st.Phases {
+			if ps.phaseHistograms[phase], err = hdrhistogram.Decode([]byte(encoded)); err != nil {
+				return nil, fmt.Errorf("invalid %s phase %d histogram: %w", name, phase, err)
+			}
+		}
+	}
+	return ps, nil
+}
+
+// SaveRunState writes the summary and histograms of a run, gzip-compressed; recording must
+// have stopped, but the stats must not be closed yet
+func SaveRunState(w io.Writer, summary *RunSummary, stats *WorkloadStats) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+	state := RunState{Version: runStateVersion, Summary: data, Stats: make(map[string]*StatsState)}
+	for name, ps := range map[string]*PerformanceStats{
+		"get":          stats.GetStats,
+		"set":          stats.SetStats,
+		"setup":        stats.SetupStats,
+		"negative_get": stats.NegativeGetStats,
+	} {
+		if state.Stats[name], err = saveStatsState(ps); err != nil {
+			return fmt.Errorf("failed to encode %s histograms: %w", name, err)
+		}
+	}
+	if stats.Pacer != nil {
+		testTime := int(summary.DurationSeconds)
+		if summary.Workload != nil {
+			testTime = summary.Workload.TestTime
+		}
+		state.Pacing = newPacingState(stats, testTime)
+	}
+
+	gz := gzip.NewWriter(w)
+	if err := json.NewEncoder(gz).Encode(state); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// LoadRunState reads a run state written by SaveRunState, returning its summary (migrated to
+// the current schema, with the caveats of the migration), the stats rebuilt from the
+// histograms and the load shaping schedule (nil when the run wasn't paced). The stats are
+// closed: they can be read but not recorded to.
+func LoadRunState(r io.Reader) (*RunSummary, *WorkloadStats, *PacingState, []string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("not a run state file: %w", err)
+	}
+	defer gz.Close()
+
+	var state RunState
+	if err := json.NewDecoder(gz).Decode(&state); err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("invalid run state: %w", err)
+	}
+	if state.Version < 1 || state.Version > runStateVersion {
+		return nil, nil, nil, nil, fmt.Errorf("run state v%d is not supported by this build (up to v%d)", state.Version, runStateVersion)
+	}
+	summary, notes, err := decodeRunSummary(state.Summary)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("invalid run summary: %w", err)
+	}
+
+	stats := &WorkloadStats{Phases: NewRunPhases(), Hints: NewRunHints()}
+	for _, s := range []struct {
+		key, name string
+		target    **PerformanceStats
+	}{
+		{"get", "GET", &stats.GetStats},
+		{"set", "SET", &stats.SetStats},
+		{"setup", "SETUP", &stats.SetupStats},
+		{"negative_get", "NEGATIVE_GET", &stats.NegativeGetStats},
+	} {
+		saved := state.Stats[s.key]
+		if saved == nil {
+			return nil, nil, nil, nil, fmt.Errorf("run state has no %s histograms", s.key)
+		}
+		if *s.target, err = saved.restore(s.name); err != nil {
+			return nil, nil, nil, nil, err
+		}
+	}
+	return summary, stats, state.Pacing, notes, nil
+}
+
+// saveRunStateFile writes a --save-state file
+func saveRunStateFile(filename string, summary *RunSummary, stats *WorkloadStats) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create state file: %w", err)
+	}
+	defer file.Close()
+	if err := SaveRunState(file, summary, stats); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return file.Close()
+}
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Regenerate the reports of a run from its saved state",
+	Long: `Regenerate the reports of a run saved with run --save-state, without re-running the
+benchmark: the latency figures are recomputed from the saved histograms, so they can be
+exported in another format (--output json, csv or hdr) or with other percentiles
+(--percentiles) than the run used.
+
+Without --output, the results are printed to the console.
+
+Examples:
+  # Save the state at the end of a run, then export it as an HDR histogram log
+  serverless-cache-benchmark run --cache-type redis --test-time 300 --save-state run.state
+  serverless-cache-benchmark report --from run.state --output hdr --output-file run.hlog
+
+  # Recompute the JSON summary with tail percentiles the run didn't report
+  serverless-cache-benchmark report --from run.state --percentiles 50,99,99.9,99.99 --output json`,
+	Run: runReport,
+}
+
+func runReport(cmd *cobra.Command, args []string) {
+	from, _ := cmd.Flags().GetString("from")
+	outputFormat, _ := cmd.Flags().GetString("output")
+	outputFile, _ := cmd.Flags().GetString("output-file")
+
+	if from == "" {
+		log.Fatalf("A saved run state is required (--from)")
+	}
+	if err := validateOutputFormat(outputFormat); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := percentilesFromFlags(cmd); err != nil {
+		log.Fatalf("Invalid percentiles: %v", err)
+	}
+
+	file, err := os.Open(from)
+	if err != nil {
+		log.Fatalf("Failed to open run state: %v", err)
+	}
+	summary, stats, pacing, notes, err := LoadRunState(file)
+	file.Close()
+	if err != nil {
+		log.Fatalf("Failed to load %s: %v", from, err)
+	}
+	printMigrationNotes(from, notes)
+
+	// Recompute the latency figures from the histograms; the sketches aren't saved, so their
+	// figures are kept from the summary
+	seconds := summary.DurationSeconds
+	for _, op := range []struct {
+		latency *LatencySummary
+		stats   *PerformanceStats
+	}{
+		{&summary.Get, stats.GetStats},
+		{&summary.Set, stats.SetStats},
+	} {
+		sketch := op.latency.Sketch
+		*op.latency = summarizeLatency(op.stats, op.latency.Ops, op.latency.Errors, seconds)
+		op.latency.Sketch = sketch
+	}
+	if summary.Setup != nil {
+		setup := summarizeLatency(stats.SetupStats, summary.Setup.Ops, summary.Setup.Errors, 0)
+		summary.Setup = &setup
+	}
+
+	if outputFormat == "" {
+		printReportSummary(summary, stats, pacing)
+		return
+	}
+	if outputFile == "" {
+		outputFile = defaultOutputFile(outputFormat, summary.StartTime)
+	}
+	if err := writeRunOutput(outputFormat, outputFile, summary, stats); err != nil {
+		log.Fatalf("Failed to export results: %v", err)
+	}
+	fmt.Printf("Results written to: %s\n", outputFile)
+}
+
+// printReportSummary prints the totals and latency of a reloaded run, with the load shaping
+// breakdown when it was paced
+func printReportSummary(summary *RunSummary, stats *WorkloadStats, pacing *PacingState) {
+	fmt.Printf("Run of %s started %s, %.0f seconds measured\n",
+		summary.Engine, summary.StartTime.Format("2006-01-02 15:04:05"), summary.DurationSeconds)
+	if summary.Command != nil {
+		fmt.Printf("Command: %s\n", summary.Command)
+	}
+	fmt.Printf("Total Operations: %d, Errors: %d\n", summary.TotalOps, summary.TotalErrors)
+	for _, op := range []struct {
+		name    string
+		latency *LatencySummary
+		stats   *PerformanceStats
+	}{
+		{"GET", &summary.Get, stats.GetStats},
+		{"SET", &summary.Set, stats.SetStats},
+		{"Setup", summary.Setup, stats.SetupStats},
+	} {
+		if op.latency == nil || op.latency.Ops == 0 {
+			continue
+		}
+		fmt.Printf("%s: %d ops (%.0f ops/s), %d errors - Mean: %.0f μs, %s, Max: %d μs\n",
+			op.name, op.latency.Ops, op.latency.QPS, op.latency.Errors, op.latency.Mean,
+			formatPercentiles(op.latency.Percentiles), op.latency.Max)
+		if corrected := op.stats.GetCorrectedPercentiles(); corrected != nil {
+			fmt.Printf("%s Latency (intended start) - %s\n", op.name, formatPercentiles(corrected))
+		}
+		// Sketches aren't saved: their figures are the ones the run computed
+		if sketch := op.latency.Sketch; sketch != nil && sketch.Count > 0 {
+			fmt.Printf("%s Latency (%s, as run) - %s\n", op.name, sketch.Kind, formatPercentiles(sketch.Percentiles))
+		}
+	}
+	fmt.Println()
+
+	if pacing != nil {
+		printPacingState(pacing, stats)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+
+	reportCmd.Flags().String("from", "", "Run state saved with run --save-state")
+	reportCmd.Flags().String("output", "", "Export the results: json (full summary), csv or hdr (HDR histogram log); default: print them")
+	reportCmd.Flags().String("output-file", "", "File for --output (default: auto-generated filename)")
+}
@@ -1,6 +1,10 @@
 package cmd
 
 import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -9,13 +13,224 @@ import (
 
 const MetricWindowSizeSeconds = 5
 
-// LatencyEvent represents a latency measurement event
+// latencySampleMax caps the adaptive sampling interval of PerformanceStats created while
+// it is set (1 = never sample: events are dropped when the collector falls behind)
+var latencySampleMax int64 = 1024
+
+// latencyMaxMicros is the highest latency the histograms can record (1 minute)
+const latencyMaxMicros = 60 * 1000 * 1000
+
+// latencyOverflowPolicy is how PerformanceStats created while it is set record latencies
+// over latencyMaxMicros: cap (at the maximum) or drop (left out of the histograms). Both
+// count them, as the histograms would otherwise reject them without notice.
+var latencyOverflowPolicy = "cap"
+
+// The collector re-evaluates its sampling interval every sampleCheckEvents events: it
+// doubles the interval while its channel is over half full and halves it once the
+// channel has drained below a sixteenth
+const sampleCheckEvents = 1024
+
+// statsChannelSize is the event buffer of a StatsCollector, split across its goroutines
+// (lowMemChannelSize in low-memory mode): 1M events of 48 bytes for the whole run
+const statsChannelSize = 1000000
+
+// statsCollectorShards caps the collector goroutines of a StatsCollector
+const statsCollectorShards = 4
+
+// LatencyEvent represents a latency measurement event. Collector buffers hold up to
+// statsChannelSize of them, so it is kept to 48 bytes.
 type LatencyEvent struct {
 	LatencyMicros int64
-	Timestamp     time.Time
+	Timestamp     int64 // Unix second of the measurement, the resolution of the windows
+	Count         int64 // Operations the event stands for (1-in-Count sampling); 0 means 1
+
+	// Paced (load-shaped) operations only
+	CorrectedMicros int64 // Latency measured from the intended start time
+	Phase           int32
+	Paced           bool
+
+	isError bool              // A failed operation rather than a latency
+	stats   *PerformanceStats // Stats the event is collected into; nil for a Flush marker
+}
+
+// StatsCollector runs the latency collection of all PerformanceStats created in its scope
+// on a fixed set of collector goroutines, each owning the events of the stats assigned to
+// it, with a single lifecycle: Flush waits for the events recorded so far and Close flushes
+// and stops the goroutines. Stats that are never closed, such as those of an early return,
+// are stopped with their collector rather than leaking a goroutine and a buffer each.
+type StatsCollector struct {
+	shards []*statsShard
+	next   uint64 // Round-robin assignment of new stats to shards
+	closed int32
+	once   sync.Once
+	wg     sync.WaitGroup
+	done   chan struct{} // Closed once the goroutines have stopped
+
+	flushMutex sync.Mutex
+}
+
+// statsShard is one collector goroutine and its event buffer
+type statsShard struct {
+	events chan LatencyEvent
+	stop   chan struct{}
+	acks   chan chan struct{} // Of the pending Flush markers, in order
+}
+
+// statsCollectorScope collects the PerformanceStats created while it is set (nil = the
+// process-wide default collector)
+var statsCollectorScope *StatsCollector
+
+var (
+	defaultStatsCollector     *StatsCollector
+	defaultStatsCollectorOnce sync.Once
+)
+
+// NewStatsCollector starts collector goroutines sharing a buffer of bufferSize events
+func NewStatsCollector(bufferSize int) *StatsCollector {
+	// Two goroutines at least, so the GET and SET stats (created in a row) don't share one
+	shards := min(max(runtime.GOMAXPROCS(0), 2), statsCollectorShards)
+	sc := &StatsCollector{done: make(chan struct{})}
+	for i := 0; i < shards; i++ {
+		shard := &statsShard{
+			events: make(chan LatencyEvent, bufferSize/shards),
+			stop:   make(chan struct{}),
+			acks:   make(chan chan struct{}, 1),
+		}
+		sc.shards = append(sc.shards, shard)
+		sc.wg.Add(1)
+		go sc.run(shard)
+	}
+	return sc
+}
+
+// StartStatsCollector collects all PerformanceStats created from now on until it is closed
+func StartStatsCollector(lowMem bool) *StatsCollector {
+	bufferSize := statsChannelSize
+	if lowMem {
+		bufferSize = lowMemChannelSize
+	}
+	sc := NewStatsCollector(bufferSize)
+	statsCollectorScope = sc
+	return sc
+}
+
+// currentStatsCollector returns the collector of new PerformanceStats
+func currentStatsCollector() *StatsCollector {
+	if sc := statsCollectorScope; sc != nil {
+		return sc
+	}
+	defaultStatsCollectorOnce.Do(func() {
+		bufferSize := statsChannelSize
+		if lowMemStats != nil {
+			bufferSize = lowMemChannelSize
+		}
+		defaultStatsCollector = NewStatsCollector(bufferSize)
+	})
+	return defaultStatsCollector
+}
+
+// assign returns the shard of a new PerformanceStats
+func (sc *StatsCollector) assign() *statsShard {
+	return sc.shards[(atomic.AddUint64(&sc.next, 1)-1)%uint64(len(sc.shards))]
+}
+
+// run collects the events of a shard until Close, then the events queued before it
+func (sc *StatsCollector) run(shard *statsShard) {
+	defer sc.wg.Done()
+	for {
+		select {
+		case event := <-shard.events:
+			shard.collect(event)
+		case <-shard.stop:
+			for {
+				select {
+				case event := <-shard.events:
+					shard.collect(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// collect applies an event to its stats (collector goroutine only)
+func (shard *statsShard) collect(event LatencyEvent) {
+	switch {
+	case event.stats == nil:
+		close(<-shard.acks) // Flush marker
+	case event.isError:
+		// No atomic needed - only this goroutine modifies these counters
+		event.stats.FailedOps++
+		event.stats.TotalOps++
+	default:
+		event.stats.collect(event)
+	}
+}
+
+// Closed reports whether the collector has stopped accepting events
+func (sc *StatsCollector) Closed() bool {
+	return atomic.LoadInt32(&sc.closed) != 0
 }
 
-// PerformanceStats tracks performance metrics with channel-based latency collection
+// Flush waits until the events recorded before the call are in their stats
+func (sc *StatsCollector) Flush() {
+	if sc.Closed() {
+		return
+	}
+	// One Flush at a time, so each shard has at most one marker pending with its ack
+	sc.flushMutex.Lock()
+	defer sc.flushMutex.Unlock()
+	acks := make([]chan struct{}, 0, len(sc.shards))
+	for _, shard := range sc.shards {
+		ack := make(chan struct{})
+		shard.acks <- ack
+		select {
+		case shard.events <- LatencyEvent{}:
+			acks = append(acks, ack)
+		case <-sc.done:
+			return
+		}
+	}
+	for _, ack := range acks {
+		select {
+		case <-ack:
+		case <-sc.done:
+			return
+		}
+	}
+}
+
+// Close collects the events queued so far and stops the collector goroutines; events
+// recorded afterwards are discarded
+func (sc *StatsCollector) Close() {
+	sc.once.Do(func() {
+		atomic.StoreInt32(&sc.closed, 1)
+		if statsCollectorScope == sc {
+			statsCollectorScope = nil
+		}
+		for _, shard := range sc.shards {
+			close(shard.stop)
+		}
+		sc.wg.Wait()
+		close(sc.done)
+	})
+}
+
+// CloseWithin is Close bounded by a timeout, for a crashing run whose collector may never
+// drain; it reports whether the goroutines have stopped, so the stats can be read
+func (sc *StatsCollector) CloseWithin(timeout time.Duration) bool {
+	go sc.Close()
+	select {
+	case <-sc.done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// PerformanceStats tracks performance metrics with channel-based latency collection by
+// the goroutines of its StatsCollector
 type PerformanceStats struct {
 	TotalOps   int64
 	SuccessOps int64
@@ -23,99 +238,325 @@ type PerformanceStats struct {
 	Histogram  *hdrhistogram.Histogram
 	StartTime  time.Time
 
-	// Channel-based latency collection (no locks needed)
-	latencyChannel chan LatencyEvent
-	errorChannel   chan struct{}
-	done           chan struct{}
+	// Measurement window in Unix nanoseconds (0 = not set), accessed atomically. Once
+	// started, GetQPS divides by the window instead of the time since StartTime.
+	measureStart int64
+	measureEnd   int64
+
+	// Name tags windows spilled to disk in low-memory mode
+	Name string
+
+	// Optional quantile sketch recorded alongside the histogram (--sketch); set before recording starts
+	Sketch LatencySketch
+
+	// Coordinated-omission corrected latency (intended start to completion) and its
+	// per-phase breakdown. Only populated by paced runs; created by the collector on
+	// first use and safe to read once recording has stopped.
+	CorrectedHistogram *hdrhistogram.Histogram
+	phaseHistograms    map[int]*hdrhistogram.Histogram
+
+	// Adaptive sampling under overload: recorders send 1 in sampleEvery latency events,
+	// each counted sampleEvery times, rather than dropping whatever arrives while the
+	// channel is full. Set by the collector, read by recorders (atomic).
+	sampleEvery    int64
+	sampleMax      int64
+	sampleCounter  int64
+	MaxSampleEvery int64 // Highest sampling interval used (collector goroutine only)
+	Dropped        int64 // Events dropped with the channel full despite sampling (atomic)
+
+	// Latencies over latencyMaxMicros, handled by overflowPolicy (atomic)
+	Overflow          int64
+	CorrectedOverflow int64
+	overflowPolicy    string
+
+	// Channel-based latency collection (no locks needed): events go to the shard of the
+	// collector the stats were assigned to
+	collector *StatsCollector
+	shard     *statsShard
+	closed    int32 // Set by Close, read by recorders (atomic)
+	collected int   // Events collected (collector goroutine only)
 
 	// Per-second histograms (only accessed by stats goroutine)
 	currentWindowStartSecond int64
 	currentHistogram         *hdrhistogram.Histogram
 	windowedHistograms       map[int64]*hdrhistogram.Histogram
+
+	// Summary of the previous metrics window, published by the collector for the readers
+	// of live stats (progress, CSV, Prometheus, CloudWatch); nil when that window is empty
+	previousWindow atomic.Pointer[windowSnapshot]
+
+	// Low-memory mode: bounded window ring with older windows spilled to disk (nil = unbounded)
+	lowMem  *LowMemStats
+	spilled int // Windows evicted to the spill file (collector goroutine only)
 }
 
 func NewPerformanceStats() *PerformanceStats {
 	// Create histogram with 1 microsecond to 1 minute range, 3 significant digits
-	hist := hdrhistogram.New(1, 60*1000*1000, 3)
+	hist := hdrhistogram.New(1, latencyMaxMicros, 3)
 
+	collector := currentStatsCollector()
 	ps := &PerformanceStats{
 		Histogram:          hist,
 		StartTime:          time.Now(),
 		windowedHistograms: make(map[int64]*hdrhistogram.Histogram),
-		currentHistogram:   hdrhistogram.New(1, 60*1000*1000, 3),
-		latencyChannel:     make(chan LatencyEvent, 1000000), // Buffered channel to prevent blocking
-		errorChannel:       make(chan struct{}, 100),         // Buffered for errors
-		done:               make(chan struct{}),
+		currentHistogram:   hdrhistogram.New(1, latencyMaxMicros, 3),
+		collector:          collector,
+		shard:              collector.assign(),
+		lowMem:             lowMemStats,
+		sampleEvery:        1,
+		sampleMax:          max(latencySampleMax, 1),
+		MaxSampleEvery:     1,
+		overflowPolicy:     latencyOverflowPolicy,
 	}
-
-	// Start the stats collection goroutine
-	go ps.statsCollector()
-
 	return ps
 }
 
-// statsCollector runs in a dedicated goroutine to process latency events without locks
-func (ps *PerformanceStats) statsCollector() {
-	for {
-		select {
-		case event := <-ps.latencyChannel:
-			currentSecond := event.Timestamp.Unix()
+// collect records a latency event (collector goroutine only, so no locks are needed)
+func (ps *PerformanceStats) collect(event LatencyEvent) {
+	currentSecond := event.Timestamp
+	count := max(event.Count, 1)
 
-			// Record in overall histogram (no lock needed, single goroutine)
-			ps.Histogram.RecordValue(event.LatencyMicros)
+	if ps.collected++; ps.collected%sampleCheckEvents == 0 {
+		ps.adaptSampling()
+	}
 
-			// Record in current monitoring window histogram (no lock needed, single goroutine)
-			if currentSecond-ps.currentWindowStartSecond >= MetricWindowSizeSeconds {
-				if ps.currentHistogram.TotalCount() > 0 {
-					ps.windowedHistograms[ps.currentWindowStartSecond] = ps.currentHistogram
-				}
-				ps.currentWindowStartSecond = currentSecond
-				ps.currentHistogram = hdrhistogram.New(1, 60*1000*1000, 3)
+	latency, record := ps.capLatency(event.LatencyMicros, count, &ps.Overflow)
+
+	// Record in overall histogram (no lock needed, single goroutine)
+	if record {
+		ps.Histogram.RecordValues(latency, count)
+		if ps.Sketch != nil {
+			ps.Sketch.Add(latency, count)
+		}
+	}
+
+	// Record in current monitoring window histogram (no lock needed, single goroutine)
+	if currentSecond-ps.currentWindowStartSecond >= MetricWindowSizeSeconds {
+		var previous *windowSnapshot
+		if ps.currentHistogram.TotalCount() > 0 {
+			// The closing window is the previous one only when the next starts right after it
+			if currentSecond-ps.currentWindowStartSecond == MetricWindowSizeSeconds {
+				previous = newWindowSnapshot(ps.currentHistogram)
 			}
-			ps.currentHistogram.RecordValue(event.LatencyMicros)
+			ps.windowedHistograms[ps.currentWindowStartSecond] = ps.currentHistogram
+			if ps.lowMem != nil && len(ps.windowedHistograms) > ps.lowMem.MaxWindows {
+				ps.evictOldestWindow()
+			}
+		}
+		ps.previousWindow.Store(previous)
+		ps.currentWindowStartSecond = currentSecond
+		ps.currentHistogram = hdrhistogram.New(1, latencyMaxMicros, 3)
+	}
+	if record {
+		ps.currentHistogram.RecordValues(latency, count)
+	}
+
+	if event.Paced {
+		ps.recordCorrected(event, count)
+	}
 
-			// No atomic needed - only this goroutine modifies these counters
-			ps.SuccessOps++
-			ps.TotalOps++
+	// No atomic needed - only this goroutine modifies these counters
+	ps.SuccessOps += count
+	ps.TotalOps += count
+}
 
-		case <-ps.errorChannel:
-			// No atomic needed - only this goroutine modifies these counters
-			ps.FailedOps++
-			ps.TotalOps++
+// capLatency applies the overflow policy to a latency, counting it in overflow when over the
+// histogram maximum; it returns the value to record and whether to record it at all
+func (ps *PerformanceStats) capLatency(latencyMicros, count int64, overflow *int64) (int64, bool) {
+	if latencyMicros <= latencyMaxMicros {
+		return latencyMicros, true
+	}
+	atomic.AddInt64(overflow, count)
+	return latencyMaxMicros, ps.overflowPolicy != "drop"
+}
 
-		case <-ps.done:
-			return
+// evictOldestWindow spills the oldest in-memory window to disk (collector goroutine only)
+func (ps *PerformanceStats) evictOldestWindow() {
+	oldest := int64(-1)
+	for start := range ps.windowedHistograms {
+		if oldest == -1 || start < oldest {
+			oldest = start
 		}
 	}
+	ps.lowMem.spill(ps.spillName(), LatencyWindow{StartSecond: oldest, Histogram: ps.windowedHistograms[oldest]})
+	delete(ps.windowedHistograms, oldest)
+	ps.spilled++
 }
 
-// RecordLatency sends a latency event to the stats collector (lock-free)
-func (ps *PerformanceStats) RecordLatency(latencyMicros int64) {
+// spillName tags the windows of the stats in the spill file
+func (ps *PerformanceStats) spillName() string {
+	if ps.Name == "" {
+		return "stats"
+	}
+	return ps.Name
+}
+
+// adaptSampling adjusts the sampling interval to the backlog of the stats' collector
+// goroutine, shared with the other stats it collects (collector goroutine only)
+func (ps *PerformanceStats) adaptSampling() {
+	backlog, capacity := len(ps.shard.events), cap(ps.shard.events)
+	every := atomic.LoadInt64(&ps.sampleEvery)
+	switch {
+	case backlog > capacity/2 && every < ps.sampleMax:
+		every = min(every*2, ps.sampleMax)
+	case backlog < capacity/16 && every > 1:
+		every /= 2
+	default:
+		return
+	}
+	atomic.StoreInt64(&ps.sampleEvery, every)
+	ps.MaxSampleEvery = max(ps.MaxSampleEvery, every)
+}
+
+// sample decides whether to send a latency event and how many operations it stands for.
+// Systematic 1-in-N sampling keeps the latency distribution unbiased, unlike dropping
+// the events that happen to arrive while the channel is full.
+func (ps *PerformanceStats) sample() (int64, bool) {
+	every := atomic.LoadInt64(&ps.sampleEvery)
+	if every <= 1 {
+		return 1, true
+	}
+	return every, atomic.AddInt64(&ps.sampleCounter, 1)%every == 0
+}
+
+// send queues a latency event without blocking; events of closed stats are discarded
+func (ps *PerformanceStats) send(event LatencyEvent) {
+	if atomic.LoadInt32(&ps.closed) != 0 || ps.collector.Closed() {
+		return
+	}
+	event.stats = ps
 	select {
-	case ps.latencyChannel <- LatencyEvent{
-		LatencyMicros: latencyMicros,
-		Timestamp:     time.Now(),
-	}:
+	case ps.shard.events <- event:
 		// Event sent successfully
 	default:
-		// Channel is full, drop the event to prevent blocking
-		// This is acceptable for high-throughput scenarios
+		// Channel is full even with sampling: drop the event to prevent blocking
+		atomic.AddInt64(&ps.Dropped, event.Count)
 	}
 }
 
+// RecordLatency sends a latency event to the stats collector (lock-free)
+func (ps *PerformanceStats) RecordLatency(latencyMicros int64) {
+	count, ok := ps.sample()
+	if !ok {
+		return
+	}
+	ps.send(LatencyEvent{
+		LatencyMicros: latencyMicros,
+		Timestamp:     time.Now().Unix(),
+		Count:         count,
+	})
+}
+
+// RecordPacedLatency records the service time of a paced operation together with its
+// latency measured from the intended start time, attributed to a load shaping phase
+func (ps *PerformanceStats) RecordPacedLatency(latencyMicros, correctedMicros int64, phase int) {
+	count, ok := ps.sample()
+	if !ok {
+		return
+	}
+	ps.send(LatencyEvent{
+		LatencyMicros:   latencyMicros,
+		Timestamp:       time.Now().Unix(),
+		Count:           count,
+		Paced:           true,
+		CorrectedMicros: correctedMicros,
+		Phase:           int32(phase),
+	})
+}
+
+// recordCorrected records a paced event in the corrected and phase histograms (collector goroutine only)
+func (ps *PerformanceStats) recordCorrected(event LatencyEvent, count int64) {
+	if ps.CorrectedHistogram == nil {
+		ps.CorrectedHistogram = hdrhistogram.New(1, latencyMaxMicros, 3)
+		ps.phaseHistograms = make(map[int]*hdrhistogram.Histogram)
+	}
+	corrected, record := ps.capLatency(event.CorrectedMicros, count, &ps.CorrectedOverflow)
+	if !record {
+		return
+	}
+	ps.CorrectedHistogram.RecordValues(corrected, count)
+
+	phaseHist := ps.phaseHistograms[int(event.Phase)]
+	if phaseHist == nil {
+		phaseHist = hdrhistogram.New(1, latencyMaxMicros, 3)
+		ps.phaseHistograms[int(event.Phase)] = phaseHist
+	}
+	phaseHist.RecordValues(corrected, count)
+}
+
+// GetCorrectedStats returns count, P50, P95 and P99 of the corrected latency
+func (ps *PerformanceStats) GetCorrectedStats() (int64, int64, int64, int64) {
+	if ps.CorrectedHistogram == nil || ps.CorrectedHistogram.TotalCount() == 0 {
+		return 0, 0, 0, 0
+	}
+	return ps.CorrectedHistogram.TotalCount(),
+		ps.CorrectedHistogram.ValueAtQuantile(50),
+		ps.CorrectedHistogram.ValueAtQuantile(95),
+		ps.CorrectedHistogram.ValueAtQuantile(99)
+}
+
+// GetCorrectedPercentiles returns the reported percentiles of the corrected latency (nil when not pacing)
+func (ps *PerformanceStats) GetCorrectedPercentiles() []PercentileValue {
+	if ps.CorrectedHistogram == nil || ps.CorrectedHistogram.TotalCount() == 0 {
+		return nil
+	}
+	return latencyPercentiles(ps.CorrectedHistogram)
+}
+
+// Phases returns the load shaping phases that recorded latencies, in order.
+// Only call once recording has stopped.
+func (ps *PerformanceStats) Phases() []int {
+	phases := make([]int, 0, len(ps.phaseHistograms))
+	for phase := range ps.phaseHistograms {
+		phases = append(phases, phase)
+	}
+	sort.Ints(phases)
+	return phases
+}
+
+// GetPhaseStats returns count, P50, P95 and P99 of the corrected latency in one phase.
+// Only call once recording has stopped.
+func (ps *PerformanceStats) GetPhaseStats(phase int) (int64, int64, int64, int64) {
+	hist := ps.phaseHistograms[phase]
+	if hist == nil || hist.TotalCount() == 0 {
+		return 0, 0, 0, 0
+	}
+	return hist.TotalCount(), hist.ValueAtQuantile(50), hist.ValueAtQuantile(95), hist.ValueAtQuantile(99)
+}
+
 // RecordError sends an error event to the stats collector (lock-free)
 func (ps *PerformanceStats) RecordError() {
+	if atomic.LoadInt32(&ps.closed) != 0 || ps.collector.Closed() {
+		return
+	}
 	select {
-	case ps.errorChannel <- struct{}{}:
+	case ps.shard.events <- LatencyEvent{stats: ps, isError: true}:
 		// Error event sent successfully
 	default:
 		// Channel is full, drop the event to prevent blocking
 	}
 }
 
+// StartMeasurement starts the window GetQPS divides by
+func (ps *PerformanceStats) StartMeasurement(t time.Time) {
+	atomic.StoreInt64(&ps.measureStart, t.UnixNano())
+}
+
+// StopMeasurement ends the window GetQPS divides by
+func (ps *PerformanceStats) StopMeasurement(t time.Time) {
+	atomic.StoreInt64(&ps.measureEnd, t.UnixNano())
+}
+
 func (ps *PerformanceStats) GetQPS() float64 {
 	elapsed := time.Since(ps.StartTime).Seconds()
-	if elapsed == 0 {
+	if start := atomic.LoadInt64(&ps.measureStart); start != 0 {
+		end := atomic.LoadInt64(&ps.measureEnd)
+		if end == 0 {
+			end = time.Now().UnixNano()
+		}
+		elapsed = time.Duration(end - start).Seconds()
+	}
+	if elapsed <= 0 {
 		return 0
 	}
 	return float64(atomic.LoadInt64(&ps.TotalOps)) / elapsed
@@ -142,16 +583,77 @@ func (ps *PerformanceStats) GetStats() (int64, int64, int64, float64, int64, int
 // GetPreviousWindowStats returns stats for the previous metrics window. We want to return previous window vs current since
 // current metric window can still be filling up and have stale/incomplete data since were not using locks on these.
 func (ps *PerformanceStats) GetPreviousWindowStats() (int64, int64, int64, int64, int64) {
-	histToUse := ps.windowedHistograms[ps.currentWindowStartSecond-MetricWindowSizeSeconds]
-	if histToUse == nil || histToUse.TotalCount() == 0 {
+	previous := ps.previousWindow.Load()
+	if previous == nil {
 		return 0, 0, 0, 0, 0
 	}
+	return previous.count, previous.p50, previous.p95, previous.p99, previous.max
+}
+
+// GetPreviousWindowPercentiles returns the reported percentiles of the previous metrics window
+// (zeros when it is empty), like GetPreviousWindowStats
+func (ps *PerformanceStats) GetPreviousWindowPercentiles() []PercentileValue {
+	previous := ps.previousWindow.Load()
+	if previous == nil {
+		return latencyPercentiles(emptyQuantiles{})
+	}
+	return previous.percentiles
+}
+
+// windowSnapshot is the summary of a completed metrics window, immutable once published:
+// readers on other goroutines never touch the histograms the collector writes
+type windowSnapshot struct {
+	count, p50, p95, p99, max int64
+	percentiles               []PercentileValue
+}
+
+func newWindowSnapshot(hist *hdrhistogram.Histogram) *windowSnapshot {
+	return &windowSnapshot{
+		count:       hist.TotalCount(),
+		p50:         hist.ValueAtQuantile(50),
+		p95:         hist.ValueAtQuantile(95),
+		p99:         hist.ValueAtQuantile(99),
+		max:         hist.Max(),
+		percentiles: latencyPercentiles(hist),
+	}
+}
+
+// LatencyWindow is the histogram of one metrics window
+type LatencyWindow struct {
+	StartSecond int64 // Unix time the window started
+	Histogram   *hdrhistogram.Histogram
+}
+
+// Windows returns the per-window histograms in time order, including the current window.
+// In low-memory mode only the windows still in memory are returned. Only call once recording has stopped.
+func (ps *PerformanceStats) Windows() []LatencyWindow {
+	windows := make([]LatencyWindow, 0, len(ps.windowedHistograms)+1)
+	for start, hist := range ps.windowedHistograms {
+		windows = append(windows, LatencyWindow{StartSecond: start, Histogram: hist})
+	}
+	if ps.currentHistogram.TotalCount() > 0 {
+		windows = append(windows, LatencyWindow{StartSecond: ps.currentWindowStartSecond, Histogram: ps.currentHistogram})
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].StartSecond < windows[j].StartSecond })
+	return windows
+}
 
-	return histToUse.TotalCount(),
-		histToUse.ValueAtQuantile(50),
-		histToUse.ValueAtQuantile(95),
-		histToUse.ValueAtQuantile(99),
-		histToUse.Max()
+// EachWindow calls fn with every per-window histogram in time order: in low-memory mode,
+// first the windows spilled to disk, read back one at a time, then those in memory. Spilled
+// windows are found by name, so only named stats get theirs back. Only call once recording
+// has stopped.
+func (ps *PerformanceStats) EachWindow(fn func(LatencyWindow) error) error {
+	if ps.lowMem != nil && ps.spilled > 0 && ps.Name != "" {
+		if err := ps.lowMem.readSpilled(ps.Name, fn); err != nil {
+			return err
+		}
+	}
+	for _, window := range ps.Windows() {
+		if err := fn(window); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // GetOverallStats returns overall statistics
@@ -164,7 +666,44 @@ func (ps *PerformanceStats) GetOverallStats() (int64, int64, int64, float64) {
 	return total, success, failed, qps
 }
 
-// Close shuts down the stats collector goroutine
+// Flush waits until the latencies recorded so far are in the histograms; it flushes the
+// whole collector, so all stats sharing it
+func (ps *PerformanceStats) Flush() {
+	ps.collector.Flush()
+}
+
+// Close stops recording and waits for the latencies recorded so far to be collected; the
+// collector goroutines are shared and stop with the StatsCollector. Closing twice is safe.
 func (ps *PerformanceStats) Close() {
-	close(ps.done)
+	if atomic.CompareAndSwapInt32(&ps.closed, 0, 1) {
+		ps.collector.Flush()
+	}
+}
+
+// printLatencySampling reports latency collectors that sampled or dropped events under overload
+func printLatencySampling(stats *WorkloadStats) {
+	for _, ps := range []*PerformanceStats{stats.GetStats, stats.SetStats} {
+		dropped := atomic.LoadInt64(&ps.Dropped)
+		if ps.MaxSampleEvery <= 1 && dropped == 0 {
+			continue
+		}
+		fmt.Printf("%s latency collector overloaded: sampled up to 1 in %d events (counts scaled), %d events dropped\n",
+			ps.Name, ps.MaxSampleEvery, dropped)
+	}
+}
+
+// printLatencyOverflow reports latencies that exceeded the histogram maximum
+func printLatencyOverflow(stats *WorkloadStats) {
+	for _, ps := range []*PerformanceStats{stats.GetStats, stats.SetStats} {
+		overflow, corrected := atomic.LoadInt64(&ps.Overflow), atomic.LoadInt64(&ps.CorrectedOverflow)
+		if overflow == 0 && corrected == 0 {
+			continue
+		}
+		handling := fmt.Sprintf("recorded at %ds: Max and the top percentiles are lower bounds", latencyMaxMicros/1000000)
+		if ps.overflowPolicy == "drop" {
+			handling = "left out of the latency histograms"
+		}
+		fmt.Printf("%s latency over the histogram maximum: %d operations (%d corrected), %s\n",
+			ps.Name, overflow, corrected, handling)
+	}
 }
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// LatencySummary is the machine-readable summary of one operation type
+type LatencySummary struct {
+	Ops    int64   `json:"ops"` // Successful operations
+	Errors int64   `json:"errors"`
+	QPS    float64 `json:"qps"`
+	Min    int64   `json:"min_us"`
+	Mean   float64 `json:"mean_us"`
+	P50    int64   `json:"p50_us"`
+	P75    int64   `json:"p75_us"`
+	P90    int64   `json:"p90_us"`
+	P95    int64   `json:"p95_us"`
+	P99    int64   `json:"p99_us"`
+	P999   int64   `json:"p99_9_us"`
+	P9999  int64   `json:"p99_99_us"`
+	Max    int64   `json:"max_us"`
+
+	Percentiles []PercentileValue `json:"percentiles,omitempty"` // As selected with --percentiles
+
+	// Latencies over the 60s histogram maximum (--latency-overflow); when Capped they were
+	// recorded at the maximum, so Max and the top percentiles are lower bounds
+	Overflow int64 `json:"overflow,omitempty"`
+	Capped   bool  `json:"capped,omitempty"`
+
+	Windows []WindowSummary `json:"windows,omitempty"` // Per metrics window, in time order
+	Sketch  *SketchSummary  `json:"sketch,omitempty"`  // Only with --sketch ddsketch|tdigest
+}
+
+// WindowSummary is the latency of one metrics window
+type WindowSummary struct {
+	Start time.Time `json:"start"`
+	Ops   int64     `json:"ops"`
+	P50   int64     `json:"p50_us"`
+	P95   int64     `json:"p95_us"`
+	P99   int64     `json:"p99_us"`
+	Max   int64     `json:"max_us"`
+
+	Percentiles []PercentileValue `json:"percentiles,omitempty"`
+}
+
+// RunSummary is the machine-readable result of a workload run
+type RunSummary struct {
+	SchemaVersion   int                    `json:"schema_version"` // See summarySchemaVersion
+	Engine          string                 `json:"engine"`
+	RunID           string                 `json:"run_id,omitempty"`
+	StartTime       time.Time              `json:"start_time"`
+	DurationSeconds float64                `json:"duration_seconds"`
+	Clients         int                    `json:"clients,omitempty"`
+	TrafficPattern  string                 `json:"traffic_pattern,omitempty"`
+	TotalOps        int64                  `json:"total_ops"`
+	TotalErrors     int64                  `json:"total_errors"`
+	Get             LatencySummary         `json:"get"`
+	Set             LatencySummary         `json:"set"`
+	Setup           *LatencySummary        `json:"setup,omitempty"`
+	Phases          *PhaseSummary          `json:"phases,omitempty"` // Wall time breakdown; duration_seconds is the measurement window
+	WorkloadHash    string                 `json:"workload_hash,omitempty"`
+	Workload        *WorkloadConfig        `json:"workload,omitempty"`
+	Hints           []string               `json:"hints,omitempty"` // Bottleneck analysis of the run
+	Memory          *MemorySummary         `json:"memory,omitempty"`
+	Topology        []TopologyChange       `json:"topology_changes,omitempty"` // Only with --topology-watch
+	Fragmentation   *FragmentationSummary  `json:"fragmentation,omitempty"`    // Server memory trend (--fragmentation-watch)
+	Mirror          *MirrorSummary         `json:"mirror,omitempty"`           // Shadow traffic results (--mirror)
+	Commands        []CommandSummary       `json:"commands,omitempty"`         // Per command of --command-mix
+	WriteSplit      *WriteSplitSummary     `json:"write_split,omitempty"`      // SET inserts vs updates (--write-split)
+	DeleteChurn     *DeleteChurnSummary    `json:"delete_churn,omitempty"`     // Deletes during the run (--delete-fraction)
+	ErrorLatency    *ErrorLatencySummary   `json:"error_latency,omitempty"`    // Time to failure (--error-latency)
+	SoftStart       *SoftStartSummary      `json:"soft_start,omitempty"`       // Rate ramp at the start (--soft-start)
+	Timeouts        []TimeoutCauseSummary  `json:"timeouts,omitempty"`         // Timed-out operations by cause
+	Services        []ServiceSummary       `json:"services,omitempty"`         // Per service sharing the cache (--services)
+	ModePhases      []ModePhaseSummary     `json:"mode_phases,omitempty"`      // Read-only/write-only phases (--control-addr)
+	Queue           *QueueSummary          `json:"queue,omitempty"`            // Client-side queue wait (--queue-depth)
+	ResponseTiming  *ResponseTimingSummary `json:"response_timing,omitempty"`  // First byte vs full response (--response-timing)
+	Correlation     []MetricCorrelation    `json:"correlation,omitempty"`      // Server metrics vs client P99 (--correlate-cache)
+	StatsSpill      *StatsSpillSummary     `json:"stats_spill,omitempty"`      // Windows spilled to disk (--stats-lowmem)
+	Build           *BuildInfo             `json:"build,omitempty"`            // Binary that produced the result
+	Command         *CommandInfo           `json:"command,omitempty"`          // Full resolved flag set
+
+	// Partial report of a run that crashed (see --partial-report)
+	Truncated       bool   `json:"truncated,omitempty"`
+	TruncatedReason string `json:"truncated_reason,omitempty"`
+}
+
+// summarizeLatency builds a LatencySummary from a stats collector and the operation counters
+func summarizeLatency(ps *PerformanceStats, ops, errors int64, seconds float64) LatencySummary {
+	summary := summarizeTotals(ps, ops, errors, seconds)
+	summary.Sketch = summarizeSketch(ps.Sketch)
+	err := ps.EachWindow(func(window LatencyWindow) error {
+		summary.Windows = append(summary.Windows, WindowSummary{
+			Start: time.Unix(window.StartSecond, 0),
+			Ops:   window.Histogram.TotalCount(),
+			P50:   window.Histogram.ValueAtQuantile(50),
+			P95:   window.Histogram.ValueAtQuantile(95),
+			P99:   window.Histogram.ValueAtQuantile(99),
+			Max:   window.Histogram.Max(),
+
+			Percentiles: latencyPercentiles(window.Histogram),
+		})
+		return nil
+	})
+	if err != nil {
+		log.Printf("Warning: %s windows are incomplete: %v", ps.Name, err)
+	}
+	return summary
+}
+
+// summarizeTotals builds a LatencySummary from the overall histogram only (no windows or
+// sketch). Histograms aren't safe for concurrent use: recording must have stopped.
+func summarizeTotals(ps *PerformanceStats, ops, errors int64, seconds float64) LatencySummary {
+	summary := LatencySummary{Ops: ops, Errors: errors}
+	if seconds > 0 {
+		summary.QPS = float64(ops) / seconds
+	}
+	if ps.Histogram.TotalCount() > 0 {
+		summary.Min = ps.Histogram.Min()
+		summary.Mean = ps.Histogram.Mean()
+		summary.P50 = ps.Histogram.ValueAtQuantile(50)
+		summary.P75 = ps.Histogram.ValueAtQuantile(75)
+		summary.P90 = ps.Histogram.ValueAtQuantile(90)
+		summary.P95 = ps.Histogram.ValueAtQuantile(95)
+		summary.P99 = ps.Histogram.ValueAtQuantile(99)
+		summary.P999 = ps.Histogram.ValueAtQuantile(99.9)
+		summary.P9999 = ps.Histogram.ValueAtQuantile(99.99)
+		summary.Max = ps.Histogram.Max()
+		summary.Percentiles = latencyPercentiles(ps.Histogram)
+	}
+	summary.Overflow = atomic.LoadInt64(&ps.Overflow)
+	summary.Capped = summary.Overflow > 0 && ps.overflowPolicy != "drop"
+	return summary
+}
+
+// summarizeCounters builds a LatencySummary from the operation counters alone, for stats
+// whose histograms may still be written
+func summarizeCounters(ps *PerformanceStats, ops, errors int64, seconds float64) LatencySummary {
+	summary := LatencySummary{Ops: ops, Errors: errors}
+	if seconds > 0 {
+		summary.QPS = float64(ops) / seconds
+	}
+	return summary
+}
+
+// NewRunSummary captures the final results of a run; stats must not be closed yet
+func NewRunSummary(stats *WorkloadStats, engine string, startTime time.Time, duration time.Duration) *RunSummary {
+	summary := newRunSummary(stats, engine, startTime, duration, summarizeLatency)
+	summary.Setup = summarizeSetup(stats, summarizeLatency)
+	return summary
+}
+
+func newRunSummary(stats *WorkloadStats, engine string, startTime time.Time, duration time.Duration,
+	summarize func(ps *PerformanceStats, ops, errors int64, seconds float64) LatencySummary) *RunSummary {
+	seconds := duration.Seconds()
+	getOps := atomic.LoadInt64(&stats.GetOps)
+	setOps := atomic.LoadInt64(&stats.SetOps)
+	getErrors := atomic.LoadInt64(&stats.GetErrors)
+	setErrors := atomic.LoadInt64(&stats.SetErrors)
+
+	summary := &RunSummary{
+		SchemaVersion:   summarySchemaVersion,
+		Engine:          engine,
+		StartTime:       startTime,
+		DurationSeconds: seconds,
+		TotalOps:        getOps + setOps,
+		TotalErrors:     getErrors + setErrors,
+		Get:             summarize(stats.GetStats, getOps, getErrors, seconds),
+		Set:             summarize(stats.SetStats, setOps, setErrors, seconds),
+	}
+	return summary
+}
+
+// summarizeSetup summarizes the connection setup latency, nil when none was measured; its
+// only count is the histogram's, so recording must have stopped
+func summarizeSetup(stats *WorkloadStats,
+	summarize func(ps *PerformanceStats, ops, errors int64, seconds float64) LatencySummary) *LatencySummary {
+	if stats.SetupStats.Histogram.TotalCount() == 0 {
+		return nil
+	}
+	setup := summarize(stats.SetupStats, stats.SetupStats.Histogram.TotalCount(), 0, 0)
+	return &setup
+}
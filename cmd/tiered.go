@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// L1 scopes for the tiered cache simulation
+const (
+	TieredScopeProcess = "process" // one near cache shared by all clients (a single app process)
+	TieredScopeClient  = "client"  // one near cache per client (many small app instances)
+)
+
+// TieredStats tracks L1 (in-process) vs L2 (remote) behavior of the tiered cache simulation
+type TieredStats struct {
+	Policy      string
+	L1Size      int
+	L1MaxBytes  int64
+	L1TTL       time.Duration
+	Scope       string
+	L1Hits      int64
+	L1Misses    int64
+	Evictions   int64 // Entries evicted to stay within the size limits
+	Expirations int64 // Entries dropped because they outlived the L1 TTL
+
+	NegativeCaching bool              // Cache remote misses in L1
+	NegativeHits    int64             // GETs answered "absent" from a cached miss
+	L1GetStats      *PerformanceStats // Latency of GETs served from L1
+	L2GetStats      *PerformanceStats // Latency of GETs that went to the remote backend
+
+	sharedL1 localCache // Near cache shared by all clients when Scope is process
+}
+
+func NewTieredStats(policy string, l1Size int, l1MaxBytes int64, l1TTL time.Duration, scope string, negativeCaching bool) (*TieredStats, error) {
+	if l1Size <= 0 {
+		return nil, fmt.Errorf("L1 size must be positive, got: %d", l1Size)
+	}
+	if l1MaxBytes < 0 {
+		return nil, fmt.Errorf("L1 max bytes cannot be negative, got: %d", l1MaxBytes)
+	}
+
+	ts := &TieredStats{
+		Policy:          policy,
+		L1Size:          l1Size,
+		L1MaxBytes:      l1MaxBytes,
+		L1TTL:           l1TTL,
+		Scope:           scope,
+		NegativeCaching: negativeCaching,
+	}
+
+	// Validate the policy up front so per-client caches can't fail later
+	local, err := ts.newLocalCache()
+	if err != nil {
+		return nil, err
+	}
+
+	switch scope {
+	case TieredScopeProcess:
+		ts.sharedL1 = local
+	case TieredScopeClient:
+	default:
+		return nil, fmt.Errorf("invalid L1 scope: %s. Must be '%s' or '%s'", scope, TieredScopeProcess, TieredScopeClient)
+	}
+
+	ts.L1GetStats = NewPerformanceStats()
+	ts.L2GetStats = NewPerformanceStats()
+	return ts, nil
+}
+
+func (ts *TieredStats) newLocalCache() (localCache, error) {
+	return newLocalCache(ts.Policy, localCacheLimits{
+		maxEntries:  ts.L1Size,
+		maxBytes:    ts.L1MaxBytes,
+		ttl:         ts.L1TTL,
+		evictions:   &ts.Evictions,
+		expirations: &ts.Expirations,
+	})
+}
+
+// Describe returns a short human-readable description of the L1 configuration
+func (ts *TieredStats) Describe() string {
+	desc := fmt.Sprintf("L1 %s, %d entries", ts.Policy, ts.L1Size)
+	if ts.L1MaxBytes > 0 {
+		desc += fmt.Sprintf(", %d bytes", ts.L1MaxBytes)
+	}
+	if ts.L1TTL > 0 {
+		desc += fmt.Sprintf(", TTL %v", ts.L1TTL)
+	}
+	if ts.NegativeCaching {
+		desc += ", negative caching"
+	}
+	return desc + fmt.Sprintf(", %s scope", ts.Scope)
+}
+
+// Wrap puts the L1 tier in front of a remote cache client
+func (ts *TieredStats) Wrap(remote CacheClient) CacheClient {
+	local := ts.sharedL1
+	if local == nil {
+		local, _ = ts.newLocalCache()
+	}
+	return &TieredClient{remote: remote, local: local, stats: ts}
+}
+
+// Close shuts down the stats collectors
+func (ts *TieredStats) Close() {
+	ts.L1GetStats.Close()
+	ts.L2GetStats.Close()
+}
+
+// TieredClient implements CacheClient as an in-process near cache in front of a remote backend.
+// GETs are served from L1 when possible and populate it on miss; SETs write through.
+// With negative caching, remote misses are stored in L1 as nil values.
+type TieredClient struct {
+	remote CacheClient
+	local  localCache
+	stats  *TieredStats
+}
+
+func (t *TieredClient) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	if err := t.remote.Set(ctx, key, value, expiration); err != nil {
+		return err
+	}
+	t.local.Set(key, nonNilValue(value))
+	return nil
+}
+
+func (t *TieredClient) Get(ctx context.Context, key string) ([]byte, error) {
+	start := time.Now()
+	if value, ok := t.local.Get(key); ok {
+		atomic.AddInt64(&t.stats.L1Hits, 1)
+		t.stats.L1GetStats.RecordLatency(time.Since(start).Microseconds())
+		if value == nil {
+			atomic.AddInt64(&t.stats.NegativeHits, 1)
+			return nil, ErrCacheMiss
+		}
+		return value, nil
+	}
+	atomic.AddInt64(&t.stats.L1Misses, 1)
+
+	remoteStart := time.Now()
+	value, err := t.remote.Get(ctx, key)
+	if err != nil {
+		if t.stats.NegativeCaching && errors.Is(err, ErrCacheMiss) {
+			t.stats.L2GetStats.RecordLatency(time.Since(remoteStart).Microseconds())
+			t.local.Set(key, nil)
+		}
+		return nil, err
+	}
+	t.stats.L2GetStats.RecordLatency(time.Since(remoteStart).Microseconds())
+
+	t.local.Set(key, nonNilValue(value))
+	return value, nil
+}
+
+func (t *TieredClient) Delete(ctx context.Context, key string) error {
+	t.local.Delete(key)
+	return t.remote.Delete(ctx, key)
+}
+
+// nonNilValue keeps empty values distinguishable from cached misses
+func nonNilValue(value []byte) []byte {
+	if value == nil {
+		return []byte{}
+	}
+	return value
+}
+
+func (t *TieredClient) Connect(ctx context.Context) error {
+	return t.remote.Connect(ctx)
+}
+
+func (t *TieredClient) Ping(ctx context.Context) error {
+	return t.remote.Ping(ctx)
+}
+
+func (t *TieredClient) Close() error {
+	return t.remote.Close()
+}
+
+// Reauthenticate implements Reauthenticator for the remote backend
+func (t *TieredClient) Reauthenticate(ctx context.Context, password string) (int, error) {
+	reauth, ok := t.remote.(Reauthenticator)
+	if !ok {
+		return 0, errReauthUnsupported
+	}
+	return reauth.Reauthenticate(ctx, password)
+}
+
+func (t *TieredClient) Name() string {
+	return "Tiered(L1+" + t.remote.Name() + ")"
+}
+
+// printTieredResults prints the L1/L2 breakdown of the tiered cache simulation
+func printTieredResults(stats *WorkloadStats) {
+	ts := stats.Tiered
+	hits := atomic.LoadInt64(&ts.L1Hits)
+	misses := atomic.LoadInt64(&ts.L1Misses)
+	lookups := hits + misses
+	if lookups == 0 {
+		return
+	}
+
+	_, _, _, _, l1P50, _, l1P99 := ts.L1GetStats.GetStats()
+	_, _, _, _, l2P50, _, l2P99 := ts.L2GetStats.GetStats()
+	_, _, _, _, effP50, _, effP99 := stats.GetStats.GetStats()
+
+	fmt.Printf("Tiered Cache (%s):\n", ts.Describe())
+	fmt.Printf("L1 Hits: %d, L1 Misses: %d, L1 Hit Rate: %.2f%%\n", hits, misses, float64(hits)/float64(lookups)*100)
+	fmt.Printf("L1 Evictions: %d, L1 Expirations: %d\n", atomic.LoadInt64(&ts.Evictions), atomic.LoadInt64(&ts.Expirations))
+	if ts.NegativeCaching {
+		fmt.Printf("L1 Negative Hits: %d (misses answered without a remote call)\n", atomic.LoadInt64(&ts.NegativeHits))
+	}
+	fmt.Printf("L1 GET Latency - P50: %d μs, P99: %d μs\n", l1P50, l1P99)
+	fmt.Printf("L2 GET Latency - P50: %d μs, P99: %d μs\n", l2P50, l2P99)
+	fmt.Printf("Effective GET Latency - P50: %d μs, P99: %d μs\n", effP50, effP99)
+	fmt.Println()
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// timeoutCause is where a failed operation ran out of time
+type timeoutCause uint8
+
+const (
+	timeoutNone     timeoutCause = iota
+	timeoutPool                  // Waiting for a connection of the client's pool
+	timeoutConnect               // Opening a new connection (dial, TLS and HELLO/AUTH handshake)
+	timeoutWrite                 // Sending the request
+	timeoutRead                  // Waiting for the response
+	timeoutDeadline              // The operation deadline (--timeout) expired, e.g. in a retry backoff
+	timeoutOther                 // A timeout the client doesn't attribute to a step
+	timeoutCauses
+)
+
+var timeoutCauseNames = [timeoutCauses]string{"", "pool", "connect", "write", "read", "deadline", "other"}
+
+func (c timeoutCause) String() string {
+	return timeoutCauseNames[c]
+}
+
+// classifyTimeout unwraps a failed operation's error to where the time ran out; errors that
+// aren't timeouts are timeoutNone. Socket timeouts come from the client's read, write and
+// dial timeouts, while an expired operation context means the deadline was used up before
+// or between the attempts (retry backoff).
+func classifyTimeout(err error) timeoutCause {
+	if err == nil {
+		return timeoutNone
+	}
+	if errors.Is(err, redis.ErrPoolTimeout) {
+		return timeoutPool
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Timeout() {
+		switch opErr.Op {
+		case "dial":
+			return timeoutConnect
+		case "write":
+			return timeoutWrite
+		case "read":
+			return timeoutRead
+		}
+	}
+	// go-redis strips the socket error of a failed connection handshake down to the deadline
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return timeoutConnect
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return timeoutDeadline
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return timeoutOther
+	}
+	return timeoutNone
+}
+
+// TimeoutStats counts the operations that failed on a timeout by cause. A timeout alone
+// doesn't say what to fix: pool waits point at too few connections for the concurrency,
+// connect timeouts at connection setup (TLS, DNS, connection limits), while read timeouts
+// put the time on the server or the network.
+type TimeoutStats struct {
+	Get          [timeoutCauses]int64
+	Set          [timeoutCauses]int64
+	Other        [timeoutCauses]int64 // Chains, command mix and RMW operations
+	failedMicros [timeoutCauses]int64 // Time to failure of the GETs and SETs, summed
+	failedCount  [timeoutCauses]int64
+}
+
+// Record counts a failed operation that timed out
+func (ts *TimeoutStats) Record(result workloadResult) {
+	switch {
+	case result.isRMW || result.isChain || result.isCommand:
+		atomic.AddInt64(&ts.Other[result.timeout], 1)
+	case result.isSet:
+		atomic.AddInt64(&ts.Set[result.timeout], 1)
+	default:
+		atomic.AddInt64(&ts.Get[result.timeout], 1)
+	}
+	if result.failedMicros > 0 {
+		atomic.AddInt64(&ts.failedMicros[result.timeout], result.failedMicros)
+		atomic.AddInt64(&ts.failedCount[result.timeout], 1)
+	}
+}
+
+// TimeoutCauseSummary is the machine-readable count of one timeout cause
+type TimeoutCauseSummary struct {
+	Cause             string  `json:"cause"`
+	Get               int64   `json:"get"`
+	Set               int64   `json:"set"`
+	Other             int64   `json:"other,omitempty"`
+	Percent           float64 `json:"percent"`                           // Of all timeouts
+	MeanFailureMicros float64 `json:"mean_time_to_failure_us,omitempty"` // GETs and SETs
+}
+
+// Summary returns the timeouts by cause, nil when no operation timed out
+func (ts *TimeoutStats) Summary() []TimeoutCauseSummary {
+	var total int64
+	for cause := timeoutPool; cause < timeoutCauses; cause++ {
+		total += atomic.LoadInt64(&ts.Get[cause]) + atomic.LoadInt64(&ts.Set[cause]) + atomic.LoadInt64(&ts.Other[cause])
+	}
+	if total == 0 {
+		return nil
+	}
+
+	var summaries []TimeoutCauseSummary
+	for cause := timeoutPool; cause < timeoutCauses; cause++ {
+		s := TimeoutCauseSummary{
+			Cause: cause.String(),
+			Get:   atomic.LoadInt64(&ts.Get[cause]),
+			Set:   atomic.LoadInt64(&ts.Set[cause]),
+			Other: atomic.LoadInt64(&ts.Other[cause]),
+		}
+		count := s.Get + s.Set + s.Other
+		if count == 0 {
+			continue
+		}
+		s.Percent = float64(count) / float64(total) * 100
+		if failed := atomic.LoadInt64(&ts.failedCount[cause]); failed > 0 {
+			s.MeanFailureMicros = float64(atomic.LoadInt64(&ts.failedMicros[cause])) / float64(failed)
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
+
+// timeoutAdvice tells what the dominant timeout cause points at
+var timeoutAdvice = map[string]string{
+	"pool":     "operations waited for a free pool connection: the pool is too small for the concurrency per client, or --redis-pool-timeout too short",
+	"connect":  "new connections were slow to open: check TLS handshakes, DNS and the server's connection limits, or reuse connections",
+	"write":    "requests couldn't be sent in time: the network or the server's input buffers are saturated",
+	"read":     "responses were late: the server (or the network back) is the bottleneck, not the client",
+	"deadline": "the --timeout deadline ran out across attempts: retries and backoff use up the budget, check --redis-max-retries",
+	"other":    "timeouts the client library doesn't attribute to a step",
+}
+
+// printTimeoutResults prints the timed-out operations by cause, if any
+func printTimeoutResults(stats *WorkloadStats) {
+	summaries := stats.Timeouts.Summary()
+	if summaries == nil {
+		return
+	}
+	fmt.Println("Timeouts by Cause:")
+	fmt.Printf("%-10s %-10s %-10s %-10s %-10s %-14s\n", "Cause", "GET", "SET", "Other", "Share", "Mean to fail")
+	dominant := summaries[0]
+	for _, s := range summaries {
+		mean := "-"
+		if s.MeanFailureMicros > 0 {
+			mean = fmt.Sprintf("%.0f μs", s.MeanFailureMicros)
+		}
+		fmt.Printf("%-10s %-10d %-10d %-10d %-10s %-14s\n", s.Cause, s.Get, s.Set, s.Other, fmt.Sprintf("%.1f%%", s.Percent), mean)
+		if s.Percent > dominant.Percent {
+			dominant = s
+		}
+	}
+	fmt.Printf("Mostly %s timeouts: %s\n", dominant.Cause, timeoutAdvice[dominant.Cause])
+	fmt.Println()
+}
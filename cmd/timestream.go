@@ -0,0 +1,332 @@
+//go:build !noaws
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/timestreamwrite"
+	"github.com/aws/aws-sdk-go-v2/service/timestreamwrite/types"
+	"github.com/spf13/cobra"
+)
+
+// Timestream writing limits and tuning
+const (
+	timestreamMaxBatch       = 100 // WriteRecords accepts up to 100 records per request
+	timestreamQueueSize      = 4096
+	timestreamFlushInterval  = 10 * time.Second
+	timestreamRequestTimeout = 10 * time.Second
+	timestreamMaxAttempts    = 6
+	timestreamBaseBackoff    = 250 * time.Millisecond
+	timestreamMaxBackoff     = 8 * time.Second
+	timestreamCloseTimeout   = 30 * time.Second
+	timestreamMeasureName    = "window" // Multi-measure record name of the per-window stats
+)
+
+func init() {
+	runCmd.Flags().String("timestream", "", "Write the per-window stats to this Amazon Timestream table as database/table, one multi-measure record per metrics window with the run ID, engine and workload hash as dimensions")
+	runCmd.Flags().String("timestream-dimensions", "", "Extra Timestream dimensions as Name=Value pairs (e.g. team=payments,env=staging)")
+	runCmd.Flags().String("timestream-role-arn", "", "IAM role to assume for writing to Timestream, e.g. in a central analytics account (default: --aws-role-arn)")
+
+	RegisterAWSPermissions(func(cmd *cobra.Command) []AWSPermission {
+		target, _ := cmd.Flags().GetString("timestream")
+		database, table, ok := strings.Cut(target, "/")
+		if !ok {
+			return nil
+		}
+		roleARN := awsRoleARN(cmd, "timestream-role-arn")
+		return []AWSPermission{
+			// Endpoint discovery has no resource-level permissions
+			{Action: "timestream:DescribeEndpoints", Resource: "*", RoleARN: roleARN, Reason: "Timestream endpoint discovery"},
+			{
+				Action:   "timestream:WriteRecords",
+				Resource: "arn:{partition}:timestream:{region}:{account}:database/" + database + "/table/" + table,
+				RoleARN:  roleARN,
+				Reason:   "writing the per-window stats to " + target,
+			},
+		}
+	})
+}
+
+// TimestreamPublisher writes the per-window stats of a run to an Amazon Timestream table,
+// for long-term benchmark history queryable with SQL next to other AWS data. It runs like
+// the CloudWatch publisher: records are queued without blocking the caller, batched up to
+// the WriteRecords limit and retried with backoff, so Timestream never stalls the benchmark.
+type TimestreamPublisher struct {
+	Database   string
+	Table      string
+	Dimensions []types.Dimension
+
+	Written  int64 // Records ingested by Timestream
+	Requests int64 // Successful WriteRecords requests
+	Retries  int64 // Retried WriteRecords attempts
+	Rejected int64 // Records Timestream rejected (e.g. outside the memory store retention)
+	Failed   int64 // Records lost after exhausting retries (or at shutdown)
+	Dropped  int64 // Records dropped because the queue was full
+
+	client    *timestreamwrite.Client
+	queue     chan types.Record
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mutex     sync.Mutex
+	lastError error
+
+	// Previous counters, to write errors per window rather than cumulative
+	lastGetErrors int64
+	lastSetErrors int64
+}
+
+// NewTimestreamPublisher starts a publisher for target (database/table); every record has
+// the run ID, the engine, the tool version and the workload hash as dimensions, plus the
+// Name=Value list of dimensions
+func NewTimestreamPublisher(ctx context.Context, region, roleARN, target, runID, engine, workloadHash, dimensions string) (*TimestreamPublisher, error) {
+	database, table, ok := strings.Cut(target, "/")
+	if !ok || database == "" || table == "" || strings.Contains(table, "/") {
+		return nil, fmt.Errorf("invalid Timestream table '%s': expected database/table", target)
+	}
+
+	dims := []types.Dimension{
+		{Name: aws.String("run_id"), Value: aws.String(runID)},
+		{Name: aws.String("engine"), Value: aws.String(engine)},
+		{Name: aws.String("tool_version"), Value: aws.String(currentBuildInfo().Version)},
+		{Name: aws.String("workload_hash"), Value: aws.String(workloadHash)},
+	}
+	if dimensions != "" {
+		for _, pair := range strings.Split(dimensions, ",") {
+			name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || name == "" || value == "" {
+				return nil, fmt.Errorf("invalid Timestream dimension '%s': expected Name=Value", pair)
+			}
+			dims = append(dims, types.Dimension{Name: aws.String(name), Value: aws.String(value)})
+		}
+	}
+	if len(dims) > 128 {
+		return nil, fmt.Errorf("too many Timestream dimensions: %d (max 128)", len(dims))
+	}
+
+	cfg, err := loadAWSConfig(ctx, region, roleARN)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &TimestreamPublisher{
+		Database:   database,
+		Table:      table,
+		Dimensions: dims,
+		// Retries are handled by the publisher so they can be counted and backed off
+		client: timestreamwrite.NewFromConfig(cfg, func(o *timestreamwrite.Options) {
+			o.Retryer = aws.NopRetryer{}
+		}),
+		queue: make(chan types.Record, timestreamQueueSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go p.run()
+	return p, nil
+}
+
+// timestreamMeasureSuffix turns a percentile label into a measure name suffix (P99.9 -> p99_9)
+func timestreamMeasureSuffix(p float64) string {
+	return strings.ToLower(strings.ReplaceAll(percentileLabel(p), ".", "_"))
+}
+
+// PublishSnapshot queues the stats of one progress window as a multi-measure record; it
+// never blocks
+func (p *TimestreamPublisher) PublishSnapshot(snapshot MetricsSnapshot) {
+	getErrors := snapshot.GetErrors - p.lastGetErrors
+	setErrors := snapshot.SetErrors - p.lastSetErrors
+	p.lastGetErrors, p.lastSetErrors = snapshot.GetErrors, snapshot.SetErrors
+
+	var measures []types.MeasureValue
+	double := func(name string, value float64) {
+		measures = append(measures, types.MeasureValue{
+			Name:  aws.String(name),
+			Value: aws.String(strconv.FormatFloat(value, 'f', -1, 64)),
+			Type:  types.MeasureValueTypeDouble,
+		})
+	}
+	bigint := func(name string, value int64) {
+		measures = append(measures, types.MeasureValue{
+			Name:  aws.String(name),
+			Value: aws.String(strconv.FormatInt(value, 10)),
+			Type:  types.MeasureValueTypeBigint,
+		})
+	}
+
+	double("total_qps", snapshot.ActualTotalQPS)
+	double("get_qps", snapshot.ActualGetQPS)
+	double("set_qps", snapshot.ActualSetQPS)
+	if snapshot.TargetQPS >= 0 {
+		bigint("target_qps", int64(snapshot.TargetQPS))
+	}
+	bigint("get_errors", getErrors)
+	bigint("set_errors", setErrors)
+	bigint("clients", int64(snapshot.ActualClients))
+	// One measure per --percentiles entry (get_p50_us, get_p99_9_us...)
+	for _, v := range snapshot.GetPercentiles {
+		bigint("get_"+timestreamMeasureSuffix(v.Percentile)+"_us", v.Value)
+	}
+	bigint("get_max_us", snapshot.GetLatencyMax)
+	for _, v := range snapshot.SetPercentiles {
+		bigint("set_"+timestreamMeasureSuffix(v.Percentile)+"_us", v.Value)
+	}
+	bigint("set_max_us", snapshot.SetLatencyMax)
+	double("cpu_percent", snapshot.CPUPercent)
+	double("network_rx_mbps", snapshot.NetworkRxMBps)
+	double("network_tx_mbps", snapshot.NetworkTxMBps)
+
+	record := types.Record{
+		Dimensions:       p.Dimensions,
+		MeasureName:      aws.String(timestreamMeasureName),
+		MeasureValueType: types.MeasureValueTypeMulti,
+		MeasureValues:    measures,
+		Time:             aws.String(strconv.FormatInt(snapshot.Timestamp.UnixMilli(), 10)),
+		TimeUnit:         types.TimeUnitMilliseconds,
+	}
+	select {
+	case p.queue <- record:
+	default:
+		atomic.AddInt64(&p.Dropped, 1)
+	}
+}
+
+// run batches queued records and flushes them when a batch is full or on every interval
+func (p *TimestreamPublisher) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(timestreamFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]types.Record, 0, timestreamMaxBatch)
+	for {
+		select {
+		case record := <-p.queue:
+			batch = p.add(batch, record)
+		case <-ticker.C:
+			p.flush(batch)
+			batch = batch[:0]
+		case <-p.stop:
+			// Drain what was queued before Close
+			for {
+				select {
+				case record := <-p.queue:
+					batch = p.add(batch, record)
+				default:
+					p.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// add appends a record to the batch, sending the batch once it reaches the request limit
+func (p *TimestreamPublisher) add(batch []types.Record, record types.Record) []types.Record {
+	batch = append(batch, record)
+	if len(batch) == timestreamMaxBatch {
+		p.flush(batch)
+		batch = batch[:0]
+	}
+	return batch
+}
+
+// flush sends a batch, retrying throttled and transient failures with exponential backoff.
+// Rejected records (a duplicate time, a time outside the memory store retention) are not
+// retried: the rest of the batch has been ingested.
+func (p *TimestreamPublisher) flush(batch []types.Record) {
+	if len(batch) == 0 {
+		return
+	}
+
+	retryable := retry.IsErrorRetryables(retry.DefaultRetryables)
+	backoff := timestreamBaseBackoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), timestreamRequestTimeout)
+		_, err := p.client.WriteRecords(ctx, &timestreamwrite.WriteRecordsInput{
+			DatabaseName: aws.String(p.Database),
+			TableName:    aws.String(p.Table),
+			Records:      batch,
+		})
+		cancel()
+		if err == nil {
+			atomic.AddInt64(&p.Written, int64(len(batch)))
+			atomic.AddInt64(&p.Requests, 1)
+			return
+		}
+
+		var rejected *types.RejectedRecordsException
+		if errors.As(err, &rejected) {
+			atomic.AddInt64(&p.Rejected, int64(len(rejected.RejectedRecords)))
+			atomic.AddInt64(&p.Written, int64(len(batch)-len(rejected.RejectedRecords)))
+			atomic.AddInt64(&p.Requests, 1)
+			if len(rejected.RejectedRecords) > 0 {
+				err = fmt.Errorf("record rejected: %s", aws.ToString(rejected.RejectedRecords[0].Reason))
+			}
+			p.setLastError(err)
+			return
+		}
+
+		if attempt == timestreamMaxAttempts || retryable.IsErrorRetryable(err) != aws.TrueTernary {
+			atomic.AddInt64(&p.Failed, int64(len(batch)))
+			p.setLastError(err)
+			return
+		}
+
+		atomic.AddInt64(&p.Retries, 1)
+		time.Sleep(backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)))) // Jittered
+		backoff = min(backoff*2, timestreamMaxBackoff)
+	}
+}
+
+func (p *TimestreamPublisher) setLastError(err error) {
+	p.mutex.Lock()
+	p.lastError = err
+	p.mutex.Unlock()
+}
+
+// Close flushes the queued records, waiting at most timestreamCloseTimeout; records still
+// queued after that are counted as failed
+func (p *TimestreamPublisher) Close() {
+	p.closeOnce.Do(func() {
+		close(p.stop)
+		select {
+		case <-p.done:
+		case <-time.After(timestreamCloseTimeout):
+			atomic.AddInt64(&p.Failed, int64(len(p.queue)))
+			p.setLastError(fmt.Errorf("timed out flushing records at shutdown"))
+		}
+	})
+}
+
+// LastError returns the error of the most recent failed or partly rejected write, if any
+func (p *TimestreamPublisher) LastError() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.lastError
+}
+
+// printTimestreamResults reports writing outcomes separately from the benchmark results
+func printTimestreamResults(publisher *TimestreamPublisher) {
+	fmt.Printf("\nTimestream Writing (%s/%s):\n", publisher.Database, publisher.Table)
+	fmt.Printf("Records Written: %d in %d requests, Retries: %d\n",
+		atomic.LoadInt64(&publisher.Written), atomic.LoadInt64(&publisher.Requests), atomic.LoadInt64(&publisher.Retries))
+	rejected, failed, dropped := atomic.LoadInt64(&publisher.Rejected), atomic.LoadInt64(&publisher.Failed), atomic.LoadInt64(&publisher.Dropped)
+	if rejected > 0 || failed > 0 || dropped > 0 {
+		fmt.Printf("Warning: %d records rejected, %d failed to write, %d dropped (queue full)\n", rejected, failed, dropped)
+		if err := publisher.LastError(); err != nil {
+			fmt.Printf("Last write error: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// tlsMatrixFailFast is the number of failed probes without a success after which a
+// configuration is taken as unsupported by the server and skipped
+const tlsMatrixFailFast = 3
+
+// tlsMatrixCell is one TLS configuration of the matrix: a protocol version and, for TLS 1.2,
+// a cipher suite
+type tlsMatrixCell struct {
+	Name  string
+	Conn  *FreshConnStats   // Per-phase latencies of the cell's fresh connections
+	Total *PerformanceStats // Connection start to the first reply
+
+	Ops       int64
+	Errors    int64
+	mutex     sync.Mutex
+	lastError string
+}
+
+// Unsupported reports whether the server rejected every attempt so far
+func (c *tlsMatrixCell) Unsupported() bool {
+	return atomic.LoadInt64(&c.Ops) == 0 && atomic.LoadInt64(&c.Errors) >= tlsMatrixFailFast
+}
+
+func (c *tlsMatrixCell) Close() {
+	c.Conn.Close()
+	c.Total.Close()
+}
+
+// tlsMatrixCmd represents the tls-matrix command
+var tlsMatrixCmd = &cobra.Command{
+	Use:   "tls-matrix",
+	Short: "Compare connect and first-operation latency across TLS versions and cipher suites",
+	Long: `Compare the cost of opening a connection under different TLS settings: every probe opens a
+fresh connection (DNS, TCP connect, TLS handshake, AUTH), sends one operation and closes it,
+once per TLS configuration in turn, so all configurations see the same network conditions.
+
+The matrix covers the TLS versions of --tls-versions; TLS 1.2 is repeated for every cipher
+suite of --ciphers, while TLS 1.3 suites aren't configurable in Go and are reported as the
+one negotiated. Configurations the server rejects are reported as unsupported. Session
+resumption is off, so every handshake is a full one: the cost a client pays on a cold start.
+
+Examples:
+  # TLS 1.2 with the default suites against TLS 1.3, 200 connections each
+  serverless-cache-benchmark tls-matrix --redis-uri rediss://my-cache.serverless.use1.cache.amazonaws.com:6379 \
+    --connections 200
+
+  # AES-GCM vs ChaCha20 on TLS 1.2 only, with a SET as the first operation
+  serverless-cache-benchmark tls-matrix --redis-uri rediss://cache:6379 --tls-versions 1.2 \
+    --ciphers TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256 --command set`,
+	Run: runTLSMatrix,
+}
+
+// lookupCipherSuite returns the ID of a TLS 1.2 cipher suite by its Go (IANA) name
+func lookupCipherSuite(name string) (uint16, error) {
+	suites := append(tls.CipherSuites(), tls.InsecureCipherSuites()...)
+	for _, suite := range suites {
+		if suite.Name != name {
+			continue
+		}
+		for _, version := range suite.SupportedVersions {
+			if version == tls.VersionTLS12 {
+				return suite.ID, nil
+			}
+		}
+		return 0, fmt.Errorf("cipher suite %s isn't a TLS 1.2 suite (TLS 1.3 suites can't be selected)", name)
+	}
+	return 0, fmt.Errorf("unknown cipher suite '%s'", name)
+}
+
+// tlsMatrixConfigs returns the TLS configurations of the matrix, named for the report
+func tlsMatrixConfigs(versions, ciphers []string) ([]string, []*tls.Config, error) {
+	var names []string
+	var configs []*tls.Config
+	for _, version := range versions {
+		switch strings.TrimSpace(version) {
+		case "1.2":
+			if len(ciphers) == 0 {
+				names = append(names, "TLS 1.2")
+				configs = append(configs, &tls.Config{MinVersion: tls.VersionTLS12, MaxVersion: tls.VersionTLS12})
+			}
+			for _, cipher := range ciphers {
+				cipher = strings.TrimSpace(cipher)
+				id, err := lookupCipherSuite(cipher)
+				if err != nil {
+					return nil, nil, err
+				}
+				names = append(names, "TLS 1.2 "+strings.TrimPrefix(cipher, "TLS_"))
+				configs = append(configs, &tls.Config{MinVersion: tls.VersionTLS12, MaxVersion: tls.VersionTLS12, CipherSuites: []uint16{id}})
+			}
+		case "1.3":
+			names = append(names, "TLS 1.3")
+			configs = append(configs, &tls.Config{MinVersion: tls.VersionTLS13, MaxVersion: tls.VersionTLS13})
+		default:
+			return nil, nil, fmt.Errorf("unsupported TLS version '%s': expected 1.2 or 1.3", version)
+		}
+	}
+	if len(configs) == 0 {
+		return nil, nil, fmt.Errorf("no TLS configurations selected")
+	}
+	return names, configs, nil
+}
+
+func runTLSMatrix(cmd *cobra.Command, args []string) {
+	uri, _ := cmd.Flags().GetString("redis-uri")
+	versions, _ := cmd.Flags().GetStringSlice("tls-versions")
+	ciphers, _ := cmd.Flags().GetStringSlice("ciphers")
+	connections, _ := cmd.Flags().GetInt("connections")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	command, _ := cmd.Flags().GetString("command")
+	key, _ := cmd.Flags().GetString("key")
+	valueSize, _ := cmd.Flags().GetInt("value-size")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	skipVerify, _ := cmd.Flags().GetBool("tls-skip-verify")
+
+	if connections <= 0 || concurrency <= 0 {
+		log.Fatalf("Connections and concurrency must be positive")
+	}
+	if command != "get" && command != "set" && command != "ping" {
+		log.Fatalf("Invalid --command '%s': expected get, set or ping", command)
+	}
+	if timeout <= 0 {
+		log.Fatalf("Timeout must be positive")
+	}
+	names, configs, err := tlsMatrixConfigs(versions, ciphers)
+	if err != nil {
+		log.Fatalf("Invalid TLS matrix: %v", err)
+	}
+	password, err := resolvePasswordFrom(cmd)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	var cells []*tlsMatrixCell
+	defer func() {
+		for _, cell := range cells {
+			cell.Close()
+		}
+	}()
+	for i, config := range configs {
+		fs, err := NewFreshConnStats(uri, timeout, timeout, false)
+		if err != nil {
+			log.Fatalf("Invalid Redis URI: %v", err)
+		}
+		if fs.TLSConfig == nil {
+			log.Fatalf("The TLS matrix needs a rediss:// URI")
+		}
+		config.ServerName = fs.TLSConfig.ServerName
+		config.InsecureSkipVerify = skipVerify || fs.TLSConfig.InsecureSkipVerify
+		fs.TLSConfig = config
+		if password != "" {
+			fs.Password = password
+		}
+		cells = append(cells, &tlsMatrixCell{Name: names[i], Conn: fs, Total: NewPerformanceStats()})
+	}
+
+	value := []byte(strings.Repeat("x", valueSize))
+	probe := func(ctx context.Context, cell *tlsMatrixCell) {
+		client := cell.Conn.NewClient()
+		probeCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		start := time.Now()
+		var err error
+		switch command {
+		case "get":
+			_, err = client.Get(probeCtx, key)
+			if errors.Is(err, ErrCacheMiss) {
+				err = nil
+			}
+		case "set":
+			err = client.Set(probeCtx, key, value, time.Minute)
+		default:
+			err = client.Ping(probeCtx)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return // Interrupted, not a failure of the configuration
+			}
+			atomic.AddInt64(&cell.Errors, 1)
+			cell.mutex.Lock()
+			cell.lastError = err.Error()
+			cell.mutex.Unlock()
+			return
+		}
+		atomic.AddInt64(&cell.Ops, 1)
+		cell.Total.RecordLatency(time.Since(start).Microseconds())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		fmt.Println("\nReceived interrupt signal. Stopping and printing summary...")
+		cancel()
+	}()
+
+	fmt.Printf("TLS matrix: %d configurations x %d fresh connections, %d at a time, each sending %s\n",
+		len(cells), connections, concurrency, strings.ToUpper(command))
+
+	// Every round probes each configuration once, starting from a different one every round,
+	// so neither time nor order favors a configuration
+	var rounds int64
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				round := int(atomic.AddInt64(&rounds, 1) - 1)
+				if round >= connections {
+					return
+				}
+				for i := range cells {
+					cell := cells[(round+i)%len(cells)]
+					if !cell.Unsupported() {
+						probe(ctx, cell)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Collect the last probes before reporting (the stats share the collector)
+	cells[0].Total.Flush()
+	printTLSMatrixResults(cells)
+}
+
+// printTLSMatrixResults prints the handshake and first-operation latency of every configuration
+func printTLSMatrixResults(cells []*tlsMatrixCell) {
+	fmt.Println("\n" + strings.Repeat("=", 150))
+	fmt.Println("TLS MATRIX RESULTS")
+	fmt.Println(strings.Repeat("=", 150))
+	fmt.Printf("%-48s %-42s %-6s %-6s %-8s %-8s %-8s %-8s %-8s %-8s %-8s\n",
+		"Configuration", "Negotiated suite", "OK", "Errors", "TCP P50", "TLS P50", "TLS P99", "Op P50", "Op P99", "All P50", "All P99")
+
+	var fastest *tlsMatrixCell
+	var fastestP50 int64
+	for _, cell := range cells {
+		ops := atomic.LoadInt64(&cell.Ops)
+		negotiated := "-"
+		if ops > 0 {
+			negotiated = tls.CipherSuiteName(uint16(atomic.LoadUint32(&cell.Conn.NegotiatedCipher)))
+		}
+		_, _, _, _, tcpP50, _, _ := cell.Conn.ConnectStats.GetStats()
+		_, _, _, _, tlsP50, _, tlsP99 := cell.Conn.TLSStats.GetStats()
+		_, _, _, _, opP50, _, opP99 := cell.Conn.CommandStats.GetStats()
+		_, _, _, _, allP50, _, allP99 := cell.Total.GetStats()
+		fmt.Printf("%-48s %-42s %-6d %-6d %-8d %-8d %-8d %-8d %-8d %-8d %-8d\n",
+			cell.Name, negotiated, ops, atomic.LoadInt64(&cell.Errors), tcpP50, tlsP50, tlsP99, opP50, opP99, allP50, allP99)
+		if ops > 0 && (fastest == nil || tlsP50 < fastestP50) {
+			fastest, fastestP50 = cell, tlsP50
+		}
+	}
+	fmt.Println("(latencies in μs; Op is the first operation on the new connection, All is connection start to its reply)")
+	fmt.Println()
+
+	for _, cell := range cells {
+		if cell.Unsupported() {
+			fmt.Printf("%s: unsupported by the server (%s)\n", cell.Name, cell.lastError)
+		} else if failed := atomic.LoadInt64(&cell.Errors); failed > 0 {
+			fmt.Printf("%s: %d failed probes, last: %s\n", cell.Name, failed, cell.lastError)
+		}
+	}
+	if fastest != nil {
+		fmt.Printf("Fastest handshake: %s (TLS P50 %d μs)\n", fastest.Name, fastestP50)
+		for _, cell := range cells {
+			_, _, _, _, tlsP50, _, _ := cell.Conn.TLSStats.GetStats()
+			if cell != fastest && atomic.LoadInt64(&cell.Ops) > 0 && fastestP50 > 0 {
+				fmt.Printf("  %s: %+.0f%%\n", cell.Name, float64(tlsP50-fastestP50)/float64(fastestP50)*100)
+			}
+		}
+	}
+	fmt.Println(strings.Repeat("=", 150))
+}
+
+func init() {
+	rootCmd.AddCommand(tlsMatrixCmd)
+
+	tlsMatrixCmd.Flags().StringP("redis-uri", "u", "rediss://localhost:6379", "Redis URI (rediss://)")
+	tlsMatrixCmd.Flags().StringSlice("tls-versions", []string{"1.2", "1.3"}, "TLS versions to compare: 1.2, 1.3")
+	tlsMatrixCmd.Flags().StringSlice("ciphers", []string{
+		"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+		"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+	}, "TLS 1.2 cipher suites to compare, by Go (IANA) name; empty uses Go's defaults as one configuration")
+	tlsMatrixCmd.Flags().Int("connections", 100, "Fresh connections per configuration")
+	tlsMatrixCmd.Flags().Int("concurrency", 1, "Connections opened at a time")
+	tlsMatrixCmd.Flags().String("command", "get", "First operation on each connection: get, set or ping")
+	tlsMatrixCmd.Flags().String("key", "memtier-tls-matrix", "Key of the get and set operations")
+	tlsMatrixCmd.Flags().Int("value-size", 100, "Value size in bytes of the set operations")
+	tlsMatrixCmd.Flags().Duration("timeout", 10*time.Second, "Timeout of each connection and its operation")
+	tlsMatrixCmd.Flags().Bool("tls-skip-verify", false, "Skip TLS certificate verification")
+}
@@ -0,0 +1,372 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	runCmd.Flags().Duration("topology-watch", 0, "Poll the cluster topology (CLUSTER SHARDS, or CLUSTER SLOTS on older servers) at this interval, log every change and report its latency impact (0 = off)")
+	runCmd.Flags().Duration("topology-impact-window", 30*time.Second, "Time after each topology change attributed to it when reporting its latency impact")
+}
+
+// clusterSlots is the number of hash slots of a Redis Cluster
+const clusterSlots = 16384
+
+// topologySnapshot is the slot owners and node roles of a cluster at one poll
+type topologySnapshot struct {
+	owners [clusterSlots]string // Primary address of each slot ("" when unassigned)
+	nodes  map[string]string    // Address to role (primary or replica, with the health when not online)
+}
+
+// TopologyChange is one detected topology change and the results of the operations right after it
+type TopologyChange struct {
+	Time    time.Time `json:"time"`
+	Changes []string  `json:"changes"`
+	Ops     int64     `json:"ops"`
+	Errors  int64     `json:"errors"`
+	P50     int64     `json:"p50_us"`
+	P99     int64     `json:"p99_us"`
+	Max     int64     `json:"max_us"`
+
+	histogram *hdrhistogram.Histogram
+}
+
+// TopologyWatcher polls the cluster topology of the target during a run, to show what a
+// resharding, a failover or a node replacement costs the clients. Every change is logged as
+// it is detected and annotated in the report; operations within the impact window after a
+// change are attributed to it, the others form the baseline.
+type TopologyWatcher struct {
+	Interval    time.Duration
+	Window      time.Duration
+	PollsFailed int64
+
+	client      *RedisClient // Plain connection to the seed endpoint, outside the workload's pools
+	slotsOnly   bool         // CLUSTER SHARDS is unsupported, use CLUSTER SLOTS
+	current     *topologySnapshot
+	mutex       sync.Mutex
+	changes     []*TopologyChange
+	baseline    *hdrhistogram.Histogram
+	baselineOps int64
+	baselineErr int64
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// NewTopologyWatcher connects to the seed endpoint and reads the initial topology
+func NewTopologyWatcher(uri string, config RedisConfig, interval, window time.Duration) (*TopologyWatcher, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("topology watch interval must be positive, got: %v", interval)
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("topology impact window must be positive, got: %v", window)
+	}
+
+	config.ClusterMode = false
+	config.Reauthenticate = false
+	client, err := NewRedisClientFromURI(uri, config)
+	if err != nil {
+		return nil, err
+	}
+	tw := &TopologyWatcher{
+		Interval: interval,
+		Window:   window,
+		client:   client,
+		baseline: hdrhistogram.New(1, 60*1000*1000, 3),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	tw.current, err = tw.poll()
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to read the cluster topology (is the target a cluster?): %w", err)
+	}
+	return tw, nil
+}
+
+// poll reads the current topology
+func (tw *TopologyWatcher) poll() (*topologySnapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tw.Interval)
+	defer cancel()
+
+	snapshot := &topologySnapshot{nodes: make(map[string]string)}
+	if !tw.slotsOnly {
+		shards, err := tw.client.client.ClusterShards(ctx).Result()
+		if err == nil {
+			for _, shard := range shards {
+				var primary string
+				for _, node := range shard.Nodes {
+					port := node.Port
+					if port == 0 {
+						port = node.TLSPort
+					}
+					host := node.Endpoint
+					if host == "" || host == "?" {
+						host = node.IP
+					}
+					addr := fmt.Sprintf("%s:%d", host, port)
+					role := node.Role
+					if role == "master" {
+						role = "primary"
+					}
+					if node.Health != "" && node.Health != "online" {
+						role += ", " + node.Health
+					}
+					snapshot.nodes[addr] = role
+					if node.Role == "master" {
+						primary = addr
+					}
+				}
+				for _, slots := range shard.Slots {
+					for slot := slots.Start; slot <= slots.End && slot < clusterSlots; slot++ {
+						snapshot.owners[slot] = primary
+					}
+				}
+			}
+			return snapshot, nil
+		}
+		if !strings.Contains(strings.ToLower(err.Error()), "unknown") {
+			return nil, err
+		}
+		tw.slotsOnly = true
+	}
+
+	slots, err := tw.client.client.ClusterSlots(ctx).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, slot := range slots {
+		for i, node := range slot.Nodes {
+			if i == 0 {
+				snapshot.nodes[node.Addr] = "primary"
+			} else if _, ok := snapshot.nodes[node.Addr]; !ok {
+				snapshot.nodes[node.Addr] = "replica"
+			}
+		}
+		if len(slot.Nodes) == 0 {
+			continue
+		}
+		for s := slot.Start; s <= slot.End && s < clusterSlots; s++ {
+			snapshot.owners[s] = slot.Nodes[0].Addr
+		}
+	}
+	return snapshot, nil
+}
+
+// diffTopology describes the changes from one snapshot to the next
+func diffTopology(before, after *topologySnapshot) []string {
+	var changes []string
+
+	addrs := make([]string, 0, len(before.nodes)+len(after.nodes))
+	for addr := range before.nodes {
+		addrs = append(addrs, addr)
+	}
+	for addr := range after.nodes {
+		if _, ok := before.nodes[addr]; !ok {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Strings(addrs)
+	for _, addr := range addrs {
+		was, existed := before.nodes[addr]
+		is, exists := after.nodes[addr]
+		switch {
+		case !existed:
+			changes = append(changes, fmt.Sprintf("node %s added (%s)", addr, is))
+		case !exists:
+			changes = append(changes, fmt.Sprintf("node %s removed (was %s)", addr, was))
+		case was != is:
+			changes = append(changes, fmt.Sprintf("node %s: %s -> %s", addr, was, is))
+		}
+	}
+
+	// Slot moves are grouped by old and new owner
+	type move struct{ from, to string }
+	moved := make(map[move]int)
+	var order []move
+	for slot := 0; slot < clusterSlots; slot++ {
+		if before.owners[slot] == after.owners[slot] {
+			continue
+		}
+		m := move{before.owners[slot], after.owners[slot]}
+		if moved[m] == 0 {
+			order = append(order, m)
+		}
+		moved[m]++
+	}
+	for _, m := range order {
+		from, to := m.from, m.to
+		if from == "" {
+			from = "unassigned"
+		}
+		if to == "" {
+			to = "unassigned"
+		}
+		change := fmt.Sprintf("%d slots moved %s -> %s", moved[m], from, to)
+		if strings.HasPrefix(before.nodes[m.to], "replica") {
+			change += " (failover)"
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// Start polls the topology in the background until Stop
+func (tw *TopologyWatcher) Start() {
+	go func() {
+		defer close(tw.done)
+		ticker := time.NewTicker(tw.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				tw.check()
+			case <-tw.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the polling and closes the watcher's connection
+func (tw *TopologyWatcher) Stop() {
+	select {
+	case <-tw.stop:
+	default:
+		close(tw.stop)
+		<-tw.done
+		tw.client.Close()
+	}
+}
+
+// check polls the topology and records a change when it differs from the last one
+func (tw *TopologyWatcher) check() {
+	snapshot, err := tw.poll()
+	if err != nil {
+		tw.mutex.Lock()
+		tw.PollsFailed++
+		tw.mutex.Unlock()
+		log.Printf("Topology poll failed: %v", err)
+		return
+	}
+	changes := diffTopology(tw.current, snapshot)
+	tw.current = snapshot
+	if len(changes) == 0 {
+		return
+	}
+
+	log.Printf("Topology change: %s", strings.Join(changes, "; "))
+	tw.mutex.Lock()
+	tw.changes = append(tw.changes, &TopologyChange{
+		Time:      time.Now(),
+		Changes:   changes,
+		histogram: hdrhistogram.New(1, 60*1000*1000, 3),
+	})
+	tw.mutex.Unlock()
+}
+
+// Record attributes an operation to the topology change whose window it falls in, or to the baseline
+func (tw *TopologyWatcher) Record(result workloadResult) {
+	now := time.Now()
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+
+	if n := len(tw.changes); n > 0 && now.Sub(tw.changes[n-1].Time) < tw.Window {
+		change := tw.changes[n-1]
+		if result.isError {
+			change.Errors++
+		} else {
+			change.Ops++
+			change.histogram.RecordValue(result.latencyMicros)
+		}
+		return
+	}
+	if result.isError {
+		tw.baselineErr++
+	} else {
+		tw.baselineOps++
+		tw.baseline.RecordValue(result.latencyMicros)
+	}
+}
+
+// Changes returns the topology changes so far, with their latency, for the run summary
+func (tw *TopologyWatcher) Changes() []TopologyChange {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+	changes := make([]TopologyChange, 0, len(tw.changes))
+	for _, change := range tw.changes {
+		c := *change
+		c.P50 = change.histogram.ValueAtQuantile(50)
+		c.P99 = change.histogram.ValueAtQuantile(99)
+		c.Max = change.histogram.Max()
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+// validateTopologyWatch rejects --topology-watch for targets without a cluster topology
+func validateTopologyWatch(cmd *cobra.Command, cacheType string) error {
+	if interval, _ := cmd.Flags().GetDuration("topology-watch"); interval == 0 {
+		return nil
+	}
+	if cacheType != "redis" {
+		return fmt.Errorf("topology watching is only supported with redis, got: %s", cacheType)
+	}
+	return nil
+}
+
+// topologyWatcherFromFlags creates the watcher selected with --topology-watch (nil when not set)
+func topologyWatcherFromFlags(cmd *cobra.Command) (*TopologyWatcher, error) {
+	interval, _ := cmd.Flags().GetDuration("topology-watch")
+	if interval == 0 {
+		return nil, nil
+	}
+	window, _ := cmd.Flags().GetDuration("topology-impact-window")
+	uri, _ := cmd.Flags().GetString("redis-uri")
+	config, err := redisConfigFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return NewTopologyWatcher(uri, config, interval, window)
+}
+
+// printTopologyResults prints every topology change with the latency in the window after it
+func printTopologyResults(stats *WorkloadStats) {
+	tw := stats.Topology
+	changes := tw.Changes()
+
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+	fmt.Printf("Cluster Topology (polled every %v): %d changes, %d failed polls\n", tw.Interval, len(changes), tw.PollsFailed)
+	if len(changes) == 0 {
+		fmt.Println("No topology change during the run")
+		fmt.Println()
+		return
+	}
+
+	p99 := tw.baseline.ValueAtQuantile(99)
+	fmt.Printf("%-10s %-10s %-10s %-8s %-10s %-10s %-10s\n", "Change", "Time", "Ops", "Errors", "P50", "P99", "Max")
+	fmt.Printf("%-10s %-10s %-10d %-8d %-10d %-10d %-10d\n", "baseline", "-",
+		tw.baselineOps, tw.baselineErr, tw.baseline.ValueAtQuantile(50), p99, tw.baseline.Max())
+	for i, change := range changes {
+		fmt.Printf("%-10d %-10s %-10d %-8d %-10d %-10d %-10d",
+			i+1, change.Time.Format("15:04:05"), change.Ops, change.Errors, change.P50, change.P99, change.Max)
+		if p99 > 0 && change.Ops > 0 {
+			fmt.Printf(" (P99 %+.0f%%)", (float64(change.P99)/float64(p99)-1)*100)
+		}
+		fmt.Println()
+		for _, description := range change.Changes {
+			fmt.Printf("           %s\n", description)
+		}
+	}
+	fmt.Printf("(latencies in μs during the %v after each change)\n", tw.Window)
+	fmt.Println()
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// WorkloadConfig is the fully resolved workload of a run: everything that shapes the
+// generated traffic, but not the engine, endpoints or output options, so runs against
+// different backends with the same workload hash are directly comparable
+type WorkloadConfig struct {
+	Clients          int     `json:"clients"`
+	TestTime         int     `json:"test_time"`
+	RPS              int     `json:"rps"`
+	TrafficPattern   string  `json:"traffic_pattern"` // SHA-256 of the --traffic-pattern file
+	Ratio            string  `json:"ratio"`
+	KeyZipfExp       float64 `json:"key_zipf_exp"`
+	KeyPrefix        string  `json:"key_prefix"`
+	KeyCodec         string  `json:"key_codec,omitempty"` // Only when not numeric IDs with the default template
+	KeyTemplate      string  `json:"key_template,omitempty"`
+	KeyMinimum       int     `json:"key_minimum"`
+	KeyMaximum       int     `json:"key_maximum"`
+	DataSize         int     `json:"data_size"`
+	RandomData       bool    `json:"random_data"`
+	DefaultTTL       int     `json:"default_ttl"`
+	Timeout          int     `json:"timeout"`
+	NegativeGetRatio float64 `json:"negative_get_ratio"`
+	RMWRatio         float64 `json:"rmw_ratio"`
+	RMWKeys          int     `json:"rmw_keys"`
+	RMWOptimistic    bool    `json:"rmw_optimistic"`
+	RMWMaxAttempts   int     `json:"rmw_max_attempts,omitempty"` // With --rmw-optimistic
+	NoPool           bool    `json:"no_pool"`
+	TLSResumption    bool    `json:"tls_session_resumption"`
+	Tiered           bool    `json:"tiered"`
+	TieredL1Policy   string  `json:"tiered_l1_policy,omitempty"`
+	TieredL1Size     int     `json:"tiered_l1_size,omitempty"`
+	TieredL1MaxBytes int64   `json:"tiered_l1_max_bytes,omitempty"`
+	TieredL1TTL      int     `json:"tiered_l1_ttl,omitempty"`
+	TieredL1Scope    string  `json:"tiered_l1_scope,omitempty"`
+	TieredNegative   bool    `json:"tiered_negative_cache,omitempty"`
+	Rate             float64 `json:"rate"`
+	Ramp             string  `json:"ramp"`
+	Step             string  `json:"step"`
+	Sine             string  `json:"sine"`
+	Arrival          string  `json:"arrival"`
+	SoftStart        string  `json:"soft_start,omitempty"`   // Duration:from:max errors
+	Canary           int     `json:"canary,omitempty"`       // --canary-rate, with --canary
+	DeleteChurn      string  `json:"delete_churn,omitempty"` // Fraction:rate:start:clients
+	QueueDepth       int     `json:"queue_depth,omitempty"`
+	Rebalance        string  `json:"rebalance,omitempty"` // Interval:fraction
+	ReplayLog        string  `json:"replay_log"`          // SHA-256 of the replayed operation log
+	Databases        string  `json:"databases,omitempty"`
+	Chains           string  `json:"chains,omitempty"`   // SHA-256 of the --chains file
+	Services         string  `json:"services,omitempty"` // SHA-256 of the --services file
+	ChainRatio       float64 `json:"chain_ratio,omitempty"`
+	CommandMix       string  `json:"command_mix,omitempty"`      // Resolved table (GET 70.0%, ...)
+	FunctionLibrary  string  `json:"function_library,omitempty"` // SHA-256 of the --function-library file
+	RNG              string  `json:"rng,omitempty"`              // Only with a fixed --seed, as time-based seeds differ anyway
+	Seed             int64   `json:"seed,omitempty"`
+	RNGStreamOffset  int     `json:"rng_stream_offset,omitempty"`
+}
+
+// resolveWorkloadConfig captures the workload flags of a run; clients is the effective
+// client count (a replay overrides --clients)
+func resolveWorkloadConfig(cmd *cobra.Command, clients int) *WorkloadConfig {
+	flags := cmd.Flags()
+	config := &WorkloadConfig{Clients: clients}
+	config.TestTime, _ = flags.GetInt("test-time")
+	config.RPS, _ = flags.GetInt("rps")
+	if pattern, _ := flags.GetString("traffic-pattern"); pattern != "" {
+		config.TrafficPattern = fileSHA256(pattern)
+	}
+	config.Ratio, _ = flags.GetString("ratio")
+	config.KeyZipfExp, _ = flags.GetFloat64("key-zipf-exp")
+	config.KeyPrefix, _ = flags.GetString("key-prefix")
+	if format, err := keyFormatFromFlags(cmd); err == nil && !format.IsDefault() {
+		config.KeyCodec = format.Codec.Name()
+		config.KeyTemplate = format.Template
+	}
+	config.KeyMinimum, _ = flags.GetInt("key-minimum")
+	config.KeyMaximum, _ = flags.GetInt("key-maximum")
+	config.DataSize, _ = flags.GetInt("data-size")
+	config.RandomData, _ = flags.GetBool("random-data")
+	config.DefaultTTL, _ = flags.GetInt("default-ttl")
+	config.Timeout, _ = flags.GetInt("timeout")
+	config.NegativeGetRatio, _ = flags.GetFloat64("negative-get-ratio")
+	config.RMWRatio, _ = flags.GetFloat64("rmw-ratio")
+	config.RMWKeys, _ = flags.GetInt("rmw-keys")
+	if config.RMWOptimistic, _ = flags.GetBool("rmw-optimistic"); config.RMWOptimistic {
+		config.RMWMaxAttempts, _ = flags.GetInt("rmw-max-attempts")
+	}
+	config.NoPool, _ = flags.GetBool("no-pool")
+	config.TLSResumption, _ = flags.GetBool("tls-session-resumption")
+	config.Tiered, _ = flags.GetBool("tiered")
+	if config.Tiered {
+		config.TieredL1Policy, _ = flags.GetString("tiered-l1-policy")
+		config.TieredL1Size, _ = flags.GetInt("tiered-l1-size")
+		config.TieredL1MaxBytes, _ = flags.GetInt64("tiered-l1-max-bytes")
+		config.TieredL1TTL, _ = flags.GetInt("tiered-l1-ttl")
+		config.TieredL1Scope, _ = flags.GetString("tiered-l1-scope")
+		config.TieredNegative, _ = flags.GetBool("tiered-negative-cache")
+	}
+	config.Rate, _ = flags.GetFloat64("rate")
+	config.Ramp, _ = flags.GetString("ramp")
+	config.Step, _ = flags.GetString("step")
+	config.Sine, _ = flags.GetString("sine")
+	config.Arrival, _ = flags.GetString("arrival")
+	if softStart, _ := flags.GetDuration("soft-start"); softStart > 0 {
+		from, _ := flags.GetFloat64("soft-start-from")
+		maxErrors, _ := flags.GetFloat64("soft-start-max-errors")
+		config.SoftStart = fmt.Sprintf("%v:%g:%g", softStart, from, maxErrors)
+	}
+	if canary, _ := flags.GetBool("canary"); canary {
+		config.Canary, _ = flags.GetInt("canary-rate")
+	}
+	if fraction, _ := flags.GetFloat64("delete-fraction"); fraction > 0 {
+		deleteRate, _ := flags.GetInt("delete-rate")
+		start, _ := flags.GetDuration("delete-start")
+		clients, _ := flags.GetInt("delete-clients")
+		config.DeleteChurn = fmt.Sprintf("%g:%d:%v:%d", fraction, deleteRate, start, clients)
+	}
+	config.QueueDepth, _ = flags.GetInt("queue-depth")
+	if interval, _ := flags.GetDuration("rebalance-interval"); interval > 0 {
+		fraction, _ := flags.GetFloat64("rebalance-fraction")
+		config.Rebalance = fmt.Sprintf("%v:%g", interval, fraction)
+	}
+	config.Databases, _ = flags.GetString("db")
+	if services, _ := flags.GetString("services"); services != "" {
+		config.Services = fileSHA256(services)
+	}
+	if chains, _ := flags.GetString("chains"); chains != "" {
+		config.Chains = fileSHA256(chains)
+		config.ChainRatio, _ = flags.GetFloat64("chain-ratio")
+	}
+	if commandMix, _ := flags.GetString("command-mix"); commandMix != "" {
+		if mix, err := parseCommandMix(commandMix); err == nil {
+			config.CommandMix = mix.Describe()
+		}
+	}
+	if library, _ := flags.GetString("function-library"); library != "" {
+		config.FunctionLibrary = fileSHA256(library)
+	}
+	if config.Seed, _ = flags.GetInt64("seed"); config.Seed != 0 {
+		config.RNG, _ = flags.GetString("rng")
+		config.RNGStreamOffset, _ = flags.GetInt("rng-stream-offset")
+	}
+	if replaySelf, _ := flags.GetString("replay-self"); replaySelf != "" {
+		config.ReplayLog = fileSHA256(replaySelf)
+	}
+	return config
+}
+
+// fileSHA256 returns the hex SHA-256 of a file's content, or the path itself if it can't be read
+func fileSHA256(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return path
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return path
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Hash returns the SHA-256 of the canonical JSON encoding (fixed field order) of the workload
+func (c *WorkloadConfig) Hash() string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Diff returns the JSON names of the fields that differ between two workloads
+func (c *WorkloadConfig) Diff(other *WorkloadConfig) []string {
+	var fields []string
+	a, b := reflect.ValueOf(*c), reflect.ValueOf(*other)
+	for i := 0; i < a.NumField(); i++ {
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			name, _, _ := strings.Cut(a.Type().Field(i).Tag.Get("json"), ",")
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
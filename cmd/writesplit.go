@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+func init() {
+	runCmd.Flags().Bool("write-split", false, "Split SET stats into inserts (first write of a key in the run) and updates (overwrites), tracked client-side: keys that existed before the run count as inserts on their first write, keys that expired during it as updates")
+}
+
+// WriteSplit classifies SETs as inserts of new keys or updates of existing ones, with a
+// histogram each, since serverless engines may allocate for inserts but update in place.
+// Whether a key exists is tracked client-side with one bit per key ID written in the run.
+type WriteSplit struct {
+	keyMin  int
+	written []uint64
+
+	InsertOps    int64
+	InsertErrors int64
+	UpdateOps    int64
+	UpdateErrors int64
+	Inserts      *PerformanceStats
+	Updates      *PerformanceStats
+}
+
+func NewWriteSplit(keyMin, totalKeys int) *WriteSplit {
+	ws := &WriteSplit{
+		keyMin:  keyMin,
+		written: make([]uint64, (totalKeys+63)/64),
+		Inserts: NewPerformanceStats(),
+		Updates: NewPerformanceStats(),
+	}
+	ws.Inserts.Name = "SET_INSERT"
+	ws.Updates.Name = "SET_UPDATE"
+	return ws
+}
+
+func (ws *WriteSplit) Close() {
+	ws.Inserts.Close()
+	ws.Updates.Close()
+}
+
+// markWritten marks a key ID as written, reporting whether this write is its insert
+func (ws *WriteSplit) markWritten(keyID int) bool {
+	index := keyID - ws.keyMin
+	if index < 0 || index/64 >= len(ws.written) {
+		return false
+	}
+	bit := uint64(1) << (index % 64)
+	return atomic.OrUint64(&ws.written[index/64], bit)&bit == 0
+}
+
+// unmark forgets the insert of a key whose write failed, so the next write inserts it
+func (ws *WriteSplit) unmark(keyID int) {
+	index := keyID - ws.keyMin
+	atomic.AndUint64(&ws.written[index/64], ^(uint64(1) << (index % 64)))
+}
+
+// Record adds the result of a SET to the insert or update stats
+func (ws *WriteSplit) Record(result workloadResult) {
+	ops, errors, ps := &ws.UpdateOps, &ws.UpdateErrors, ws.Updates
+	if result.isInsert {
+		ops, errors, ps = &ws.InsertOps, &ws.InsertErrors, ws.Inserts
+	}
+	if result.isError {
+		atomic.AddInt64(errors, 1)
+		return
+	}
+	atomic.AddInt64(ops, 1)
+	ps.RecordLatency(result.latencyMicros)
+}
+
+// WriteSplitSummary is the machine-readable insert vs update split of the SETs
+type WriteSplitSummary struct {
+	Inserts LatencySummary `json:"inserts"`
+	Updates LatencySummary `json:"updates"`
+}
+
+// Summary returns the split over the measurement window
+func (ws *WriteSplit) Summary(seconds float64) *WriteSplitSummary {
+	return &WriteSplitSummary{
+		Inserts: summarizeTotals(ws.Inserts, atomic.LoadInt64(&ws.InsertOps), atomic.LoadInt64(&ws.InsertErrors), seconds),
+		Updates: summarizeTotals(ws.Updates, atomic.LoadInt64(&ws.UpdateOps), atomic.LoadInt64(&ws.UpdateErrors), seconds),
+	}
+}
+
+// printWriteSplitResults prints the latency of inserts next to that of updates
+func printWriteSplitResults(ws *WriteSplit) {
+	inserts, updates := atomic.LoadInt64(&ws.InsertOps), atomic.LoadInt64(&ws.UpdateOps)
+	fmt.Printf("SET Inserts vs Updates (%.1f%% inserts):\n", float64(inserts)/float64(max(inserts+updates, 1))*100)
+	fmt.Printf("Inserts - Ops: %d, Errors: %d, %s\n", inserts, atomic.LoadInt64(&ws.InsertErrors),
+		formatPercentiles(latencyPercentiles(ws.Inserts.Histogram)))
+	fmt.Printf("Updates - Ops: %d, Errors: %d, %s\n", updates, atomic.LoadInt64(&ws.UpdateErrors),
+		formatPercentiles(latencyPercentiles(ws.Updates.Histogram)))
+	fmt.Println()
+}
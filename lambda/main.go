@@ -0,0 +1,157 @@
+//go:build !noaws
+
+/*
+Copyright © 2025 Redis Performance Group  <performance <at> redis <dot> com>
+*/
+
+// Command lambda is an AWS Lambda handler that runs a short benchmark workload per
+// invocation from inside the Lambda runtime and stores the result summary in
+// DynamoDB and/or S3, so latencies can be measured from the actual runtime
+// environment at scale (many concurrent invocations, cold vs warm starts).
+//
+// Invocation event:
+//
+//	{
+//	  "args": ["--cache-type", "redis", "--redis-uri", "rediss://host:6379", "--test-time", "10"],
+//	  "report_table": "benchmark-runs",
+//	  "report_bucket": "my-results-bucket",
+//	  "report_prefix": "lambda-runs/",
+//	  "report_role_arn": "arn:aws:iam::111111111111:role/benchmark-results-writer"
+//	}
+//
+// report_table, report_bucket, report_prefix and report_role_arn default to the REPORT_TABLE,
+// REPORT_BUCKET, REPORT_PREFIX and REPORT_ROLE_ARN environment variables. The DynamoDB table
+// must use "run_id" (string) as its partition key. With report_role_arn, reports are written
+// with that role, e.g. into a central results account. Args that run shell commands on the
+// host (--pre-hook, --post-hook) are refused.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/redis-performance/serverless-cache-benchmark/cmd"
+)
+
+// invocationEvent is the payload the handler is invoked with
+type invocationEvent struct {
+	Args         []string `json:"args"`
+	ReportTable  string   `json:"report_table"`
+	ReportBucket string   `json:"report_bucket"`
+	ReportPrefix string   `json:"report_prefix"`
+	ReportRole   string   `json:"report_role_arn"`
+}
+
+// invocationReport is the per-invocation record written to DynamoDB/S3
+type invocationReport struct {
+	RunID           string          `json:"run_id"`
+	FunctionName    string          `json:"function_name"`
+	FunctionVersion string          `json:"function_version"`
+	MemoryLimitMB   int             `json:"memory_limit_mb"`
+	Region          string          `json:"region"`
+	ColdStart       bool            `json:"cold_start"`
+	Args            []string        `json:"args"`
+	Summary         *cmd.RunSummary `json:"summary"`
+}
+
+// Build metadata, linked in by the Makefile
+var (
+	Version   = ""
+	GitSHA1   = ""
+	GitDirty  = "0"
+	BuildDate = ""
+)
+
+// coldStart is true only for the first invocation of an execution environment
+var coldStart = true
+
+// defaultArgs keep a single invocation short, bound stats memory for small functions and
+// write the CSV log and the stats spill file to workDir, under the only writable location in
+// the Lambda runtime; event args override them
+func defaultArgs(workDir string) []string {
+	return []string{
+		"--test-time", "10",
+		"--quiet",
+		"--stats-lowmem",
+		"--stats-spill-dir", workDir,
+		"--csv-output", filepath.Join(workDir, "workload.csv"),
+	}
+}
+
+func handler(ctx context.Context, event invocationEvent) (*invocationReport, error) {
+	isColdStart := coldStart
+	coldStart = false
+
+	requestID := fmt.Sprintf("local-%d", time.Now().UnixNano())
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		requestID = lc.AwsRequestID
+	}
+
+	// Warm invocations share /tmp (512 MB by default), so every file of the run is removed
+	// once it's over, whether it succeeded or not
+	workDir := filepath.Join(os.TempDir(), "run-"+requestID)
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create the run directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	args := append(defaultArgs(workDir), event.Args...)
+	summary, err := cmd.RunRemoteWithArgs(args)
+	if err != nil {
+		return nil, fmt.Errorf("workload failed: %w", err)
+	}
+	if summary == nil {
+		return nil, fmt.Errorf("workload did not produce a summary (connection setup benchmarks are not supported)")
+	}
+
+	report := &invocationReport{
+		RunID:           requestID,
+		FunctionName:    lambdacontext.FunctionName,
+		FunctionVersion: lambdacontext.FunctionVersion,
+		MemoryLimitMB:   lambdacontext.MemoryLimitInMB,
+		Region:          os.Getenv("AWS_REGION"),
+		ColdStart:       isColdStart,
+		Args:            event.Args,
+		Summary:         summary,
+	}
+
+	roleARN := valueOrEnv(event.ReportRole, "REPORT_ROLE_ARN")
+	table := valueOrEnv(event.ReportTable, "REPORT_TABLE")
+	if table != "" {
+		if err := cmd.WriteReportToDynamoDB(ctx, "", roleARN, table, report); err != nil {
+			return nil, err
+		}
+	}
+
+	bucket := valueOrEnv(event.ReportBucket, "REPORT_BUCKET")
+	if bucket != "" {
+		key := valueOrEnv(event.ReportPrefix, "REPORT_PREFIX") + requestID + ".json"
+		if err := cmd.WriteReportToS3(ctx, "", roleARN, bucket, key, report); err != nil {
+			return nil, err
+		}
+	}
+
+	if table == "" && bucket == "" {
+		log.Printf("No report table or bucket configured, returning the report only")
+	}
+	return report, nil
+}
+
+// valueOrEnv returns value, or the environment variable when value is empty
+func valueOrEnv(value, env string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
+
+func main() {
+	cmd.SetBuildInfo(Version, GitSHA1, GitDirty, BuildDate)
+	lambda.Start(handler)
+}
@@ -5,6 +5,15 @@ package main
 
 import "github.com/redis-performance/serverless-cache-benchmark/cmd"
 
+// Build metadata, linked in by the Makefile
+var (
+	Version   = ""
+	GitSHA1   = ""
+	GitDirty  = "0"
+	BuildDate = ""
+)
+
 func main() {
+	cmd.SetBuildInfo(Version, GitSHA1, GitDirty, BuildDate)
 	cmd.Execute()
 }